@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +20,7 @@ import (
 	"github.com/tetratelabs/wazero/experimental/logging"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/sysfs"
 	"github.com/tetratelabs/wazero/internal/version"
 	"github.com/tetratelabs/wazero/sys"
 )
@@ -149,6 +151,21 @@ func doRun(args []string, stdOut io.Writer, stdErr logging.Writer, exit func(cod
 		"a comma-separated list of host function scopes to log to stderr. "+
 			"This may be specified multiple times. Supported values: all,clock,filesystem,memory,proc,poll,random")
 
+	var hostloggingFormat string
+	flags.StringVar(&hostloggingFormat, "hostlogging-format", "text",
+		"the format of host function logs. Supported values: text,json. "+
+			"json writes one object per host function call.")
+
+	var hostloggingTiming bool
+	flags.BoolVar(&hostloggingTiming, "hostlogging-timing", false,
+		"includes the duration of each host function call in text logs. json logs always include it.")
+
+	var hostloggingSample logSamplingFlag
+	flags.Var(&hostloggingSample, "hostlogging-sample",
+		"a comma-separated list of rules to sample text host function logs, implying -hostlogging-timing. "+
+			"1/N logs one of every N calls and a duration string such as \"10ms\" logs calls at least that slow. "+
+			"A call is logged if any rule selects it. The default logs all calls.")
+
 	cacheDir := cacheDirFlag(flags)
 
 	_ = flags.Parse(args)
@@ -204,7 +221,12 @@ func doRun(args []string, stdOut io.Writer, stdErr logging.Writer, exit func(cod
 		rtc = wazero.NewRuntimeConfig()
 	}
 
-	ctx := maybeHostLogging(context.Background(), logging.LogScopes(hostlogging), stdErr)
+	ctx := maybeHostLogging(context.Background(), hostLoggingConfig{
+		scopes:   logging.LogScopes(hostlogging),
+		format:   hostloggingFormat,
+		timed:    hostloggingTiming,
+		sampling: logging.LogSampling(hostloggingSample),
+	}, stdErr, exit)
 
 	if cache := maybeUseCacheDir(cacheDir, stdErr, exit); cache != nil {
 		rtc = rtc.WithCompilationCache(cache)
@@ -298,6 +320,8 @@ func doRun(args []string, stdOut io.Writer, stdErr logging.Writer, exit func(cod
 
 func validateMounts(mounts sliceFlag, stdErr logging.Writer, exit func(code int)) (rootPath string, config wazero.FSConfig) {
 	config = wazero.NewFSConfig()
+	var fss []sysfs.FS
+	var guestPaths []string
 	for _, mount := range mounts {
 		if len(mount) == 0 {
 			fmt.Fprintln(stdErr, "invalid mount: empty string")
@@ -344,11 +368,20 @@ func validateMounts(mounts sliceFlag, stdErr logging.Writer, exit func(code int)
 		} else {
 			config = config.WithDirMount(dir, guestPath)
 		}
+		fss = append(fss, sysfs.NewDirFS(dir))
+		guestPaths = append(guestPaths, guestPath)
 
 		if guestPath == "/" {
 			rootPath = dir
 		}
 	}
+
+	// Validate the guest paths together, as FSConfig would otherwise replace
+	// duplicates, and only fail on overlapping paths when instantiating.
+	if err := sysfs.ValidateMounts(fss, guestPaths); err != nil {
+		fmt.Fprintf(stdErr, "invalid mounts: %v\n", err)
+		exit(1)
+	}
 	return
 }
 
@@ -376,9 +409,37 @@ func detectImports(imports []api.FunctionDefinition) importMode {
 	return modeDefault
 }
 
-func maybeHostLogging(ctx context.Context, scopes logging.LogScopes, stdErr logging.Writer) context.Context {
-	if scopes != 0 {
-		return context.WithValue(ctx, experimental.FunctionListenerFactoryKey{}, logging.NewHostLoggingListenerFactory(stdErr, scopes))
+// hostLoggingConfig is the configuration of host function logging.
+type hostLoggingConfig struct {
+	scopes   logging.LogScopes
+	format   string
+	timed    bool
+	sampling logging.LogSampling
+}
+
+func maybeHostLogging(ctx context.Context, c hostLoggingConfig, stdErr logging.Writer, exit func(code int)) context.Context {
+	var factory experimental.FunctionListenerFactory
+	switch c.format {
+	case "text":
+		if c.sampling != (logging.LogSampling{}) {
+			factory = logging.NewSampledHostLoggingListenerFactory(stdErr, c.scopes, c.sampling)
+		} else if c.timed {
+			factory = logging.NewTimedHostLoggingListenerFactory(stdErr, c.scopes)
+		} else {
+			factory = logging.NewHostLoggingListenerFactory(stdErr, c.scopes)
+		}
+	case "json":
+		if c.sampling != (logging.LogSampling{}) {
+			fmt.Fprintln(stdErr, "invalid hostlogging-sample: only supported with hostlogging-format=text")
+			exit(1)
+		}
+		factory = logging.NewHostJSONLoggingListenerFactory(stdErr, c.scopes)
+	default:
+		fmt.Fprintf(stdErr, "invalid hostlogging-format: %s\n", c.format)
+		exit(1)
+	}
+	if c.scopes != 0 {
+		return context.WithValue(ctx, experimental.FunctionListenerFactoryKey{}, factory)
 	}
 	return ctx
 }
@@ -473,3 +534,35 @@ func (f *logScopesFlag) Set(input string) error {
 	}
 	return nil
 }
+
+type logSamplingFlag logging.LogSampling
+
+func (f *logSamplingFlag) String() string {
+	var rules []string
+	if f.Every > 0 {
+		rules = append(rules, "1/"+strconv.FormatUint(f.Every, 10))
+	}
+	if f.SlowerThan > 0 {
+		rules = append(rules, f.SlowerThan.String())
+	}
+	return strings.Join(rules, ",")
+}
+
+func (f *logSamplingFlag) Set(input string) error {
+	for _, s := range strings.Split(input, ",") {
+		if s == "" {
+			continue
+		} else if n := strings.TrimPrefix(s, "1/"); n != s {
+			every, err := strconv.ParseUint(n, 10, 64)
+			if err != nil || every == 0 {
+				return errors.New("not a sampling rate")
+			}
+			f.Every = every
+		} else if d, err := time.ParseDuration(s); err != nil || d <= 0 {
+			return errors.New("not a sampling rule")
+		} else {
+			f.SlowerThan = d
+		}
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental/logging"
@@ -331,9 +332,9 @@ func TestRun(t *testing.T) {
 <== (opened_fd=4,errno=ESUCCESS)
 ==> wasi_snapshot_preview1.fd_filestat_get(fd=4)
 <== (filestat={filetype=REGULAR_FILE,size=5,mtim=%d},errno=ESUCCESS)
-==> wasi_snapshot_preview1.fd_read(fd=4,iovs=64776,iovs_len=1)
+==> wasi_snapshot_preview1.fd_read(fd=4,iovs=64776,iovs_len=1,size=512)
 <== (nread=5,errno=ESUCCESS)
-==> wasi_snapshot_preview1.fd_read(fd=4,iovs=64776,iovs_len=1)
+==> wasi_snapshot_preview1.fd_read(fd=4,iovs=64776,iovs_len=1,size=507)
 <== (nread=0,errno=ESUCCESS)
 ==> wasi_snapshot_preview1.fd_close(fd=4)
 <== errno=ESUCCESS
@@ -563,6 +564,14 @@ func TestRun_Errors(t *testing.T) {
 			message: "invalid mount", // not found
 			args:    []string{"--mount=te", "testdata/wasi_env.wasm"},
 		},
+		{
+			message: "invalid mounts: duplicate guest path",
+			args:    []string{"--mount=.:/animals", "--mount=testdata:/animals/", "testdata/wasi_env.wasm"},
+		},
+		{
+			message: "invalid mounts: only single-level guest paths allowed",
+			args:    []string{"--mount=.:/animals", "--mount=testdata:/animals/bears", "testdata/wasi_env.wasm"},
+		},
 		{
 			message: "invalid cachedir",
 			args:    []string{"--cachedir", notWasmPath, wasmPath},
@@ -571,6 +580,14 @@ func TestRun_Errors(t *testing.T) {
 			message: "timeout duration may not be negative",
 			args:    []string{"-timeout=-10s", wasmPath},
 		},
+		{
+			message: "invalid hostlogging-sample",
+			args:    []string{"--hostlogging=filesystem", "--hostlogging-format=json", "--hostlogging-sample=1/2", wasmPath},
+		},
+		{
+			message: "invalid hostlogging-format",
+			args:    []string{"--hostlogging=filesystem", "--hostlogging-format=xml", wasmPath},
+		},
 	}
 
 	for _, tc := range tests {
@@ -725,6 +742,79 @@ func Test_logScopesFlag(t *testing.T) {
 	}
 }
 
+func Test_logSamplingFlag(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      []string
+		expected    logging.LogSampling
+		expectedErr string
+	}{
+		{
+			name:     "defaults to none",
+			expected: logging.LogSampling{},
+		},
+		{
+			name:     "ignores empty",
+			values:   []string{""},
+			expected: logging.LogSampling{},
+		},
+		{
+			name:     "rate",
+			values:   []string{"1/100"},
+			expected: logging.LogSampling{Every: 100},
+		},
+		{
+			name:     "duration",
+			values:   []string{"10ms"},
+			expected: logging.LogSampling{SlowerThan: 10 * time.Millisecond},
+		},
+		{
+			name:     "rate,duration",
+			values:   []string{"1/100,10ms"},
+			expected: logging.LogSampling{Every: 100, SlowerThan: 10 * time.Millisecond},
+		},
+		{
+			name:     "rate duration",
+			values:   []string{"1/100", "10ms"},
+			expected: logging.LogSampling{Every: 100, SlowerThan: 10 * time.Millisecond},
+		},
+		{
+			name:        "zero rate",
+			values:      []string{"1/0"},
+			expectedErr: "not a sampling rate",
+		},
+		{
+			name:        "invalid rate",
+			values:      []string{"1/a"},
+			expectedErr: "not a sampling rate",
+		},
+		{
+			name:        "invalid rule",
+			values:      []string{"2/100"},
+			expectedErr: "not a sampling rule",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var f logSamplingFlag
+			var err error
+			for _, v := range tc.values {
+				if err = f.Set(v); err != nil {
+					break
+				}
+			}
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, logging.LogSampling(f))
+			}
+		})
+	}
+}
+
 func TestHelp(t *testing.T) {
 	exitCode, _, stderr := runMain(t, "", []string{"-h"})
 	require.Equal(t, 0, exitCode)
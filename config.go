@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/tetratelabs/wazero/api"
+	experimentalapi "github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/engine/compiler"
 	"github.com/tetratelabs/wazero/internal/engine/interpreter"
 	"github.com/tetratelabs/wazero/internal/filecache"
@@ -775,6 +776,27 @@ func (c *moduleConfig) WithRandSource(source io.Reader) ModuleConfig {
 	return ret
 }
 
+// stdioBufferSize is the buffer size of stdout and stderr when buffered,
+// which is the same as BUFSIZ in glibc.
+const stdioBufferSize = 8192
+
+// withStdioBuffering returns a copy of this config whose stdout and stderr are
+// buffered according to `mode`.
+func (c *moduleConfig) withStdioBuffering(mode experimentalapi.StdioBuffering) *moduleConfig {
+	if mode == experimentalapi.StdioUnbuffered {
+		return c
+	}
+	ret := c.clone()
+	flushOnNewline := mode == experimentalapi.StdioLineBuffered
+	if ret.stdout != nil {
+		ret.stdout = internalsys.NewBufferedWriter(ret.stdout, stdioBufferSize, flushOnNewline)
+	}
+	if ret.stderr != nil {
+		ret.stderr = internalsys.NewBufferedWriter(ret.stderr, stdioBufferSize, flushOnNewline)
+	}
+	return ret
+}
+
 // toSysContext creates a baseline wasm.Context configured by ModuleConfig.
 func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
 	var environ [][]byte // Intentionally doesn't pre-allocate to reduce logic to default to nil.
@@ -1,6 +1,7 @@
 package wazero
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	_ "embed"
@@ -9,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	experimentalapi "github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/fstest"
 	"github.com/tetratelabs/wazero/internal/platform"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
@@ -587,6 +589,72 @@ func TestModuleConfig_toSysContext_WithOsyield(t *testing.T) {
 	require.True(t, yielded)
 }
 
+func TestModuleConfig_toSysContext_withStdioBuffering(t *testing.T) {
+	tests := []struct {
+		name                 string
+		mode                 experimentalapi.StdioBuffering
+		expectedAfterWrite   string
+		expectedAfterNewline string
+		expectedAfterClose   string
+	}{
+		{
+			name:                 "unbuffered",
+			mode:                 experimentalapi.StdioUnbuffered,
+			expectedAfterWrite:   "wa",
+			expectedAfterNewline: "wazero\n",
+			expectedAfterClose:   "wazero\n",
+		},
+		{
+			name:                 "line buffered",
+			mode:                 experimentalapi.StdioLineBuffered,
+			expectedAfterWrite:   "",
+			expectedAfterNewline: "wazero\n",
+			expectedAfterClose:   "wazero\n",
+		},
+		{
+			name:                 "fully buffered",
+			mode:                 experimentalapi.StdioFullyBuffered,
+			expectedAfterWrite:   "",
+			expectedAfterNewline: "",
+			expectedAfterClose:   "wazero\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			sysCtx, err := NewModuleConfig().WithStdout(&stdout).WithStderr(&stderr).(*moduleConfig).
+				withStdioBuffering(tc.mode).toSysContext()
+			require.NoError(t, err)
+			fsc := sysCtx.FS()
+
+			for _, fd := range []uint32{internalsys.FdStdout, internalsys.FdStderr} {
+				f, ok := fsc.LookupFile(fd)
+				require.True(t, ok)
+				w := f.File.(io.Writer)
+				_, err = w.Write([]byte("wa"))
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.expectedAfterWrite, stdout.String())
+			require.Equal(t, tc.expectedAfterWrite, stderr.String())
+
+			for _, fd := range []uint32{internalsys.FdStdout, internalsys.FdStderr} {
+				f, _ := fsc.LookupFile(fd)
+				_, err = f.File.(io.Writer).Write([]byte("zero\n"))
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.expectedAfterNewline, stdout.String())
+			require.Equal(t, tc.expectedAfterNewline, stderr.String())
+
+			require.NoError(t, fsc.Close(testCtx))
+			require.Equal(t, tc.expectedAfterClose, stdout.String())
+			require.Equal(t, tc.expectedAfterClose, stderr.String())
+		})
+	}
+}
+
 func TestModuleConfig_toSysContext_Errors(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -618,6 +686,11 @@ func TestModuleConfig_toSysContext_Errors(t *testing.T) {
 			input:       NewModuleConfig().WithEnv("", "a"),
 			expectedErr: "environ invalid: empty key",
 		},
+		{
+			name:        "WithFSConfig nested guest path",
+			input:       NewModuleConfig().WithFSConfig(NewFSConfig().WithDirMount(".", "/").WithDirMount(".", "/usr/bin")),
+			expectedErr: "only single-level guest paths allowed: [.:/ .:/usr/bin]",
+		},
 	}
 	for _, tt := range tests {
 		tc := tt
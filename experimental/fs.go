@@ -0,0 +1,46 @@
+package experimental
+
+// FileModeKey is a context.Context Value key. Its associated value should be
+// a fs.FileMode, whose permission bits are requested for files created by
+// modules instantiated with that context. WASI path_open has no parameter for
+// this, so it otherwise defaults to 0o666. UmaskKey applies to the result.
+//
+// For example:
+//
+//	ctx = context.WithValue(ctx, experimental.FileModeKey{}, fs.FileMode(0o644))
+//	mod, _ := r.InstantiateModule(ctx, compiled, config)
+type FileModeKey struct{}
+
+// UmaskKey is a context.Context Value key. Its associated value should be a
+// fs.FileMode, whose permission bits are cleared from files and directories
+// created by modules instantiated with that context. This defaults to 0o077,
+// so only the owner has access.
+//
+// For example, to allow others to read created files:
+//
+//	ctx = context.WithValue(ctx, experimental.UmaskKey{}, fs.FileMode(0o022))
+//	mod, _ := r.InstantiateModule(ctx, compiled, config)
+//
+// Note: The host process umask still applies.
+type UmaskKey struct{}
+
+// MaxIOSizeKey is a context.Context Value key. Its associated value should be
+// a uint32, which is the maximum count of bytes processed by a single read or
+// write call, such as fd_read, by modules instantiated with that context.
+// Calls for more are short reads or writes. Zero, the default, is unlimited.
+//
+// For example, to limit the memory a host file reads at once:
+//
+//	ctx = context.WithValue(ctx, experimental.MaxIOSizeKey{}, uint32(1<<20))
+//	mod, _ := r.InstantiateModule(ctx, compiled, config)
+type MaxIOSizeKey struct{}
+
+// StrictIovecsKey is a context.Context Value key. Its associated value should
+// be a bool, which when true fails reads into overlapping buffers, such as
+// fd_read with iovecs that share memory, with EINVAL. This defaults to false.
+//
+// For example:
+//
+//	ctx = context.WithValue(ctx, experimental.StrictIovecsKey{}, true)
+//	mod, _ := r.InstantiateModule(ctx, compiled, config)
+type StrictIovecsKey struct{}
@@ -0,0 +1,166 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/logging"
+)
+
+// NewHostJSONLoggingListenerFactory is like NewHostLoggingListenerFactory,
+// except it writes one JSON object per line for each completed call, for
+// consumption by tools instead of people.
+//
+// For example, a call to "fd_write" is written like this:
+//
+//	{"module":"wasi_snapshot_preview1","function":"fd_write","params":{"fd":"4","iovs":"1","iovs_len":"2","size":"6"},"results":{"nwritten":"6"},"errno":"ESUCCESS","duration_ns":1500}
+//
+// The fields are:
+//   - "module" and "function": the name of the function called.
+//   - "params": each parameter, decoded the same as the text format.
+//   - "results": each result, decoded the same as the text format, except
+//     the WASI errno which is written to "errno".
+//   - "errno": the WASI errno name, only present for WASI functions.
+//   - "error": the error message if the call failed, in which case there are
+//     no "results".
+//   - "duration_ns": the wall-clock duration of the call in nanoseconds.
+//
+// Parameters and results are keyed by name, or by index if unnamed, and all
+// values are strings.
+func NewHostJSONLoggingListenerFactory(w Writer, scopes logging.LogScopes) experimental.FunctionListenerFactory {
+	return &jsonLoggingListenerFactory{loggingListenerFactory{w: toInternalWriter(w), hostOnly: true, scopes: scopes}}
+}
+
+type jsonLoggingListenerFactory struct {
+	loggingListenerFactory
+}
+
+// NewListener implements the same method as documented on
+// experimental.FunctionListener.
+func (f *jsonLoggingListenerFactory) NewListener(fnd api.FunctionDefinition) experimental.FunctionListener {
+	pSampler, pLoggers, rLoggers, ok := f.config(fnd)
+	if !ok {
+		return nil
+	}
+	return &jsonLoggingListener{
+		w:        f.w,
+		prefix:   `{"module":` + jsonString(fnd.ModuleName()) + `,"function":` + jsonString(fnd.Name()),
+		pLoggers: pLoggers,
+		pSampler: pSampler,
+		rLoggers: rLoggers,
+	}
+}
+
+// jsonLogState saves a copy of params between calls as the slice underlying
+// them is a stack reused for results.
+type jsonLogState struct {
+	l      *jsonLoggingListener
+	start  time.Time
+	params []uint64
+}
+
+// jsonLoggerKey is a context.Context Value key with a *jsonLogState value.
+type jsonLoggerKey struct{}
+
+// jsonLoggingListener implements experimental.FunctionListener to log each
+// function call as a JSON object, after it completes.
+type jsonLoggingListener struct {
+	w        logging.Writer
+	prefix   string
+	pLoggers []logging.ParamLogger
+	pSampler logging.ParamSampler
+	rLoggers []logging.ResultLogger
+}
+
+// Before saves the params and start time of the call for After.
+func (l *jsonLoggingListener) Before(ctx context.Context, mod api.Module, _ api.FunctionDefinition, params []uint64) context.Context {
+	if s := l.pSampler; s != nil && !s(ctx, mod, params) {
+		return ctx
+	}
+
+	ls := &jsonLogState{l: l, params: make([]uint64, len(params))}
+	copy(ls.params, params) // safe copy
+	ls.start = time.Now()
+	return context.WithValue(ctx, jsonLoggerKey{}, ls)
+}
+
+// After logs the call as a single line of JSON.
+func (l *jsonLoggingListener) After(ctx context.Context, mod api.Module, _ api.FunctionDefinition, err error, results []uint64) {
+	state, ok := ctx.Value(jsonLoggerKey{}).(*jsonLogState)
+	if !ok || state.l != l { // Before skipped this call.
+		return
+	}
+	duration := time.Since(state.start)
+
+	var buf bytes.Buffer
+	var errno string
+	buf.WriteString(l.prefix)
+	buf.WriteString(`,"params":{`)
+	for i, pLogger := range l.pLoggers {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeJSONField(&buf, i, func(w logging.Writer) { pLogger(ctx, mod, w, state.params) })
+	}
+	buf.WriteByte('}')
+
+	if err != nil {
+		buf.WriteString(`,"error":`)
+		buf.WriteString(jsonString(err.Error()))
+	} else {
+		buf.WriteString(`,"results":{`)
+		n := 0
+		for i, rLogger := range l.rLoggers {
+			var field bytes.Buffer
+			rLogger(ctx, mod, &field, state.params, results)
+			if s := field.String(); strings.HasPrefix(s, "errno=") {
+				errno = s[len("errno="):]
+				continue
+			}
+			if n > 0 {
+				buf.WriteByte(',')
+			}
+			n++
+			writeJSONField(&buf, i, func(w logging.Writer) { w.Write(field.Bytes()) }) //nolint
+		}
+		buf.WriteByte('}')
+		if errno != "" {
+			buf.WriteString(`,"errno":`)
+			buf.WriteString(jsonString(errno))
+		}
+	}
+
+	buf.WriteString(`,"duration_ns":`)
+	buf.WriteString(strconv.FormatInt(int64(duration), 10))
+	buf.WriteString("}\n")
+	l.w.Write(buf.Bytes()) //nolint
+
+	if f, ok := l.w.(flusher); ok {
+		f.Flush() //nolint
+	}
+}
+
+// writeJSONField writes the output of `log` as a JSON field. Output in the
+// form "name=value" is keyed by name, otherwise it is keyed by `idx`.
+func writeJSONField(buf *bytes.Buffer, idx int, log func(logging.Writer)) {
+	var field bytes.Buffer
+	log(&field)
+	key, value, ok := strings.Cut(field.String(), "=")
+	if !ok {
+		key, value = strconv.Itoa(idx), key
+	}
+	buf.WriteString(jsonString(key))
+	buf.WriteByte(':')
+	buf.WriteString(jsonString(value))
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s) // strings always marshal.
+	return string(b)
+}
@@ -0,0 +1,146 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/logging"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	wasi "github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func Test_jsonLoggingListener(t *testing.T) {
+	tests := []struct {
+		name                    string
+		moduleName, funcName    string
+		functype                wasm.FunctionType
+		paramNames, resultNames []string
+		params, results         []uint64
+		err                     error
+		expected                map[string]interface{}
+	}{
+		{
+			name:       "wasi",
+			moduleName: wasi.InternalModuleName,
+			funcName:   wasi.RandomGetName,
+			functype: wasm.FunctionType{
+				Params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32},
+				Results: []api.ValueType{api.ValueTypeI32},
+			},
+			paramNames:  []string{"buf", "buf_len"},
+			resultNames: []string{"errno"},
+			params:      []uint64{0, 8},
+			results:     []uint64{uint64(wasi.ErrnoSuccess)},
+			expected: map[string]interface{}{
+				"module":   "wasi_snapshot_preview1",
+				"function": "random_get",
+				"params":   map[string]interface{}{"buf": "0", "buf_len": "8"},
+				"results":  map[string]interface{}{},
+				"errno":    "ESUCCESS",
+			},
+		},
+		{
+			name:       "wasi errno",
+			moduleName: wasi.InternalModuleName,
+			funcName:   wasi.FdCloseName,
+			functype: wasm.FunctionType{
+				Params:  []api.ValueType{api.ValueTypeI32},
+				Results: []api.ValueType{api.ValueTypeI32},
+			},
+			paramNames:  []string{"fd"},
+			resultNames: []string{"errno"},
+			params:      []uint64{42},
+			results:     []uint64{uint64(wasi.ErrnoBadf)},
+			expected: map[string]interface{}{
+				"module":   "wasi_snapshot_preview1",
+				"function": "fd_close",
+				"params":   map[string]interface{}{"fd": "42"},
+				"results":  map[string]interface{}{},
+				"errno":    "EBADF",
+			},
+		},
+		{
+			name:       "error",
+			moduleName: wasi.InternalModuleName,
+			funcName:   wasi.FdCloseName,
+			functype: wasm.FunctionType{
+				Params:  []api.ValueType{api.ValueTypeI32},
+				Results: []api.ValueType{api.ValueTypeI32},
+			},
+			paramNames:  []string{"fd"},
+			resultNames: []string{"errno"},
+			params:      []uint64{42},
+			err:         io.EOF,
+			expected: map[string]interface{}{
+				"module":   "wasi_snapshot_preview1",
+				"function": "fd_close",
+				"params":   map[string]interface{}{"fd": "42"},
+				"error":    "EOF",
+			},
+		},
+		{
+			name:       "unnamed",
+			moduleName: "test",
+			funcName:   "fn",
+			functype: wasm.FunctionType{
+				Params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI64},
+				Results: []api.ValueType{api.ValueTypeI32},
+			},
+			params:  []uint64{1, 2},
+			results: []uint64{3},
+			expected: map[string]interface{}{
+				"module":   "test",
+				"function": "fn",
+				"params":   map[string]interface{}{"0": "1", "1": "2"},
+				"results":  map[string]interface{}{"0": "3"},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	lf := logging.NewHostJSONLoggingListenerFactory(&out, logging.LogScopeAll)
+	fn := func() {}
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &wasm.Module{
+				TypeSection:     []wasm.FunctionType{tc.functype},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{wasm.MustParseGoReflectFuncCode(fn)},
+				NameSection: &wasm.NameSection{
+					ModuleName:    tc.moduleName,
+					FunctionNames: wasm.NameMap{{Name: tc.funcName}},
+					LocalNames:    wasm.IndirectNameMap{{NameMap: toNameMap(tc.paramNames)}},
+					ResultNames:   wasm.IndirectNameMap{{NameMap: toNameMap(tc.resultNames)}},
+				},
+			}
+			m.BuildFunctionDefinitions()
+			def := &m.FunctionDefinitionSection[0]
+			l := lf.NewListener(def)
+
+			out.Reset()
+			ctx := l.Before(testCtx, nil, def, tc.params)
+			l.After(ctx, nil, def, tc.err, tc.results)
+
+			// Each call is exactly one line of JSON.
+			line, err := out.ReadBytes('\n')
+			require.NoError(t, err)
+			require.Zero(t, out.Len())
+
+			var actual map[string]interface{}
+			require.NoError(t, json.Unmarshal(line, &actual))
+
+			// The duration isn't deterministic, so only check its type.
+			duration, ok := actual["duration_ns"].(float64)
+			require.True(t, ok)
+			require.True(t, duration >= 0)
+			delete(actual, "duration_ns")
+
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
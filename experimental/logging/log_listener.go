@@ -2,8 +2,11 @@ package logging
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"io"
+	"sync/atomic"
+	"time"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental"
@@ -76,6 +79,62 @@ func NewHostLoggingListenerFactory(w Writer, scopes logging.LogScopes) experimen
 	return &loggingListenerFactory{w: toInternalWriter(w), hostOnly: true, scopes: scopes}
 }
 
+// NewTimedHostLoggingListenerFactory is like NewHostLoggingListenerFactory,
+// except it also logs the wall-clock duration of each call. This helps
+// identify slow host functions, such as filesystem operations on a slow
+// backend.
+//
+// For example:
+//
+//	==> wasi_snapshot_preview1.fd_read(fd=4,iovs=1,iovs_len=1,size=512)
+//	<== (nread=5,errno=ESUCCESS) duration=1.5ms
+func NewTimedHostLoggingListenerFactory(w Writer, scopes logging.LogScopes) experimental.FunctionListenerFactory {
+	return &loggingListenerFactory{w: toInternalWriter(w), hostOnly: true, scopes: scopes, timed: true}
+}
+
+// LogSampling reduces the calls logged by NewSampledHostLoggingListenerFactory,
+// to lower the noise and overhead of logging high-frequency calls.
+//
+// When both fields are set, a call is logged if either selects it. When
+// neither are set, all calls are logged.
+type LogSampling struct {
+	// Every logs one of every N calls, starting with the first. Zero doesn't
+	// sample calls by count.
+	Every uint64
+
+	// SlowerThan logs any call whose duration is at least this long, even if
+	// not selected by Every. Zero doesn't sample calls by duration.
+	//
+	// Note: The "==>" line of these calls is only written once they complete.
+	SlowerThan time.Duration
+}
+
+// NewSampledHostLoggingListenerFactory is like
+// NewTimedHostLoggingListenerFactory, except only calls selected by
+// `sampling` are logged.
+func NewSampledHostLoggingListenerFactory(w Writer, scopes logging.LogScopes, sampling LogSampling) experimental.FunctionListenerFactory {
+	f := &loggingListenerFactory{w: toInternalWriter(w), hostOnly: true, scopes: scopes, timed: true}
+	if sampling.Every > 1 || sampling.SlowerThan > 0 {
+		f.sampling = &logSampler{LogSampling: sampling}
+	}
+	return f
+}
+
+// logSampler is shared by each listener of a factory, so that Every counts
+// calls to all functions.
+type logSampler struct {
+	LogSampling
+	calls uint64
+}
+
+// sample returns true if the current call is selected by count.
+func (s *logSampler) sample() bool {
+	if s.Every == 0 {
+		return false
+	}
+	return (atomic.AddUint64(&s.calls, 1)-1)%s.Every == 0
+}
+
 func toInternalWriter(w Writer) logging.Writer {
 	if w, ok := w.(logging.Writer); ok {
 		return w
@@ -87,6 +146,8 @@ type loggingListenerFactory struct {
 	w        logging.Writer
 	hostOnly bool
 	scopes   logging.LogScopes
+	timed    bool
+	sampling *logSampler
 }
 
 type flusher interface {
@@ -96,25 +157,50 @@ type flusher interface {
 // NewListener implements the same method as documented on
 // experimental.FunctionListener.
 func (f *loggingListenerFactory) NewListener(fnd api.FunctionDefinition) experimental.FunctionListener {
+	pSampler, pLoggers, rLoggers, ok := f.config(fnd)
+	if !ok {
+		return nil
+	}
+
+	var before, after string
+	if fnd.GoFunction() != nil {
+		before = "==> " + fnd.DebugName()
+		after = "<=="
+	} else {
+		before = "--> " + fnd.DebugName()
+		after = "<--"
+	}
+	return &loggingListener{
+		w:            f.w,
+		beforePrefix: before,
+		afterPrefix:  after,
+		pLoggers:     pLoggers,
+		pSampler:     pSampler,
+		rLoggers:     rLoggers,
+		timed:        f.timed,
+		sampling:     f.sampling,
+	}
+}
+
+// config returns the loggers of the function, or false if it shouldn't be
+// logged.
+func (f *loggingListenerFactory) config(fnd api.FunctionDefinition) (pSampler logging.ParamSampler, pLoggers []logging.ParamLogger, rLoggers []logging.ResultLogger, ok bool) {
 	exported := len(fnd.ExportNames()) > 0
 	if f.hostOnly && // choose functions defined or callable by the host
 		fnd.GoFunction() == nil && // not defined by the host
 		!exported { // not callable by the host
-		return nil
+		return
 	}
 
-	var pLoggers []logging.ParamLogger
-	var pSampler logging.ParamSampler
-	var rLoggers []logging.ResultLogger
 	switch fnd.ModuleName() {
 	case wasip1.InternalModuleName:
 		if !wasilogging.IsInLogScope(fnd, f.scopes) {
-			return nil
+			return
 		}
 		pSampler, pLoggers, rLoggers = wasilogging.Config(fnd)
 	case "go":
 		if !gologging.IsInLogScope(fnd, f.scopes) {
-			return nil
+			return
 		}
 		pSampler, pLoggers, rLoggers = gologging.Config(fnd, f.scopes)
 	case "env":
@@ -124,45 +210,36 @@ func (f *loggingListenerFactory) NewListener(fnd api.FunctionDefinition) experim
 		switch fnd.Name() {
 		case "emscripten_notify_memory_growth":
 			if !logging.LogScopeMemory.IsEnabled(f.scopes) {
-				return nil
+				return
 			}
 		default:
 			if !aslogging.IsInLogScope(fnd, f.scopes) {
-				return nil
+				return
 			}
 		}
 	default:
 		// We don't know the scope of the function, so compare against all.
 		if f.scopes != logging.LogScopeAll {
-			return nil
+			return
 		}
 		pLoggers, rLoggers = logging.Config(fnd)
 	}
-
-	var before, after string
-	if fnd.GoFunction() != nil {
-		before = "==> " + fnd.DebugName()
-		after = "<=="
-	} else {
-		before = "--> " + fnd.DebugName()
-		after = "<--"
-	}
-	return &loggingListener{
-		w:            f.w,
-		beforePrefix: before,
-		afterPrefix:  after,
-		pLoggers:     pLoggers,
-		pSampler:     pSampler,
-		rLoggers:     rLoggers,
-	}
+	ok = true
+	return
 }
 
 // logState saves a copy of params between calls as the slice underlying them
 // is a stack reused for results.
 type logState struct {
-	w         logging.Writer
+	// deferred holds the "before" line of a call only logged if it is slow.
+	deferred  *bytes.Buffer
 	nestLevel int
 	params    []uint64
+	// start is when the call began, if the listener is timed.
+	start time.Time
+	// skipped is true when the call isn't logged. nestLevel is then that of
+	// the caller, so that calls it makes are indented the same.
+	skipped bool
 }
 
 // loggingListener implements experimental.FunctionListener to log entrance and after
@@ -173,30 +250,45 @@ type loggingListener struct {
 	pLoggers                  []logging.ParamLogger
 	pSampler                  logging.ParamSampler
 	rLoggers                  []logging.ResultLogger
+	timed                     bool
+	sampling                  *logSampler
 }
 
 // Before logs to stdout the module and function name, prefixed with '-->' and
 // indented based on the call nesting level.
 func (l *loggingListener) Before(ctx context.Context, mod api.Module, _ api.FunctionDefinition, params []uint64) context.Context {
-	if s := l.pSampler; s != nil && !s(ctx, mod, params) {
-		return ctx
-	}
-
 	var nestLevel int
 	if ls := ctx.Value(logging.LoggerKey{}); ls != nil {
 		nestLevel = ls.(*logState).nestLevel
 	}
+
+	// Mark skipped calls, so that After doesn't log the state of the caller.
+	if s := l.pSampler; s != nil && !s(ctx, mod, params) {
+		return context.WithValue(ctx, logging.LoggerKey{}, &logState{nestLevel: nestLevel, skipped: true})
+	}
 	nestLevel++
 
-	l.logIndented(ctx, mod, nestLevel, true, params, nil, nil)
+	ls := &logState{nestLevel: nestLevel}
+	if s := l.sampling; s != nil && !s.sample() {
+		if s.SlowerThan == 0 {
+			return context.WithValue(ctx, logging.LoggerKey{}, &logState{nestLevel: nestLevel - 1, skipped: true})
+		}
+		// Defer the decision until After, when the duration is known.
+		ls.deferred = &bytes.Buffer{}
+		l.logIndented(ctx, mod, ls.deferred, nestLevel, true, params, nil, nil, 0)
+	} else {
+		l.logIndented(ctx, mod, l.w, nestLevel, true, params, nil, nil, 0)
+	}
 
-	ls := &logState{w: l.w, nestLevel: nestLevel}
 	if pLen := len(params); pLen > 0 {
 		ls.params = make([]uint64, pLen)
 		copy(ls.params, params) // safe copy
 	} else { // empty
 		ls.params = params
 	}
+	if l.timed {
+		ls.start = time.Now()
+	}
 
 	// Increase the next nesting level.
 	return context.WithValue(ctx, logging.LoggerKey{}, ls)
@@ -207,64 +299,78 @@ func (l *loggingListener) Before(ctx context.Context, mod api.Module, _ api.Func
 func (l *loggingListener) After(ctx context.Context, mod api.Module, _ api.FunctionDefinition, err error, results []uint64) {
 	// Note: We use the nest level directly even though it is the "next" nesting level.
 	// This works because our indent of zero nesting is one tab.
-	if state, ok := ctx.Value(logging.LoggerKey{}).(*logState); ok {
-		l.logIndented(ctx, mod, state.nestLevel, false, state.params, err, results)
+	if state, ok := ctx.Value(logging.LoggerKey{}).(*logState); ok && !state.skipped {
+		var duration time.Duration
+		if l.timed {
+			duration = time.Since(state.start)
+		}
+		if state.deferred != nil {
+			if duration < l.sampling.SlowerThan {
+				return
+			}
+			l.w.Write(state.deferred.Bytes()) //nolint
+		}
+		l.logIndented(ctx, mod, l.w, state.nestLevel, false, state.params, err, results, duration)
 	}
 }
 
-// logIndented logs an indented l.w like this: "-->\t\t\t$nestLevel$funcName\n"
-func (l *loggingListener) logIndented(ctx context.Context, mod api.Module, nestLevel int, isBefore bool, params []uint64, err error, results []uint64) {
+// logIndented logs an indented w like this: "-->\t\t\t$nestLevel$funcName\n"
+func (l *loggingListener) logIndented(ctx context.Context, mod api.Module, w logging.Writer, nestLevel int, isBefore bool, params []uint64, err error, results []uint64, duration time.Duration) {
 	for i := 1; i < nestLevel; i++ {
-		l.w.WriteByte('\t') //nolint
+		w.WriteByte('\t') //nolint
 	}
 	if isBefore { // before
-		l.w.WriteString(l.beforePrefix) //nolint
-		l.logParams(ctx, mod, params)
+		w.WriteString(l.beforePrefix) //nolint
+		l.logParams(ctx, mod, w, params)
 	} else { // after
-		l.w.WriteString(l.afterPrefix) //nolint
+		w.WriteString(l.afterPrefix) //nolint
 		if err != nil {
-			l.w.WriteString(" error: ")  //nolint
-			l.w.WriteString(err.Error()) //nolint
+			w.WriteString(" error: ")  //nolint
+			w.WriteString(err.Error()) //nolint
 		} else {
-			l.logResults(ctx, mod, params, results)
+			l.logResults(ctx, mod, w, params, results)
+		}
+		if l.timed {
+			w.WriteString(" duration=")      //nolint
+			w.WriteString(duration.String()) //nolint
 		}
 	}
-	l.w.WriteByte('\n') //nolint
+	w.WriteByte('\n') //nolint
 
-	if f, ok := l.w.(flusher); ok {
+	if f, ok := w.(flusher); ok {
 		f.Flush() //nolint
 	}
 }
 
-func (l *loggingListener) logParams(ctx context.Context, mod api.Module, params []uint64) {
+func (l *loggingListener) logParams(ctx context.Context, mod api.Module, w logging.Writer, params []uint64) {
 	paramLen := len(l.pLoggers)
-	l.w.WriteByte('(') //nolint
+	w.WriteByte('(') //nolint
 	if paramLen > 0 {
-		l.pLoggers[0](ctx, mod, l.w, params)
+		l.pLoggers[0](ctx, mod, w, params)
 		for i := 1; i < paramLen; i++ {
-			l.w.WriteByte(',') //nolint
-			l.pLoggers[i](ctx, mod, l.w, params)
+			w.WriteByte(',') //nolint
+			l.pLoggers[i](ctx, mod, w, params)
 		}
 	}
-	l.w.WriteByte(')') //nolint
+	w.WriteByte(')') //nolint
 }
 
-func (l *loggingListener) logResults(ctx context.Context, mod api.Module, params, results []uint64) {
+func (l *loggingListener) logResults(ctx context.Context, mod api.Module, w logging.Writer, params, results []uint64) {
 	resultLen := len(l.rLoggers)
 	if resultLen == 0 {
 		return
 	}
-	l.w.WriteByte(' ') //nolint
+	w.WriteByte(' ') //nolint
 	switch resultLen {
 	case 1:
-		l.rLoggers[0](ctx, mod, l.w, params, results)
+		l.rLoggers[0](ctx, mod, w, params, results)
 	default:
-		l.w.WriteByte('(') //nolint
-		l.rLoggers[0](ctx, mod, l.w, params, results)
+		w.WriteByte('(') //nolint
+		l.rLoggers[0](ctx, mod, w, params, results)
 		for i := 1; i < resultLen; i++ {
-			l.w.WriteByte(',') //nolint
-			l.rLoggers[i](ctx, mod, l.w, params, results)
+			w.WriteByte(',') //nolint
+			l.rLoggers[i](ctx, mod, w, params, results)
 		}
-		l.w.WriteByte(')') //nolint
+		w.WriteByte(')') //nolint
 	}
 }
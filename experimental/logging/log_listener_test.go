@@ -5,9 +5,13 @@ import (
 	"context"
 	"io"
 	"math"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/experimental/logging"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	wasi "github.com/tetratelabs/wazero/internal/wasip1"
@@ -347,3 +351,152 @@ func Test_loggingListener_indentation(t *testing.T) {
 <--
 `, out.String())
 }
+
+func Test_loggingListener_timed(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	lf := logging.NewTimedHostLoggingListenerFactory(out, logging.LogScopeFilesystem)
+	m := &wasm.Module{
+		TypeSection: []wasm.FunctionType{{
+			Params:  []api.ValueType{api.ValueTypeI32},
+			Results: []api.ValueType{api.ValueTypeI32},
+		}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{wasm.MustParseGoReflectFuncCode(func() {})},
+		NameSection: &wasm.NameSection{
+			ModuleName:    wasi.InternalModuleName,
+			FunctionNames: wasm.NameMap{{Name: wasi.FdSyncName}},
+			LocalNames:    wasm.IndirectNameMap{{NameMap: toNameMap([]string{"fd"})}},
+			ResultNames:   wasm.IndirectNameMap{{NameMap: toNameMap([]string{"errno"})}},
+		},
+	}
+	m.BuildFunctionDefinitions()
+	def := &m.FunctionDefinitionSection[0]
+	l := lf.NewListener(def)
+
+	// Simulate a slow backend by delaying between the start and end of the call.
+	delay := 20 * time.Millisecond
+	ctx := l.Before(testCtx, nil, def, []uint64{4})
+	time.Sleep(delay)
+	l.After(ctx, nil, def, nil, []uint64{uint64(wasi.ErrnoSuccess)})
+
+	lines := strings.Split(out.String(), "\n")
+	require.Equal(t, 3, len(lines))
+	require.Equal(t, "==> wasi_snapshot_preview1.fd_sync(fd=4)", lines[0])
+
+	prefix := "<== errno=ESUCCESS duration="
+	require.True(t, strings.HasPrefix(lines[1], prefix), lines[1])
+	duration, err := time.ParseDuration(lines[1][len(prefix):])
+	require.NoError(t, err)
+	require.True(t, duration >= delay, duration.String())
+}
+
+func Test_loggingListener_sampled(t *testing.T) {
+	m := &wasm.Module{
+		TypeSection: []wasm.FunctionType{{
+			Params:  []api.ValueType{api.ValueTypeI32},
+			Results: []api.ValueType{api.ValueTypeI32},
+		}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{wasm.MustParseGoReflectFuncCode(func() {})},
+		NameSection: &wasm.NameSection{
+			ModuleName:    wasi.InternalModuleName,
+			FunctionNames: wasm.NameMap{{Name: wasi.FdSyncName}},
+			LocalNames:    wasm.IndirectNameMap{{NameMap: toNameMap([]string{"fd"})}},
+			ResultNames:   wasm.IndirectNameMap{{NameMap: toNameMap([]string{"errno"})}},
+		},
+	}
+	m.BuildFunctionDefinitions()
+	def := &m.FunctionDefinitionSection[0]
+
+	// call simulates a call to fd_sync on the given fd, which takes at least
+	// the given delay.
+	call := func(l experimental.FunctionListener, fd uint64, delay time.Duration) {
+		ctx := l.Before(testCtx, nil, def, []uint64{fd})
+		time.Sleep(delay)
+		l.After(ctx, nil, def, nil, []uint64{uint64(wasi.ErrnoSuccess)})
+	}
+
+	t.Run("every", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		lf := logging.NewSampledHostLoggingListenerFactory(out, logging.LogScopeFilesystem, logging.LogSampling{Every: 10})
+		l := lf.NewListener(def)
+
+		for i := 0; i < 100; i++ {
+			call(l, uint64(i), 0)
+		}
+
+		// Each call logs a line before and after.
+		require.Equal(t, 2*10, strings.Count(out.String(), "\n"))
+		require.True(t, strings.HasPrefix(out.String(), "==> wasi_snapshot_preview1.fd_sync(fd=0)\n"), out.String())
+		require.Contains(t, out.String(), "==> wasi_snapshot_preview1.fd_sync(fd=90)\n")
+	})
+
+	t.Run("slower than", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		lf := logging.NewSampledHostLoggingListenerFactory(out, logging.LogScopeFilesystem, logging.LogSampling{SlowerThan: 20 * time.Millisecond})
+		l := lf.NewListener(def)
+
+		call(l, 1, 0)
+		call(l, 2, 20*time.Millisecond)
+		call(l, 3, 0)
+
+		lines := strings.Split(out.String(), "\n")
+		require.Equal(t, 3, len(lines), out.String())
+		require.Equal(t, "==> wasi_snapshot_preview1.fd_sync(fd=2)", lines[0])
+		require.True(t, strings.HasPrefix(lines[1], "<== errno=ESUCCESS duration="), lines[1])
+	})
+
+	t.Run("every or slower than", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		lf := logging.NewSampledHostLoggingListenerFactory(out, logging.LogScopeFilesystem, logging.LogSampling{Every: 2, SlowerThan: 20 * time.Millisecond})
+		l := lf.NewListener(def)
+
+		call(l, 1, 0)                   // sampled by count
+		call(l, 2, 0)                   // skipped
+		call(l, 3, 0)                   // sampled by count
+		call(l, 4, 20*time.Millisecond) // sampled by duration
+
+		require.Equal(t, 2*3, strings.Count(out.String(), "\n"))
+		require.False(t, strings.Contains(out.String(), "fd=2"))
+		require.Contains(t, out.String(), "fd=4")
+	})
+}
+
+// Test_loggingListener_sampledNested ensures a nested call which isn't sampled
+// logs nothing, instead of the result of its caller.
+func Test_loggingListener_sampledNested(t *testing.T) {
+	m := &wasm.Module{
+		TypeSection: []wasm.FunctionType{{
+			Params:  []api.ValueType{api.ValueTypeI32},
+			Results: []api.ValueType{api.ValueTypeI32},
+		}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection:     []wasm.Code{wasm.MustParseGoReflectFuncCode(func() {}), wasm.MustParseGoReflectFuncCode(func() {})},
+		NameSection: &wasm.NameSection{
+			ModuleName:    wasi.InternalModuleName,
+			FunctionNames: wasm.NameMap{{Index: 0, Name: wasi.FdSyncName}, {Index: 1, Name: wasi.FdDatasyncName}},
+			LocalNames:    wasm.IndirectNameMap{{Index: 0, NameMap: toNameMap([]string{"fd"})}, {Index: 1, NameMap: toNameMap([]string{"fd"})}},
+			ResultNames:   wasm.IndirectNameMap{{Index: 0, NameMap: toNameMap([]string{"errno"})}, {Index: 1, NameMap: toNameMap([]string{"errno"})}},
+		},
+	}
+	m.BuildFunctionDefinitions()
+	def1, def2 := &m.FunctionDefinitionSection[0], &m.FunctionDefinitionSection[1]
+
+	out := bytes.NewBuffer(nil)
+	lf := logging.NewSampledHostLoggingListenerFactory(out, logging.LogScopeFilesystem, logging.LogSampling{Every: 2})
+	l1, l2 := lf.NewListener(def1), lf.NewListener(def2)
+
+	ctx := l1.Before(testCtx, nil, def1, []uint64{1}) // sampled by count
+	ctx2 := l2.Before(ctx, nil, def2, []uint64{2})    // skipped
+	ctx3 := l1.Before(ctx2, nil, def1, []uint64{3})   // sampled by count
+	l1.After(ctx3, nil, def1, nil, []uint64{uint64(wasi.ErrnoSuccess)})
+	l2.After(ctx2, nil, def2, nil, []uint64{uint64(wasi.ErrnoSuccess)})
+	l1.After(ctx, nil, def1, nil, []uint64{uint64(wasi.ErrnoSuccess)})
+
+	// Sampled calls are timed, so ignore durations.
+	require.Equal(t, `==> wasi_snapshot_preview1.fd_sync(fd=1)
+	==> wasi_snapshot_preview1.fd_sync(fd=3)
+	<== errno=ESUCCESS
+<== errno=ESUCCESS
+`, regexp.MustCompile(` duration=\S+`).ReplaceAllString(out.String(), ""))
+}
@@ -0,0 +1,34 @@
+package experimental
+
+// StdioBufferingKey is a context.Context Value key. Its associated value
+// should be a StdioBuffering, which applies to the stdout and stderr of
+// modules instantiated with that context.
+//
+// For example:
+//
+//	ctx = context.WithValue(ctx, experimental.StdioBufferingKey{}, experimental.StdioLineBuffered)
+//	mod, _ := r.InstantiateModule(ctx, compiled, config)
+type StdioBufferingKey struct{}
+
+// StdioBuffering is the buffering mode of stdout and stderr, similar to
+// setvbuf in C stdio.
+//
+// Buffering reduces the count of writes to the host io.Writer when a module
+// writes in small pieces, such as a character at a time. Any buffered data is
+// written on fd_sync or when the module is closed.
+//
+// See https://linux.die.net/man/3/setvbuf
+type StdioBuffering uint8
+
+const (
+	// StdioUnbuffered writes each write from the module immediately. This is
+	// the default.
+	StdioUnbuffered StdioBuffering = iota
+
+	// StdioLineBuffered buffers writes until a newline is written or the
+	// buffer is full.
+	StdioLineBuffered
+
+	// StdioFullyBuffered buffers writes until the buffer is full.
+	StdioFullyBuffered
+)
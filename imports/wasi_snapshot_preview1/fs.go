@@ -8,6 +8,7 @@ import (
 	"math"
 	"path"
 	"reflect"
+	"sort"
 	"strings"
 	"syscall"
 	"unsafe"
@@ -22,8 +23,6 @@ import (
 
 // The following interfaces are used until we finalize our own FD-scoped file.
 type (
-	// syncFile is implemented by os.File in file_posix.go
-	syncFile interface{ Sync() error }
 	// truncateFile is implemented by os.File in file_posix.go
 	truncateFile interface{ Truncate(size int64) error }
 )
@@ -40,35 +39,39 @@ var fdAdvise = newHostFunc(
 
 func fdAdviseFn(_ context.Context, mod api.Module, params []uint64) syscall.Errno {
 	fd := uint32(params[0])
-	_ = params[1]
-	_ = params[2]
+	offset := int64(params[1])
+	length := int64(params[2])
 	advice := byte(params[3])
 	fsc := mod.(*wasm.CallContext).Sys.FS()
 
-	_, ok := fsc.LookupFile(fd)
+	f, ok := fsc.LookupFile(fd)
 	if !ok {
 		return syscall.EBADF
 	}
 
+	var a platform.Advice
 	switch advice {
-	case wasip1.FdAdviceNormal,
-		wasip1.FdAdviceSequential,
-		wasip1.FdAdviceRandom,
-		wasip1.FdAdviceWillNeed,
-		wasip1.FdAdviceDontNeed,
-		wasip1.FdAdviceNoReuse:
+	case wasip1.FdAdviceNormal:
+		a = platform.AdviceNormal
+	case wasip1.FdAdviceSequential:
+		a = platform.AdviceSequential
+	case wasip1.FdAdviceRandom:
+		a = platform.AdviceRandom
+	case wasip1.FdAdviceWillNeed:
+		a = platform.AdviceWillNeed
+	case wasip1.FdAdviceDontNeed:
+		a = platform.AdviceDontNeed
+	case wasip1.FdAdviceNoReuse:
+		a = platform.AdviceNoReuse
 	default:
 		return syscall.EINVAL
 	}
 
-	// FdAdvice corresponds to posix_fadvise, but it can only be supported on linux.
-	// However, the purpose of the call is just to do best-effort optimization on OS kernels,
-	// so just making this noop rather than returning NoSup error makes sense and doesn't affect
-	// the semantics of Wasm applications.
-	// TODO: invoke posix_fadvise on linux, and partially on darwin.
-	// - https://gitlab.com/cznic/fileutil/-/blob/v1.1.2/fileutil_linux.go#L87-95
-	// - https://github.com/bytecodealliance/system-interface/blob/62b97f9776b86235f318c3a6e308395a1187439b/src/fs/file_io_ext.rs#L430-L442
-	return 0
+	// FdAdvice corresponds to posix_fadvise, which is only supported on linux.
+	// As it is a best-effort optimization, platform.Fadvise ignores it
+	// elsewhere, rather than returning ENOSYS, and also ignores files the host
+	// doesn't advise, such as pipes.
+	return platform.Fadvise(f.File, offset, length, a)
 }
 
 // fdAllocate is the WASI function named FdAllocateName which forces the
@@ -83,8 +86,8 @@ var fdAllocate = newHostFunc(
 
 func fdAllocateFn(_ context.Context, mod api.Module, params []uint64) syscall.Errno {
 	fd := uint32(params[0])
-	offset := params[1]
-	length := params[2]
+	offset := int64(params[1])
+	length := int64(params[2])
 
 	fsc := mod.(*wasm.CallContext).Sys.FS()
 	f, ok := fsc.LookupFile(fd)
@@ -92,27 +95,7 @@ func fdAllocateFn(_ context.Context, mod api.Module, params []uint64) syscall.Er
 		return syscall.EBADF
 	}
 
-	tail := int64(offset + length)
-	if tail < 0 {
-		return syscall.EINVAL
-	}
-
-	st, err := f.Stat()
-	if err != nil {
-		return platform.UnwrapOSError(err)
-	}
-
-	if st.Size >= tail {
-		// We already have enough space.
-		return 0
-	}
-
-	osf, ok := f.File.(truncateFile)
-	if !ok {
-		return syscall.EBADF
-	}
-
-	return platform.UnwrapOSError(osf.Truncate(tail))
+	return platform.Fallocate(f.File, offset, length)
 }
 
 // fdClose is the WASI function named FdCloseName which closes a file
@@ -217,12 +200,11 @@ func fdFdstatGetFn(_ context.Context, mod api.Module, params []uint64) syscall.E
 		return syscall.EBADF
 	} else if st, err = f.File.Stat(); err != nil {
 		return platform.UnwrapOSError(err)
-	} else if _, ok := f.File.(io.Writer); ok {
-		// TODO: maybe cache flags to open instead
-		fdflags = wasip1.FD_APPEND
+	} else {
+		fdflags = fdflagsOf(f)
 	}
 
-	filetype := getWasiFiletype(st.Mode())
+	filetype := wasip1.ToFiletype(st.Mode())
 	writeFdstat(buf, filetype, fdflags)
 
 	return 0
@@ -235,6 +217,28 @@ var blockFdstat = []byte{
 	0, 0, 0, 0, 0, 0, 0, 0, // fs_rights_inheriting
 }
 
+// fdflagsOf returns the fdflags of the file, from the flags it was opened
+// with, or changed to by fd_fdstat_set_flags.
+func fdflagsOf(f *sys.FileEntry) (fdflags uint16) {
+	if f.IsAppend() {
+		fdflags |= wasip1.FD_APPEND
+	}
+	if f.IsNonblock() {
+		fdflags |= wasip1.FD_NONBLOCK
+	}
+	flag := f.OpenFlag()
+	if flag&platform.O_DSYNC != 0 {
+		fdflags |= wasip1.FD_DSYNC
+	}
+	if flag&platform.O_RSYNC != 0 {
+		fdflags |= wasip1.FD_RSYNC
+	}
+	if flag&syscall.O_SYNC == syscall.O_SYNC {
+		fdflags |= wasip1.FD_SYNC
+	}
+	return
+}
+
 func writeFdstat(buf []byte, filetype uint8, fdflags uint16) {
 	// memory is re-used, so ensure the result is defaulted.
 	copy(buf, blockFdstat)
@@ -250,14 +254,17 @@ func fdFdstatSetFlagsFn(_ context.Context, mod api.Module, params []uint64) sysc
 	fd, wasiFlag := uint32(params[0]), uint16(params[1])
 	fsc := mod.(*wasm.CallContext).Sys.FS()
 
-	// We can only support APPEND flag.
-	if wasip1.FD_DSYNC&wasiFlag != 0 || wasip1.FD_NONBLOCK&wasiFlag != 0 || wasip1.FD_RSYNC&wasiFlag != 0 || wasip1.FD_SYNC&wasiFlag != 0 {
+	// We can only support APPEND and NONBLOCK flags.
+	if wasip1.FD_DSYNC&wasiFlag != 0 || wasip1.FD_RSYNC&wasiFlag != 0 || wasip1.FD_SYNC&wasiFlag != 0 {
 		return syscall.EINVAL
 	}
 
 	var flag int
 	if wasip1.FD_APPEND&wasiFlag != 0 {
-		flag = syscall.O_APPEND
+		flag |= syscall.O_APPEND
+	}
+	if wasip1.FD_NONBLOCK&wasiFlag != 0 {
+		flag |= platform.O_NONBLOCK
 	}
 
 	return fsc.ChangeOpenFlag(fd, flag)
@@ -349,30 +356,10 @@ func fdFilestatGetFunc(mod api.Module, fd, resultBuf uint32) syscall.Errno {
 	return writeFilestat(buf, &st)
 }
 
-func getWasiFiletype(fm fs.FileMode) uint8 {
-	switch {
-	case fm.IsRegular():
-		return wasip1.FILETYPE_REGULAR_FILE
-	case fm.IsDir():
-		return wasip1.FILETYPE_DIRECTORY
-	case fm&fs.ModeSymlink != 0:
-		return wasip1.FILETYPE_SYMBOLIC_LINK
-	case fm&fs.ModeDevice != 0:
-		// Unlike ModeDevice and ModeCharDevice, FILETYPE_CHARACTER_DEVICE and
-		// FILETYPE_BLOCK_DEVICE are set mutually exclusively.
-		if fm&fs.ModeCharDevice != 0 {
-			return wasip1.FILETYPE_CHARACTER_DEVICE
-		}
-		return wasip1.FILETYPE_BLOCK_DEVICE
-	default: // unknown
-		return wasip1.FILETYPE_UNKNOWN
-	}
-}
-
 func writeFilestat(buf []byte, st *platform.Stat_t) (errno syscall.Errno) {
 	le.PutUint64(buf, st.Dev)
 	le.PutUint64(buf[8:], st.Ino)
-	le.PutUint64(buf[16:], uint64(getWasiFiletype(st.Mode)))
+	le.PutUint64(buf[16:], uint64(wasip1.ToFiletype(st.Mode)))
 	le.PutUint64(buf[24:], st.Nlink)
 	le.PutUint64(buf[32:], uint64(st.Size))
 	le.PutUint64(buf[40:], uint64(st.Atim))
@@ -384,21 +371,31 @@ func writeFilestat(buf []byte, st *platform.Stat_t) (errno syscall.Errno) {
 // fdFilestatSetSize is the WASI function named FdFilestatSetSizeName which
 // adjusts the size of an open file.
 //
+// The file offset is unchanged, even if beyond the new size, so a subsequent
+// write leaves a hole of zeros, the same as POSIX ftruncate.
+//
+// This returns syscall.EPERM when the file was opened without write access,
+// and syscall.EINVAL when the size is larger than an int64.
+//
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fd_filestat_set_sizefd-fd-size-filesize---errno
 var fdFilestatSetSize = newHostFunc(wasip1.FdFilestatSetSizeName, fdFilestatSetSizeFn, []wasm.ValueType{i32, i64}, "fd", "size")
 
 func fdFilestatSetSizeFn(_ context.Context, mod api.Module, params []uint64) syscall.Errno {
 	fd := uint32(params[0])
-	size := uint32(params[1])
+	size := int64(params[1])
 
 	fsc := mod.(*wasm.CallContext).Sys.FS()
 
 	// Check to see if the file descriptor is available
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return syscall.EBADF
+	} else if f.IsReadOnly() {
+		return syscall.EPERM
+	} else if size < 0 {
+		return syscall.EINVAL
 	} else if truncateFile, ok := f.File.(truncateFile); !ok {
 		return syscall.EBADF // possibly a fake file
-	} else if err := truncateFile.Truncate(int64(size)); err != nil {
+	} else if err := truncateFile.Truncate(size); err != nil {
 		return platform.UnwrapOSError(err)
 	}
 	return 0
@@ -600,7 +597,9 @@ func fdPrestatDirNameFn(_ context.Context, mod api.Module, params []uint64) sysc
 // fdPwrite is the WASI function named FdPwriteName which writes to a file
 // descriptor, without using and updating the file descriptor's offset.
 //
-// Except for handling offset, this implementation is identical to fdWrite.
+// Except for handling offset, this implementation is identical to fdWrite, but
+// a file opened read-only fails with syscall.EPERM, the same as
+// fdFilestatSetSize.
 //
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fd_pwritefd-fd-iovs-ciovec_array-offset-filesize---errno-size
 var fdPwrite = newHostFunc(
@@ -703,10 +702,23 @@ func fdReadOrPread(mod api.Module, params []uint64, isPread bool) syscall.Errno
 	if !ok {
 		return syscall.EFAULT
 	}
+	if fsc.StrictIovecs() && iovecsOverlap(iovsBuf) {
+		return syscall.EINVAL
+	}
 
+	maxIOSize := fsc.MaxIOSize()
 	for iovsPos := uint32(0); iovsPos < iovsStop; iovsPos += 8 {
 		offset := le.Uint32(iovsBuf[iovsPos:])
 		l := le.Uint32(iovsBuf[iovsPos+4:])
+		if l == 0 {
+			continue // zero-length reads don't reach the file.
+		}
+		if maxIOSize != 0 && l > maxIOSize-nread {
+			// Cap the total, resulting in a short read.
+			if l = maxIOSize - nread; l == 0 {
+				break
+			}
+		}
 
 		b, ok := mem.Read(offset, l)
 		if !ok {
@@ -730,6 +742,30 @@ func fdReadOrPread(mod api.Module, params []uint64, isPread bool) syscall.Errno
 	}
 }
 
+// iovecsOverlap returns true if any of the non-empty iovecs encoded in
+// `iovsBuf` share memory.
+func iovecsOverlap(iovsBuf []byte) bool {
+	type span struct{ start, end uint64 }
+	spans := make([]span, 0, len(iovsBuf)>>3)
+	for pos := 0; pos < len(iovsBuf); pos += 8 {
+		start := uint64(le.Uint32(iovsBuf[pos:]))
+		if l := uint64(le.Uint32(iovsBuf[pos+4:])); l != 0 {
+			spans = append(spans, span{start, start + l})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var end uint64 // the furthest end of the spans before i
+	for i, s := range spans {
+		if i > 0 && s.start < end {
+			return true
+		}
+		if s.end > end {
+			end = s.end
+		}
+	}
+	return false
+}
+
 // fdRead_shouldContinueRead decides whether to continue reading the next iovec
 // based on the amount read (n/l) and a possible error returned from io.Reader.
 //
@@ -1034,7 +1070,7 @@ func writeDirent(buf []byte, dNext uint64, ino uint64, dNamlen uint32, dType fs.
 	le.PutUint64(buf, dNext)        // d_next
 	le.PutUint64(buf[8:], ino)      // d_ino
 	le.PutUint32(buf[16:], dNamlen) // d_namlen
-	filetype := getWasiFiletype(dType)
+	filetype := wasip1.ToFiletype(dType)
 	le.PutUint32(buf[20:], uint32(filetype)) //  d_type
 }
 
@@ -1169,12 +1205,9 @@ func fdSyncFn(_ context.Context, mod api.Module, params []uint64) syscall.Errno
 	// Check to see if the file descriptor is available
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return syscall.EBADF
-	} else if syncFile, ok := f.File.(syncFile); !ok {
-		return syscall.EBADF // possibly a fake file
-	} else if err := syncFile.Sync(); err != nil {
-		return platform.UnwrapOSError(err)
+	} else {
+		return sysfs.FileSync(f.File)
 	}
-	return 0
 }
 
 // fdTell is the WASI function named FdTellName which returns the current
@@ -1274,6 +1307,11 @@ func fdWriteOrPwrite(mod api.Module, params []uint64, isPwrite bool) syscall.Err
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return syscall.EBADF
 	} else if isPwrite {
+		if f.IsReadOnly() {
+			return syscall.EPERM
+		} else if _, ok = f.File.(io.Writer); !ok {
+			return syscall.EBADF // possibly a fake file
+		}
 		offset := int64(params[3])
 		writer = sysfs.WriterAtOffset(f.File, offset)
 		resultNwritten = uint32(params[4])
@@ -1291,9 +1329,19 @@ func fdWriteOrPwrite(mod api.Module, params []uint64, isPwrite bool) syscall.Err
 		return syscall.EFAULT
 	}
 
+	maxIOSize := fsc.MaxIOSize()
 	for iovsPos := uint32(0); iovsPos < iovsStop; iovsPos += 8 {
 		offset := le.Uint32(iovsBuf[iovsPos:])
 		l := le.Uint32(iovsBuf[iovsPos+4:])
+		if l == 0 {
+			continue // zero-length writes don't reach the file.
+		}
+		if maxIOSize != 0 && l > maxIOSize-nwritten {
+			// Cap the total, resulting in a short write.
+			if l = maxIOSize - nwritten; l == 0 {
+				break
+			}
+		}
 
 		var n int
 		if writer == io.Discard { // special-case default
@@ -1304,11 +1352,16 @@ func fdWriteOrPwrite(mod api.Module, params []uint64, isPwrite bool) syscall.Err
 				return syscall.EFAULT
 			}
 			n, err = writer.Write(b)
-			if err != nil {
-				return platform.UnwrapOSError(err)
-			}
 		}
 		nwritten += uint32(n)
+		if err != nil {
+			// Report bytes written before the error, so the guest can retry
+			// only the rest.
+			if nwritten > 0 && !mem.WriteUint32Le(resultNwritten, nwritten) {
+				return syscall.EFAULT
+			}
+			return platform.UnwrapOSError(err)
+		}
 	}
 
 	if !mod.Memory().WriteUint32Le(resultNwritten, nwritten) {
@@ -1356,7 +1409,7 @@ func pathCreateDirectoryFn(_ context.Context, mod api.Module, params []uint64) s
 		return errno
 	}
 
-	if errno = preopen.Mkdir(pathName, 0o700); errno != 0 {
+	if errno = preopen.Mkdir(pathName, sys.DefaultDirMode&^fsc.Umask()); errno != 0 {
 		return errno
 	}
 
@@ -1490,8 +1543,7 @@ func pathLinkFn(_ context.Context, mod api.Module, params []uint64) syscall.Errn
 	fsc := mod.(*wasm.CallContext).Sys.FS()
 
 	oldFd := uint32(params[0])
-	// TODO: use old_flags?
-	_ = uint32(params[1])
+	oldFlags := uint16(params[1])
 	oldPath := uint32(params[2])
 	oldPathLen := uint32(params[3])
 
@@ -1509,8 +1561,16 @@ func pathLinkFn(_ context.Context, mod api.Module, params []uint64) syscall.Errn
 		return errno
 	}
 
-	if oldFS != newFS { // TODO: handle link across filesystems
-		return syscall.ENOSYS
+	if oldFS != newFS { // hard links can't cross mount points.
+		return syscall.EXDEV
+	}
+
+	// Link the target of a symbolic link instead of the link itself.
+	if oldFlags&wasip1.LOOKUP_SYMLINK_FOLLOW != 0 {
+		if oldName, errno = sysfs.RealPath(oldFS, oldName); errno != 0 {
+			return errno
+		}
+		oldName = strings.TrimPrefix(oldName, "/")
 	}
 
 	return oldFS.Link(oldName, newName)
@@ -1608,24 +1668,12 @@ func pathOpenFn(_ context.Context, mod api.Module, params []uint64) syscall.Errn
 		return syscall.EINVAL // use pathCreateDirectory!
 	}
 
-	newFD, errno := fsc.OpenFile(preopen, pathName, fileOpenFlags, 0o600)
+	// Note: FSContext.OpenFile verifies the file is a directory when isDir.
+	newFD, errno := fsc.OpenFile(preopen, pathName, fileOpenFlags, fsc.FileMode()&^fsc.Umask())
 	if errno != 0 {
 		return errno
 	}
 
-	// Check any flags that require the file to evaluate.
-	if isDir {
-		if f, ok := fsc.LookupFile(newFD); !ok {
-			return syscall.EBADF // unexpected
-		} else if _, ft, err := f.CachedStat(); err != nil {
-			_ = fsc.CloseFile(newFD)
-			return platform.UnwrapOSError(err)
-		} else if ft.Type() != fs.ModeDir {
-			_ = fsc.CloseFile(newFD)
-			return syscall.ENOTDIR
-		}
-	}
-
 	if !mod.Memory().WriteUint32Le(resultOpenedFd, newFD) {
 		_ = fsc.CloseFile(newFD)
 		return syscall.EFAULT
@@ -1718,6 +1766,20 @@ func openFlags(dirflags, oflags, fdflags uint16, rights uint32) (openFlags int)
 	if fdflags&wasip1.FD_APPEND != 0 {
 		openFlags |= syscall.O_RDWR | syscall.O_APPEND
 	}
+	if fdflags&wasip1.FD_NONBLOCK != 0 {
+		openFlags |= platform.O_NONBLOCK
+	}
+	// platform.O_DSYNC and platform.O_RSYNC are placeholders, which are
+	// recorded for fd_fdstat_get, and opened with syscall.O_SYNC on the host.
+	if fdflags&wasip1.FD_SYNC != 0 {
+		openFlags |= syscall.O_SYNC
+	}
+	if fdflags&wasip1.FD_DSYNC != 0 {
+		openFlags |= platform.O_DSYNC
+	}
+	if fdflags&wasip1.FD_RSYNC != 0 {
+		openFlags |= platform.O_RSYNC
+	}
 	// Since rights were discontinued in wasi, we only interpret RIGHT_FD_WRITE
 	// because it is the only way to know that we need to set write permissions
 	// on a file if the application did not pass any of O_CREATE, O_APPEND, nor
@@ -1766,6 +1828,11 @@ func pathReadlinkFn(_ context.Context, mod api.Module, params []uint64) syscall.
 		return errno
 	}
 
+	// Like POSIX readlink, truncate the result to the buffer, without a NUL.
+	if uint32(len(dst)) > bufLen {
+		dst = dst[:bufLen]
+	}
+
 	if ok := mem.WriteString(buf, dst); !ok {
 		return syscall.EFAULT
 	}
@@ -1838,6 +1905,7 @@ func pathRemoveDirectoryFn(_ context.Context, mod api.Module, params []uint64) s
 //   - syscall.ENOENT: `old_path` does not exist.
 //   - syscall.ENOTDIR: `old` is a directory and `new` exists, but is a file.
 //   - syscall.EISDIR: `old` is a file and `new` exists, but is a directory.
+//   - syscall.EXDEV: `fd` and `new_fd` are in different mounts.
 //
 // # Notes
 //   - This is similar to unlinkat in POSIX.
@@ -1871,8 +1939,8 @@ func pathRenameFn(_ context.Context, mod api.Module, params []uint64) syscall.Er
 		return errno
 	}
 
-	if oldFS != newFS { // TODO: handle renames across filesystems
-		return syscall.ENOSYS
+	if oldFS != newFS { // renames can't cross mount points.
+		return syscall.EXDEV
 	}
 
 	return oldFS.Rename(oldPathName, newPathName)
@@ -41,6 +41,56 @@ func Test_fdAdvise(t *testing.T) {
 	requireErrnoResult(t, wasip1.ErrnoBadf, mod, wasip1.FdAdviseName, uint64(1111111), 0, 0, uint64(wasip1.FdAdviceNoReuse+1))
 }
 
+// Test_fdAllocate_nospc ensures a full backing store returns ErrnoNospc.
+func Test_fdAllocate_nospc(t *testing.T) {
+	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
+	require.NoError(t, os.WriteFile(joinPath(tmpDir, "file"), nil, 0o600))
+
+	full := sysfs.FaultFS(sysfs.NewDirFS(tmpDir), func(op, _ string) error {
+		if op == "File.Truncate" {
+			return syscall.ENOSPC
+		}
+		return nil
+	})
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(
+		wazero.NewFSConfig().WithFSMount(full.(fs.FS), "/"),
+	))
+	defer r.Close(testCtx)
+	fd := requireOpenFD(t, mod, "file")
+	log.Reset()
+
+	requireErrnoResult(t, wasip1.ErrnoNospc, mod, wasip1.FdAllocateName, uint64(fd), 0, 10)
+	require.Equal(t, `
+==> wasi_snapshot_preview1.fd_allocate(fd=4,offset=0,len=10)
+<== errno=ENOSPC
+`, "\n"+log.String())
+}
+
+// Test_fdAdvise_file ensures advice on a real file reaches the host, where
+// supported, without failing.
+func Test_fdAdvise_file(t *testing.T) {
+	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
+	require.NoError(t, os.WriteFile(joinPath(tmpDir, "file"), []byte("wazero"), 0o600))
+
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(
+		wazero.NewFSConfig().WithDirMount(tmpDir, "/"),
+	))
+	defer r.Close(testCtx)
+	fd := requireOpenFD(t, mod, "file")
+
+	for advice := wasip1.FdAdviceNormal; advice <= wasip1.FdAdviceNoReuse; advice++ {
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdAdviseName, uint64(fd), 0, 6, uint64(advice))
+	}
+
+	log.Reset()
+	minusOne := int64(-1)
+	requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdAdviseName, uint64(fd), uint64(minusOne), 0, uint64(wasip1.FdAdviceNormal))
+	require.Equal(t, `
+==> wasi_snapshot_preview1.fd_advise(fd=4,offset=-1,len=0,advice=0)
+<== errno=EINVAL
+`, "\n"+log.String())
+}
+
 // Test_fdAllocate only tests it is stubbed for GrainLang per #271
 func Test_fdAllocate(t *testing.T) {
 	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
@@ -75,6 +125,7 @@ func Test_fdAllocate(t *testing.T) {
 		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdAllocateName, uint64(fd), uint64(minusOne), uint64(minusOne))
 		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdAllocateName, uint64(fd), 0, uint64(minusOne))
 		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdAllocateName, uint64(fd), uint64(minusOne), 0)
+		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdAllocateName, uint64(fd), uint64(minusOne), 1)
 	})
 
 	t.Run("do not change size", func(t *testing.T) {
@@ -112,6 +163,8 @@ func Test_fdAllocate(t *testing.T) {
 <== errno=EINVAL
 ==> wasi_snapshot_preview1.fd_allocate(fd=4,offset=-1,len=0)
 <== errno=EINVAL
+==> wasi_snapshot_preview1.fd_allocate(fd=4,offset=-1,len=1)
+<== errno=EINVAL
 ==> wasi_snapshot_preview1.fd_allocate(fd=4,offset=0,len=10)
 <== errno=ESUCCESS
 ==> wasi_snapshot_preview1.fd_allocate(fd=4,offset=5,len=5)
@@ -218,6 +271,36 @@ func Test_fdDatasync(t *testing.T) {
 	}
 }
 
+func Test_fdDatasync_afterWrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	pathName := "test_path"
+	mod, fd, log, r := requireOpenFile(t, tmpDir, pathName, []byte{}, false)
+	defer r.Close(testCtx)
+
+	iovs, resultNwritten := uint32(0), uint32(16) // arbitrary offsets
+	ok := mod.Memory().Write(iovs, []byte{
+		32, 0, 0, 0, // = iovs[0].offset
+		6, 0, 0, 0, // = iovs[0].length
+	})
+	require.True(t, ok)
+	ok = mod.Memory().Write(32, []byte("wazero"))
+	require.True(t, ok)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdWriteName, uint64(fd), uint64(iovs), 1, uint64(resultNwritten))
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdDatasyncName, uint64(fd))
+	require.Equal(t, `
+==> wasi_snapshot_preview1.fd_write(fd=4,iovs=0,iovs_len=1,size=6)
+<== (nwritten=6,errno=ESUCCESS)
+==> wasi_snapshot_preview1.fd_datasync(fd=4)
+<== errno=ESUCCESS
+`, "\n"+log.String())
+
+	// The data should be visible through a fresh open, while the fd is open.
+	actual, err := os.ReadFile(joinPath(tmpDir, pathName))
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(actual))
+}
+
 func Test_fdFdstatGet(t *testing.T) {
 	file, dir := "animals.txt", "sub"
 	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFS(fstest.FS))
@@ -404,7 +487,7 @@ func Test_fdFdstatSetFlags(t *testing.T) {
 
 		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdWriteName, uint64(fd), uint64(iovs), uint64(iovsCount), uint64(resultNwritten))
 		require.Equal(t, `
-==> wasi_snapshot_preview1.fd_write(fd=4,iovs=1,iovs_len=2)
+==> wasi_snapshot_preview1.fd_write(fd=4,iovs=1,iovs_len=2,size=6)
 <== (nwritten=6,errno=ESUCCESS)
 `, "\n"+log.String())
 		log.Reset()
@@ -444,9 +527,31 @@ func Test_fdFdstatSetFlags(t *testing.T) {
 	writeWazero()
 	requireFileContent("wazero6789" + "wazero" + "wazero")
 
+	t.Run("fd_fdstat_get reflects flags", func(t *testing.T) {
+		requireFdflags := func(expected uint16) {
+			const resultFdstat = 0x100
+			requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFdstatGetName, uint64(fd), resultFdstat)
+			fdflags, ok := mod.Memory().ReadUint16Le(resultFdstat + 2)
+			require.True(t, ok)
+			require.Equal(t, expected, fdflags)
+		}
+
+		requireFdflags(wasip1.FD_APPEND)
+
+		for _, flags := range []uint16{
+			wasip1.FD_NONBLOCK,
+			wasip1.FD_APPEND | wasip1.FD_NONBLOCK,
+			0,
+			wasip1.FD_APPEND,
+		} {
+			requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFdstatSetFlagsName, uint64(fd), uint64(flags))
+			requireFdflags(flags)
+		}
+		log.Reset()
+	})
+
 	t.Run("errors", func(t *testing.T) {
 		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdFdstatSetFlagsName, uint64(fd), uint64(wasip1.FD_DSYNC))
-		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdFdstatSetFlagsName, uint64(fd), uint64(wasip1.FD_NONBLOCK))
 		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdFdstatSetFlagsName, uint64(fd), uint64(wasip1.FD_RSYNC))
 		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdFdstatSetFlagsName, uint64(fd), uint64(wasip1.FD_SYNC))
 		requireErrnoResult(t, wasip1.ErrnoBadf, mod, wasip1.FdFdstatSetFlagsName, uint64(12345), uint64(wasip1.FD_APPEND))
@@ -636,12 +741,60 @@ func Test_fdFilestatGet(t *testing.T) {
 	}
 }
 
+// Test_fdFilestatGet_devices ensures character and block devices have
+// distinct filetypes, for programs that stat entries in /dev.
+func Test_fdFilestatGet_devices(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no device files on windows")
+	}
+
+	// Make a block device, which is never opened, so the device number is
+	// arbitrary.
+	tmpDir := t.TempDir()
+	if errno := platform.Mknod(joinPath(tmpDir, "blk"), fs.ModeDevice|0o600, platform.Mkdev(7, 0)); errno == syscall.EPERM {
+		t.Skip("mknod of a block device requires privileges")
+	} else {
+		require.Zero(t, errno)
+	}
+
+	fsConfig := wazero.NewFSConfig().
+		WithReadOnlyDirMount("/dev", "/dev").
+		WithReadOnlyDirMount(tmpDir, "/tmp")
+	mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+	defer r.Close(testCtx)
+
+	fsc := mod.(*wasm.CallContext).Sys.FS()
+	preopen := fsc.RootFS()
+	nullFD, errno := fsc.OpenFile(preopen, "dev/null", os.O_RDONLY, 0)
+	require.Zero(t, errno)
+
+	resultFilestat := uint32(0) // arbitrary offset
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFilestatGetName, uint64(nullFD), uint64(resultFilestat))
+	chrType, ok := mod.Memory().ReadByte(resultFilestat + 16) // filestat.filetype
+	require.True(t, ok)
+	require.Equal(t, wasip1.FILETYPE_CHARACTER_DEVICE, chrType)
+
+	// Stat the block device by path, as opening it would fail without a
+	// driver for its device number.
+	tmpFD, errno := fsc.OpenFile(preopen, "tmp", os.O_RDONLY, 0)
+	require.Zero(t, errno)
+
+	pathName := uint32(100) // arbitrary offset
+	ok = mod.Memory().Write(pathName, []byte("blk"))
+	require.True(t, ok)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PathFilestatGetName, uint64(tmpFD), 0, uint64(pathName), 3, uint64(resultFilestat))
+	blkType, ok := mod.Memory().ReadByte(resultFilestat + 16) // filestat.filetype
+	require.True(t, ok)
+	require.Equal(t, wasip1.FILETYPE_BLOCK_DEVICE, blkType)
+}
+
 func Test_fdFilestatSetSize(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	tests := []struct {
 		name                     string
-		size                     uint32
+		size                     uint64
+		readOnly                 bool
 		content, expectedContent []byte
 		expectedLog              string
 		expectedErrno            wasip1.Errno
@@ -687,6 +840,29 @@ func Test_fdFilestatSetSize(t *testing.T) {
 			expectedLog: `
 ==> wasi_snapshot_preview1.fd_filestat_set_size(fd=4,size=106)
 <== errno=ESUCCESS
+`,
+		},
+		{
+			name:            "read-only",
+			content:         []byte("123456"),
+			expectedContent: []byte("123456"),
+			readOnly:        true,
+			size:            5,
+			expectedErrno:   wasip1.ErrnoPerm,
+			expectedLog: `
+==> wasi_snapshot_preview1.fd_filestat_set_size(fd=4,size=5)
+<== errno=EPERM
+`,
+		},
+		{
+			name:            "larger than int64",
+			content:         []byte("123456"),
+			expectedContent: []byte("123456"),
+			size:            math.MaxUint64,
+			expectedErrno:   wasip1.ErrnoInval,
+			expectedLog: `
+==> wasi_snapshot_preview1.fd_filestat_set_size(fd=4,size=-1)
+<== errno=EINVAL
 `,
 		},
 	}
@@ -695,13 +871,13 @@ func Test_fdFilestatSetSize(t *testing.T) {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
 			filepath := path.Base(t.Name())
-			mod, fd, log, r := requireOpenFile(t, tmpDir, filepath, tc.content, false)
+			mod, fd, log, r := requireOpenFile(t, tmpDir, filepath, tc.content, tc.readOnly)
 			defer r.Close(testCtx)
 
 			if filepath == "badf" {
 				fd++
 			}
-			requireErrnoResult(t, tc.expectedErrno, mod, wasip1.FdFilestatSetSizeName, uint64(fd), uint64(tc.size))
+			requireErrnoResult(t, tc.expectedErrno, mod, wasip1.FdFilestatSetSizeName, uint64(fd), tc.size)
 
 			actual, err := os.ReadFile(joinPath(tmpDir, filepath))
 			require.NoError(t, err)
@@ -712,6 +888,61 @@ func Test_fdFilestatSetSize(t *testing.T) {
 	}
 }
 
+// Test_fdFilestatSetSize_grow ensures growing a file zero-fills its tail,
+// reading it back through WASI instead of the host filesystem.
+func Test_fdFilestatSetSize_grow(t *testing.T) {
+	mod, fd, _, r := requireOpenFile(t, t.TempDir(), "test_path", []byte("123456"), false)
+	defer r.Close(testCtx)
+
+	// Fill the read buffer with non-zero bytes to ensure they are overwritten.
+	buf, iovs, resultNread := uint32(32), uint32(8), uint32(16) // arbitrary offsets
+	ok := mod.Memory().Write(buf, bytes.Repeat([]byte{'?'}, 10))
+	require.True(t, ok)
+	ok = mod.Memory().Write(iovs, []byte{
+		byte(buf), 0, 0, 0, // = iovs[0].offset
+		10, 0, 0, 0, // = iovs[0].length
+	})
+	require.True(t, ok)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFilestatSetSizeName, uint64(fd), 10)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdPreadName, uint64(fd), uint64(iovs), 1, 0, uint64(resultNread))
+
+	nread, ok := mod.Memory().ReadUint32Le(resultNread)
+	require.True(t, ok)
+	require.Equal(t, uint32(10), nread)
+	actual, ok := mod.Memory().Read(buf, 10)
+	require.True(t, ok)
+	require.Equal(t, []byte{'1', '2', '3', '4', '5', '6', 0, 0, 0, 0}, actual)
+}
+
+// Test_fdFilestatSetSize_belowOffset ensures truncating a file leaves its
+// offset unchanged, so the next write creates a hole, like POSIX ftruncate.
+func Test_fdFilestatSetSize_belowOffset(t *testing.T) {
+	tmpDir := t.TempDir()
+	pathName := "test_path"
+	mod, fd, _, r := requireOpenFile(t, tmpDir, pathName, []byte("123456"), false)
+	defer r.Close(testCtx)
+
+	resultNewoffset := uint32(0) // arbitrary offset
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdSeekName, uint64(fd), 6, uint64(io.SeekStart), uint64(resultNewoffset))
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFilestatSetSizeName, uint64(fd), 2)
+
+	iovs, resultNwritten := uint32(8), uint32(16) // arbitrary offsets
+	ok := mod.Memory().Write(iovs, []byte{
+		24, 0, 0, 0, // = iovs[0].offset
+		1, 0, 0, 0, // = iovs[0].length
+	})
+	require.True(t, ok)
+	ok = mod.Memory().WriteByte(24, 'x')
+	require.True(t, ok)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdWriteName, uint64(fd), uint64(iovs), 1, uint64(resultNwritten))
+
+	actual, err := os.ReadFile(joinPath(tmpDir, pathName))
+	require.NoError(t, err)
+	require.Equal(t, []byte{'1', '2', 0, 0, 0, 0, 'x'}, actual)
+}
+
 func Test_fdFilestatSetTimes(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -922,7 +1153,7 @@ func Test_fdPread(t *testing.T) {
 				'?',
 			),
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=1,iovs_len=2,offset=0)
+==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=1,iovs_len=2,size=6,offset=0)
 <== (nread=6,errno=ESUCCESS)
 `,
 		},
@@ -937,7 +1168,7 @@ func Test_fdPread(t *testing.T) {
 				'?',
 			),
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=1,iovs_len=2,offset=2)
+==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=1,iovs_len=2,size=6,offset=2)
 <== (nread=4,errno=ESUCCESS)
 `,
 		},
@@ -1018,9 +1249,9 @@ func Test_fdPread_offset(t *testing.T) {
 	require.Equal(t, expectedMemory, actual)
 
 	expectedLog := `
-==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=1,iovs_len=2,offset=2)
+==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=1,iovs_len=2,size=6,offset=2)
 <== (nread=4,errno=ESUCCESS)
-==> wasi_snapshot_preview1.fd_read(fd=4,iovs=1,iovs_len=2)
+==> wasi_snapshot_preview1.fd_read(fd=4,iovs=1,iovs_len=2,size=6)
 <== (nread=6,errno=ESUCCESS)
 `
 	require.Equal(t, expectedLog, "\n"+log.String())
@@ -1046,7 +1277,7 @@ func Test_fdPread_Errors(t *testing.T) {
 			memory:        []byte{'?', '?', '?', '?'}, // pass result.nread validation
 			expectedErrno: wasip1.ErrnoBadf,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pread(fd=42,iovs=65532,iovs_len=0,offset=0)
+==> wasi_snapshot_preview1.fd_pread(fd=42,iovs=65532,iovs_len=0,size=0,offset=0)
 <== (nread=,errno=EBADF)
 `,
 		},
@@ -1057,7 +1288,7 @@ func Test_fdPread_Errors(t *testing.T) {
 			memory:        []byte{'?'},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=65536,iovs_len=0,offset=0)
+==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=65536,iovs_len=0,size=0,offset=0)
 <== (nread=,errno=EFAULT)
 `,
 		},
@@ -1071,7 +1302,7 @@ func Test_fdPread_Errors(t *testing.T) {
 			},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=65532,iovs_len=1,offset=0)
+==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=65532,iovs_len=1,size=,offset=0)
 <== (nread=,errno=EFAULT)
 `,
 		},
@@ -1086,7 +1317,7 @@ func Test_fdPread_Errors(t *testing.T) {
 			},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=65528,iovs_len=1,offset=0)
+==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=65528,iovs_len=1,size=1,offset=0)
 <== (nread=,errno=EFAULT)
 `,
 		},
@@ -1102,7 +1333,7 @@ func Test_fdPread_Errors(t *testing.T) {
 			},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=65527,iovs_len=1,offset=0)
+==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=65527,iovs_len=1,size=65536,offset=0)
 <== (nread=,errno=EFAULT)
 `,
 		},
@@ -1119,7 +1350,7 @@ func Test_fdPread_Errors(t *testing.T) {
 			},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=65527,iovs_len=1,offset=0)
+==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=65527,iovs_len=1,size=1,offset=0)
 <== (nread=,errno=EFAULT)
 `,
 		},
@@ -1138,7 +1369,7 @@ func Test_fdPread_Errors(t *testing.T) {
 			offset:        int64(-1),
 			expectedErrno: wasip1.ErrnoIo,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=65523,iovs_len=1,offset=-1)
+==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=65523,iovs_len=1,size=1,offset=-1)
 <== (nread=,errno=EIO)
 `,
 		},
@@ -1244,6 +1475,37 @@ func Test_fdPrestatGet_Errors(t *testing.T) {
 	}
 }
 
+// Test_fdPrestat_mounts enumerates pre-opens the way wasi-libc does, which
+// stops at the first descriptor after stdio that isn't pre-opened.
+func Test_fdPrestat_mounts(t *testing.T) {
+	fsConfig := wazero.NewFSConfig().
+		WithDirMount(t.TempDir(), "/").
+		WithReadOnlyDirMount(t.TempDir(), "/etc").
+		WithDirMount(t.TempDir(), "/tmp")
+	mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+	defer r.Close(testCtx)
+
+	const resultPrestat, path = 0, 0x10
+	var names []string
+	for fd := uint32(sys.FdPreopen); ; fd++ {
+		results, err := mod.ExportedFunction(wasip1.FdPrestatGetName).Call(testCtx, uint64(fd), resultPrestat)
+		require.NoError(t, err)
+		if errno := wasip1.Errno(results[0]); errno == wasip1.ErrnoBadf {
+			break
+		} else {
+			require.Equal(t, wasip1.ErrnoSuccess, errno)
+		}
+
+		nameLen, ok := mod.Memory().ReadUint32Le(resultPrestat + 4)
+		require.True(t, ok)
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdPrestatDirNameName, uint64(fd), path, uint64(nameLen))
+		name, ok := mod.Memory().Read(path, nameLen)
+		require.True(t, ok)
+		names = append(names, string(name))
+	}
+	require.Equal(t, []string{"/", "/etc", "/tmp"}, names)
+}
+
 func Test_fdPrestatDirName(t *testing.T) {
 	fsConfig := wazero.NewFSConfig().WithDirMount(t.TempDir(), "/")
 	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
@@ -1395,7 +1657,7 @@ func Test_fdPwrite(t *testing.T) {
 			),
 			expectedContents: "wazero",
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=1,iovs_len=2,offset=0)
+==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=1,iovs_len=2,size=6,offset=0)
 <== (nwritten=6,errno=ESUCCESS)
 `,
 		},
@@ -1410,7 +1672,7 @@ func Test_fdPwrite(t *testing.T) {
 			),
 			expectedContents: "wawazero", // "wa" from the first test!
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=1,iovs_len=2,offset=2)
+==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=1,iovs_len=2,size=6,offset=2)
 <== (nwritten=6,errno=ESUCCESS)
 `,
 		},
@@ -1504,9 +1766,9 @@ func Test_fdPwrite_offset(t *testing.T) {
 	require.Equal(t, expectedMemory, actual)
 
 	expectedLog := `
-==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=1,iovs_len=1,offset=3)
+==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=1,iovs_len=1,size=3,offset=3)
 <== (nwritten=3,errno=ESUCCESS)
-==> wasi_snapshot_preview1.fd_write(fd=4,iovs=1,iovs_len=1)
+==> wasi_snapshot_preview1.fd_write(fd=4,iovs=1,iovs_len=1,size=3)
 <== (nwritten=3,errno=ESUCCESS)
 `
 	require.Equal(t, expectedLog, "\n"+log.String())
@@ -1517,6 +1779,59 @@ func Test_fdPwrite_offset(t *testing.T) {
 	require.Equal(t, "wazero", string(b))
 }
 
+func Test_fdPwrite_readOnly(t *testing.T) {
+	mod, fd, _, r := requireOpenFile(t, t.TempDir(), "test_path", []byte("wazero"), true)
+	defer r.Close(testCtx)
+
+	iovs, resultNwritten := uint32(0), uint32(16) // arbitrary offsets
+	ok := mod.Memory().Write(iovs, []byte{
+		32, 0, 0, 0, // = iovs[0].offset
+		1, 0, 0, 0, // = iovs[0].length
+	})
+	require.True(t, ok)
+
+	// EPERM, the same as fd_filestat_set_size on a file not open for writing.
+	requireErrnoResult(t, wasip1.ErrnoPerm, mod, wasip1.FdPwriteName, uint64(fd), uint64(iovs), 1, 0, uint64(resultNwritten))
+}
+
+// failAfterWriter accepts the first write, then fails the rest.
+type failAfterWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *failAfterWriter) Write(p []byte) (int, error) {
+	if w.buf.Len() > 0 {
+		return 0, syscall.EIO
+	}
+	return w.buf.Write(p)
+}
+
+// Test_fdWrite_partial ensures the count of bytes written before an error is
+// reported, so that the guest doesn't write them again.
+func Test_fdWrite_partial(t *testing.T) {
+	stdout := &failAfterWriter{}
+	mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().WithStdout(stdout))
+	defer r.Close(testCtx)
+
+	iovs, resultNwritten := uint32(0), uint32(16) // arbitrary offsets
+	ok := mod.Memory().Write(iovs, []byte{
+		32, 0, 0, 0, // = iovs[0].offset
+		4, 0, 0, 0, // = iovs[0].length
+		36, 0, 0, 0, // = iovs[1].offset
+		2, 0, 0, 0, // = iovs[1].length
+	})
+	require.True(t, ok)
+	ok = mod.Memory().Write(32, []byte("wazero"))
+	require.True(t, ok)
+
+	requireErrnoResult(t, wasip1.ErrnoIo, mod, wasip1.FdWriteName, uint64(sys.FdStdout), uint64(iovs), 2, uint64(resultNwritten))
+
+	nwritten, ok := mod.Memory().ReadUint32Le(resultNwritten)
+	require.True(t, ok)
+	require.Equal(t, uint32(4), nwritten)
+	require.Equal(t, "waze", stdout.buf.String())
+}
+
 func Test_fdPwrite_Errors(t *testing.T) {
 	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
 	pathName := "test_path"
@@ -1537,7 +1852,7 @@ func Test_fdPwrite_Errors(t *testing.T) {
 			memory:        []byte{'?', '?', '?', '?'}, // pass result.nwritten validation
 			expectedErrno: wasip1.ErrnoBadf,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pwrite(fd=42,iovs=65532,iovs_len=0,offset=0)
+==> wasi_snapshot_preview1.fd_pwrite(fd=42,iovs=65532,iovs_len=0,size=0,offset=0)
 <== (nwritten=,errno=EBADF)
 `,
 		},
@@ -1548,7 +1863,7 @@ func Test_fdPwrite_Errors(t *testing.T) {
 			memory:        []byte{'?'},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=65536,iovs_len=0,offset=0)
+==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=65536,iovs_len=0,size=0,offset=0)
 <== (nwritten=,errno=EFAULT)
 `,
 		},
@@ -1562,7 +1877,7 @@ func Test_fdPwrite_Errors(t *testing.T) {
 			},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=65532,iovs_len=1,offset=0)
+==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=65532,iovs_len=1,size=,offset=0)
 <== (nwritten=,errno=EFAULT)
 `,
 		},
@@ -1577,7 +1892,7 @@ func Test_fdPwrite_Errors(t *testing.T) {
 			},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=65528,iovs_len=1,offset=0)
+==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=65528,iovs_len=1,size=1,offset=0)
 <== (nwritten=,errno=EFAULT)
 `,
 		},
@@ -1593,7 +1908,7 @@ func Test_fdPwrite_Errors(t *testing.T) {
 			},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=65527,iovs_len=1,offset=0)
+==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=65527,iovs_len=1,size=65536,offset=0)
 <== (nwritten=,errno=EFAULT)
 `,
 		},
@@ -1610,7 +1925,7 @@ func Test_fdPwrite_Errors(t *testing.T) {
 			},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=65527,iovs_len=1,offset=0)
+==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=65527,iovs_len=1,size=1,offset=0)
 <== (nwritten=,errno=EFAULT)
 `,
 		},
@@ -1629,7 +1944,7 @@ func Test_fdPwrite_Errors(t *testing.T) {
 			offset:        int64(-1),
 			expectedErrno: wasip1.ErrnoIo,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=65523,iovs_len=1,offset=-1)
+==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=65523,iovs_len=1,size=1,offset=-1)
 <== (nwritten=,errno=EIO)
 `,
 		},
@@ -1683,7 +1998,7 @@ func Test_fdRead(t *testing.T) {
 
 	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdReadName, uint64(fd), uint64(iovs), uint64(iovsCount), uint64(resultNread))
 	require.Equal(t, `
-==> wasi_snapshot_preview1.fd_read(fd=4,iovs=1,iovs_len=2)
+==> wasi_snapshot_preview1.fd_read(fd=4,iovs=1,iovs_len=2,size=6)
 <== (nread=6,errno=ESUCCESS)
 `, "\n"+log.String())
 
@@ -1709,7 +2024,7 @@ func Test_fdRead_Errors(t *testing.T) {
 			memory:        []byte{'?', '?', '?', '?'}, // pass result.nread validation
 			expectedErrno: wasip1.ErrnoBadf,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_read(fd=42,iovs=65532,iovs_len=65532)
+==> wasi_snapshot_preview1.fd_read(fd=42,iovs=65532,iovs_len=65532,size=)
 <== (nread=,errno=EBADF)
 `,
 		},
@@ -1720,7 +2035,7 @@ func Test_fdRead_Errors(t *testing.T) {
 			memory:        []byte{'?'},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_read(fd=4,iovs=65536,iovs_len=65535)
+==> wasi_snapshot_preview1.fd_read(fd=4,iovs=65536,iovs_len=65535,size=)
 <== (nread=,errno=EFAULT)
 `,
 		},
@@ -1734,7 +2049,7 @@ func Test_fdRead_Errors(t *testing.T) {
 			},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_read(fd=4,iovs=65532,iovs_len=65532)
+==> wasi_snapshot_preview1.fd_read(fd=4,iovs=65532,iovs_len=65532,size=)
 <== (nread=,errno=EFAULT)
 `,
 		},
@@ -1749,7 +2064,7 @@ func Test_fdRead_Errors(t *testing.T) {
 			},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_read(fd=4,iovs=65528,iovs_len=65528)
+==> wasi_snapshot_preview1.fd_read(fd=4,iovs=65528,iovs_len=65528,size=)
 <== (nread=,errno=EFAULT)
 `,
 		},
@@ -1765,7 +2080,7 @@ func Test_fdRead_Errors(t *testing.T) {
 			},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_read(fd=4,iovs=65527,iovs_len=65527)
+==> wasi_snapshot_preview1.fd_read(fd=4,iovs=65527,iovs_len=65527,size=)
 <== (nread=,errno=EFAULT)
 `,
 		},
@@ -1782,7 +2097,7 @@ func Test_fdRead_Errors(t *testing.T) {
 			},
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_read(fd=4,iovs=65527,iovs_len=65527)
+==> wasi_snapshot_preview1.fd_read(fd=4,iovs=65527,iovs_len=65527,size=)
 <== (nread=,errno=EFAULT)
 `,
 		},
@@ -2430,6 +2745,36 @@ func Test_fdRenumber(t *testing.T) {
 			expectedLog: `
 ==> wasi_snapshot_preview1.fd_renumber(fd=5,to=12345)
 <== errno=ESUCCESS
+`,
+		},
+		{
+			name:          "file to itself",
+			from:          fileFD,
+			to:            fileFD,
+			expectedErrno: wasip1.ErrnoSuccess,
+			expectedLog: `
+==> wasi_snapshot_preview1.fd_renumber(fd=4,to=4)
+<== errno=ESUCCESS
+`,
+		},
+		{
+			name:          "file to stdout",
+			from:          fileFD,
+			to:            sys.FdStdout,
+			expectedErrno: wasip1.ErrnoSuccess,
+			expectedLog: `
+==> wasi_snapshot_preview1.fd_renumber(fd=4,to=1)
+<== errno=ESUCCESS
+`,
+		},
+		{
+			name:          "closed to file",
+			from:          54,
+			to:            fileFD,
+			expectedErrno: wasip1.ErrnoBadf,
+			expectedLog: `
+==> wasi_snapshot_preview1.fd_renumber(fd=54,to=4)
+<== errno=EBADF
 `,
 		},
 		{
@@ -2637,6 +2982,15 @@ func Test_fdSync(t *testing.T) {
 	mod, fd, log, r := requireOpenFile(t, tmpDir, pathName, []byte{}, false)
 	defer r.Close(testCtx)
 
+	fsc := mod.(*wasm.CallContext).Sys.FS()
+	preopen := fsc.RootFS()
+
+	readOnlyFD, errno := fsc.OpenFile(preopen, pathName, os.O_RDONLY, 0)
+	require.Zero(t, errno)
+
+	dirFD, errno := fsc.OpenFile(preopen, ".", os.O_RDONLY, 0)
+	require.Zero(t, errno)
+
 	tests := []struct {
 		name          string
 		fd            uint32
@@ -2659,6 +3013,24 @@ func Test_fdSync(t *testing.T) {
 			expectedLog: `
 ==> wasi_snapshot_preview1.fd_sync(fd=4)
 <== errno=ESUCCESS
+`,
+		},
+		{
+			name:          "read-only FD",
+			fd:            readOnlyFD,
+			expectedErrno: wasip1.ErrnoSuccess,
+			expectedLog: `
+==> wasi_snapshot_preview1.fd_sync(fd=5)
+<== errno=ESUCCESS
+`,
+		},
+		{
+			name:          "directory FD",
+			fd:            dirFD,
+			expectedErrno: wasip1.ErrnoSuccess,
+			expectedLog: `
+==> wasi_snapshot_preview1.fd_sync(fd=6)
+<== errno=ESUCCESS
 `,
 		},
 	}
@@ -2666,6 +3038,9 @@ func Test_fdSync(t *testing.T) {
 	for _, tt := range tests {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
+			if runtime.GOOS == "windows" && tc.fd != fd && tc.fd != 42 {
+				t.Skip("windows can't sync read-only handles")
+			}
 			defer log.Reset()
 
 			requireErrnoResult(t, tc.expectedErrno, mod, wasip1.FdSyncName, uint64(tc.fd))
@@ -2717,6 +3092,40 @@ func Test_fdTell(t *testing.T) {
 	require.Equal(t, expectedOffset, offset) // test that the offset of file is actually updated.
 }
 
+// Test_fdTell_fdSeek ensures fd_tell returns the same offset as fd_seek with
+// whence=cur, including after a read moved it.
+func Test_fdTell_fdSeek(t *testing.T) {
+	mod, fd, _, r := requireOpenFile(t, t.TempDir(), "test_path", []byte("wazero"), true)
+	defer r.Close(testCtx)
+
+	resultTell, resultSeek := uint32(0), uint32(8) // arbitrary offsets
+	requireSameOffset := func(expected uint64) {
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdTellName, uint64(fd), uint64(resultTell))
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdSeekName, uint64(fd), 0, uint64(io.SeekCurrent), uint64(resultSeek))
+
+		tell, ok := mod.Memory().ReadUint64Le(resultTell)
+		require.True(t, ok)
+		seek, ok := mod.Memory().ReadUint64Le(resultSeek)
+		require.True(t, ok)
+		require.Equal(t, seek, tell)
+		require.Equal(t, expected, tell)
+	}
+
+	requireSameOffset(0)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdSeekName, uint64(fd), 2, uint64(io.SeekStart), uint64(resultSeek))
+	requireSameOffset(2)
+
+	iovs, resultNread := uint32(16), uint32(24) // arbitrary offsets
+	ok := mod.Memory().Write(iovs, []byte{
+		32, 0, 0, 0, // = iovs[0].offset
+		3, 0, 0, 0, // = iovs[0].length
+	})
+	require.True(t, ok)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdReadName, uint64(fd), uint64(iovs), 1, uint64(resultNread))
+	requireSameOffset(5)
+}
+
 func Test_fdTell_Errors(t *testing.T) {
 	mod, fd, log, r := requireOpenFile(t, t.TempDir(), "test_path", []byte("wazero"), true)
 	defer r.Close(testCtx)
@@ -2762,6 +3171,137 @@ func Test_fdTell_Errors(t *testing.T) {
 	}
 }
 
+// Test_fdWrite_pastEOF ensures a write after seeking past the end of a file
+// leaves a hole of zeros, like POSIX.
+func Test_fdWrite_pastEOF(t *testing.T) {
+	tmpDir := t.TempDir()
+	pathName := "test_path"
+	mod, fd, _, r := requireOpenFile(t, tmpDir, pathName, []byte{}, false)
+	defer r.Close(testCtx)
+
+	resultNewoffset := uint32(0) // arbitrary offset
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdSeekName, uint64(fd), 100, uint64(io.SeekStart), uint64(resultNewoffset))
+
+	iovs, resultNwritten := uint32(8), uint32(16) // arbitrary offsets
+	ok := mod.Memory().Write(iovs, []byte{
+		24, 0, 0, 0, // = iovs[0].offset
+		1, 0, 0, 0, // = iovs[0].length
+	})
+	require.True(t, ok)
+	ok = mod.Memory().WriteByte(24, 'x')
+	require.True(t, ok)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdWriteName, uint64(fd), uint64(iovs), 1, uint64(resultNwritten))
+
+	resultFilestat := uint32(32) // arbitrary offset
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFilestatGetName, uint64(fd), uint64(resultFilestat))
+	size, ok := mod.Memory().ReadUint64Le(resultFilestat + 32) // filestat.size
+	require.True(t, ok)
+	require.Equal(t, uint64(101), size)
+
+	actual, err := os.ReadFile(joinPath(tmpDir, pathName))
+	require.NoError(t, err)
+	require.Equal(t, append(make([]byte, 100), 'x'), actual)
+}
+
+// Test_fdRead_fdWrite_zeroLength ensures a zero-length iovec is skipped,
+// instead of stopping the read or write of the iovecs after it.
+func Test_fdRead_fdWrite_zeroLength(t *testing.T) {
+	tmpDir := t.TempDir()
+	pathName := "test_path"
+	mod, fd, _, r := requireOpenFile(t, tmpDir, pathName, []byte("wazero"), false)
+	defer r.Close(testCtx)
+
+	iovs, resultN := uint32(0), uint32(16) // arbitrary offsets
+	ok := mod.Memory().Write(iovs, []byte{
+		32, 0, 0, 0, // = iovs[0].offset
+		0, 0, 0, 0, // = iovs[0].length
+		32, 0, 0, 0, // = iovs[1].offset
+		6, 0, 0, 0, // = iovs[1].length
+	})
+	require.True(t, ok)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdReadName, uint64(fd), uint64(iovs), 2, uint64(resultN))
+	nread, ok := mod.Memory().ReadUint32Le(resultN)
+	require.True(t, ok)
+	require.Equal(t, uint32(6), nread)
+	actual, ok := mod.Memory().Read(32, 6)
+	require.True(t, ok)
+	require.Equal(t, "wazero", string(actual))
+
+	// Write the same bytes again, appending them as the offset is at EOF.
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdWriteName, uint64(fd), uint64(iovs), 2, uint64(resultN))
+	nwritten, ok := mod.Memory().ReadUint32Le(resultN)
+	require.True(t, ok)
+	require.Equal(t, uint32(6), nwritten)
+
+	b, err := os.ReadFile(joinPath(tmpDir, pathName))
+	require.NoError(t, err)
+	require.Equal(t, "wazerowazero", string(b))
+}
+
+// Test_fdRead_fdWrite_maxIOSize ensures reads and writes are capped to the
+// configured size, even when an iovec is larger than memory.
+func Test_fdRead_fdWrite_maxIOSize(t *testing.T) {
+	tmpDir := t.TempDir()
+	pathName := "test_path"
+	mod, fd, _, r := requireOpenFile(t, tmpDir, pathName, []byte("wazero"), false)
+	defer r.Close(testCtx)
+
+	mod.(*wasm.CallContext).Sys.FS().SetMaxIOSize(4)
+
+	iovs, resultN := uint32(0), uint32(16) // arbitrary offsets
+	ok := mod.Memory().Write(iovs, []byte{
+		32, 0, 0, 0, // = iovs[0].offset
+		3, 0, 0, 0, // = iovs[0].length
+		35, 0, 0, 0, // = iovs[1].offset
+		0, 0, 0, 0x80, // = iovs[1].length, much larger than memory
+	})
+	require.True(t, ok)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdReadName, uint64(fd), uint64(iovs), 2, uint64(resultN))
+	nread, ok := mod.Memory().ReadUint32Le(resultN)
+	require.True(t, ok)
+	require.Equal(t, uint32(4), nread)
+	actual, ok := mod.Memory().Read(32, 4)
+	require.True(t, ok)
+	require.Equal(t, "waze", string(actual))
+
+	// Overwrite from the start, which also only writes 4 bytes.
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdPwriteName, uint64(fd), uint64(iovs), 2, 0, uint64(resultN))
+	nwritten, ok := mod.Memory().ReadUint32Le(resultN)
+	require.True(t, ok)
+	require.Equal(t, uint32(4), nwritten)
+}
+
+func Test_fdRead_strictIovecs(t *testing.T) {
+	mod, fd, _, r := requireOpenFile(t, t.TempDir(), "test_path", []byte("wazero"), true)
+	defer r.Close(testCtx)
+
+	iovs, resultNread := uint32(0), uint32(16) // arbitrary offsets
+	ok := mod.Memory().Write(iovs, []byte{
+		32, 0, 0, 0, // = iovs[0].offset
+		4, 0, 0, 0, // = iovs[0].length
+		34, 0, 0, 0, // = iovs[1].offset, which overlaps iovs[0]
+		2, 0, 0, 0, // = iovs[1].length
+	})
+	require.True(t, ok)
+
+	fsc := mod.(*wasm.CallContext).Sys.FS()
+	fsc.SetStrictIovecs(true)
+	requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdReadName, uint64(fd), uint64(iovs), 2, uint64(resultNread))
+	requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdPreadName, uint64(fd), uint64(iovs), 2, 0, uint64(resultNread))
+
+	// Nothing should have been read, so the next read starts at the beginning.
+	fsc.SetStrictIovecs(false)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdReadName, uint64(fd), uint64(iovs), 2, uint64(resultNread))
+	nread, ok := mod.Memory().ReadUint32Le(resultNread)
+	require.True(t, ok)
+	require.Equal(t, uint32(6), nread)
+	actual, ok := mod.Memory().Read(32, 4)
+	require.True(t, ok)
+	require.Equal(t, "waro", string(actual)) // iovs[1] overwrote "ze" with "ro"
+}
+
 func Test_fdWrite(t *testing.T) {
 	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
 	pathName := "test_path"
@@ -2795,7 +3335,7 @@ func Test_fdWrite(t *testing.T) {
 
 	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdWriteName, uint64(fd), uint64(iovs), uint64(iovsCount), uint64(resultNwritten))
 	require.Equal(t, `
-==> wasi_snapshot_preview1.fd_write(fd=4,iovs=1,iovs_len=2)
+==> wasi_snapshot_preview1.fd_write(fd=4,iovs=1,iovs_len=2,size=6)
 <== (nwritten=6,errno=ESUCCESS)
 `, "\n"+log.String())
 
@@ -2874,7 +3414,7 @@ func Test_fdWrite_Errors(t *testing.T) {
 			fd:            42, // arbitrary invalid fd
 			expectedErrno: wasip1.ErrnoBadf,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_write(fd=42,iovs=0,iovs_len=1)
+==> wasi_snapshot_preview1.fd_write(fd=42,iovs=0,iovs_len=1,size=6907904)
 <== (nwritten=,errno=EBADF)
 `,
 		},
@@ -2890,7 +3430,7 @@ func Test_fdWrite_Errors(t *testing.T) {
 			iovs:          memSize - 2,
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_write(fd=4,iovs=65534,iovs_len=1)
+==> wasi_snapshot_preview1.fd_write(fd=4,iovs=65534,iovs_len=1,size=)
 <== (nwritten=,errno=EFAULT)
 `,
 		},
@@ -2900,7 +3440,7 @@ func Test_fdWrite_Errors(t *testing.T) {
 			iovs:          memSize - 4, // iovs[0].offset was 4 bytes and iovs[0].length next, but not enough mod.Memory()!
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_write(fd=4,iovs=65532,iovs_len=1)
+==> wasi_snapshot_preview1.fd_write(fd=4,iovs=65532,iovs_len=1,size=)
 <== (nwritten=,errno=EFAULT)
 `,
 		},
@@ -2910,7 +3450,7 @@ func Test_fdWrite_Errors(t *testing.T) {
 			iovs:          memSize - 5, // iovs[0].offset (where to read "hi") is outside memory.
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_write(fd=4,iovs=65531,iovs_len=1)
+==> wasi_snapshot_preview1.fd_write(fd=4,iovs=65531,iovs_len=1,size=)
 <== (nwritten=,errno=EFAULT)
 `,
 		},
@@ -2920,7 +3460,7 @@ func Test_fdWrite_Errors(t *testing.T) {
 			iovs:          memSize - 9, // iovs[0].offset (where to read "hi") is in memory, but truncated.
 			expectedErrno: wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_write(fd=4,iovs=65527,iovs_len=1)
+==> wasi_snapshot_preview1.fd_write(fd=4,iovs=65527,iovs_len=1,size=1744830464)
 <== (nwritten=,errno=EFAULT)
 `,
 		},
@@ -2930,7 +3470,7 @@ func Test_fdWrite_Errors(t *testing.T) {
 			resultNwritten: memSize, // read was ok, but there wasn't enough memory to write the result.
 			expectedErrno:  wasip1.ErrnoFault,
 			expectedLog: `
-==> wasi_snapshot_preview1.fd_write(fd=4,iovs=0,iovs_len=1)
+==> wasi_snapshot_preview1.fd_write(fd=4,iovs=0,iovs_len=1,size=6907904)
 <== (nwritten=,errno=EFAULT)
 `,
 		},
@@ -2955,6 +3495,44 @@ func Test_fdWrite_Errors(t *testing.T) {
 	}
 }
 
+func Test_pathCreateDirectory_umask(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows only supports the read-only bit")
+	}
+
+	tests := []struct {
+		name     string
+		umask    fs.FileMode
+		expected fs.FileMode
+	}{
+		{name: "default", umask: sys.DefaultUmask, expected: 0o700},
+		{name: "umask", umask: 0o022, expected: 0o755},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().
+				WithFSConfig(wazero.NewFSConfig().WithDirMount(tmpDir, "/")))
+			defer r.Close(testCtx)
+
+			mod.(*wasm.CallContext).Sys.FS().SetUmask(tc.umask)
+
+			pathName := "created"
+			path := uint32(0) // arbitrary offset
+			ok := mod.Memory().Write(path, []byte(pathName))
+			require.True(t, ok)
+
+			requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PathCreateDirectoryName, uint64(sys.FdPreopen), uint64(path), uint64(len(pathName)))
+
+			st, err := os.Stat(joinPath(tmpDir, pathName))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, st.Mode().Perm())
+		})
+	}
+}
+
 func Test_pathCreateDirectory(t *testing.T) {
 	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
 	fsConfig := wazero.NewFSConfig().WithDirMount(tmpDir, "/")
@@ -3648,12 +4226,49 @@ func Test_pathLink(t *testing.T) {
 		require.Equal(t, uint64(2), st.Nlink)
 	})
 
+	t.Run("follows symlink", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("symlinks require privileges on windows")
+		}
+
+		symlinkName := "symlink"
+		require.NoError(t, os.Symlink(fileName, joinPath(oldDirPath, symlinkName)))
+		symlink := uint32(0x200)
+		require.True(t, mem.Write(symlink, []byte(symlinkName)))
+
+		followedName := "followed"
+		followed := uint32(0x210)
+		require.True(t, mem.Write(followed, []byte(followedName)))
+
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PathLinkName,
+			uint64(oldFd), uint64(wasip1.LOOKUP_SYMLINK_FOLLOW), uint64(symlink), uint64(len(symlinkName)),
+			uint64(newFd), uint64(followed), uint64(len(followedName)))
+
+		// The link is to the file, not the symlink.
+		st, err := os.Lstat(joinPath(newDirPath, followedName))
+		require.NoError(t, err)
+		require.True(t, st.Mode().IsRegular())
+		b, err := os.ReadFile(joinPath(newDirPath, followedName))
+		require.NoError(t, err)
+		require.Equal(t, []byte{1, 2, 3, 4}, b)
+	})
+
 	t.Run("errors", func(t *testing.T) {
 		for _, tc := range []struct {
 			errno                                      wasip1.Errno
 			oldFd /* oldFlags, */, oldPath, oldPathLen uint32
 			newFd, newPath, newPathLen                 uint32
 		}{
+			{
+				errno: wasip1.ErrnoExist, oldFd: oldFd, newFd: newFd,
+				oldPath: file, oldPathLen: uint32(len(fileName)),
+				newPath: destination, newPathLen: uint32(len(destinationName)),
+			},
+			{
+				errno: wasip1.ErrnoNoent, oldFd: oldFd, newFd: newFd,
+				oldPath: notFoundFile, oldPathLen: uint32(len(notFoundFileName)),
+				newPath: notFoundFile, newPathLen: uint32(len(notFoundFileName)),
+			},
 			{errno: wasip1.ErrnoBadf, oldFd: 1000},
 			{errno: wasip1.ErrnoBadf, oldFd: oldFd, newFd: 1000},
 			{errno: wasip1.ErrnoNotdir, oldFd: oldFd, newFd: 1},
@@ -3681,6 +4296,109 @@ func Test_pathLink(t *testing.T) {
 	})
 }
 
+func Test_pathLink_acrossMounts(t *testing.T) {
+	tmpDir, mntDir := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(joinPath(tmpDir, "file"), nil, 0o600))
+
+	fsConfig := wazero.NewFSConfig().WithDirMount(tmpDir, "/").WithDirMount(mntDir, "/mnt")
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+	defer r.Close(testCtx)
+
+	pathName := "file"
+	require.True(t, mod.Memory().Write(0, []byte(pathName)))
+
+	requireErrnoResult(t, wasip1.ErrnoXdev, mod, wasip1.PathLinkName,
+		uint64(sys.FdPreopen), 0, 0, uint64(len(pathName)),
+		uint64(sys.FdPreopen+1), 0, uint64(len(pathName)))
+	require.Equal(t, `
+==> wasi_snapshot_preview1.path_link(old_fd=3,old_flags=,old_path=file,new_fd=4,new_path=file)
+<== errno=EXDEV
+`, "\n"+log.String())
+}
+
+// Test_pathOpen_fdflags ensures fd_fdstat_get returns the fdflags a file was
+// opened with.
+func Test_pathOpen_fdflags(t *testing.T) {
+	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
+	require.NoError(t, os.WriteFile(joinPath(tmpDir, "file"), []byte("wazero"), 0o600))
+
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(
+		wazero.NewFSConfig().WithDirMount(tmpDir, "/"),
+	))
+	defer r.Close(testCtx)
+
+	pathName := "file"
+	const path, resultOpenedFd, resultFdstat = 0, 0x10, 0x20
+	require.True(t, mod.Memory().Write(path, []byte(pathName)))
+
+	for _, fdflags := range []uint16{
+		0,
+		wasip1.FD_APPEND,
+		wasip1.FD_NONBLOCK,
+		wasip1.FD_SYNC,
+		wasip1.FD_DSYNC,
+		wasip1.FD_DSYNC | wasip1.FD_RSYNC,
+		wasip1.FD_APPEND | wasip1.FD_NONBLOCK | wasip1.FD_SYNC,
+	} {
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PathOpenName,
+			uint64(sys.FdPreopen), 0, path, uint64(len(pathName)), 0, 0, 0, uint64(fdflags), resultOpenedFd)
+		fd, ok := mod.Memory().ReadUint32Le(resultOpenedFd)
+		require.True(t, ok)
+
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFdstatGetName, uint64(fd), resultFdstat)
+		actual, ok := mod.Memory().ReadUint16Le(resultFdstat + 2)
+		require.True(t, ok)
+		require.Equal(t, fdflags, actual)
+
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdCloseName, uint64(fd))
+	}
+
+	// The flags are logged by name.
+	require.Contains(t, log.String(), "fdflags=APPEND|NONBLOCK|SYNC")
+}
+
+func Test_pathOpen_fileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows only supports the read-only bit")
+	}
+
+	tests := []struct {
+		name            string
+		fileMode, umask fs.FileMode
+		expected        fs.FileMode
+	}{
+		{name: "default", fileMode: sys.DefaultFileMode, umask: sys.DefaultUmask, expected: 0o600},
+		{name: "umask", fileMode: sys.DefaultFileMode, umask: 0o022, expected: 0o644},
+		{name: "file mode", fileMode: 0o640, umask: 0o022, expected: 0o640},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().
+				WithFSConfig(wazero.NewFSConfig().WithDirMount(tmpDir, "/")))
+			defer r.Close(testCtx)
+
+			fsc := mod.(*wasm.CallContext).Sys.FS()
+			fsc.SetFileMode(tc.fileMode)
+			fsc.SetUmask(tc.umask)
+
+			pathName := "created"
+			path, resultOpenedFd := uint32(0), uint32(16) // arbitrary offsets
+			ok := mod.Memory().Write(path, []byte(pathName))
+			require.True(t, ok)
+
+			requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PathOpenName, uint64(sys.FdPreopen), 0, uint64(path),
+				uint64(len(pathName)), uint64(wasip1.O_CREAT), uint64(wasip1.RIGHT_FD_WRITE), 0, 0, uint64(resultOpenedFd))
+
+			st, err := os.Stat(joinPath(tmpDir, pathName))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, st.Mode().Perm())
+		})
+	}
+}
+
 func Test_pathOpen(t *testing.T) {
 	dir := t.TempDir() // open before loop to ensure no locking problems.
 	writeFS := sysfs.NewDirFS(dir)
@@ -3955,6 +4673,88 @@ func Test_pathOpen(t *testing.T) {
 	}
 }
 
+// Test_pathOpen_sandbox tries the same escape vectors as sysfs.TestSandbox
+// with path_open, which must not open a file outside the mount.
+func Test_pathOpen_sandbox(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+
+	tmpDir := t.TempDir()
+	root, outside := joinPath(tmpDir, "root"), joinPath(tmpDir, "outside")
+	require.NoError(t, os.MkdirAll(joinPath(root, "a/b"), 0o700))
+	require.NoError(t, os.Mkdir(outside, 0o700))
+	require.NoError(t, os.WriteFile(joinPath(outside, "secret"), []byte("secret"), 0o600))
+	for link, target := range map[string]string{
+		"abs":         outside,
+		"abs_secret":  joinPath(outside, "secret"),
+		"up":          "../outside",
+		"up_chain":    "a/b/../../../../../outside/secret",
+		"a/b/up":      "../../../outside",
+		"slash":       "/",
+		"slash_up":    "/../outside/secret",
+		"link_link":   "up",
+		"a/link_link": "../a/b/up",
+	} {
+		require.NoError(t, os.Symlink(target, joinPath(root, link)))
+	}
+
+	fsConfig := wazero.NewFSConfig().WithDirMount(root, "/")
+	mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+	defer r.Close(testCtx)
+	fsc := mod.(*wasm.CallContext).Sys.FS()
+
+	tests := []struct {
+		pathName string
+		// knownEscape is set for vectors which currently escape, as the host
+		// follows symbolic links in the parent directories of a path.
+		knownEscape bool
+	}{
+		{pathName: "../outside/secret"},
+		{pathName: "../../../../outside/secret"},
+		{pathName: "a/b/../../../outside/secret"},
+		{pathName: "/../outside/secret"},
+		{pathName: "abs/secret", knownEscape: true},
+		{pathName: "abs_secret"}, // not followed without symlink_follow
+		{pathName: "up/secret", knownEscape: true},
+		{pathName: "up_chain"},
+		{pathName: "a/b/up/secret", knownEscape: true},
+		{pathName: "slash/../outside/secret"},
+		{pathName: "slash_up"},
+		{pathName: "link_link/secret", knownEscape: true},
+		{pathName: "a/link_link/secret", knownEscape: true},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.pathName, func(t *testing.T) {
+			if tc.knownEscape {
+				t.Skip("known escape: the host follows symbolic links in parents")
+			}
+
+			pathLen, resultOpenedFd := uint32(len(tc.pathName)), uint32(len(tc.pathName))
+			require.True(t, mod.Memory().Write(0, []byte(tc.pathName)))
+			results, err := mod.ExportedFunction(wasip1.PathOpenName).Call(testCtx, uint64(sys.FdPreopen), 0, 0,
+				uint64(pathLen), 0, 0, 0, 0, uint64(resultOpenedFd))
+			require.NoError(t, err)
+			if wasip1.Errno(results[0]) != wasip1.ErrnoSuccess {
+				return
+			}
+
+			fd, ok := mod.Memory().ReadUint32Le(resultOpenedFd)
+			require.True(t, ok)
+			defer fsc.CloseFile(fd) //nolint
+
+			f, ok := fsc.LookupFile(fd)
+			require.True(t, ok)
+			if r, ok := f.File.(io.Reader); ok {
+				b, _ := io.ReadAll(r)
+				require.NotEqual(t, "secret", string(b))
+			}
+		})
+	}
+}
+
 func requireOpenFD(t *testing.T, mod api.Module, path string) uint32 {
 	fsc := mod.(*wasm.CallContext).Sys.FS()
 	preopen := fsc.RootFS()
@@ -4251,7 +5051,29 @@ func Test_pathReadlink(t *testing.T) {
 		}
 	})
 
+	t.Run("truncated to bufLen", func(t *testing.T) {
+		const buf, bufLen, resultBufused = 0x100, 3, 0x200
+		require.True(t, mem.Write(buf, []byte("????")))
+
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PathReadlinkName,
+			uint64(dirFD), uint64(destinationPath), uint64(len(destinationPathName)),
+			buf, bufLen, resultBufused)
+
+		size, ok := mem.ReadUint32Le(resultBufused)
+		require.True(t, ok)
+		require.Equal(t, uint32(bufLen), size)
+
+		// Only bufLen bytes were written.
+		actual, ok := mem.Read(buf, bufLen+1)
+		require.True(t, ok)
+		require.Equal(t, originalRelativePath[:bufLen]+"?", string(actual))
+	})
+
 	t.Run("errors", func(t *testing.T) {
+		originalFilePath := uint32(0x300)
+		ok := mem.Write(originalFilePath, []byte(originalFileName))
+		require.True(t, ok)
+
 		for _, tc := range []struct {
 			name                                          string
 			fd, path, pathLen, buf, bufLen, resultBufused uint32
@@ -4285,6 +5107,13 @@ func Test_pathReadlink(t *testing.T) {
 				path: destinationPath, pathLen: uint32(len(destinationPathName)) - 1,
 				fd: dirFD,
 			},
+			{
+				name:          "not a symlink",
+				expectedErrno: wasip1.ErrnoInval,
+				bufLen:        100, buf: 50,
+				path: originalFilePath, pathLen: uint32(len(originalFileName)),
+				fd: dirFD,
+			},
 		} {
 			name := tc.name
 			if name == "" {
@@ -4331,6 +5160,38 @@ func Test_pathRemoveDirectory(t *testing.T) {
 	require.Error(t, err)
 }
 
+// Test_pathRemoveDirectory_symlinkToDir ensures a symbolic link to a directory
+// isn't followed, so neither it nor the directory are removed.
+func Test_pathRemoveDirectory_symlinkToDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows removes directory symlinks with rmdir")
+	}
+
+	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
+	fsConfig := wazero.NewFSConfig().WithDirMount(tmpDir, "/")
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+	defer r.Close(testCtx)
+
+	dir, link := joinPath(tmpDir, "dir"), joinPath(tmpDir, "link")
+	require.NoError(t, os.Mkdir(dir, 0o700))
+	require.NoError(t, os.Symlink(dir, link))
+
+	pathName := "link"
+	ok := mod.Memory().Write(0, []byte(pathName))
+	require.True(t, ok)
+
+	requireErrnoResult(t, wasip1.ErrnoNotdir, mod, wasip1.PathRemoveDirectoryName, uint64(sys.FdPreopen), 0, uint64(len(pathName)))
+	require.Equal(t, `
+==> wasi_snapshot_preview1.path_remove_directory(fd=3,path=link)
+<== errno=ENOTDIR
+`, "\n"+log.String())
+
+	_, err := os.Lstat(link)
+	require.NoError(t, err)
+	_, err = os.Stat(dir)
+	require.NoError(t, err)
+}
+
 func Test_pathRemoveDirectory_Errors(t *testing.T) {
 	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
 	fsConfig := wazero.NewFSConfig().WithDirMount(tmpDir, "/")
@@ -4486,6 +5347,16 @@ func Test_pathSymlink_errors(t *testing.T) {
 		st, err := os.Lstat(joinPath(dirPath, linkName))
 		require.NoError(t, err)
 		require.Equal(t, st.Mode()&os.ModeSymlink, os.ModeSymlink)
+
+		// Read the link back through the ABI.
+		const buf, bufLen, resultBufused = 0x300, 0x20, 0x400
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PathReadlinkName,
+			uint64(fd), uint64(link), uint64(len(linkName)), buf, bufLen, resultBufused)
+		size, ok := mem.ReadUint32Le(resultBufused)
+		require.True(t, ok)
+		actual, ok := mem.Read(buf, size)
+		require.True(t, ok)
+		require.Equal(t, fileName, string(actual))
 	})
 
 	t.Run("errors", func(t *testing.T) {
@@ -4566,6 +5437,86 @@ func Test_pathRename(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func Test_pathRename_acrossMounts(t *testing.T) {
+	tmpDir, mntDir := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(joinPath(tmpDir, "file"), nil, 0o600))
+
+	fsConfig := wazero.NewFSConfig().WithDirMount(tmpDir, "/").WithDirMount(mntDir, "/mnt")
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+	defer r.Close(testCtx)
+
+	pathName := "file"
+	require.True(t, mod.Memory().Write(0, []byte(pathName)))
+
+	requireErrnoResult(t, wasip1.ErrnoXdev, mod, wasip1.PathRenameName,
+		uint64(sys.FdPreopen), 0, uint64(len(pathName)),
+		uint64(sys.FdPreopen+1), 0, uint64(len(pathName)))
+	require.Equal(t, `
+==> wasi_snapshot_preview1.path_rename(fd=3,old_path=file,new_fd=4,new_path=file)
+<== errno=EXDEV
+`, "\n"+log.String())
+}
+
+// Test_pathRename_dirFds ensures paths resolve relative to their own directory
+// fd, and that renaming over an existing file replaces it.
+func Test_pathRename_dirFds(t *testing.T) {
+	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
+	require.NoError(t, os.Mkdir(joinPath(tmpDir, "a"), 0o700))
+	require.NoError(t, os.Mkdir(joinPath(tmpDir, "b"), 0o700))
+	require.NoError(t, os.WriteFile(joinPath(tmpDir, "a/wazero"), []byte("new"), 0o600))
+	require.NoError(t, os.WriteFile(joinPath(tmpDir, "b/wazero"), []byte("old"), 0o600))
+
+	fsConfig := wazero.NewFSConfig().WithDirMount(tmpDir, "/")
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+	defer r.Close(testCtx)
+
+	oldfd := requireOpenFD(t, mod, "a")
+	newfd := requireOpenFD(t, mod, "b")
+
+	pathName := "wazero"
+	oldPath, newPath := uint32(0), uint32(16)
+	require.True(t, mod.Memory().Write(oldPath, []byte(pathName)))
+	require.True(t, mod.Memory().Write(newPath, []byte(pathName)))
+
+	t.Run("across directory fds", func(t *testing.T) {
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PathRenameName,
+			uint64(oldfd), uint64(oldPath), uint64(len(pathName)),
+			uint64(newfd), uint64(newPath), uint64(len(pathName)))
+		require.Equal(t, `
+==> wasi_snapshot_preview1.path_rename(fd=4,old_path=wazero,new_fd=5,new_path=wazero)
+<== errno=ESUCCESS
+`, "\n"+log.String())
+
+		// ensure the file replaced the existing one
+		_, err := os.Stat(joinPath(tmpDir, "a/wazero"))
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		b, err := os.ReadFile(joinPath(tmpDir, "b/wazero"))
+		require.NoError(t, err)
+		require.Equal(t, "new", string(b))
+	})
+
+	t.Run("within a directory fd", func(t *testing.T) {
+		log.Reset()
+
+		newPathName := "wahzero"
+		require.True(t, mod.Memory().Write(newPath, []byte(newPathName)))
+
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PathRenameName,
+			uint64(newfd), uint64(oldPath), uint64(len(pathName)),
+			uint64(newfd), uint64(newPath), uint64(len(newPathName)))
+		require.Equal(t, `
+==> wasi_snapshot_preview1.path_rename(fd=5,old_path=wazero,new_fd=5,new_path=wahzero)
+<== errno=ESUCCESS
+`, "\n"+log.String())
+
+		_, err := os.Stat(joinPath(tmpDir, "b/wazero"))
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		b, err := os.ReadFile(joinPath(tmpDir, "b/wahzero"))
+		require.NoError(t, err)
+		require.Equal(t, "new", string(b))
+	})
+}
+
 func Test_pathRename_Errors(t *testing.T) {
 	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
 	fsConfig := wazero.NewFSConfig().WithDirMount(tmpDir, "/")
@@ -4771,6 +5722,36 @@ func Test_pathUnlinkFile(t *testing.T) {
 	require.Error(t, err)
 }
 
+// Test_pathUnlinkFile_symlinkToDir ensures unlinking a symbolic link to a
+// directory removes the link, instead of failing with EISDIR.
+func Test_pathUnlinkFile_symlinkToDir(t *testing.T) {
+	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
+	fsConfig := wazero.NewFSConfig().WithDirMount(tmpDir, "/")
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+	defer r.Close(testCtx)
+
+	dir, link := joinPath(tmpDir, "dir"), joinPath(tmpDir, "link")
+	require.NoError(t, os.Mkdir(dir, 0o700))
+	require.NoError(t, os.Symlink(dir, link))
+
+	pathName := "link"
+	ok := mod.Memory().Write(0, []byte(pathName))
+	require.True(t, ok)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PathUnlinkFileName, uint64(sys.FdPreopen), 0, uint64(len(pathName)))
+	require.Equal(t, `
+==> wasi_snapshot_preview1.path_unlink_file(fd=3,path=link)
+<== errno=ESUCCESS
+`, "\n"+log.String())
+
+	// Only the link was removed.
+	_, err := os.Lstat(link)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	st, err := os.Stat(dir)
+	require.NoError(t, err)
+	require.True(t, st.IsDir())
+}
+
 func Test_pathUnlinkFile_Errors(t *testing.T) {
 	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
 	fsConfig := wazero.NewFSConfig().WithDirMount(tmpDir, "/")
@@ -2,7 +2,6 @@ package wasi_snapshot_preview1
 
 import (
 	"io"
-	"os"
 	"syscall"
 	"testing"
 
@@ -98,6 +97,35 @@ func Test_fdRead_shouldContinueRead(t *testing.T) {
 	}
 }
 
+func Test_iovecsOverlap(t *testing.T) {
+	tests := []struct {
+		name     string
+		iovs     [][2]uint32 // offset, length
+		expected bool
+	}{
+		{name: "none"},
+		{name: "one", iovs: [][2]uint32{{0, 8}}},
+		{name: "adjacent", iovs: [][2]uint32{{8, 8}, {0, 8}}},
+		{name: "empty within another", iovs: [][2]uint32{{0, 8}, {4, 0}}},
+		{name: "same offset", iovs: [][2]uint32{{0, 8}, {0, 4}}, expected: true},
+		{name: "partial", iovs: [][2]uint32{{4, 8}, {0, 8}}, expected: true},
+		{name: "within an earlier one", iovs: [][2]uint32{{0, 100}, {10, 10}, {50, 10}}, expected: true},
+		{name: "at the end of memory", iovs: [][2]uint32{{0xfffffff0, 0x10}, {0xfffffff8, 1}}, expected: true},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			iovsBuf := make([]byte, len(tc.iovs)*8)
+			for i, iov := range tc.iovs {
+				le.PutUint32(iovsBuf[i*8:], iov[0])
+				le.PutUint32(iovsBuf[i*8+4:], iov[1])
+			}
+			require.Equal(t, tc.expected, iovecsOverlap(iovsBuf))
+		})
+	}
+}
+
 func Test_lastDirents(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -403,6 +431,21 @@ func Test_openFlags(t *testing.T) {
 			fdflags:           wasip1.FD_APPEND,
 			expectedOpenFlags: platform.O_NOFOLLOW | syscall.O_RDWR | syscall.O_APPEND,
 		},
+		{
+			name:              "fdflags=FD_NONBLOCK",
+			fdflags:           wasip1.FD_NONBLOCK,
+			expectedOpenFlags: platform.O_NOFOLLOW | syscall.O_RDONLY | platform.O_NONBLOCK,
+		},
+		{
+			name:              "fdflags=FD_SYNC|FD_DSYNC|FD_RSYNC",
+			fdflags:           wasip1.FD_SYNC | wasip1.FD_DSYNC | wasip1.FD_RSYNC,
+			expectedOpenFlags: platform.O_NOFOLLOW | syscall.O_RDONLY | syscall.O_SYNC | platform.O_DSYNC | platform.O_RSYNC,
+		},
+		{
+			name:              "fdflags=FD_DSYNC",
+			fdflags:           wasip1.FD_DSYNC,
+			expectedOpenFlags: platform.O_NOFOLLOW | syscall.O_RDONLY | platform.O_DSYNC,
+		},
 		{
 			name:              "oflags=O_TRUNC|O_CREAT",
 			oflags:            wasip1.O_TRUNC | wasip1.O_CREAT,
@@ -429,13 +472,3 @@ func Test_openFlags(t *testing.T) {
 		})
 	}
 }
-
-func Test_getWasiFiletype_DevNull(t *testing.T) {
-	st, err := os.Stat(os.DevNull)
-	require.NoError(t, err)
-
-	ft := getWasiFiletype(st.Mode())
-
-	// Should be a character device, and not contain permissions
-	require.Equal(t, wasip1.FILETYPE_CHARACTER_DEVICE, ft)
-}
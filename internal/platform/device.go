@@ -0,0 +1,29 @@
+//go:build !darwin && !freebsd
+
+package platform
+
+// Mkdev returns a device number from its major and minor numbers, in the same
+// encoding as Stat_t.Dev. See https://man7.org/linux/man-pages/man3/makedev.3.html
+//
+// Note: This uses the encoding of Linux, except on darwin and freebsd.
+func Mkdev(major, minor uint32) uint64 {
+	dev := (uint64(major) & 0x00000fff) << 8
+	dev |= (uint64(major) & 0xfffff000) << 32
+	dev |= (uint64(minor) & 0x000000ff) << 0
+	dev |= (uint64(minor) & 0xffffff00) << 12
+	return dev
+}
+
+// Major returns the major number of a device number made with Mkdev.
+func Major(dev uint64) uint32 {
+	major := uint32((dev & 0x00000000000fff00) >> 8)
+	major |= uint32((dev & 0xfffff00000000000) >> 32)
+	return major
+}
+
+// Minor returns the minor number of a device number made with Mkdev.
+func Minor(dev uint64) uint32 {
+	minor := uint32((dev & 0x00000000000000ff) >> 0)
+	minor |= uint32((dev & 0x00000ffffff00000) >> 12)
+	return minor
+}
@@ -0,0 +1,17 @@
+package platform
+
+// Mkdev returns a device number from its major and minor numbers, in the same
+// encoding as Stat_t.Dev.
+func Mkdev(major, minor uint32) uint64 {
+	return (uint64(major) << 24) | uint64(minor)
+}
+
+// Major returns the major number of a device number made with Mkdev.
+func Major(dev uint64) uint32 {
+	return uint32((dev >> 24) & 0xff)
+}
+
+// Minor returns the minor number of a device number made with Mkdev.
+func Minor(dev uint64) uint32 {
+	return uint32(dev & 0xffffff)
+}
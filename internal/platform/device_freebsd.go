@@ -0,0 +1,18 @@
+package platform
+
+// Mkdev returns a device number from its major and minor numbers, in the same
+// encoding as Stat_t.Dev.
+func Mkdev(major, minor uint32) uint64 {
+	return ((uint64(major) & 0xffffff00) << 32) | ((uint64(major) & 0xff) << 8) |
+		((uint64(minor) & 0xff00) << 24) | (uint64(minor) & 0xffff00ff)
+}
+
+// Major returns the major number of a device number made with Mkdev.
+func Major(dev uint64) uint32 {
+	return uint32(((dev >> 32) & 0xffffff00) | ((dev >> 8) & 0xff))
+}
+
+// Minor returns the minor number of a device number made with Mkdev.
+func Minor(dev uint64) uint32 {
+	return uint32(((dev >> 24) & 0xff00) | (dev & 0xffff00ff))
+}
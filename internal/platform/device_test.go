@@ -0,0 +1,28 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMkdev(t *testing.T) {
+	tests := []struct {
+		name         string
+		major, minor uint32
+	}{
+		{name: "zero"},
+		{name: "null", major: 1, minor: 3},
+		{name: "large minor", major: 8, minor: 0xabcd},
+		{name: "max byte", major: 0xff, minor: 0xff},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			dev := Mkdev(tc.major, tc.minor)
+			require.Equal(t, tc.major, Major(dev))
+			require.Equal(t, tc.minor, Minor(dev))
+		})
+	}
+}
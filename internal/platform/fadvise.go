@@ -0,0 +1,59 @@
+package platform
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// Advice is how a file will be accessed, as a hint to the host. The values
+// are the same as WASI, not the host.
+type Advice uint8
+
+const (
+	// AdviceNormal is no advice, which is the default.
+	AdviceNormal Advice = iota
+	// AdviceSequential is that the data will be accessed sequentially.
+	AdviceSequential
+	// AdviceRandom is that the data will be accessed randomly.
+	AdviceRandom
+	// AdviceWillNeed is that the data will be accessed soon.
+	AdviceWillNeed
+	// AdviceDontNeed is that the data won't be accessed soon.
+	AdviceDontNeed
+	// AdviceNoReuse is that the data will be accessed only once.
+	AdviceNoReuse
+)
+
+// Adviser is implemented by files that handle Advice themselves, instead of
+// passing it to the host.
+type Adviser interface {
+	Advise(offset, length int64, advice Advice) error
+}
+
+// Fadvise is like posix_fadvise: it hints how `length` bytes of the file at
+// `offset` will be accessed, so that the host can optimize caching. A `length`
+// of zero means until the end of the file.
+//
+// When the file implements Adviser, it handles the advice. Otherwise, it
+// reaches the host on linux, and is ignored elsewhere.
+//
+// Note: This is only a hint, so it returns with no error instead of
+// syscall.ENOSYS when unimplemented, including on fake files and files the
+// host doesn't advise, such as pipes. An unsupported `advice` value returns
+// syscall.EINVAL.
+func Fadvise(f fs.File, offset, length int64, advice Advice) syscall.Errno {
+	if offset < 0 || length < 0 || advice > AdviceNoReuse {
+		return syscall.EINVAL
+	}
+	if a, ok := f.(Adviser); ok {
+		return UnwrapOSError(a.Advise(offset, length, advice))
+	}
+	return fadvise(f, offset, length, advice)
+}
+
+// Readahead hints that `length` bytes of the file at `offset` will be read
+// soon, so that the host can load them into its page cache before a
+// sequential scan. This is the same as Fadvise with AdviceWillNeed.
+func Readahead(f fs.File, offset, length int64) syscall.Errno {
+	return Fadvise(f, offset, length, AdviceWillNeed)
+}
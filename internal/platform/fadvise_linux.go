@@ -0,0 +1,34 @@
+//go:build (amd64 || arm64 || riscv64) && linux
+
+package platform
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// posixFadvise maps Advice to the POSIX_FADV_* values on linux, which order
+// sequential and random differently than WASI.
+var posixFadvise = [...]uintptr{
+	AdviceNormal:     0,
+	AdviceSequential: 2,
+	AdviceRandom:     1,
+	AdviceWillNeed:   3,
+	AdviceDontNeed:   4,
+	AdviceNoReuse:    5,
+}
+
+func fadvise(f fs.File, offset, length int64, advice Advice) syscall.Errno {
+	if fd, ok := f.(fdFile); ok {
+		_, _, errno := syscall.Syscall6(syscall.SYS_FADVISE64, fd.Fd(),
+			uintptr(offset), uintptr(length), posixFadvise[advice], 0, 0)
+		switch errno {
+		case syscall.ESPIPE, syscall.EINVAL:
+			// The file, such as a pipe, or its file system doesn't support
+			// the hint. The arguments were already validated.
+			return 0
+		}
+		return errno
+	}
+	return 0
+}
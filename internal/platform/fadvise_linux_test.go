@@ -0,0 +1,25 @@
+//go:build (amd64 || arm64 || riscv64) && linux
+
+package platform
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// TestFadvise_native ensures the hint reaches the host, which ignores it on a
+// pipe as it has no page cache to read into, but fails on a closed file.
+func TestFadvise_native(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer w.Close()
+
+	require.Zero(t, Fadvise(r, 0, 0, AdviceSequential))
+	require.Zero(t, Readahead(r, 0, 0))
+
+	require.NoError(t, r.Close())
+	require.EqualErrno(t, syscall.EBADF, Fadvise(r, 0, 0, AdviceSequential))
+}
@@ -0,0 +1,83 @@
+package platform
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestReadahead(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := path.Join(tmpDir, "file")
+	require.NoError(t, os.WriteFile(file, []byte("wazero"), 0o600))
+
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+
+	t.Run("file", func(t *testing.T) {
+		require.Zero(t, Readahead(f, 0, 0))
+		require.Zero(t, Readahead(f, 2, 2))
+		require.Zero(t, Readahead(f, 100, 100)) // past EOF is ok
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		require.EqualErrno(t, syscall.EINVAL, Readahead(f, -1, 0))
+		require.EqualErrno(t, syscall.EINVAL, Readahead(f, 0, -1))
+	})
+
+	t.Run("fake file", func(t *testing.T) {
+		fake, err := fstest.MapFS{"file": {Data: []byte("wazero")}}.Open("file")
+		require.NoError(t, err)
+		defer fake.Close()
+
+		require.Zero(t, Readahead(fake, 0, 0))
+	})
+}
+
+func TestFadvise(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := path.Join(tmpDir, "file")
+	require.NoError(t, os.WriteFile(file, []byte("wazero"), 0o600))
+
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+
+	t.Run("file", func(t *testing.T) {
+		for advice := AdviceNormal; advice <= AdviceNoReuse; advice++ {
+			require.Zero(t, Fadvise(f, 0, 0, advice))
+		}
+	})
+
+	t.Run("invalid advice", func(t *testing.T) {
+		require.EqualErrno(t, syscall.EINVAL, Fadvise(f, 0, 0, AdviceNoReuse+1))
+	})
+
+	t.Run("Adviser", func(t *testing.T) {
+		a := &adviserFile{err: errors.New("ignored")}
+		require.EqualErrno(t, syscall.EIO, Fadvise(a, 1, 2, AdviceRandom))
+		require.Equal(t, []interface{}{int64(1), int64(2), AdviceRandom}, a.args)
+
+		a = &adviserFile{err: syscall.EBADF}
+		require.EqualErrno(t, syscall.EBADF, Fadvise(a, 0, 0, AdviceDontNeed))
+	})
+}
+
+// adviserFile records the arguments of Advise, and returns err.
+type adviserFile struct {
+	fs.File
+	args []interface{}
+	err  error
+}
+
+func (a *adviserFile) Advise(offset, length int64, advice Advice) error {
+	a.args = []interface{}{offset, length, advice}
+	return a.err
+}
@@ -0,0 +1,12 @@
+//go:build !((amd64 || arm64 || riscv64) && linux)
+
+package platform
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+func fadvise(fs.File, int64, int64, Advice) syscall.Errno {
+	return 0
+}
@@ -0,0 +1,42 @@
+package platform
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// Allocator is implemented by files that allocate space themselves, instead
+// of emulating it with Truncate.
+type Allocator interface {
+	Allocate(offset, length int64) error
+}
+
+// Fallocate ensures space is allocated for `length` bytes of the file at
+// `offset`, growing the file if that region is past its end. Existing data is
+// preserved. A zero syscall.Errno is success.
+//
+// When the file implements Allocator, it allocates. Otherwise, this is
+// emulated by growing the file with Truncate, which doesn't reserve blocks
+// in sparse files, so later writes may still fail with syscall.ENOSPC.
+func Fallocate(f fs.File, offset, length int64) syscall.Errno {
+	tail := offset + length
+	if offset < 0 || length < 0 || tail < 0 {
+		return syscall.EINVAL
+	}
+
+	if a, ok := f.(Allocator); ok {
+		return UnwrapOSError(a.Allocate(offset, length))
+	}
+
+	st, errno := StatFile(f)
+	if errno != 0 {
+		return errno
+	} else if st.Size >= tail {
+		return 0 // we already have enough space.
+	}
+
+	if t, ok := f.(interface{ Truncate(int64) error }); ok {
+		return UnwrapOSError(t.Truncate(tail))
+	}
+	return syscall.EBADF
+}
@@ -0,0 +1,77 @@
+package platform
+
+import (
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestFallocate(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := path.Join(tmpDir, "file")
+	require.NoError(t, os.WriteFile(file, []byte("wazero"), 0o600))
+
+	f, err := os.OpenFile(file, os.O_RDWR, 0)
+	require.NoError(t, err)
+	defer f.Close()
+
+	requireSize := func(expected int64) {
+		st, errno := StatFile(f)
+		require.Zero(t, errno)
+		require.Equal(t, expected, st.Size)
+	}
+
+	t.Run("within the file", func(t *testing.T) {
+		require.Zero(t, Fallocate(f, 0, 6))
+		require.Zero(t, Fallocate(f, 2, 2))
+		requireSize(6)
+	})
+
+	t.Run("grows the file", func(t *testing.T) {
+		require.Zero(t, Fallocate(f, 4, 6))
+		requireSize(10)
+
+		b, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Equal(t, []byte("wazero\x00\x00\x00\x00"), b)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		require.EqualErrno(t, syscall.EINVAL, Fallocate(f, -1, 1))
+		require.EqualErrno(t, syscall.EINVAL, Fallocate(f, 0, -1))
+		require.EqualErrno(t, syscall.EINVAL, Fallocate(f, 1, math.MaxInt64))
+	})
+
+	t.Run("Allocator", func(t *testing.T) {
+		a := &allocatorFile{err: syscall.ENOSPC}
+		require.EqualErrno(t, syscall.ENOSPC, Fallocate(a, 1, 2))
+		require.Equal(t, [2]int64{1, 2}, a.args)
+	})
+
+	t.Run("can't truncate", func(t *testing.T) {
+		fake, err := fstest.MapFS{"file": {Data: []byte("wazero")}}.Open("file")
+		require.NoError(t, err)
+		defer fake.Close()
+
+		require.Zero(t, Fallocate(fake, 0, 6))
+		require.EqualErrno(t, syscall.EBADF, Fallocate(fake, 0, 7))
+	})
+}
+
+// allocatorFile records the arguments of Allocate, and returns err.
+type allocatorFile struct {
+	fs.File
+	args [2]int64
+	err  error
+}
+
+func (a *allocatorFile) Allocate(offset, length int64) error {
+	a.args = [2]int64{offset, length}
+	return a.err
+}
@@ -19,7 +19,9 @@ type ReadFile interface {
 type File interface {
 	ReadFile
 	io.Writer
-	io.WriterAt // for pwrite
+	// WriterAt is for pwrite. Writing past the end of the file must extend
+	// it, filling the gap with zeros, the same as POSIX pwrite.
+	io.WriterAt
 	chmodFile
 	syncFile
 	truncateFile
@@ -46,5 +48,8 @@ type (
 	// syncFile is implemented by os.File in file_posix.go
 	syncFile interface{ Sync() error }
 	// truncateFile is implemented by os.File in file_posix.go
+	//
+	// Like POSIX ftruncate, Truncate must not change the file offset, even
+	// when it is beyond the new size: the next write then zero-fills the gap.
 	truncateFile interface{ Truncate(size int64) error }
 )
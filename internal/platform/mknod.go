@@ -0,0 +1,44 @@
+package platform
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// Mknod is like syscall.Mknod, except the type of the file to create is
+// defined by `mode` and it returns a syscall.Errno. A syscall.Errno of zero is
+// success.
+//
+// The type of `mode` can be zero for a regular file, fs.ModeNamedPipe for a
+// FIFO, fs.ModeDevice for a block device or fs.ModeDevice|fs.ModeCharDevice
+// for a character device. Any other type returns syscall.EINVAL. `dev` is the
+// device number, made with Mkdev, and is only used for devices.
+//
+// # Notes
+//
+//   - Creating devices usually requires privileges, such as root on Linux.
+//     Otherwise, syscall.EPERM results.
+//   - This always returns syscall.ENOSYS on unsupported platforms, such as
+//     windows.
+//
+// See https://linux.die.net/man/2/mknod
+func Mknod(path string, mode fs.FileMode, dev uint64) syscall.Errno {
+	if !mknodSupported {
+		return syscall.ENOSYS
+	}
+
+	var typ uint32
+	switch mode.Type() {
+	case 0:
+		typ = s_IFREG
+	case fs.ModeNamedPipe:
+		typ = s_IFIFO
+	case fs.ModeDevice:
+		typ = s_IFBLK
+	case fs.ModeDevice | fs.ModeCharDevice:
+		typ = s_IFCHR
+	default:
+		return syscall.EINVAL
+	}
+	return UnwrapOSError(mknod(path, typ|uint32(mode.Perm()), dev))
+}
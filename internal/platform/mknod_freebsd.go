@@ -0,0 +1,7 @@
+package platform
+
+import "syscall"
+
+func mknod(path string, mode uint32, dev uint64) error {
+	return syscall.Mknod(path, mode, dev)
+}
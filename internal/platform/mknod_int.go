@@ -0,0 +1,9 @@
+//go:build linux || darwin || illumos || solaris
+
+package platform
+
+import "syscall"
+
+func mknod(path string, mode uint32, dev uint64) error {
+	return syscall.Mknod(path, mode, int(dev))
+}
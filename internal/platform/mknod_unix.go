@@ -0,0 +1,14 @@
+//go:build linux || darwin || freebsd || illumos || solaris
+
+package platform
+
+import "syscall"
+
+const (
+	mknodSupported = true
+
+	s_IFREG = syscall.S_IFREG
+	s_IFIFO = syscall.S_IFIFO
+	s_IFBLK = syscall.S_IFBLK
+	s_IFCHR = syscall.S_IFCHR
+)
@@ -0,0 +1,19 @@
+//go:build !(linux || darwin || freebsd || illumos || solaris)
+
+package platform
+
+import "syscall"
+
+const (
+	mknodSupported = false
+
+	// Values below are unused, but defined so that Mknod compiles.
+	s_IFREG = 0
+	s_IFIFO = 0
+	s_IFBLK = 0
+	s_IFCHR = 0
+)
+
+func mknod(string, uint32, uint64) error {
+	return syscall.ENOSYS
+}
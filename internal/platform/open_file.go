@@ -13,11 +13,17 @@ import (
 const (
 	O_DIRECTORY = syscall.O_DIRECTORY
 	O_NOFOLLOW  = syscall.O_NOFOLLOW
+	O_NONBLOCK  = syscall.O_NONBLOCK
 )
 
+// DirectoryFlagEnforced is true when OpenFile fails with syscall.ENOTDIR if
+// O_DIRECTORY is set and the path isn't a directory.
+const DirectoryFlagEnforced = true
+
 // OpenFile is like os.OpenFile except it returns syscall.Errno. A zero
 // syscall.Errno is success.
 func OpenFile(path string, flag int, perm fs.FileMode) (File, syscall.Errno) {
+	flag = replaceSyncPlaceholders(flag)
 	f, err := os.OpenFile(path, flag, perm)
 	return f, UnwrapOSError(err)
 }
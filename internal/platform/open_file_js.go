@@ -10,10 +10,16 @@ import (
 const (
 	O_DIRECTORY = 1 << 29
 	O_NOFOLLOW  = 1 << 30
+	// O_NONBLOCK is a placeholder, as syscall doesn't define it on js.
+	O_NONBLOCK = 1 << 28
 )
 
+// DirectoryFlagEnforced is false as O_DIRECTORY is a placeholder.
+const DirectoryFlagEnforced = false
+
 func OpenFile(path string, flag int, perm fs.FileMode) (File, syscall.Errno) {
-	flag &= ^(O_DIRECTORY | O_NOFOLLOW) // erase placeholders
+	flag = replaceSyncPlaceholders(flag)
+	flag &= ^(O_DIRECTORY | O_NOFOLLOW | O_NONBLOCK) // erase placeholders
 	f, err := os.OpenFile(path, flag, perm)
 	return f, UnwrapOSError(err)
 }
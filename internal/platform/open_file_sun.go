@@ -12,9 +12,15 @@ const (
 	// See https://github.com/illumos/illumos-gate/blob/edd580643f2cf1434e252cd7779e83182ea84945/usr/src/uts/common/sys/fcntl.h#L90
 	O_DIRECTORY = 0x1000000
 	O_NOFOLLOW  = syscall.O_NOFOLLOW
+	O_NONBLOCK  = syscall.O_NONBLOCK
 )
 
+// DirectoryFlagEnforced is true when OpenFile fails with syscall.ENOTDIR if
+// O_DIRECTORY is set and the path isn't a directory.
+const DirectoryFlagEnforced = true
+
 func OpenFile(path string, flag int, perm fs.FileMode) (File, syscall.Errno) {
+	flag = replaceSyncPlaceholders(flag)
 	f, err := os.OpenFile(path, flag, perm)
 	return f, UnwrapOSError(err)
 }
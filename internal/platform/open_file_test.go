@@ -33,6 +33,12 @@ func TestOpenFile(t *testing.T) {
 	}
 }
 
+func Test_replaceSyncPlaceholders(t *testing.T) {
+	require.Equal(t, os.O_RDWR, replaceSyncPlaceholders(os.O_RDWR))
+	require.Equal(t, os.O_RDWR|syscall.O_SYNC, replaceSyncPlaceholders(os.O_RDWR|O_DSYNC))
+	require.Equal(t, syscall.O_SYNC, replaceSyncPlaceholders(O_DSYNC|O_RSYNC|syscall.O_SYNC))
+}
+
 func TestOpenFile_Errors(t *testing.T) {
 	tmpDir := t.TempDir()
 
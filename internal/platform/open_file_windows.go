@@ -28,6 +28,12 @@ const (
 	O_NOFOLLOW  = 1 << 30
 )
 
+// O_NONBLOCK is aliased for portability with js, which doesn't define it.
+const O_NONBLOCK = syscall.O_NONBLOCK
+
+// DirectoryFlagEnforced is false as O_DIRECTORY is a placeholder.
+const DirectoryFlagEnforced = false
+
 func OpenFile(path string, flag int, perm fs.FileMode) (File, syscall.Errno) {
 	if f, errno := openFile(path, flag, perm); errno != 0 {
 		return nil, errno
@@ -38,6 +44,7 @@ func OpenFile(path string, flag int, perm fs.FileMode) (File, syscall.Errno) {
 
 func openFile(path string, flag int, perm fs.FileMode) (*os.File, syscall.Errno) {
 	isDir := flag&O_DIRECTORY > 0
+	flag = replaceSyncPlaceholders(flag)
 	flag &= ^(O_DIRECTORY | O_NOFOLLOW) // erase placeholders
 
 	// TODO: document why we are opening twice
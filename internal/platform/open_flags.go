@@ -0,0 +1,24 @@
+package platform
+
+import "syscall"
+
+// O_DSYNC and O_RSYNC are placeholders, which OpenFile replaces with
+// syscall.O_SYNC, as it is stricter than both. They aren't defined by the
+// syscall package of all platforms, and on linux they overlap syscall.O_SYNC,
+// so they couldn't be told apart after opening.
+const (
+	O_DSYNC = 1 << 26
+	O_RSYNC = 1 << 27
+)
+
+// oSyncPlaceholders are the placeholders for synchronized I/O flags.
+const oSyncPlaceholders = O_DSYNC | O_RSYNC
+
+// replaceSyncPlaceholders replaces oSyncPlaceholders in `flag` with
+// syscall.O_SYNC.
+func replaceSyncPlaceholders(flag int) int {
+	if flag&oSyncPlaceholders != 0 {
+		flag = flag&^oSyncPlaceholders | syscall.O_SYNC
+	}
+	return flag
+}
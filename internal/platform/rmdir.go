@@ -0,0 +1,18 @@
+package platform
+
+import "syscall"
+
+// Rmdir is like syscall.Rmdir, except removing a directory that isn't empty
+// consistently fails with syscall.ENOTEMPTY.
+//
+// POSIX allows rmdir to fail with either syscall.EEXIST or syscall.ENOTEMPTY
+// in this case, and some platforms, such as illumos, return the former.
+//
+// See https://pubs.opengroup.org/onlinepubs/9699919799/functions/rmdir.html
+func Rmdir(path string) syscall.Errno {
+	errno := UnwrapOSError(syscall.Rmdir(path))
+	if errno == syscall.EEXIST {
+		errno = syscall.ENOTEMPTY
+	}
+	return errno
+}
@@ -0,0 +1,36 @@
+package platform
+
+import (
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestRmdir(t *testing.T) {
+	t.Run("doesn't exist", func(t *testing.T) {
+		errno := Rmdir(path.Join(t.TempDir(), "non-existent"))
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("not empty", func(t *testing.T) {
+		dir := path.Join(t.TempDir(), "dir")
+		require.NoError(t, os.Mkdir(dir, 0o700))
+		require.NoError(t, os.WriteFile(path.Join(dir, "file"), nil, 0o600))
+
+		errno := Rmdir(dir)
+		require.EqualErrno(t, syscall.ENOTEMPTY, errno)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		dir := path.Join(t.TempDir(), "dir")
+		require.NoError(t, os.Mkdir(dir, 0o700))
+
+		require.Zero(t, Rmdir(dir))
+
+		_, err := os.Stat(dir)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
@@ -0,0 +1,50 @@
+package platform
+
+import (
+	"io"
+	"io/fs"
+	"syscall"
+)
+
+// SeekData is like lseek with SEEK_DATA. It returns the offset of the next
+// region at or after `offset` that contains data.
+//
+// # Errors
+//
+// The following errors are expected:
+//   - syscall.ENXIO: there is no data at or after `offset`.
+//   - syscall.ENOSYS: the file or platform doesn't support sparse files.
+func SeekData(f fs.File, offset int64) (int64, syscall.Errno) {
+	return seekSparse(f, offset, seekData)
+}
+
+// SeekHole is like lseek with SEEK_HOLE. It returns the offset of the next
+// hole at or after `offset`. The end of the file is considered a hole.
+//
+// # Errors
+//
+// The following errors are expected:
+//   - syscall.ENXIO: `offset` is at or past the end of the file.
+//   - syscall.ENOSYS: the file or platform doesn't support sparse files.
+func SeekHole(f fs.File, offset int64) (int64, syscall.Errno) {
+	return seekSparse(f, offset, seekHole)
+}
+
+func seekSparse(f fs.File, offset int64, whence int) (int64, syscall.Errno) {
+	if whence == -1 {
+		return 0, syscall.ENOSYS
+	}
+
+	// Only files backed by a file descriptor pass whence through to lseek.
+	// Other implementations of io.Seeker would misinterpret it.
+	if _, ok := f.(fdFile); !ok {
+		return 0, syscall.ENOSYS
+	}
+	s, ok := f.(io.Seeker)
+	if !ok {
+		return 0, syscall.ENOSYS
+	}
+
+	newOffset, err := s.Seek(offset, whence)
+	return newOffset, UnwrapOSError(err)
+}
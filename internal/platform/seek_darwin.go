@@ -0,0 +1,6 @@
+package platform
+
+const (
+	seekData = 4 // SEEK_DATA
+	seekHole = 3 // SEEK_HOLE
+)
@@ -0,0 +1,50 @@
+package platform
+
+import (
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestSeekDataHole(t *testing.T) {
+	f, err := os.Create(path.Join(t.TempDir(), "sparse"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	// Make a file that ends with data after a large hole.
+	const dataOffset = 1 << 20
+	require.NoError(t, f.Truncate(dataOffset))
+	_, err = f.WriteAt([]byte("wazero"), dataOffset)
+	require.NoError(t, err)
+
+	data, errno := SeekData(f, 0)
+	if errno == syscall.ENOSYS {
+		return // don't continue if it isn't supported.
+	}
+	require.Zero(t, errno)
+	require.True(t, data <= dataOffset)
+
+	hole, errno := SeekHole(f, data)
+	require.Zero(t, errno)
+	require.Equal(t, int64(dataOffset+6), hole)
+
+	_, errno = SeekData(f, hole)
+	require.EqualErrno(t, syscall.ENXIO, errno)
+}
+
+func TestSeekDataHole_Unsupported(t *testing.T) {
+	testFS := fstest.MapFS{"file": {Data: []byte("wazero")}}
+	f, err := testFS.Open("file")
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, errno := SeekData(f, 0)
+	require.EqualErrno(t, syscall.ENOSYS, errno)
+
+	_, errno = SeekHole(f, 0)
+	require.EqualErrno(t, syscall.ENOSYS, errno)
+}
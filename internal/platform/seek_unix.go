@@ -0,0 +1,8 @@
+//go:build linux || freebsd
+
+package platform
+
+const (
+	seekData = 3 // SEEK_DATA
+	seekHole = 4 // SEEK_HOLE
+)
@@ -0,0 +1,9 @@
+//go:build !(linux || freebsd || darwin)
+
+package platform
+
+// seekData and seekHole are -1 as sparse files aren't supported.
+const (
+	seekData = -1
+	seekHole = -1
+)
@@ -19,6 +19,10 @@ type Stat_t struct {
 	// Ino is the file serial number.
 	Ino uint64
 
+	// Rdev is the device ID of the file, if it is a device, or zero if
+	// unsupported. Use Major and Minor to decode it.
+	Rdev uint64
+
 	// Uid is the user ID that owns the file, or zero if unsupported.
 	// For example, this is unsupported on some virtual filesystems or windows.
 	Uid uint32
@@ -43,6 +43,7 @@ func statFromFileInfo(t fs.FileInfo) Stat_t {
 		st := Stat_t{}
 		st.Dev = uint64(d.Dev)
 		st.Ino = uint64(d.Ino)
+		st.Rdev = uint64(d.Rdev)
 		st.Uid = d.Uid
 		st.Gid = d.Gid
 		st.Mode = t.Mode()
@@ -5,6 +5,17 @@ import (
 	"syscall"
 )
 
+// Fsync is like syscall.Fsync, except it accepts any fs.File.
+//
+// Note: This returns with no error instead of syscall.ENOSYS when
+// unimplemented. This prevents fake filesystems from erring.
+func Fsync(f fs.File) syscall.Errno {
+	if s, ok := f.(syncFile); ok {
+		return UnwrapOSError(s.Sync())
+	}
+	return 0
+}
+
 // Fdatasync is like syscall.Fdatasync except that's only defined in linux.
 //
 // Note: This returns with no error instead of syscall.ENOSYS when
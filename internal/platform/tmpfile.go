@@ -0,0 +1,28 @@
+package platform
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// OpenTmpfile opens a new regular file in the directory `dir` for reading and
+// writing, which has no name, like open with O_TMPFILE on Linux. The file is
+// deleted when it is closed, unless LinkFd gives it a name. A zero
+// syscall.Errno is success.
+//
+// This returns syscall.ENOSYS where unsupported, including file systems that
+// can't create files without a name.
+func OpenTmpfile(dir string, perm fs.FileMode) (File, syscall.Errno) {
+	return openTmpfile(dir, perm)
+}
+
+// LinkFd gives the file `f`, opened by OpenTmpfile, the name `newPath`, like
+// linkat with AT_EMPTY_PATH on Linux. Writes to `f` before or after remain
+// visible at `newPath`, as it is the same file. A zero syscall.Errno is
+// success.
+//
+// Like Link, this fails with syscall.EEXIST if `newPath` already exists. This
+// returns syscall.ENOSYS where unsupported.
+func LinkFd(f fs.File, newPath string) syscall.Errno {
+	return linkFd(f, newPath)
+}
@@ -0,0 +1,53 @@
+//go:build (amd64 || arm64 || riscv64) && linux
+
+package platform
+
+import (
+	"io/fs"
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// _O_TMPFILE includes O_DIRECTORY, so that kernels without it fail
+	// instead of opening `dir`.
+	_O_TMPFILE         = 0o20000000 | syscall.O_DIRECTORY
+	_AT_SYMLINK_FOLLOW = 0x400
+)
+
+func openTmpfile(dir string, perm fs.FileMode) (File, syscall.Errno) {
+	fd, err := syscall.Open(dir, _O_TMPFILE|syscall.O_RDWR|syscall.O_CLOEXEC, uint32(perm.Perm()))
+	switch err {
+	case nil:
+		return os.NewFile(uintptr(fd), dir), 0
+	case syscall.EOPNOTSUPP, syscall.EISDIR:
+		return nil, syscall.ENOSYS // unsupported by the file system or kernel.
+	default:
+		return nil, UnwrapOSError(err)
+	}
+}
+
+func linkFd(f fs.File, newPath string) syscall.Errno {
+	fd, ok := f.(fdFile)
+	if !ok {
+		return syscall.ENOSYS
+	}
+	// linkat with AT_EMPTY_PATH requires CAP_DAC_READ_SEARCH, so follow the
+	// link to the file in /proc instead, which is equivalent.
+	oldPath, err := syscall.BytePtrFromString("/proc/self/fd/" + strconv.FormatUint(uint64(fd.Fd()), 10))
+	if err != nil {
+		return syscall.EINVAL
+	}
+	newPathPtr, err := syscall.BytePtrFromString(newPath)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	dirfd := _AT_FDCWD
+	_, _, errno := syscall.Syscall6(syscall.SYS_LINKAT, uintptr(dirfd), uintptr(unsafe.Pointer(oldPath)),
+		uintptr(dirfd), uintptr(unsafe.Pointer(newPathPtr)), _AT_SYMLINK_FOLLOW, 0)
+	runtime.KeepAlive(f) // until the syscall no longer uses its fd.
+	return errno
+}
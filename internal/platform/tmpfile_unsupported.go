@@ -0,0 +1,16 @@
+//go:build !((amd64 || arm64 || riscv64) && linux)
+
+package platform
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+func openTmpfile(string, fs.FileMode) (File, syscall.Errno) {
+	return nil, syscall.ENOSYS
+}
+
+func linkFd(fs.File, string) syscall.Errno {
+	return syscall.ENOSYS
+}
@@ -0,0 +1,63 @@
+package sys
+
+import (
+	"bytes"
+	"io"
+)
+
+// bufferedWriter combines small writes to an underlying io.Writer. This
+// avoids a syscall per write when a module writes a byte at a time, for
+// example printf char-by-char to stdout.
+//
+// Buffered data is written in the same order it was received, when the buffer
+// would exceed its size, when a write includes a newline if flushOnNewline,
+// and on Flush.
+type bufferedWriter struct {
+	w              io.Writer
+	buf            []byte
+	flushOnNewline bool
+}
+
+// NewBufferedWriter returns an io.Writer that combines writes to `w` up to
+// `size` bytes. When `flushOnNewline` is true, writes that include a newline
+// are flushed immediately.
+//
+// The result implements Flush, which is called on fd_sync or when the file
+// is closed.
+func NewBufferedWriter(w io.Writer, size int, flushOnNewline bool) io.Writer {
+	return &bufferedWriter{w: w, buf: make([]byte, 0, size), flushOnNewline: flushOnNewline}
+}
+
+// Write implements io.Writer
+func (b *bufferedWriter) Write(p []byte) (n int, err error) {
+	// Writes larger than the buffer bypass it, after flushing any data before
+	// them to retain order.
+	if len(b.buf)+len(p) > cap(b.buf) {
+		if err = b.Flush(); err != nil {
+			return
+		}
+		if len(p) >= cap(b.buf) {
+			return b.w.Write(p)
+		}
+	}
+
+	b.buf = append(b.buf, p...)
+	if b.flushOnNewline && bytes.IndexByte(p, '\n') != -1 {
+		err = b.Flush()
+	}
+	return len(p), err
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (b *bufferedWriter) Flush() error {
+	if len(b.buf) == 0 {
+		return nil
+	}
+	n, err := b.w.Write(b.buf)
+	if err == nil && n < len(b.buf) {
+		err = io.ErrShortWrite
+	}
+	// Drop what was written, so a retry doesn't duplicate it.
+	b.buf = b.buf[:copy(b.buf, b.buf[n:])]
+	return err
+}
@@ -0,0 +1,108 @@
+package sys
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestBufferedWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewBufferedWriter(&out, 8, false).(*bufferedWriter)
+
+	// Small writes are combined.
+	for _, s := range []string{"a", "b", "c\n"} {
+		n, err := w.Write([]byte(s))
+		require.NoError(t, err)
+		require.Equal(t, len(s), n)
+	}
+	require.Zero(t, out.Len())
+
+	// Exceeding the size flushes what was buffered first, retaining order.
+	_, err := w.Write([]byte("defgh"))
+	require.NoError(t, err)
+	require.Equal(t, "abc\n", out.String())
+
+	// Writes at least the size bypass the buffer, after flushing.
+	_, err = w.Write([]byte("0123456789"))
+	require.NoError(t, err)
+	require.Equal(t, "abc\ndefgh0123456789", out.String())
+
+	_, err = w.Write([]byte("ij"))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	require.Equal(t, "abc\ndefgh0123456789ij", out.String())
+}
+
+func TestBufferedWriter_flushOnNewline(t *testing.T) {
+	var out bytes.Buffer
+	w := NewBufferedWriter(&out, 64, true)
+
+	_, err := w.Write([]byte("hello "))
+	require.NoError(t, err)
+	require.Zero(t, out.Len())
+
+	_, err = w.Write([]byte("world\nagain"))
+	require.NoError(t, err)
+	require.Equal(t, "hello world\nagain", out.String())
+}
+
+func TestFSContext_bufferedStdio(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	fsc, err := NewFSContext(nil, NewBufferedWriter(&stdout, 64, false), NewBufferedWriter(&stderr, 64, false), nil)
+	require.NoError(t, err)
+
+	for _, fd := range []uint32{FdStdout, FdStderr} {
+		_, err = WriterForFile(fsc, fd).Write([]byte("wazero"))
+		require.NoError(t, err)
+	}
+	require.Zero(t, stdout.Len())
+	require.Zero(t, stderr.Len())
+
+	// Sync flushes, as done by fd_sync.
+	f, ok := fsc.LookupFile(FdStdout)
+	require.True(t, ok)
+	require.NoError(t, f.File.(interface{ Sync() error }).Sync())
+	require.Equal(t, "wazero", stdout.String())
+
+	// Close flushes.
+	require.NoError(t, fsc.Close(testCtx))
+	require.Equal(t, "wazero", stderr.String())
+}
+
+func BenchmarkBufferedWriter(b *testing.B) {
+	f, err := os.Create(path.Join(b.TempDir(), "out"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+
+	benches := []struct {
+		name string
+		w    io.Writer
+	}{
+		{name: "unbuffered", w: f},
+		{name: "buffered", w: NewBufferedWriter(f, 4096, false)},
+		{name: "line buffered", w: NewBufferedWriter(f, 4096, true)},
+	}
+
+	line := []byte("hello world\n")
+	for _, bc := range benches {
+		bc := bc
+
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				// Write a byte at a time, like printf char-by-char.
+				for j := range line {
+					if _, err := bc.w.Write(line[j : j+1]); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
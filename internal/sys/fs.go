@@ -52,6 +52,9 @@ func (w *stdioFileWriter) Read([]byte) (n int, err error) {
 
 // Write implements io.Writer
 func (w *stdioFileWriter) Write(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return // don't write to the host, as it may block.
+	}
 	return w.w.Write(p)
 }
 
@@ -61,6 +64,25 @@ func (w *stdioFileWriter) Close() error {
 	return nil
 }
 
+// bufferedStdioFileWriter is a stdioFileWriter over a bufferedWriter, which
+// flushes on Sync and Close.
+type bufferedStdioFileWriter struct {
+	stdioFileWriter
+}
+
+// Sync implements the same method as documented on os.File, by flushing any
+// buffered writes.
+func (w *bufferedStdioFileWriter) Sync() error {
+	return w.w.(*bufferedWriter).Flush()
+}
+
+// Close implements fs.File
+func (w *bufferedStdioFileWriter) Close() error {
+	// Flush, but don't actually close the underlying file, as we didn't open
+	// it!
+	return w.Sync()
+}
+
 type stdioFileReader struct {
 	r io.Reader
 	s fs.FileInfo
@@ -71,6 +93,9 @@ func (r *stdioFileReader) Stat() (fs.FileInfo, error) { return r.s, nil }
 
 // Read implements fs.File
 func (r *stdioFileReader) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return // don't read from the host, as it may block.
+	}
 	return r.r.Read(p)
 }
 
@@ -197,7 +222,37 @@ func (f *FileEntry) CachedStat() (ino uint64, fileType fs.FileMode, err error) {
 	return f.cachedStat.Ino, f.cachedStat.Type, nil
 }
 
-// Stat returns the underlying stat of this file.
+// IsReadOnly returns true when the file was opened by path without write
+// access, such as with os.O_RDONLY. This is false for stdio and pre-opens.
+func (f *FileEntry) IsReadOnly() bool {
+	return f.openPath != "" && f.openFlag&(syscall.O_WRONLY|syscall.O_RDWR) == 0
+}
+
+// IsAppend returns true when writes append to the end of the file: when it
+// was opened with, or changed to, syscall.O_APPEND. Stdout and stderr are
+// always appended to.
+func (f *FileEntry) IsAppend() bool {
+	if f.openPath == "" { // stdio or a pre-open
+		_, ok := f.File.(io.Writer)
+		return ok
+	}
+	return f.openFlag&syscall.O_APPEND != 0
+}
+
+// OpenFlag returns the flag the file was opened with, as changed by
+// FSContext.ChangeOpenFlag. This is zero for stdio and pre-opens.
+func (f *FileEntry) OpenFlag() int {
+	return f.openFlag
+}
+
+// IsNonblock returns true when the file was opened with, or changed to,
+// platform.O_NONBLOCK.
+func (f *FileEntry) IsNonblock() bool {
+	return f.openFlag&platform.O_NONBLOCK != 0
+}
+
+// Stat returns the underlying stat of this file. Only the parts returned by
+// CachedStat are cached, so the size reflects any prior write or truncate.
 func (f *FileEntry) Stat() (st platform.Stat_t, err error) {
 	var errno syscall.Errno
 	if ld, ok := f.File.(*lazyDir); ok {
@@ -241,8 +296,36 @@ type FSContext struct {
 	// (or directories) and defaults to empty.
 	// TODO: This is unguarded, so not goroutine-safe!
 	openedFiles FileTable
+
+	// fileMode is the permission requested for files created by the guest.
+	fileMode fs.FileMode
+
+	// umask masks the permission of files and directories created by the
+	// guest.
+	umask fs.FileMode
+
+	// maxIOSize caps the bytes read or written per call, unless zero.
+	maxIOSize uint32
+
+	// strictIovecs rejects reads into overlapping iovecs.
+	strictIovecs bool
 }
 
+const (
+	// DefaultFileMode is the permission requested for files created by the
+	// guest, unless overridden by FSContext.SetFileMode.
+	DefaultFileMode fs.FileMode = 0o666
+
+	// DefaultDirMode is the permission requested for directories created by
+	// the guest.
+	DefaultDirMode fs.FileMode = 0o777
+
+	// DefaultUmask is the umask of the guest, unless overridden by
+	// FSContext.SetUmask. This only allows access by the owner, so files are
+	// created with 0o600 and directories with 0o700 by default.
+	DefaultUmask fs.FileMode = 0o077
+)
+
 // FileTable is an specialization of the descriptor.Table type used to map file
 // descriptors to file entries.
 type FileTable = descriptor.Table[uint32, *FileEntry]
@@ -253,7 +336,7 @@ type FileTable = descriptor.Table[uint32, *FileEntry]
 // If `preopened` is not sysfs.UnimplementedFS, it is inserted into
 // the file descriptor table as FdPreopen.
 func NewFSContext(stdin io.Reader, stdout, stderr io.Writer, rootFS sysfs.FS) (fsc *FSContext, err error) {
-	fsc = &FSContext{rootFS: rootFS}
+	fsc = &FSContext{rootFS: rootFS, fileMode: DefaultFileMode, umask: DefaultUmask}
 	fsc.openedFiles.Insert(stdinReader(stdin))
 	fsc.openedFiles.Insert(stdioWriter(stdout, noopStdoutStat))
 	fsc.openedFiles.Insert(stdioWriter(stderr, noopStderrStat))
@@ -297,6 +380,10 @@ func stdioWriter(w io.Writer, defaultStat stdioFileInfo) *FileEntry {
 		w = io.Discard
 	}
 	s := stdioStat(w, defaultStat)
+	if bw, ok := w.(*bufferedWriter); ok {
+		s = stdioStat(bw.w, defaultStat)
+		return &FileEntry{Name: s.Name(), File: &bufferedStdioFileWriter{stdioFileWriter{w: w, s: s}}}
+	}
 	return &FileEntry{Name: s.Name(), File: &stdioFileWriter{w: w, s: s}}
 }
 
@@ -326,21 +413,94 @@ func (c *FSContext) RootFS() sysfs.FS {
 	return c.rootFS
 }
 
+// FileMode returns the permission requested for files created by the guest,
+// such as with path_open, which has no parameter for it. This defaults to
+// DefaultFileMode. The umask applies to this, like open(2).
+func (c *FSContext) FileMode() fs.FileMode {
+	return c.fileMode
+}
+
+// SetFileMode overrides the permission requested for files created by the
+// guest. Only the permission bits of `perm` are used.
+func (c *FSContext) SetFileMode(perm fs.FileMode) {
+	c.fileMode = perm.Perm()
+}
+
+// Umask returns the permission bits cleared from files and directories
+// created by the guest. This defaults to DefaultUmask.
+func (c *FSContext) Umask() fs.FileMode {
+	return c.umask
+}
+
+// SetUmask overrides the umask of the guest. Only the permission bits of
+// `umask` are used.
+//
+// Note: The host process umask still applies.
+func (c *FSContext) SetUmask(umask fs.FileMode) {
+	c.umask = umask.Perm()
+}
+
+// MaxIOSize returns the maximum count of bytes read or written by a single
+// call, such as fd_read, or zero if unlimited, which is the default.
+//
+// Calls for more than this are short reads or writes, which the guest
+// retries, instead of processing huge buffers at once.
+func (c *FSContext) MaxIOSize() uint32 {
+	return c.maxIOSize
+}
+
+// SetMaxIOSize overrides the maximum count of bytes read or written by a
+// single call. Zero is unlimited.
+func (c *FSContext) SetMaxIOSize(maxIOSize uint32) {
+	c.maxIOSize = maxIOSize
+}
+
+// StrictIovecs returns true when reads, such as fd_read, fail with
+// syscall.EINVAL if their iovecs overlap. This defaults to false.
+//
+// Reading into overlapping buffers is valid, but the result is the last data
+// read, which is rarely intended.
+func (c *FSContext) StrictIovecs() bool {
+	return c.strictIovecs
+}
+
+// SetStrictIovecs overrides whether reads into overlapping iovecs fail.
+func (c *FSContext) SetStrictIovecs(strictIovecs bool) {
+	c.strictIovecs = strictIovecs
+}
+
 // OpenFile opens the file into the table and returns its file descriptor.
 // The result must be closed by CloseFile or Close.
-func (c *FSContext) OpenFile(fs sysfs.FS, path string, flag int, perm fs.FileMode) (uint32, syscall.Errno) {
-	if f, errno := fs.OpenFile(path, flag, perm); errno != 0 {
+//
+// When `flag` includes platform.O_DIRECTORY, this fails with syscall.ENOTDIR
+// if the file isn't a directory.
+func (c *FSContext) OpenFile(fsys sysfs.FS, path string, flag int, perm fs.FileMode) (uint32, syscall.Errno) {
+	f, errno := fsys.OpenFile(path, flag, perm)
+	if errno != 0 {
 		return 0, errno
+	}
+
+	fe := &FileEntry{openPath: path, FS: fsys, File: f, openFlag: flag, openPerm: perm}
+	if path == "/" || path == "." {
+		fe.Name = ""
 	} else {
-		fe := &FileEntry{openPath: path, FS: fs, File: f, openFlag: flag, openPerm: perm}
-		if path == "/" || path == "." {
-			fe.Name = ""
-		} else {
-			fe.Name = path
+		fe.Name = path
+	}
+
+	// Only stat the file when the filesystem didn't already verify it is a
+	// directory, as this adds overhead to every open.
+	if flag&platform.O_DIRECTORY != 0 && !sysfs.DirectoryFlagEnforced(fsys) {
+		if _, ft, err := fe.CachedStat(); err != nil {
+			_ = f.Close()
+			return 0, platform.UnwrapOSError(err)
+		} else if ft.Type() != fs.ModeDir {
+			_ = f.Close()
+			return 0, syscall.ENOTDIR
 		}
-		newFD := c.openedFiles.Insert(fe)
-		return newFD, 0
 	}
+
+	newFD := c.openedFiles.Insert(fe)
+	return newFD, 0
 }
 
 // ReOpenDir re-opens the directory while keeping the same file descriptor.
@@ -380,7 +540,11 @@ func (c *FSContext) reopen(f *FileEntry) syscall.Errno {
 }
 
 // ChangeOpenFlag changes the open flag of the given opened file pointed by `fd`.
-// Currently, this only supports the change of syscall.O_APPEND flag.
+// Currently, this only supports the change of syscall.O_APPEND and
+// platform.O_NONBLOCK flags.
+//
+// Note: platform.O_NONBLOCK is only recorded, so that IsNonblock reflects it,
+// as reads and writes are blocking either way.
 func (c *FSContext) ChangeOpenFlag(fd uint32, flag int) syscall.Errno {
 	f, ok := c.LookupFile(fd)
 	if !ok {
@@ -391,10 +555,14 @@ func (c *FSContext) ChangeOpenFlag(fd uint32, flag int) syscall.Errno {
 		return syscall.EISDIR
 	}
 
-	if flag&syscall.O_APPEND != 0 {
-		f.openFlag |= syscall.O_APPEND
-	} else {
-		f.openFlag &= ^syscall.O_APPEND
+	oldFlag := f.openFlag
+	f.openFlag &= ^(syscall.O_APPEND | platform.O_NONBLOCK)
+	f.openFlag |= flag & (syscall.O_APPEND | platform.O_NONBLOCK)
+
+	// Files not opened by path, such as stdio, can't be re-opened, and
+	// re-opening is only needed to change append mode.
+	if f.openPath == "" || (oldFlag^f.openFlag)&syscall.O_APPEND == 0 {
+		return 0
 	}
 
 	// Changing the flag while opening is not really supported well in Go. Even when using
@@ -416,13 +584,17 @@ func (c *FSContext) LookupFile(fd uint32) (*FileEntry, bool) {
 	return f, ok
 }
 
-// Renumber assigns the file pointed by the descriptor `from` to `to`.
+// Renumber assigns the file pointed by the descriptor `from` to `to`, closing
+// any file previously at `to`, such as when redirecting stdio. This returns
+// syscall.EBADF if `from` isn't open.
 func (c *FSContext) Renumber(from, to uint32) syscall.Errno {
 	fromFile, ok := c.openedFiles.Lookup(from)
 	if !ok {
 		return syscall.EBADF
 	} else if fromFile.IsPreopen {
 		return syscall.ENOTSUP
+	} else if from == to {
+		return 0 // Renumbering onto itself must not close the file.
 	}
 
 	// If toFile is already open, we close it to prevent windows lock issues.
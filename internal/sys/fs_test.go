@@ -130,6 +130,44 @@ func TestFSContext_CloseFile(t *testing.T) {
 	})
 }
 
+func TestFSContext_OpenFile_O_DIRECTORY(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), nil, 0o600))
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "dir"), 0o700))
+
+	tests := []struct {
+		name           string
+		fs             sysfs.FS
+		expectStatSkip bool
+	}{
+		{name: "sysfs.NewDirFS", fs: sysfs.NewDirFS(tmpDir), expectStatSkip: platform.DirectoryFlagEnforced},
+		{name: "sysfs.NewReadFS", fs: sysfs.NewReadFS(sysfs.NewDirFS(tmpDir)), expectStatSkip: platform.DirectoryFlagEnforced},
+		{name: "os.DirFS", fs: sysfs.Adapt(os.DirFS(tmpDir)), expectStatSkip: false},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			fsc, err := NewFSContext(nil, nil, nil, tc.fs)
+			require.NoError(t, err)
+			defer fsc.Close(testCtx)
+
+			_, errno := fsc.OpenFile(tc.fs, "file", os.O_RDONLY|platform.O_DIRECTORY, 0)
+			require.EqualErrno(t, syscall.ENOTDIR, errno)
+
+			fd, errno := fsc.OpenFile(tc.fs, "dir", os.O_RDONLY|platform.O_DIRECTORY, 0)
+			require.Zero(t, errno)
+			f, ok := fsc.LookupFile(fd)
+			require.True(t, ok)
+
+			// The stat is only performed when the filesystem doesn't enforce
+			// O_DIRECTORY itself.
+			require.Equal(t, tc.expectStatSkip, f.cachedStat == nil)
+		})
+	}
+}
+
 func TestUnimplementedFSContext(t *testing.T) {
 	testFS, err := NewFSContext(nil, nil, nil, sysfs.UnimplementedFS{})
 	require.NoError(t, err)
@@ -144,10 +182,52 @@ func TestUnimplementedFSContext(t *testing.T) {
 		require.NoError(t, err)
 
 		// Closes opened files
-		require.Equal(t, &FSContext{rootFS: sysfs.UnimplementedFS{}}, testFS)
+		require.Equal(t, &FSContext{rootFS: sysfs.UnimplementedFS{}, fileMode: DefaultFileMode, umask: DefaultUmask}, testFS)
 	})
 }
 
+func TestFSContext_FileMode(t *testing.T) {
+	testFS, err := NewFSContext(nil, nil, nil, sysfs.UnimplementedFS{})
+	require.NoError(t, err)
+	require.Equal(t, DefaultFileMode, testFS.FileMode())
+
+	// Only permission bits are retained.
+	testFS.SetFileMode(fs.ModeDir | 0o644)
+	require.Equal(t, fs.FileMode(0o644), testFS.FileMode())
+}
+
+func TestFSContext_Umask(t *testing.T) {
+	testFS, err := NewFSContext(nil, nil, nil, sysfs.UnimplementedFS{})
+	require.NoError(t, err)
+	require.Equal(t, DefaultUmask, testFS.Umask())
+
+	// The defaults only allow access by the owner.
+	require.Equal(t, fs.FileMode(0o600), DefaultFileMode&^DefaultUmask)
+	require.Equal(t, fs.FileMode(0o700), DefaultDirMode&^DefaultUmask)
+
+	// Only permission bits are retained.
+	testFS.SetUmask(fs.ModeDir | 0o022)
+	require.Equal(t, fs.FileMode(0o022), testFS.Umask())
+}
+
+func TestFSContext_MaxIOSize(t *testing.T) {
+	testFS, err := NewFSContext(nil, nil, nil, sysfs.UnimplementedFS{})
+	require.NoError(t, err)
+	require.Zero(t, testFS.MaxIOSize()) // unlimited
+
+	testFS.SetMaxIOSize(4096)
+	require.Equal(t, uint32(4096), testFS.MaxIOSize())
+}
+
+func TestFSContext_StrictIovecs(t *testing.T) {
+	testFS, err := NewFSContext(nil, nil, nil, sysfs.UnimplementedFS{})
+	require.NoError(t, err)
+	require.False(t, testFS.StrictIovecs())
+
+	testFS.SetStrictIovecs(true)
+	require.True(t, testFS.StrictIovecs())
+}
+
 func TestCompositeFSContext(t *testing.T) {
 	tmpDir1 := t.TempDir()
 	testFS1 := sysfs.NewDirFS(tmpDir1)
@@ -174,7 +254,7 @@ func TestCompositeFSContext(t *testing.T) {
 		require.NoError(t, err)
 
 		// Closes opened files
-		require.Equal(t, &FSContext{rootFS: rootFS}, testFS)
+		require.Equal(t, &FSContext{rootFS: rootFS, fileMode: DefaultFileMode, umask: DefaultUmask}, testFS)
 	})
 }
 
@@ -299,6 +379,34 @@ func TestFSContext_Renumber(t *testing.T) {
 		require.False(t, ok)
 	}
 
+	t.Run("onto itself", func(t *testing.T) {
+		fd, errno := c.OpenFile(dirFs, dirName, os.O_RDONLY, 0)
+		require.Zero(t, errno)
+
+		require.Zero(t, c.Renumber(fd, fd))
+
+		// The file is still open.
+		f, ok := c.LookupFile(fd)
+		require.True(t, ok)
+		_, err := f.Stat()
+		require.NoError(t, err)
+	})
+
+	t.Run("onto stdout", func(t *testing.T) {
+		fd, errno := c.OpenFile(dirFs, "stdout", os.O_WRONLY|os.O_CREATE, 0o600)
+		require.Zero(t, errno)
+		f, ok := c.LookupFile(fd)
+		require.True(t, ok)
+
+		require.Zero(t, c.Renumber(fd, FdStdout))
+
+		stdout, ok := c.LookupFile(FdStdout)
+		require.True(t, ok)
+		require.Equal(t, f, stdout)
+		_, ok = c.LookupFile(fd)
+		require.False(t, ok)
+	})
+
 	t.Run("errors", func(t *testing.T) {
 		// Sanity check for 3 being preopen.
 		preopen, ok := c.LookupFile(3)
@@ -348,6 +456,57 @@ func TestFSContext_ChangeOpenFlag(t *testing.T) {
 	f2, ok := c.openedFiles.Lookup(fd)
 	require.True(t, ok)
 	require.Equal(t, f2.openFlag&syscall.O_APPEND, 0)
+
+	// NONBLOCK is recorded without re-opening the file.
+	require.Zero(t, c.ChangeOpenFlag(fd, platform.O_NONBLOCK))
+	f3, ok := c.openedFiles.Lookup(fd)
+	require.True(t, ok)
+	require.True(t, f3.IsNonblock())
+	require.False(t, f3.IsAppend())
+
+	t.Run("stdio", func(t *testing.T) {
+		// Stdio can't be re-opened, but the flags are still recorded.
+		require.Zero(t, c.ChangeOpenFlag(FdStdin, platform.O_NONBLOCK))
+		stdin, ok := c.openedFiles.Lookup(FdStdin)
+		require.True(t, ok)
+		require.True(t, stdin.IsNonblock())
+		require.False(t, stdin.IsAppend())
+
+		stdout, ok := c.openedFiles.Lookup(FdStdout)
+		require.True(t, ok)
+		require.True(t, stdout.IsAppend())
+	})
+}
+
+func TestFileEntry_Stat_Size(t *testing.T) {
+	tmpDir := t.TempDir()
+	dirFs := sysfs.NewDirFS(tmpDir)
+
+	c, errno := NewFSContext(nil, nil, nil, dirFs)
+	require.NoError(t, errno)
+	defer func() {
+		require.NoError(t, c.Close(context.Background()))
+	}()
+
+	fd, errno := c.OpenFile(dirFs, "file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.Zero(t, errno)
+	f, ok := c.LookupFile(fd)
+	require.True(t, ok)
+
+	// Populate the cache, which shouldn't include the size.
+	_, _, err := f.CachedStat()
+	require.NoError(t, err)
+
+	_, err = f.File.(io.Writer).Write([]byte("wazero"))
+	require.NoError(t, err)
+	st, err := f.Stat()
+	require.NoError(t, err)
+	require.Equal(t, int64(6), st.Size)
+
+	require.NoError(t, f.File.(interface{ Truncate(int64) error }).Truncate(2))
+	st, err = f.Stat()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), st.Size)
 }
 
 func TestWriterForFile(t *testing.T) {
@@ -359,3 +518,37 @@ func TestWriterForFile(t *testing.T) {
 	require.Equal(t, noopStderr.File, WriterForFile(testFS, FdStderr))
 	require.Nil(t, WriterForFile(testFS, FdPreopen))
 }
+
+// failingReadWriter fails the test if called.
+type failingReadWriter struct{ t *testing.T }
+
+func (f failingReadWriter) Read([]byte) (int, error) {
+	f.t.Fatal("unexpected read")
+	return 0, nil
+}
+
+func (f failingReadWriter) Write([]byte) (int, error) {
+	f.t.Fatal("unexpected write")
+	return 0, nil
+}
+
+func TestStdio_ZeroLength(t *testing.T) {
+	rw := failingReadWriter{t}
+	testFS, err := NewFSContext(rw, rw, rw, sysfs.UnimplementedFS{})
+	require.NoError(t, err)
+	defer testFS.Close(testCtx)
+
+	stdin, ok := testFS.LookupFile(FdStdin)
+	require.True(t, ok)
+	n, err := stdin.File.Read(nil)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	for _, fd := range []uint32{FdStdout, FdStderr} {
+		f, ok := testFS.LookupFile(fd)
+		require.True(t, ok)
+		n, err := f.File.(io.Writer).Write(nil)
+		require.NoError(t, err)
+		require.Zero(t, n)
+	}
+}
@@ -31,6 +31,8 @@ func Adapt(fs fs.FS) FS {
 
 type adapter struct {
 	UnimplementedFS
+	// ReadOnly is embedded as fs.FS has no functions to write.
+	ReadOnly
 	fs fs.FS
 }
 
@@ -0,0 +1,76 @@
+package sysfs
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// AliasFS wraps `base` such that each path key in `aliases` is served from
+// the path value instead, like a symlink that doesn't exist in `base`. For
+// example, mapping "etc/hostname" to "host/hostname" lets the guest read a
+// file provided by the host at a conventional location.
+//
+// Aliases apply to paths under them, so aliasing a directory also aliases its
+// contents. When aliases overlap, the longest matches.
+//
+// # Notes
+//
+//   - Aliases apply to OpenFile, Stat and Lstat, which covers reading files
+//     and directories. Other operations, such as Unlink, use the path as-is.
+//   - Unlike a symlink, an alias isn't listed when reading its parent
+//     directory.
+func AliasFS(base FS, aliases map[string]string) FS {
+	if len(aliases) == 0 {
+		return base
+	}
+	a := &aliasFS{FS: base, aliases: make(map[string]string, len(aliases))}
+	for from, to := range aliases {
+		a.aliases[path.Clean(from)] = path.Clean(to)
+	}
+	return a
+}
+
+type aliasFS struct {
+	FS
+	aliases map[string]string
+}
+
+// resolve returns the path `p` after replacing the longest alias which is it
+// or one of its parent directories.
+func (a *aliasFS) resolve(p string) string {
+	p = path.Clean(p)
+	for prefix := p; ; {
+		if to, ok := a.aliases[prefix]; ok {
+			return to + p[len(prefix):]
+		}
+		i := strings.LastIndexByte(prefix, '/')
+		if i < 0 {
+			return p
+		}
+		prefix = prefix[:i]
+	}
+}
+
+// Open implements the same method as documented on fs.FS
+func (a *aliasFS) Open(name string) (fs.File, error) {
+	return fsOpen(a, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (a *aliasFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	return a.FS.OpenFile(a.resolve(path), flag, perm)
+}
+
+// Lstat implements FS.Lstat
+func (a *aliasFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	return a.FS.Lstat(a.resolve(path))
+}
+
+// Stat implements FS.Stat
+func (a *aliasFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	return a.FS.Stat(a.resolve(path))
+}
@@ -0,0 +1,75 @@
+package sysfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestAliasFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(tmpDir, "host", "dir"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "host", "hostname"), []byte("wazero"), 0o600))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "host", "dir", "a"), []byte("a"), 0o600))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "b"), []byte("b"), 0o600))
+
+	testFS := AliasFS(NewDirFS(tmpDir), map[string]string{
+		"etc/hostname": "host/hostname",
+		"etc/dir":      "host/dir",
+		"etc/dir/b":    "b", // the longest alias wins
+	})
+
+	readFile := func(t *testing.T, name string) string {
+		f, errno := testFS.OpenFile(name, os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	t.Run("file", func(t *testing.T) {
+		require.Equal(t, "wazero", readFile(t, "etc/hostname"))
+
+		st, errno := testFS.Stat("etc/hostname")
+		require.Zero(t, errno)
+		require.Equal(t, int64(6), st.Size)
+
+		st, errno = testFS.Lstat("./etc/hostname")
+		require.Zero(t, errno)
+		require.Equal(t, int64(6), st.Size)
+	})
+
+	t.Run("under directory", func(t *testing.T) {
+		require.Equal(t, "a", readFile(t, "etc/dir/a"))
+		require.Equal(t, "b", readFile(t, "etc/dir/b"))
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		f, errno := testFS.OpenFile("etc/dir", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		dirents, errno := platform.Readdir(f, -1)
+		require.Zero(t, errno)
+		require.Equal(t, 1, len(dirents))
+		require.Equal(t, "a", dirents[0].Name)
+	})
+
+	t.Run("not aliased", func(t *testing.T) {
+		require.Equal(t, "b", readFile(t, "b"))
+
+		_, errno := testFS.Stat("etc")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+}
+
+func TestAliasFS_NoAliases(t *testing.T) {
+	base := NewDirFS(t.TempDir())
+	require.Equal(t, base, AliasFS(base, nil))
+}
@@ -0,0 +1,151 @@
+package sysfs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// anonymousAttempts is how many names OpenAnonymous tries before giving up.
+const anonymousAttempts = 10000
+
+// anonymousSeq makes names unique within the process.
+var anonymousSeq uint32
+
+// anonymousFS is implemented by file systems which open files without a
+// directory entry natively, such as the host on Linux. Either method returns
+// syscall.ENOSYS when unsupported.
+type anonymousFS interface {
+	// openAnonymous implements OpenAnonymous.
+	openAnonymous(dir string, perm fs.FileMode) (fs.File, syscall.Errno)
+	// linkAnonymous implements LinkAnonymous, for files opened by
+	// openAnonymous.
+	linkAnonymous(f fs.File, name string) syscall.Errno
+}
+
+// OpenAnonymous opens a new file in `dir` for reading and writing, which has
+// no directory entry, similar to O_TMPFILE on Linux. The file is deleted when
+// it is closed. A zero syscall.Errno is success.
+//
+// Where supported, such as by NewDirFS on Linux, this uses O_TMPFILE.
+// Otherwise, the file is created with a unique name, then unlinked while still
+// open. Where the file system can't unlink an open file, such as on Windows,
+// the directory entry remains until the file is closed.
+func OpenAnonymous(fsys FS, dir string, perm fs.FileMode) (fs.File, syscall.Errno) {
+	if a, ok := fsys.(anonymousFS); ok {
+		if f, errno := a.openAnonymous(dir, perm); errno != syscall.ENOSYS {
+			return f, errno
+		}
+	}
+
+	f, name, errno := createUnique(fsys, dir, os.O_RDWR, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	if fsys.Unlink(name) == 0 {
+		return f, 0
+	}
+
+	// Defer removing the directory entry until the file is closed.
+	return &unlinkOnCloseFile{wrappedFile: wrappedFile{path: name, f: f}, fs: fsys}, 0
+}
+
+// LinkAnonymous gives the file `f`, opened by OpenAnonymous on `fsys`, the
+// name `path`, similar to linkat with AT_EMPTY_PATH. This supports writing a
+// file completely before publishing it. A zero syscall.Errno is success.
+//
+// Like Link, this fails with syscall.EEXIST if `name` already exists.
+//
+// Where the file was opened with O_TMPFILE, it is linked by its file
+// descriptor, so `name` is the same file as `f`.
+//
+// Note: Otherwise, once unlinked, a file can't be linked again portably.
+// Instead, its contents are copied to a new file, which is linked atomically.
+// Writes to `f` after this returns aren't visible at `name`.
+func LinkAnonymous(fsys FS, f fs.File, name string) syscall.Errno {
+	if uf, ok := f.(*unlinkOnCloseFile); ok && uf.fs == fsys {
+		return fsys.Link(uf.path, name) // still linked, so share the file.
+	}
+
+	if a, ok := fsys.(anonymousFS); ok {
+		switch errno := a.linkAnonymous(f, name); errno {
+		case syscall.ENOSYS, syscall.ENOENT, syscall.EXDEV:
+			// ENOENT is also how Linux refuses to link a file which was
+			// unlinked, rather than opened with O_TMPFILE: copy it instead.
+		default:
+			return errno
+		}
+	}
+
+	st, errno := platform.StatFile(f)
+	if errno != 0 {
+		return errno
+	}
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		return syscall.ENOSYS
+	}
+
+	tmp, tmpName, errno := createUnique(fsys, path.Dir(name), os.O_WRONLY, st.Mode.Perm())
+	if errno != 0 {
+		return errno
+	}
+
+	if w, ok := tmp.(io.Writer); !ok {
+		errno = syscall.EBADF
+	} else if _, err := io.Copy(w, io.NewSectionReader(ra, 0, st.Size)); err != nil {
+		errno = platform.UnwrapOSError(err)
+	}
+	if e := platform.UnwrapOSError(tmp.Close()); errno == 0 {
+		errno = e
+	}
+	if errno == 0 {
+		errno = fsys.Link(tmpName, name)
+	}
+	_ = fsys.Unlink(tmpName)
+	return errno
+}
+
+// createUnique creates a new file in `dir`, with a name that didn't exist,
+// and returns it with its path.
+func createUnique(fsys FS, dir string, flag int, perm fs.FileMode) (fs.File, string, syscall.Errno) {
+	prefix := ".anonymous-" + strconv.FormatInt(time.Now().UnixNano(), 36) + "-"
+	for i := 0; i < anonymousAttempts; i++ {
+		name := path.Join(dir, prefix+strconv.FormatUint(uint64(atomic.AddUint32(&anonymousSeq, 1)), 36))
+		f, errno := fsys.OpenFile(name, flag|os.O_CREATE|os.O_EXCL, perm)
+		if errno == syscall.EEXIST {
+			continue
+		}
+		return f, name, errno
+	}
+	return nil, "", syscall.EEXIST
+}
+
+// unlinkOnCloseFile unlinks the file after Close.
+type unlinkOnCloseFile struct {
+	wrappedFile
+	fs FS
+}
+
+// GoString implements fmt.GoStringer
+func (f *unlinkOnCloseFile) GoString() string {
+	return fmt.Sprintf("&sysfs.unlinkOnCloseFile{path: %q, f: %s}", f.path, goStringFile(f.f))
+}
+
+// Close implements fs.File
+func (f *unlinkOnCloseFile) Close() error {
+	err := f.f.Close()
+	if errno := f.fs.Unlink(f.path); err == nil && errno != 0 {
+		return errno
+	}
+	return err
+}
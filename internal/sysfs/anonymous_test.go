@@ -0,0 +1,142 @@
+package sysfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestOpenAnonymous(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := NewDirFS(tmpDir)
+	require.Zero(t, testFS.Mkdir("dir", 0o700))
+
+	f, errno := OpenAnonymous(testFS, "dir", 0o600)
+	require.Zero(t, errno)
+
+	// The file can be written and read back.
+	_, err := f.(io.Writer).Write([]byte("wazero"))
+	require.NoError(t, err)
+	_, err = f.(io.Seeker).Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(b))
+
+	// There's no directory entry, except where an open file can't be unlinked.
+	if runtime.GOOS != "windows" {
+		requireDirEntries(t, testFS, "dir", 0)
+	}
+
+	require.NoError(t, f.Close())
+	requireDirEntries(t, testFS, "dir", 0)
+}
+
+func TestOpenAnonymous_unlinkOnClose(t *testing.T) {
+	tmpDir := t.TempDir()
+	// Fail the first unlink, as if the file system can't unlink open files.
+	testFS := FaultFS(NewDirFS(tmpDir), failNth("Unlink", 1, syscall.EBUSY))
+
+	f, errno := OpenAnonymous(testFS, ".", 0o600)
+	require.Zero(t, errno)
+
+	_, err := f.(io.Writer).Write([]byte("wazero"))
+	require.NoError(t, err)
+
+	// The directory entry remains until the file is closed.
+	requireDirEntries(t, testFS, ".", 1)
+	require.NoError(t, f.Close())
+	requireDirEntries(t, testFS, ".", 0)
+}
+
+func TestOpenAnonymous_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	_, errno := OpenAnonymous(NewDirFS(tmpDir), "missing", 0o600)
+	require.EqualErrno(t, syscall.ENOENT, errno)
+
+	_, errno = OpenAnonymous(NewReadFS(NewDirFS(tmpDir)), ".", 0o600)
+	require.EqualErrno(t, syscall.ENOSYS, errno)
+}
+
+func TestLinkAnonymous(t *testing.T) {
+	tests := []struct {
+		name string
+		fs   func(dir string) FS
+	}{
+		{name: "unlinked", fs: func(dir string) FS { return NewDirFS(dir) }},
+		{name: "copied", fs: func(dir string) FS {
+			// Hide O_TMPFILE, so that the unlinked file is copied.
+			return FaultFS(NewDirFS(dir), func(string, string) error { return nil })
+		}},
+		{name: "unlink on close", fs: func(dir string) FS {
+			return FaultFS(NewDirFS(dir), failNth("Unlink", 1, syscall.EBUSY))
+		}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			testFS := tc.fs(tmpDir)
+
+			f, errno := OpenAnonymous(testFS, ".", 0o640)
+			require.Zero(t, errno)
+
+			_, err := f.(io.Writer).Write([]byte("wazero"))
+			require.NoError(t, err)
+
+			require.Zero(t, LinkAnonymous(testFS, f, "published"))
+			require.EqualErrno(t, syscall.EEXIST, LinkAnonymous(testFS, f, "published"))
+			require.NoError(t, f.Close())
+
+			// Only the published name remains.
+			b, err := os.ReadFile(path.Join(tmpDir, "published"))
+			require.NoError(t, err)
+			require.Equal(t, "wazero", string(b))
+			requireDirEntries(t, testFS, ".", 1)
+		})
+	}
+}
+
+// TestLinkAnonymous_tmpfile ensures a file opened with O_TMPFILE is linked by
+// its file descriptor, so later writes are visible at the name.
+func TestLinkAnonymous_tmpfile(t *testing.T) {
+	tmpDir := t.TempDir()
+	if f, errno := platform.OpenTmpfile(tmpDir, 0o600); errno == syscall.ENOSYS {
+		t.Skip("O_TMPFILE unsupported")
+	} else {
+		require.Zero(t, errno)
+		require.NoError(t, f.Close())
+	}
+	testFS := NewDirFS(tmpDir)
+
+	f, errno := OpenAnonymous(testFS, ".", 0o600)
+	require.Zero(t, errno)
+	defer f.Close()
+
+	require.Zero(t, LinkAnonymous(testFS, f, "published"))
+	_, err := f.(io.Writer).Write([]byte("wazero"))
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(path.Join(tmpDir, "published"))
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(b))
+}
+
+func requireDirEntries(t *testing.T, fsys FS, dir string, expected int) {
+	d, errno := fsys.OpenFile(dir, os.O_RDONLY, 0)
+	require.Zero(t, errno)
+	defer d.Close()
+
+	dirents, errno := platform.Readdir(d, -1)
+	require.Zero(t, errno)
+	require.Equal(t, expected, len(dirents))
+}
@@ -0,0 +1,307 @@
+package sysfs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// archiveMaxSymlinks bounds how many symbolic links are followed resolving a
+// path in an archive, the same as MAXSYMLINKS on Linux.
+const archiveMaxSymlinks = 40
+
+// archiveFS is a read-only FS of the files in an archive, indexed by path
+// when the archive is opened.
+type archiveFS struct {
+	// readFS is embedded for its write methods, which return syscall.EROFS.
+	readFS
+	name string
+	// entries are the files in the archive, keyed by the cleaned path. Hard
+	// links share the same entry.
+	entries map[string]*archiveEntry
+}
+
+// archiveEntry is a file in an archiveFS.
+type archiveEntry struct {
+	st platform.Stat_t
+	// link is the target of a symbolic link.
+	link string
+	// names are the names in a directory, sorted by sortNames.
+	names []string
+	// open returns the contents of a regular file.
+	open func() (io.Reader, syscall.Errno)
+}
+
+// newArchiveFS returns an archiveFS with only a root directory. `name` is
+// returned by String.
+func newArchiveFS(name string) *archiveFS {
+	root := &archiveEntry{st: platform.Stat_t{Mode: fs.ModeDir | 0o555, Nlink: 1, Ino: 1}}
+	return &archiveFS{name: name, entries: map[string]*archiveEntry{".": root}}
+}
+
+// archivePath returns the cleaned path of the file `name` in an archive, or
+// an error if it is outside the archive, such as "../file".
+func archivePath(name string) (string, error) {
+	p := procPath(name)
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("invalid path in archive: %q", name)
+	}
+	return p, nil
+}
+
+// add adds `e` at the cleaned path `name`, creating its parent directories as needed. An entry
+// already at `name` is replaced, except a directory keeps its names.
+func (a *archiveFS) add(name string, e *archiveEntry) {
+	if prev, ok := a.entries[name]; ok {
+		if prev.st.Mode.IsDir() && e.st.Mode.IsDir() {
+			e.names = prev.names
+		}
+	} else if name != "." {
+		parent := a.mkdirAll(path.Dir(name))
+		parent.names = append(parent.names, path.Base(name))
+	}
+	if e.st.Ino == 0 {
+		e.st.Ino = uint64(len(a.entries) + 1)
+	}
+	a.entries[name] = e
+}
+
+// mkdirAll returns the directory at `name`, creating it and its parents if
+// they don't exist. Implied directories are read-only.
+func (a *archiveFS) mkdirAll(name string) *archiveEntry {
+	if e, ok := a.entries[name]; ok {
+		return e
+	}
+	e := &archiveEntry{st: platform.Stat_t{Mode: fs.ModeDir | 0o555, Nlink: 1}}
+	a.add(name, e)
+	return e
+}
+
+// sortNames sorts the names in each directory, once all entries are added.
+func (a *archiveFS) sortNames() {
+	for _, e := range a.entries {
+		sort.Strings(e.names)
+	}
+}
+
+// String implements fmt.Stringer
+func (a *archiveFS) String() string {
+	return a.name
+}
+
+// Open implements the same method as documented on fs.FS
+func (a *archiveFS) Open(name string) (fs.File, error) {
+	return fsOpen(a, name)
+}
+
+// lookup returns the entry at `name`, following symbolic links in its parent
+// directories, and its last element if `follow` is true. Links resolve within
+// the archive: absolute targets are relative to its root, which ".." can't
+// escape.
+func (a *archiveFS) lookup(name string, follow bool) (string, *archiveEntry, syscall.Errno) {
+	resolved, links := ".", 0
+	rest := strings.Split(name, "/")
+	for len(rest) > 0 {
+		elem := rest[0]
+		rest = rest[1:]
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		p := path.Join(resolved, elem)
+		e, ok := a.entries[p]
+		if !ok {
+			return "", nil, syscall.ENOENT
+		}
+		switch {
+		case e.st.Mode.Type() == fs.ModeSymlink && (follow || len(rest) > 0):
+			if links++; links > archiveMaxSymlinks {
+				return "", nil, syscall.ELOOP
+			}
+			if strings.HasPrefix(e.link, "/") {
+				resolved = "."
+			}
+			rest = append(strings.Split(e.link, "/"), rest...)
+		case len(rest) > 0 && !e.st.Mode.IsDir():
+			return "", nil, syscall.ENOTDIR
+		default:
+			resolved = p
+		}
+	}
+	return resolved, a.entries[resolved], 0
+}
+
+// OpenFile implements FS.OpenFile
+func (a *archiveFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	switch flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR) {
+	case os.O_WRONLY, os.O_RDWR:
+		return nil, syscall.ENOSYS
+	}
+
+	name, e, errno := a.lookup(path, flag&platform.O_NOFOLLOW == 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	switch e.st.Mode.Type() {
+	case fs.ModeDir:
+		return &archiveDir{a: a, path: name, st: e.st, names: e.names}, 0
+	case fs.ModeSymlink: // only when O_NOFOLLOW
+		return nil, syscall.ELOOP
+	}
+	if flag&platform.O_DIRECTORY != 0 {
+		return nil, syscall.ENOTDIR
+	}
+	return openArchiveFile(name, e)
+}
+
+// openArchiveFile opens the regular file `e`, at the cleaned path `name`.
+func openArchiveFile(name string, e *archiveEntry) (fs.File, syscall.Errno) {
+	if e.open == nil {
+		return nil, syscall.ENOTSUP // such as a device
+	}
+	r, errno := e.open()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &archiveFile{r: r, info: dirInfo{name: path.Base(name), stat: e.st}}, 0
+}
+
+// Lstat implements FS.Lstat
+func (a *archiveFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	_, e, errno := a.lookup(path, false)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return e.st, 0
+}
+
+// Stat implements FS.Stat
+func (a *archiveFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	_, e, errno := a.lookup(path, true)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return e.st, 0
+}
+
+// Readlink implements FS.Readlink
+func (a *archiveFS) Readlink(path string) (string, syscall.Errno) {
+	_, e, errno := a.lookup(path, false)
+	if errno != 0 {
+		return "", errno
+	} else if e.st.Mode.Type() != fs.ModeSymlink {
+		return "", syscall.EINVAL
+	}
+	return e.link, 0
+}
+
+// archiveFile reads a regular file of an archiveFS. Seek and ReadAt return
+// syscall.ENOSYS unless the reader of the archive supports them.
+type archiveFile struct {
+	r    io.Reader
+	info dirInfo
+}
+
+// GoString implements fmt.GoStringer
+func (f *archiveFile) GoString() string {
+	return fmt.Sprintf("&sysfs.archiveFile{name: %q}", f.info.name)
+}
+
+// Supports implements CapabilityFile
+func (f *archiveFile) Supports(c Capability) bool {
+	switch c {
+	case CapabilityReadAt:
+		_, ok := f.r.(io.ReaderAt)
+		return ok
+	case CapabilitySeek:
+		_, ok := f.r.(io.Seeker)
+		return ok
+	}
+	return hasMethod(f, c)
+}
+
+// Stat implements fs.File
+func (f *archiveFile) Stat() (fs.FileInfo, error) { return &f.info, nil }
+
+// Read implements io.Reader
+func (f *archiveFile) Read(p []byte) (int, error) {
+	return f.r.Read(p)
+}
+
+// ReadAt implements io.ReaderAt
+func (f *archiveFile) ReadAt(p []byte, off int64) (int, error) {
+	if ra, ok := f.r.(io.ReaderAt); ok {
+		return ra.ReadAt(p, off)
+	}
+	return 0, syscall.ENOSYS
+}
+
+// Seek implements io.Seeker
+func (f *archiveFile) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := f.r.(io.Seeker); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, syscall.ENOSYS
+}
+
+// Close implements fs.File
+func (f *archiveFile) Close() error {
+	if c, ok := f.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// archiveDir lists a directory of an archiveFS.
+type archiveDir struct {
+	a     *archiveFS
+	path  string
+	st    platform.Stat_t
+	names []string
+}
+
+// GoString implements fmt.GoStringer
+func (d *archiveDir) GoString() string {
+	return fmt.Sprintf("&sysfs.archiveDir{path: %q}", d.path)
+}
+
+// Stat implements fs.File
+func (d *archiveDir) Stat() (fs.FileInfo, error) {
+	return &dirInfo{name: path.Base(d.path), stat: d.st}, nil
+}
+
+// Read implements fs.File
+func (d *archiveDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: syscall.EISDIR}
+}
+
+// Close implements fs.File
+func (d *archiveDir) Close() error { return nil }
+
+// ReadDir implements fs.ReadDirFile
+func (d *archiveDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	n := len(d.names)
+	if n == 0 && count > 0 {
+		return nil, io.EOF
+	} else if count > 0 && n > count {
+		n = count
+	}
+	list := make([]fs.DirEntry, 0, n)
+	for _, name := range d.names[:n] {
+		e := d.a.entries[path.Join(d.path, name)]
+		list = append(list, &dirInfo{name: name, stat: e.st})
+	}
+	d.names = d.names[n:]
+	return list, nil
+}
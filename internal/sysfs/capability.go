@@ -0,0 +1,127 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// Capability is an operation on an open file, which may not be supported.
+// See Supports.
+type Capability uint8
+
+const (
+	// CapabilityReadAt is support for io.ReaderAt, used by pread.
+	CapabilityReadAt Capability = iota
+	// CapabilityWrite is support for io.Writer.
+	CapabilityWrite
+	// CapabilityWriteAt is support for io.WriterAt, used by pwrite.
+	CapabilityWriteAt
+	// CapabilitySeek is support for io.Seeker.
+	CapabilitySeek
+	// CapabilityReadDir is support for fs.ReadDirFile.
+	CapabilityReadDir
+	// CapabilitySync is support for syncing the file to storage.
+	CapabilitySync
+	// CapabilityTruncate is support for changing the size of the file.
+	CapabilityTruncate
+	// CapabilityFallocate is support for allocating space for the file, with
+	// platform.Fallocate. Unless the file implements platform.Allocator, this
+	// is emulated by growing the file with Truncate.
+	CapabilityFallocate
+	// CapabilityChmod is support for changing the mode of the file.
+	CapabilityChmod
+	// CapabilityChtimes is support for changing the times of the file, with
+	// platform.UtimensFile. This needs the file descriptor of a file that can
+	// be written, as files masked for reads keep their descriptor.
+	CapabilityChtimes
+)
+
+// String implements fmt.Stringer
+func (c Capability) String() string {
+	switch c {
+	case CapabilityReadAt:
+		return "ReadAt"
+	case CapabilityWrite:
+		return "Write"
+	case CapabilityWriteAt:
+		return "WriteAt"
+	case CapabilitySeek:
+		return "Seek"
+	case CapabilityReadDir:
+		return "ReadDir"
+	case CapabilitySync:
+		return "Sync"
+	case CapabilityTruncate:
+		return "Truncate"
+	case CapabilityFallocate:
+		return "Fallocate"
+	case CapabilityChmod:
+		return "Chmod"
+	case CapabilityChtimes:
+		return "Chtimes"
+	}
+	return "Unknown"
+}
+
+// CapabilityFile is implemented by files that report which operations they
+// support. This is needed by wrappers, which implement every method, but fail
+// with syscall.ENOSYS on those the file they wrap doesn't implement.
+type CapabilityFile interface {
+	Supports(Capability) bool
+}
+
+// Supports returns true if the file implements the operation, so that callers
+// can branch instead of trying it and handling syscall.ENOSYS.
+//
+// When the file implements CapabilityFile, it decides. Otherwise, this checks
+// the file implements the interface the operation is called through. Note
+// that this doesn't consider how the file was opened: a read-only file may
+// support CapabilityWrite, but fail with syscall.EBADF when written.
+func Supports(f fs.File, c Capability) bool {
+	if cf, ok := f.(CapabilityFile); ok {
+		return cf.Supports(c)
+	}
+	return hasMethod(f, c)
+}
+
+// supportsWrapped implements CapabilityFile for a wrapper `w` of the file `f`:
+// both must support the operation.
+func supportsWrapped(w, f fs.File, c Capability) bool {
+	return hasMethod(w, c) && Supports(f, c)
+}
+
+// hasMethod returns true if the file implements the interface the operation
+// is called through.
+func hasMethod(f fs.File, c Capability) bool {
+	var ok bool
+	switch c {
+	case CapabilityReadAt:
+		_, ok = f.(io.ReaderAt)
+	case CapabilityWrite:
+		_, ok = f.(io.Writer)
+	case CapabilityWriteAt:
+		_, ok = f.(io.WriterAt)
+	case CapabilitySeek:
+		_, ok = f.(io.Seeker)
+	case CapabilityReadDir:
+		_, ok = f.(fs.ReadDirFile)
+	case CapabilitySync:
+		_, ok = f.(interface{ Sync() error })
+	case CapabilityTruncate:
+		_, ok = f.(interface{ Truncate(int64) error })
+	case CapabilityFallocate:
+		if _, ok = f.(platform.Allocator); !ok {
+			_, ok = f.(interface{ Truncate(int64) error })
+		}
+	case CapabilityChmod:
+		_, ok = f.(interface{ Chmod(fs.FileMode) error })
+	case CapabilityChtimes:
+		_, ok = f.(interface{ Fd() uintptr })
+		if ok {
+			_, ok = f.(io.Writer)
+		}
+	}
+	return ok
+}
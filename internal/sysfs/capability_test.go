@@ -0,0 +1,84 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestSupports(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+
+	all := []Capability{
+		CapabilityReadAt, CapabilityWrite, CapabilityWriteAt, CapabilitySeek,
+		CapabilityReadDir, CapabilitySync, CapabilityTruncate, CapabilityFallocate,
+		CapabilityChmod, CapabilityChtimes,
+	}
+
+	tests := []struct {
+		name     string
+		fs       FS
+		expected []Capability
+	}{
+		{
+			name:     "dirFS",
+			fs:       NewDirFS(tmpDir),
+			expected: all, // *os.File supports everything.
+		},
+		{
+			name:     "readFS",
+			fs:       NewReadFS(NewDirFS(tmpDir)),
+			expected: []Capability{CapabilityReadAt, CapabilitySeek, CapabilityReadDir},
+		},
+		{
+			name: "FixtureFS",
+			fs:   FixtureFS(map[string][]byte{"file": []byte("wazero")}),
+			expected: []Capability{
+				CapabilityReadAt, CapabilityWrite, CapabilityWriteAt, CapabilitySeek,
+				CapabilityTruncate, CapabilityFallocate,
+			},
+		},
+		{
+			name:     "wrapper of readFS",
+			fs:       NewCountingFS(NewReadFS(NewDirFS(tmpDir)), false),
+			expected: []Capability{CapabilityReadAt, CapabilitySeek, CapabilityReadDir},
+		},
+		{
+			name: "wrapper of dirFS",
+			fs:   DeadlineFS(NewDirFS(tmpDir), time.Minute, nil),
+			// The wrapper doesn't expose the file descriptor, used by Chtimes.
+			expected: []Capability{
+				CapabilityReadAt, CapabilityWrite, CapabilityWriteAt, CapabilitySeek,
+				CapabilityReadDir, CapabilitySync, CapabilityTruncate, CapabilityFallocate,
+				CapabilityChmod,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			f, errno := tc.fs.OpenFile("file", os.O_RDONLY, 0)
+			require.Zero(t, errno)
+			defer f.Close()
+
+			var supported []Capability
+			for _, c := range all {
+				if Supports(f, c) {
+					supported = append(supported, c)
+				}
+			}
+			require.Equal(t, tc.expected, supported)
+		})
+	}
+}
+
+func TestCapability_String(t *testing.T) {
+	require.Equal(t, "Fallocate", CapabilityFallocate.String())
+	require.Equal(t, "Chtimes", CapabilityChtimes.String())
+	require.Equal(t, "Unknown", Capability(255).String())
+}
@@ -0,0 +1,222 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// CopyFS copies the directory tree rooted at "." in `src` into the root of
+// `dst`. Directories, regular files, symbolic links, named pipes and devices
+// are copied, retaining their permissions. Devices also retain their device
+// number, which usually requires privileges to create.
+//
+// When both filesystems support sparse files, holes in the source are
+// skipped instead of written as zeros, so the destination stays sparse.
+// Otherwise, regular files are copied in full.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. The first error encountered stops the
+// copy, leaving any files already copied in place.
+//
+// Files of other types, such as sockets, can't be copied and fail with
+// syscall.ENOTSUP instead of being silently skipped.
+func CopyFS(dst, src FS) syscall.Errno {
+	return copyDir(dst, src, ".")
+}
+
+// copyDir copies the contents of the directory `dir` from `src` to `dst`. The
+// directory itself must already exist in `dst`.
+func copyDir(dst, src FS, dir string) syscall.Errno {
+	f, errno := src.OpenFile(dir, os.O_RDONLY, 0)
+	if errno != 0 {
+		return errno
+	}
+	dirents, errno := platform.Readdir(f, -1)
+	_ = f.Close()
+	if errno != 0 {
+		return errno
+	}
+
+	for _, d := range dirents {
+		p := path.Join(dir, d.Name)
+		if errno = copyEntry(dst, src, p); errno != 0 {
+			return errno
+		}
+	}
+	return 0
+}
+
+// copyEntry copies the file at path `p` from `src` to `dst`, recursing into
+// directories.
+func copyEntry(dst, src FS, p string) syscall.Errno {
+	st, errno := src.Lstat(p)
+	if errno != 0 {
+		return errno
+	}
+
+	switch st.Mode.Type() {
+	case fs.ModeDir:
+		if errno = dst.Mkdir(p, st.Mode.Perm()); errno != 0 && errno != syscall.EEXIST {
+			return errno
+		}
+		return copyDir(dst, src, p)
+	case fs.ModeSymlink:
+		target, errno := src.Readlink(p)
+		if errno != 0 {
+			return errno
+		}
+		return dst.Symlink(target, p)
+	case 0: // regular file
+		return copyFile(dst, p, src, p, os.O_TRUNC, st.Mode.Perm(), st.Size)
+	case fs.ModeNamedPipe, fs.ModeDevice, fs.ModeDevice | fs.ModeCharDevice:
+		return copyNode(dst, p, st)
+	default: // such as fs.ModeSocket, which can't be re-created by path.
+		return syscall.ENOTSUP
+	}
+}
+
+// copyNode creates a named pipe or device at path `p` in `dst`, with the same
+// type, permissions and device number as `st`.
+func copyNode(dst FS, p string, st platform.Stat_t) syscall.Errno {
+	return dst.Mknod(p, st.Mode.Type()|st.Mode.Perm(), st.Rdev)
+}
+
+// CopyFile copies the regular file `srcPath` in `src` to `dstPath` in `dst`,
+// which may be the same FS. Like CopyFS, holes in sparse files are skipped
+// when both file systems support it. A zero syscall.Errno is success.
+//
+// An existing destination is truncated, then given the permissions of the
+// source. The modification and access times of the source are also copied.
+// Symbolic links are followed, and other types of files fail with
+// syscall.ENOTSUP, or syscall.EISDIR for a directory.
+func CopyFile(dst FS, dstPath string, src FS, srcPath string) syscall.Errno {
+	return copyFileWithTimes(dst, dstPath, src, srcPath, os.O_TRUNC)
+}
+
+// CopyFileExcl is like CopyFile, except it fails with syscall.EEXIST if
+// `dstPath` already exists.
+func CopyFileExcl(dst FS, dstPath string, src FS, srcPath string) syscall.Errno {
+	return copyFileWithTimes(dst, dstPath, src, srcPath, os.O_EXCL)
+}
+
+// copyFileWithTimes implements CopyFile and CopyFileExcl, opening the
+// destination with os.O_CREATE and `flag`.
+func copyFileWithTimes(dst FS, dstPath string, src FS, srcPath string, flag int) syscall.Errno {
+	st, errno := src.Stat(srcPath)
+	if errno != 0 {
+		return errno
+	}
+	switch st.Mode.Type() {
+	case 0: // regular file
+	case fs.ModeDir:
+		return syscall.EISDIR
+	default:
+		return syscall.ENOTSUP
+	}
+
+	if errno = copyFile(dst, dstPath, src, srcPath, flag, st.Mode.Perm(), st.Size); errno != 0 {
+		return errno
+	}
+
+	// An existing file keeps its permissions when truncated, and a new one
+	// may have been masked by umask.
+	if errno = dst.Chmod(dstPath, st.Mode.Perm()); errno != 0 {
+		return errno
+	}
+	times := [2]syscall.Timespec{
+		syscall.NsecToTimespec(st.Atim),
+		syscall.NsecToTimespec(st.Mtim),
+	}
+	return dst.Utimens(dstPath, &times, true)
+}
+
+// copyFile copies the regular file `srcPath` of the given `size` from `src`
+// to `dstPath` in `dst`, which is opened with os.O_CREATE and `flag`, such as
+// os.O_TRUNC to truncate any existing file.
+func copyFile(dst FS, dstPath string, src FS, srcPath string, flag int, perm fs.FileMode, size int64) (errno syscall.Errno) {
+	sf, errno := src.OpenFile(srcPath, os.O_RDONLY, 0)
+	if errno != 0 {
+		return
+	}
+	defer sf.Close()
+
+	df, errno := dst.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|flag, perm)
+	if errno != 0 {
+		return
+	}
+	defer func() {
+		if e := platform.UnwrapOSError(df.Close()); errno == 0 {
+			errno = e
+		}
+	}()
+
+	if errno = copySparse(df, sf, size); errno == syscall.ENOSYS {
+		errno = copyAll(df, sf)
+	}
+	return
+}
+
+// copyAll copies all bytes from `sf` to `df`, including any zeros read from
+// holes in a sparse file.
+func copyAll(df, sf fs.File) syscall.Errno {
+	w, ok := df.(io.Writer)
+	if !ok {
+		return syscall.EBADF
+	}
+	_, err := io.Copy(w, sf)
+	return platform.UnwrapOSError(err)
+}
+
+// copySparse copies only the data regions of `sf` to the same offsets in
+// `df`, then truncates `df` to `size`, leaving holes where `sf` had them.
+//
+// This returns syscall.ENOSYS, before writing anything, when either file
+// doesn't support sparse copies.
+func copySparse(df, sf fs.File, size int64) syscall.Errno {
+	w, ok := df.(io.WriteSeeker)
+	if !ok {
+		return syscall.ENOSYS
+	}
+	t, ok := df.(interface{ Truncate(size int64) error })
+	if !ok {
+		return syscall.ENOSYS
+	}
+	r, ok := sf.(io.ReadSeeker)
+	if !ok {
+		return syscall.ENOSYS
+	}
+
+	for offset := int64(0); offset < size; {
+		data, errno := platform.SeekData(sf, offset)
+		if errno == syscall.ENXIO {
+			break // the rest of the file is a hole
+		} else if errno != 0 {
+			return errno
+		}
+
+		hole, errno := platform.SeekHole(sf, data)
+		if errno != 0 {
+			return errno
+		}
+
+		if _, err := r.Seek(data, io.SeekStart); err != nil {
+			return platform.UnwrapOSError(err)
+		}
+		if _, err := w.Seek(data, io.SeekStart); err != nil {
+			return platform.UnwrapOSError(err)
+		}
+		if _, err := io.CopyN(w, r, hole-data); err != nil {
+			return platform.UnwrapOSError(err)
+		}
+		offset = hole
+	}
+
+	// Extend the destination over any trailing hole.
+	return platform.UnwrapOSError(t.Truncate(size))
+}
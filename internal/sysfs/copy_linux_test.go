@@ -0,0 +1,105 @@
+package sysfs
+
+import (
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestCopyFS_Sparse(t *testing.T) {
+	srcDir := t.TempDir()
+	srcPath := path.Join(srcDir, "sparse")
+
+	// Make a file with data between two large holes.
+	const size, dataOffset = 16 << 20, 8 << 20
+	f, err := os.Create(srcPath)
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(size))
+	_, err = f.WriteAt([]byte("wazero"), dataOffset)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	if allocatedSize(t, srcPath) >= size {
+		t.Skip("filesystem doesn't support sparse files")
+	}
+
+	dstDir := t.TempDir()
+	dstPath := path.Join(dstDir, "sparse")
+	require.Zero(t, CopyFS(NewDirFS(dstDir), NewDirFS(srcDir)))
+
+	requireSameContent(t, srcPath, dstPath)
+	require.True(t, allocatedSize(t, dstPath) < size)
+}
+
+// allocatedSize returns the bytes allocated on disk for the file at the path,
+// which is less than its size when it has holes.
+func allocatedSize(t *testing.T, path string) int64 {
+	var st syscall.Stat_t
+	require.NoError(t, syscall.Stat(path, &st))
+	return st.Blocks * 512
+}
+
+func TestCopyFS_Node(t *testing.T) {
+	srcDir := t.TempDir()
+	srcFS := NewDirFS(srcDir)
+	require.Zero(t, srcFS.Mknod("fifo", fs.ModeNamedPipe|0o640, 0))
+
+	// Creating a device requires privileges, so only test it when allowed.
+	nullDev := platform.Mkdev(1, 3) // same as /dev/null
+	hasDevice := true
+	switch errno := srcFS.Mknod("null", fs.ModeDevice|fs.ModeCharDevice|0o666, nullDev); errno {
+	case 0:
+	case syscall.EPERM:
+		hasDevice = false
+	default:
+		t.Fatal(errno)
+	}
+
+	dstDir := t.TempDir()
+	dstFS := NewDirFS(dstDir)
+	require.Zero(t, CopyFS(dstFS, srcFS))
+
+	st, errno := dstFS.Lstat("fifo")
+	require.Zero(t, errno)
+	require.Equal(t, fs.ModeNamedPipe, st.Mode.Type())
+	require.Equal(t, fs.FileMode(0o640), st.Mode.Perm()&0o640) // umask may clear bits
+
+	if !hasDevice {
+		t.Log("skipping character device: not permitted")
+		return
+	}
+	st, errno = dstFS.Lstat("null")
+	require.Zero(t, errno)
+	require.Equal(t, fs.ModeDevice|fs.ModeCharDevice, st.Mode.Type())
+	require.Equal(t, nullDev, st.Rdev)
+	require.Equal(t, uint32(1), platform.Major(st.Rdev))
+	require.Equal(t, uint32(3), platform.Minor(st.Rdev))
+}
+
+func TestCopyFS_Socket(t *testing.T) {
+	// Use a short path, as socket paths are limited to around 100 bytes.
+	srcDir, err := os.MkdirTemp("", "wazero")
+	require.NoError(t, err)
+	defer os.RemoveAll(srcDir)
+
+	l, err := net.Listen("unix", path.Join(srcDir, "sock"))
+	require.NoError(t, err)
+	defer l.Close()
+
+	srcFS := NewDirFS(srcDir)
+	st, errno := srcFS.Lstat("sock")
+	require.Zero(t, errno)
+	require.Equal(t, fs.ModeSocket, st.Mode.Type())
+
+	dstFS := NewDirFS(t.TempDir())
+	require.EqualErrno(t, syscall.ENOTSUP, CopyFS(dstFS, srcFS))
+
+	_, errno = dstFS.Lstat("sock")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+}
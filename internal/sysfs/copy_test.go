@@ -0,0 +1,113 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestCopyFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	tests := []struct {
+		name string
+		src  FS
+	}{
+		{name: "dirFS", src: NewDirFS(tmpDir)},
+		{name: "readFS", src: NewReadFS(NewDirFS(tmpDir))},
+		{name: "fs.FS", src: Adapt(fstest.FS)}, // no file descriptors
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			dstDir := t.TempDir()
+			require.Zero(t, CopyFS(NewDirFS(dstDir), tc.src))
+
+			require.NoError(t, fstest.TestFS(os.DirFS(dstDir)))
+			for _, name := range []string{"animals.txt", "sub/test.txt", "empty.txt"} {
+				requireSameContent(t, path.Join(tmpDir, name), path.Join(dstDir, name))
+			}
+			requireMode(t, NewDirFS(dstDir), "sub/test.txt", 0o444)
+		})
+	}
+}
+
+func TestCopyFS_Symlink(t *testing.T) {
+	srcDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(srcDir))
+	require.NoError(t, os.Symlink("sub/test.txt", path.Join(srcDir, "link")))
+
+	dstDir := t.TempDir()
+	require.Zero(t, CopyFS(NewDirFS(dstDir), NewDirFS(srcDir)))
+
+	target, err := os.Readlink(path.Join(dstDir, "link"))
+	require.NoError(t, err)
+	require.Equal(t, "sub/test.txt", target)
+}
+
+func TestCopyFS_Errors(t *testing.T) {
+	srcDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(srcDir))
+
+	// The destination is read-only.
+	err := CopyFS(NewReadFS(NewDirFS(t.TempDir())), NewDirFS(srcDir))
+	require.NotEqual(t, syscall.Errno(0), err)
+
+	// The source doesn't exist.
+	err = CopyFS(NewDirFS(t.TempDir()), NewDirFS(path.Join(srcDir, "missing")))
+	require.EqualErrno(t, syscall.ENOENT, err)
+}
+
+func TestCopyFile(t *testing.T) {
+	srcDir, dstDir := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(srcDir, "file"), []byte("wazero"), 0o444))
+	mtime := time.Unix(1234567890, 0)
+	require.NoError(t, os.Chtimes(path.Join(srcDir, "file"), mtime, mtime))
+	src, dst := NewDirFS(srcDir), NewDirFS(dstDir)
+
+	// Across file systems.
+	require.Zero(t, CopyFile(dst, "copy", src, "file"))
+	requireSameContent(t, path.Join(srcDir, "file"), path.Join(dstDir, "copy"))
+	requireMode(t, dst, "copy", 0o444)
+	st, errno := dst.Stat("copy")
+	require.Zero(t, errno)
+	require.Equal(t, mtime.UnixNano(), st.Mtim)
+
+	// Within the same file system.
+	require.Zero(t, CopyFile(src, "copy", src, "file"))
+	requireSameContent(t, path.Join(srcDir, "file"), path.Join(srcDir, "copy"))
+
+	// Overwriting a longer file leaves no stale bytes, and updates the mode.
+	require.NoError(t, os.WriteFile(path.Join(dstDir, "longer"), []byte("wazero wazero"), 0o600))
+	require.Zero(t, CopyFile(dst, "longer", src, "file"))
+	requireSameContent(t, path.Join(srcDir, "file"), path.Join(dstDir, "longer"))
+	requireMode(t, dst, "longer", 0o444)
+
+	t.Run("CopyFileExcl", func(t *testing.T) {
+		require.EqualErrno(t, syscall.EEXIST, CopyFileExcl(dst, "copy", src, "file"))
+		require.Zero(t, CopyFileExcl(dst, "excl", src, "file"))
+		requireSameContent(t, path.Join(srcDir, "file"), path.Join(dstDir, "excl"))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		require.EqualErrno(t, syscall.ENOENT, CopyFile(dst, "missing", src, "missing"))
+		require.EqualErrno(t, syscall.EISDIR, CopyFile(dst, "dir", src, "."))
+		require.EqualErrno(t, syscall.ENOENT, CopyFile(dst, "missing/copy", src, "file"))
+	})
+}
+
+func requireSameContent(t *testing.T, expectedPath, actualPath string) {
+	expected, err := os.ReadFile(expectedPath)
+	require.NoError(t, err)
+	actual, err := os.ReadFile(actualPath)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
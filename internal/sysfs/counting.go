@@ -0,0 +1,392 @@
+package sysfs
+
+import (
+	"fmt"
+	"io/fs"
+	"math/bits"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// NewCountingFS wraps `base` to count each operation, on the filesystem or on
+// files it opens, as well as the bytes read and written. Use Snapshot to read
+// the counters.
+//
+// When `latency` is true, a histogram of the latency of each operation is
+// also recorded, at the cost of reading the clock twice per operation.
+func NewCountingFS(base FS, latency bool) *CountingFS {
+	return &CountingFS{FS: base, latency: latency, start: time.Now(), ops: map[string]*opMetrics{}}
+}
+
+// CountingFS is an FS which counts operations, returned by NewCountingFS.
+type CountingFS struct {
+	FS
+	latency bool
+	start   time.Time
+
+	// mux guards the fields below, so that Snapshot is consistent.
+	mux                     sync.Mutex
+	ops                     map[string]*opMetrics
+	bytesRead, bytesWritten uint64
+}
+
+type opMetrics struct {
+	count   uint64
+	latency LatencyHistogram
+}
+
+// Metrics is a snapshot of the counters of a CountingFS.
+type Metrics struct {
+	// Elapsed is the time between NewCountingFS and the snapshot.
+	Elapsed time.Duration
+
+	// Ops are the count of each operation, keyed by the name of the method,
+	// such as "OpenFile" or "Read".
+	Ops map[string]uint64
+
+	// BytesRead are the count of bytes returned by file reads.
+	BytesRead uint64
+
+	// BytesWritten are the count of bytes accepted by file writes.
+	BytesWritten uint64
+
+	// Latency are the histograms of each operation, keyed the same as Ops, or
+	// nil if latency isn't recorded.
+	Latency map[string]LatencyHistogram
+}
+
+// OpsPerSecond returns the rate of all operations since NewCountingFS.
+func (m *Metrics) OpsPerSecond() float64 {
+	var total uint64
+	for _, count := range m.Ops {
+		total += count
+	}
+	return m.rate(total)
+}
+
+// ReadBytesPerSecond returns the rate of BytesRead since NewCountingFS.
+func (m *Metrics) ReadBytesPerSecond() float64 {
+	return m.rate(m.BytesRead)
+}
+
+// WriteBytesPerSecond returns the rate of BytesWritten since NewCountingFS.
+func (m *Metrics) WriteBytesPerSecond() float64 {
+	return m.rate(m.BytesWritten)
+}
+
+func (m *Metrics) rate(n uint64) float64 {
+	if m.Elapsed <= 0 {
+		return 0
+	}
+	return float64(n) / m.Elapsed.Seconds()
+}
+
+// LatencyHistogram counts operations by latency in powers of two: index zero
+// counts those that took less than a microsecond, and index i counts those
+// that took less than 2^i microseconds, but at least half that. The last
+// index also counts any operation slower than that.
+type LatencyHistogram [32]uint64
+
+func (h *LatencyHistogram) add(d time.Duration) {
+	i := bits.Len64(uint64(d / time.Microsecond))
+	if i >= len(h) {
+		i = len(h) - 1
+	}
+	h[i]++
+}
+
+// Snapshot returns a copy of the counters. This is safe to call concurrently
+// with other operations.
+func (c *CountingFS) Snapshot() Metrics {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	m := Metrics{
+		Elapsed:      time.Since(c.start),
+		Ops:          make(map[string]uint64, len(c.ops)),
+		BytesRead:    c.bytesRead,
+		BytesWritten: c.bytesWritten,
+	}
+	if c.latency {
+		m.Latency = make(map[string]LatencyHistogram, len(c.ops))
+	}
+	for op, om := range c.ops {
+		m.Ops[op] = om.count
+		if c.latency {
+			m.Latency[op] = om.latency
+		}
+	}
+	return m
+}
+
+// count runs the operation `op`, counting it and any bytes it transferred.
+func (c *CountingFS) count(op string, fn func() (read, written int)) {
+	var start time.Time
+	if c.latency {
+		start = time.Now()
+	}
+	read, written := fn()
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	om, ok := c.ops[op]
+	if !ok {
+		om = &opMetrics{}
+		c.ops[op] = om
+	}
+	om.count++
+	if c.latency {
+		om.latency.add(time.Since(start))
+	}
+	if read > 0 {
+		c.bytesRead += uint64(read)
+	}
+	if written > 0 {
+		c.bytesWritten += uint64(written)
+	}
+}
+
+// countErrno counts an operation that only returns syscall.Errno.
+func (c *CountingFS) countErrno(op string, fn func() syscall.Errno) (errno syscall.Errno) {
+	c.count(op, func() (int, int) {
+		errno = fn()
+		return 0, 0
+	})
+	return
+}
+
+// String implements fmt.Stringer
+func (c *CountingFS) String() string {
+	return c.FS.String()
+}
+
+// Open implements the same method as documented on fs.FS
+func (c *CountingFS) Open(name string) (fs.File, error) {
+	return fsOpen(c, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (c *CountingFS) OpenFile(path string, flag int, perm fs.FileMode) (f fs.File, errno syscall.Errno) {
+	errno = c.countErrno("OpenFile", func() (errno syscall.Errno) {
+		f, errno = c.FS.OpenFile(path, flag, perm)
+		return
+	})
+	if errno != 0 {
+		return nil, errno
+	}
+	return &countingFile{wrappedFile: wrappedFile{path: path, f: f}, c: c}, 0
+}
+
+// Lstat implements FS.Lstat
+func (c *CountingFS) Lstat(path string) (st platform.Stat_t, errno syscall.Errno) {
+	errno = c.countErrno("Lstat", func() (errno syscall.Errno) {
+		st, errno = c.FS.Lstat(path)
+		return
+	})
+	return
+}
+
+// Stat implements FS.Stat
+func (c *CountingFS) Stat(path string) (st platform.Stat_t, errno syscall.Errno) {
+	errno = c.countErrno("Stat", func() (errno syscall.Errno) {
+		st, errno = c.FS.Stat(path)
+		return
+	})
+	return
+}
+
+// Readlink implements FS.Readlink
+func (c *CountingFS) Readlink(path string) (dst string, errno syscall.Errno) {
+	errno = c.countErrno("Readlink", func() (errno syscall.Errno) {
+		dst, errno = c.FS.Readlink(path)
+		return
+	})
+	return
+}
+
+// Mkdir implements FS.Mkdir
+func (c *CountingFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	return c.countErrno("Mkdir", func() syscall.Errno { return c.FS.Mkdir(path, perm) })
+}
+
+// Chmod implements FS.Chmod
+func (c *CountingFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	return c.countErrno("Chmod", func() syscall.Errno { return c.FS.Chmod(path, perm) })
+}
+
+// Chown implements FS.Chown
+func (c *CountingFS) Chown(path string, uid, gid int) syscall.Errno {
+	return c.countErrno("Chown", func() syscall.Errno { return c.FS.Chown(path, uid, gid) })
+}
+
+// Lchown implements FS.Lchown
+func (c *CountingFS) Lchown(path string, uid, gid int) syscall.Errno {
+	return c.countErrno("Lchown", func() syscall.Errno { return c.FS.Lchown(path, uid, gid) })
+}
+
+// Rename implements FS.Rename
+func (c *CountingFS) Rename(from, to string) syscall.Errno {
+	return c.countErrno("Rename", func() syscall.Errno { return c.FS.Rename(from, to) })
+}
+
+// Rmdir implements FS.Rmdir
+func (c *CountingFS) Rmdir(path string) syscall.Errno {
+	return c.countErrno("Rmdir", func() syscall.Errno { return c.FS.Rmdir(path) })
+}
+
+// Unlink implements FS.Unlink
+func (c *CountingFS) Unlink(path string) syscall.Errno {
+	return c.countErrno("Unlink", func() syscall.Errno { return c.FS.Unlink(path) })
+}
+
+// Link implements FS.Link
+func (c *CountingFS) Link(oldPath, newPath string) syscall.Errno {
+	return c.countErrno("Link", func() syscall.Errno { return c.FS.Link(oldPath, newPath) })
+}
+
+// Symlink implements FS.Symlink
+func (c *CountingFS) Symlink(oldPath, linkName string) syscall.Errno {
+	return c.countErrno("Symlink", func() syscall.Errno { return c.FS.Symlink(oldPath, linkName) })
+}
+
+// Mknod implements FS.Mknod
+func (c *CountingFS) Mknod(path string, mode fs.FileMode, dev uint64) syscall.Errno {
+	return c.countErrno("Mknod", func() syscall.Errno { return c.FS.Mknod(path, mode, dev) })
+}
+
+// Truncate implements FS.Truncate
+func (c *CountingFS) Truncate(path string, size int64) syscall.Errno {
+	return c.countErrno("Truncate", func() syscall.Errno { return c.FS.Truncate(path, size) })
+}
+
+// Utimens implements FS.Utimens
+func (c *CountingFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	return c.countErrno("Utimens", func() syscall.Errno { return c.FS.Utimens(path, times, symlinkFollow) })
+}
+
+// countingFile counts operations on a file opened by CountingFS, including
+// those the underlying file doesn't implement.
+type countingFile struct {
+	wrappedFile
+	c *CountingFS
+}
+
+// GoString implements fmt.GoStringer
+func (f *countingFile) GoString() string {
+	return fmt.Sprintf("&sysfs.countingFile{path: %q, f: %s}", f.path, goStringFile(f.f))
+}
+
+// Stat implements fs.File
+func (f *countingFile) Stat() (info fs.FileInfo, err error) {
+	f.c.count("File.Stat", func() (int, int) {
+		info, err = f.f.Stat()
+		return 0, 0
+	})
+	return
+}
+
+// Read implements io.Reader
+func (f *countingFile) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return // not counted, as the base file isn't called.
+	}
+	f.c.count("Read", func() (int, int) {
+		n, err = f.f.Read(p)
+		return n, 0
+	})
+	return
+}
+
+// ReadAt implements io.ReaderAt
+func (f *countingFile) ReadAt(p []byte, off int64) (n int, err error) {
+	if len(p) == 0 {
+		return // not counted, as the base file isn't called.
+	}
+	f.c.count("ReadAt", func() (int, int) {
+		n, err = f.wrappedFile.ReadAt(p, off)
+		return n, 0
+	})
+	return
+}
+
+// Write implements io.Writer
+func (f *countingFile) Write(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return // not counted, as the base file isn't called.
+	}
+	f.c.count("Write", func() (int, int) {
+		n, err = f.wrappedFile.Write(p)
+		return 0, n
+	})
+	return
+}
+
+// WriteAt implements io.WriterAt
+func (f *countingFile) WriteAt(p []byte, off int64) (n int, err error) {
+	if len(p) == 0 {
+		return // not counted, as the base file isn't called.
+	}
+	f.c.count("WriteAt", func() (int, int) {
+		n, err = f.wrappedFile.WriteAt(p, off)
+		return 0, n
+	})
+	return
+}
+
+// Seek implements io.Seeker
+func (f *countingFile) Seek(offset int64, whence int) (newOffset int64, err error) {
+	f.c.count("Seek", func() (int, int) {
+		newOffset, err = f.wrappedFile.Seek(offset, whence)
+		return 0, 0
+	})
+	return
+}
+
+// ReadDir implements fs.ReadDirFile
+func (f *countingFile) ReadDir(n int) (dirents []fs.DirEntry, err error) {
+	f.c.count("ReadDir", func() (int, int) {
+		dirents, err = f.wrappedFile.ReadDir(n)
+		return 0, 0
+	})
+	return
+}
+
+// Sync implements the same method as documented on os.File
+func (f *countingFile) Sync() (err error) {
+	f.c.count("Sync", func() (int, int) {
+		err = f.wrappedFile.Sync()
+		return 0, 0
+	})
+	return
+}
+
+// Chmod implements the same method as documented on os.File
+func (f *countingFile) Chmod(mode fs.FileMode) (err error) {
+	f.c.count("File.Chmod", func() (int, int) {
+		err = f.wrappedFile.Chmod(mode)
+		return 0, 0
+	})
+	return
+}
+
+// Truncate implements the same method as documented on os.File
+func (f *countingFile) Truncate(size int64) (err error) {
+	f.c.count("File.Truncate", func() (int, int) {
+		err = f.wrappedFile.Truncate(size)
+		return 0, 0
+	})
+	return
+}
+
+// Close implements fs.File
+func (f *countingFile) Close() (err error) {
+	f.c.count("Close", func() (int, int) {
+		err = f.f.Close()
+		return 0, 0
+	})
+	return
+}
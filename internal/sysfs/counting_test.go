@@ -0,0 +1,119 @@
+package sysfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestCountingFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+
+	c := NewCountingFS(NewDirFS(tmpDir), false)
+
+	f, errno := c.OpenFile("file", os.O_RDWR, 0)
+	require.Zero(t, errno)
+	b, err := io.ReadAll(f.(io.Reader))
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(b))
+	_, err = f.(io.WriterAt).WriteAt([]byte("WA"), 0)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, errno = c.Stat("missing")
+	require.NotEqual(t, 0, errno) // errors are counted, too
+
+	m := c.Snapshot()
+	require.Equal(t, uint64(6), m.BytesRead)
+	require.Equal(t, uint64(2), m.BytesWritten)
+	require.Equal(t, uint64(1), m.Ops["OpenFile"])
+	require.Equal(t, uint64(1), m.Ops["WriteAt"])
+	require.Equal(t, uint64(1), m.Ops["Close"])
+	require.Equal(t, uint64(1), m.Ops["Stat"])
+	require.True(t, m.Ops["Read"] >= 2) // until io.EOF
+	require.True(t, m.Elapsed > 0)
+	require.True(t, m.ReadBytesPerSecond() > 0)
+	require.Nil(t, m.Latency)
+
+	// The snapshot is a copy.
+	m.Ops["Stat"] = 100
+	require.Equal(t, uint64(1), c.Snapshot().Ops["Stat"])
+}
+
+func TestCountingFS_Latency(t *testing.T) {
+	c := NewCountingFS(NewDirFS(t.TempDir()), true)
+
+	for i := 0; i < 3; i++ {
+		_, _ = c.Stat("missing")
+	}
+
+	m := c.Snapshot()
+	var total uint64
+	for _, n := range m.Latency["Stat"] {
+		total += n
+	}
+	require.Equal(t, m.Ops["Stat"], total)
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	var h LatencyHistogram
+	h.add(500 * time.Nanosecond)
+	h.add(time.Microsecond)
+	h.add(3 * time.Microsecond)
+	h.add(time.Duration(1 << 62))
+	require.Equal(t, uint64(1), h[0])
+	require.Equal(t, uint64(1), h[1])
+	require.Equal(t, uint64(1), h[2])
+	require.Equal(t, uint64(1), h[len(h)-1])
+}
+
+// TestCountingFS_SnapshotConsistent ensures a snapshot never sees the count of
+// an operation without the bytes it transferred, or vice versa.
+func TestCountingFS_SnapshotConsistent(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+
+	c := NewCountingFS(NewDirFS(tmpDir), true)
+	f, errno := c.OpenFile("file", os.O_RDONLY, 0)
+	require.Zero(t, errno)
+	defer f.Close()
+	ra := f.(io.ReaderAt)
+
+	const readers, reads = 8, 200
+	var wg sync.WaitGroup
+	wg.Add(readers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, 1)
+			for j := 0; j < reads; j++ {
+				_, _ = ra.ReadAt(buf, 0)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	for {
+		m := c.Snapshot()
+		// Each read is one byte, so the counts must match.
+		require.Equal(t, m.Ops["ReadAt"], m.BytesRead)
+		select {
+		case <-done:
+			m = c.Snapshot()
+			require.Equal(t, uint64(readers*reads), m.BytesRead)
+			return
+		default:
+		}
+	}
+}
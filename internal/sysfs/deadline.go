@@ -0,0 +1,352 @@
+package sysfs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// DeadlineFS wraps `base` such that each operation, on the filesystem or on
+// files it opens, fails with syscall.ETIMEDOUT when it doesn't complete within
+// `perOpTimeout`. This is intended for backends that can block indefinitely,
+// such as network filesystems.
+//
+// `clock` is the time source used to compute each operation's deadline, and
+// defaults to time.Now when nil.
+//
+// # Notes
+//
+//   - Each operation runs in its own goroutine. Operations that time out are
+//     abandoned, not cancelled: they may still complete in the background, but
+//     their results are discarded.
+//   - Buffers are copied before and after abandonable operations, so that an
+//     abandoned operation never touches memory owned by the caller.
+func DeadlineFS(base FS, perOpTimeout time.Duration, clock func() time.Time) FS {
+	if clock == nil {
+		clock = time.Now
+	}
+	return &deadlineFS{fs: base, timeout: perOpTimeout, clock: clock}
+}
+
+type deadlineFS struct {
+	fs      FS
+	timeout time.Duration
+	clock   func() time.Time
+}
+
+// run calls `op` in a new goroutine, returning its result unless the deadline
+// passes first.
+func (d *deadlineFS) run(op func() syscall.Errno) syscall.Errno {
+	deadline := d.clock().Add(d.timeout)
+	done := make(chan syscall.Errno, 1) // buffered so an abandoned op can exit.
+	go func() { done <- op() }()
+
+	timer := time.NewTimer(deadline.Sub(d.clock()))
+	defer timer.Stop()
+
+	select {
+	case errno := <-done:
+		return errno
+	case <-timer.C:
+		return syscall.ETIMEDOUT
+	}
+}
+
+// String implements fmt.Stringer
+func (d *deadlineFS) String() string {
+	return d.fs.String()
+}
+
+// Open implements the same method as documented on fs.FS
+func (d *deadlineFS) Open(name string) (fs.File, error) {
+	return fsOpen(d, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (d *deadlineFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	// Results are passed through a channel so that a file opened after the
+	// deadline can be closed instead of leaked.
+	opened := make(chan fs.File, 1)
+	errno := d.run(func() syscall.Errno {
+		f, errno := d.fs.OpenFile(path, flag, perm)
+		opened <- f
+		return errno
+	})
+
+	if errno == syscall.ETIMEDOUT {
+		go func() {
+			// Wait for the file to open, if it ever will, then close it.
+			if f := <-opened; f != nil {
+				_ = f.Close()
+			}
+		}()
+		return nil, errno
+	} else if errno != 0 {
+		return nil, errno
+	}
+	return &deadlineFile{wrappedFile: wrappedFile{path: path, f: <-opened}, d: d}, 0
+}
+
+// Lstat implements FS.Lstat
+func (d *deadlineFS) Lstat(path string) (st platform.Stat_t, errno syscall.Errno) {
+	res := make(chan platform.Stat_t, 1)
+	if errno = d.run(func() syscall.Errno {
+		st, errno := d.fs.Lstat(path)
+		res <- st
+		return errno
+	}); errno == 0 {
+		st = <-res
+	}
+	return
+}
+
+// Stat implements FS.Stat
+func (d *deadlineFS) Stat(path string) (st platform.Stat_t, errno syscall.Errno) {
+	res := make(chan platform.Stat_t, 1)
+	if errno = d.run(func() syscall.Errno {
+		st, errno := d.fs.Stat(path)
+		res <- st
+		return errno
+	}); errno == 0 {
+		st = <-res
+	}
+	return
+}
+
+// Readlink implements FS.Readlink
+func (d *deadlineFS) Readlink(path string) (dst string, errno syscall.Errno) {
+	res := make(chan string, 1)
+	if errno = d.run(func() syscall.Errno {
+		dst, errno := d.fs.Readlink(path)
+		res <- dst
+		return errno
+	}); errno == 0 {
+		dst = <-res
+	}
+	return
+}
+
+// Mkdir implements FS.Mkdir
+func (d *deadlineFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	return d.run(func() syscall.Errno { return d.fs.Mkdir(path, perm) })
+}
+
+// Chmod implements FS.Chmod
+func (d *deadlineFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	return d.run(func() syscall.Errno { return d.fs.Chmod(path, perm) })
+}
+
+// Chown implements FS.Chown
+func (d *deadlineFS) Chown(path string, uid, gid int) syscall.Errno {
+	return d.run(func() syscall.Errno { return d.fs.Chown(path, uid, gid) })
+}
+
+// Lchown implements FS.Lchown
+func (d *deadlineFS) Lchown(path string, uid, gid int) syscall.Errno {
+	return d.run(func() syscall.Errno { return d.fs.Lchown(path, uid, gid) })
+}
+
+// Rename implements FS.Rename
+func (d *deadlineFS) Rename(from, to string) syscall.Errno {
+	return d.run(func() syscall.Errno { return d.fs.Rename(from, to) })
+}
+
+// Rmdir implements FS.Rmdir
+func (d *deadlineFS) Rmdir(path string) syscall.Errno {
+	return d.run(func() syscall.Errno { return d.fs.Rmdir(path) })
+}
+
+// Unlink implements FS.Unlink
+func (d *deadlineFS) Unlink(path string) syscall.Errno {
+	return d.run(func() syscall.Errno { return d.fs.Unlink(path) })
+}
+
+// Link implements FS.Link
+func (d *deadlineFS) Link(oldPath, newPath string) syscall.Errno {
+	return d.run(func() syscall.Errno { return d.fs.Link(oldPath, newPath) })
+}
+
+// Symlink implements FS.Symlink
+func (d *deadlineFS) Symlink(oldPath, linkName string) syscall.Errno {
+	return d.run(func() syscall.Errno { return d.fs.Symlink(oldPath, linkName) })
+}
+
+// Mknod implements FS.Mknod
+func (d *deadlineFS) Mknod(path string, mode fs.FileMode, dev uint64) syscall.Errno {
+	return d.run(func() syscall.Errno { return d.fs.Mknod(path, mode, dev) })
+}
+
+// Truncate implements FS.Truncate
+func (d *deadlineFS) Truncate(path string, size int64) syscall.Errno {
+	return d.run(func() syscall.Errno { return d.fs.Truncate(path, size) })
+}
+
+// Utimens implements FS.Utimens
+func (d *deadlineFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	if times != nil { // copy as the caller may reuse it after a timeout.
+		t := *times
+		times = &t
+	}
+	return d.run(func() syscall.Errno { return d.fs.Utimens(path, times, symlinkFollow) })
+}
+
+// deadlineFile applies the timeout of deadlineFS to each file operation.
+type deadlineFile struct {
+	wrappedFile
+	d *deadlineFS
+}
+
+// GoString implements fmt.GoStringer
+func (f *deadlineFile) GoString() string {
+	return fmt.Sprintf("&sysfs.deadlineFile{path: %q, timeout: %v, f: %s}", f.path, f.d.timeout, goStringFile(f.f))
+}
+
+// Stat implements fs.File
+func (f *deadlineFile) Stat() (fs.FileInfo, error) {
+	res := make(chan fs.FileInfo, 1)
+	if errno := f.d.run(func() syscall.Errno {
+		info, err := f.f.Stat()
+		res <- info
+		return platform.UnwrapOSError(err)
+	}); errno != 0 {
+		return nil, errno
+	}
+	return <-res, nil
+}
+
+// Read implements io.Reader
+func (f *deadlineFile) Read(p []byte) (int, error) {
+	return f.read(p, f.f.Read)
+}
+
+// ReadAt implements io.ReaderAt
+func (f *deadlineFile) ReadAt(p []byte, off int64) (int, error) {
+	ra, ok := f.f.(io.ReaderAt)
+	if !ok {
+		return 0, syscall.ENOSYS
+	}
+	return f.read(p, func(buf []byte) (int, error) { return ra.ReadAt(buf, off) })
+}
+
+// read reads into a private buffer, only copying into `p` when the read
+// completed before the deadline.
+func (f *deadlineFile) read(p []byte, read func([]byte) (int, error)) (int, error) {
+	if len(p) == 0 {
+		return 0, nil // no need to start a goroutine.
+	}
+	buf := make([]byte, len(p))
+	var n int
+	var err error
+	if errno := f.d.run(func() syscall.Errno {
+		n, err = read(buf)
+		return 0
+	}); errno != 0 {
+		return 0, errno
+	}
+	copy(p, buf[:n])
+	return n, err
+}
+
+// Write implements io.Writer
+func (f *deadlineFile) Write(p []byte) (int, error) {
+	w, ok := f.f.(io.Writer)
+	if !ok {
+		return 0, syscall.ENOSYS
+	}
+	return f.write(p, w.Write)
+}
+
+// WriteAt implements io.WriterAt
+func (f *deadlineFile) WriteAt(p []byte, off int64) (int, error) {
+	wa, ok := f.f.(io.WriterAt)
+	if !ok {
+		return 0, syscall.ENOSYS
+	}
+	return f.write(p, func(buf []byte) (int, error) { return wa.WriteAt(buf, off) })
+}
+
+// write writes a copy of `p`, so that an abandoned write doesn't see changes
+// the caller makes after the deadline.
+func (f *deadlineFile) write(p []byte, write func([]byte) (int, error)) (int, error) {
+	if len(p) == 0 {
+		return 0, nil // no need to start a goroutine.
+	}
+	buf := make([]byte, len(p))
+	copy(buf, p)
+	var n int
+	var err error
+	if errno := f.d.run(func() syscall.Errno {
+		n, err = write(buf)
+		return 0
+	}); errno != 0 {
+		return 0, errno
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker
+func (f *deadlineFile) Seek(offset int64, whence int) (int64, error) {
+	s, ok := f.f.(io.Seeker)
+	if !ok {
+		return 0, syscall.ENOSYS
+	}
+	var newOffset int64
+	var err error
+	if errno := f.d.run(func() syscall.Errno {
+		newOffset, err = s.Seek(offset, whence)
+		return 0
+	}); errno != 0 {
+		return 0, errno
+	}
+	return newOffset, err
+}
+
+// ReadDir implements fs.ReadDirFile
+func (f *deadlineFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	rd, ok := f.f.(fs.ReadDirFile)
+	if !ok {
+		return nil, syscall.ENOTDIR
+	}
+	var dirents []fs.DirEntry
+	var err error
+	if errno := f.d.run(func() syscall.Errno {
+		dirents, err = rd.ReadDir(n)
+		return 0
+	}); errno != 0 {
+		return nil, errno
+	}
+	return dirents, err
+}
+
+// Sync implements the same method as documented on os.File
+func (f *deadlineFile) Sync() error {
+	return f.runErr(f.wrappedFile.Sync)
+}
+
+// Chmod implements the same method as documented on os.File
+func (f *deadlineFile) Chmod(mode fs.FileMode) error {
+	return f.runErr(func() error { return f.wrappedFile.Chmod(mode) })
+}
+
+// Truncate implements the same method as documented on os.File
+func (f *deadlineFile) Truncate(size int64) error {
+	return f.runErr(func() error { return f.wrappedFile.Truncate(size) })
+}
+
+// Close implements fs.File
+func (f *deadlineFile) Close() error {
+	return f.runErr(f.f.Close)
+}
+
+// runErr runs `fn` with the timeout of deadlineFS, returning a nil error
+// instead of a zero syscall.Errno.
+func (f *deadlineFile) runErr(fn func() error) error {
+	if errno := f.d.run(func() syscall.Errno { return platform.UnwrapOSError(fn()) }); errno != 0 {
+		return errno
+	}
+	return nil
+}
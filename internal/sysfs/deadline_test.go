@@ -0,0 +1,151 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// slowFS blocks each operation until release is closed.
+type slowFS struct {
+	UnimplementedFS
+	release chan struct{}
+}
+
+// OpenFile implements FS.OpenFile
+func (s *slowFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	if path == "slow" {
+		<-s.release
+	}
+	return &slowFile{release: s.release}, 0
+}
+
+// Stat implements FS.Stat
+func (s *slowFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	<-s.release
+	return platform.Stat_t{}, 0
+}
+
+// Mkdir implements FS.Mkdir
+func (s *slowFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	<-s.release
+	return 0
+}
+
+type slowFile struct {
+	fs.File
+	release chan struct{}
+}
+
+func (f *slowFile) Read(p []byte) (int, error) {
+	<-f.release
+	return copy(p, "slow"), nil
+}
+
+func (f *slowFile) Write(p []byte) (int, error) {
+	<-f.release
+	return len(p), nil
+}
+
+func (f *slowFile) Close() error {
+	return nil
+}
+
+func TestDeadlineFS_Timeout(t *testing.T) {
+	slow := &slowFS{release: make(chan struct{})}
+	defer close(slow.release) // let abandoned operations complete.
+
+	testFS := DeadlineFS(slow, 10*time.Millisecond, nil)
+
+	_, errno := testFS.Stat("file")
+	require.EqualErrno(t, syscall.ETIMEDOUT, errno)
+
+	require.EqualErrno(t, syscall.ETIMEDOUT, testFS.Mkdir("dir", 0o700))
+
+	_, errno = testFS.OpenFile("slow", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.ETIMEDOUT, errno)
+
+	f, errno := testFS.OpenFile("file", os.O_RDWR, 0)
+	require.Zero(t, errno)
+
+	buf := []byte{'a', 'b', 'c', 'd'}
+	n, err := f.Read(buf)
+	require.EqualErrno(t, syscall.ETIMEDOUT, err)
+	require.Zero(t, n)
+	require.Equal(t, "abcd", string(buf)) // untouched by the abandoned read.
+
+	n, err = f.(io.Writer).Write(buf)
+	require.EqualErrno(t, syscall.ETIMEDOUT, err)
+	require.Zero(t, n)
+
+	require.NoError(t, f.Close())
+}
+
+func TestDeadlineFS_Clock(t *testing.T) {
+	slow := &slowFS{release: make(chan struct{})}
+	defer close(slow.release) // let abandoned operations complete.
+
+	// A clock that advances an hour on each call expires the deadline before
+	// the timer starts, so it fires without waiting out the timeout.
+	var now time.Time
+	clock := func() time.Time {
+		now = now.Add(time.Hour)
+		return now
+	}
+	testFS := DeadlineFS(slow, time.Hour, clock)
+
+	_, errno := testFS.Stat("file")
+	require.EqualErrno(t, syscall.ETIMEDOUT, errno)
+
+	_, errno = testFS.OpenFile("slow", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.ETIMEDOUT, errno)
+}
+
+func TestDeadlineFS_Result(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	// Even when the clock says the deadline passed, an operation that
+	// completes before the timer fires returns its own result.
+	var now time.Time
+	clock := func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+	testFS := DeadlineFS(NewDirFS(tmpDir), 90*time.Second, clock)
+
+	_, errno := testFS.Stat("missing")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+
+	_, errno = testFS.Stat("animals.txt")
+	require.Zero(t, errno)
+}
+
+func TestDeadlineFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	testFS := DeadlineFS(NewDirFS(tmpDir), time.Minute, nil)
+	require.Equal(t, tmpDir, testFS.String())
+
+	st, errno := testFS.Stat("animals.txt")
+	require.Zero(t, errno)
+	require.Equal(t, int64(30), st.Size)
+
+	f, errno := testFS.OpenFile("animals.txt", os.O_RDONLY, 0)
+	require.Zero(t, errno)
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "bear\ncat\nshark\ndinosaur\nhuman\n", string(b))
+
+	require.NoError(t, fstest.TestFS(testFS.(fs.FS)))
+}
@@ -45,6 +45,11 @@ func (d *dirFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, sysc
 	return platform.OpenFile(d.join(path), flag, perm)
 }
 
+// directoryFlagEnforced implements directoryFlagFS
+func (d *dirFS) directoryFlagEnforced() bool {
+	return platform.DirectoryFlagEnforced
+}
+
 // Lstat implements FS.Lstat
 func (d *dirFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
 	return platform.Lstat(d.join(path))
@@ -103,10 +108,19 @@ func (d *dirFS) Link(oldName, newName string) syscall.Errno {
 	return platform.UnwrapOSError(err)
 }
 
+// openAnonymous implements anonymousFS
+func (d *dirFS) openAnonymous(dir string, perm fs.FileMode) (fs.File, syscall.Errno) {
+	return platform.OpenTmpfile(d.join(dir), perm)
+}
+
+// linkAnonymous implements anonymousFS
+func (d *dirFS) linkAnonymous(f fs.File, name string) syscall.Errno {
+	return platform.LinkFd(f, d.join(name))
+}
+
 // Rmdir implements FS.Rmdir
 func (d *dirFS) Rmdir(path string) syscall.Errno {
-	err := syscall.Rmdir(d.join(path))
-	return platform.UnwrapOSError(err)
+	return platform.Rmdir(d.join(path))
 }
 
 // Unlink implements FS.Unlink
@@ -114,6 +128,11 @@ func (d *dirFS) Unlink(path string) (err syscall.Errno) {
 	return platform.Unlink(d.join(path))
 }
 
+// Mknod implements FS.Mknod
+func (d *dirFS) Mknod(path string, mode fs.FileMode, dev uint64) syscall.Errno {
+	return platform.Mknod(d.join(path), mode, dev)
+}
+
 // Symlink implements FS.Symlink
 func (d *dirFS) Symlink(oldName, link string) syscall.Errno {
 	// Note: do not resolve `oldName` relative to this dirFS. The link result is always resolved
@@ -2,10 +2,12 @@ package sysfs
 
 import (
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"path"
 	"runtime"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
@@ -817,6 +819,46 @@ func TestDirFS_TestFS(t *testing.T) {
 	require.NoError(t, fstest.TestFS(testFS.(fs.FS)))
 }
 
+// TestDirFS_concurrent ensures many goroutines can read, write and list the
+// same directory, which is meaningful with the race detector.
+func TestDirFS_concurrent(t *testing.T) {
+	testFS := NewDirFS(t.TempDir())
+	require.Zero(t, WriteFile(testFS, "shared", []byte("wazero"), 0o600))
+
+	const goroutines = 16
+	errnos := make(chan syscall.Errno, goroutines)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		name := fmt.Sprintf("file%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errnos <- func() syscall.Errno {
+				if errno := WriteFile(testFS, name, []byte(name), 0o600); errno != 0 {
+					return errno
+				}
+				if errno := WriteFile(testFS, "shared", []byte(name), 0o600); errno != 0 {
+					return errno
+				}
+				if _, errno := ReadFile(testFS, "shared"); errno != 0 {
+					return errno
+				}
+				if _, errno := ReadDir(testFS, "."); errno != 0 {
+					return errno
+				}
+				return testFS.Unlink(name)
+			}()
+		}()
+	}
+	wg.Wait()
+	close(errnos)
+
+	for errno := range errnos {
+		require.Zero(t, errno)
+	}
+	requireDirEntries(t, testFS, ".", 1)
+}
+
 // Test_fdReaddir_opened_file_written ensures that writing files to the already-opened directory
 // is visible. This is significant on Windows.
 // https://github.com/ziglang/zig/blob/2ccff5115454bab4898bae3de88f5619310bc5c1/lib/std/fs/test.zig#L156-L184
@@ -890,6 +932,47 @@ func TestDirFS_Symlink(t *testing.T) {
 	require.True(t, st.Mode()&fs.ModeSymlink > 0)
 }
 
+func TestDirFS_Mknod(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	testFS := NewDirFS(tmpDir)
+
+	if runtime.GOOS == "windows" {
+		require.EqualErrno(t, syscall.ENOSYS, testFS.Mknod("fifo", fs.ModeNamedPipe|0o600, 0))
+		return
+	}
+
+	t.Run("fifo", func(t *testing.T) {
+		require.Zero(t, testFS.Mknod("fifo", fs.ModeNamedPipe|0o600, 0))
+
+		st, errno := testFS.Lstat("fifo")
+		require.Zero(t, errno)
+		require.Equal(t, fs.ModeNamedPipe, st.Mode.Type())
+	})
+
+	t.Run("regular file", func(t *testing.T) {
+		require.Zero(t, testFS.Mknod("file", 0o600, 0))
+
+		st, errno := testFS.Lstat("file")
+		require.Zero(t, errno)
+		require.Equal(t, fs.FileMode(0), st.Mode.Type())
+		require.Equal(t, int64(0), st.Size)
+	})
+
+	t.Run("exists", func(t *testing.T) {
+		require.EqualErrno(t, syscall.EEXIST, testFS.Mknod("sub/test.txt", fs.ModeNamedPipe|0o600, 0))
+	})
+
+	t.Run("parent doesn't exist", func(t *testing.T) {
+		require.EqualErrno(t, syscall.ENOENT, testFS.Mknod("missing/fifo", fs.ModeNamedPipe|0o600, 0))
+	})
+
+	t.Run("invalid type", func(t *testing.T) {
+		require.EqualErrno(t, syscall.EINVAL, testFS.Mknod("socket", fs.ModeSocket|0o600, 0))
+	})
+}
+
 func TestDirFS_Readlink(t *testing.T) {
 	tmpDir := t.TempDir()
 	require.NoError(t, fstest.WriteTestFiles(tmpDir))
@@ -131,3 +131,65 @@ func checkUidGid(t *testing.T, path string, uid, gid uint32) {
 	require.Equal(t, uid, sys.Uid)
 	require.Equal(t, gid, sys.Gid)
 }
+
+// TestDirFS_OpenFile_permissions ensures the host enforces permission bits of
+// files, as documented on FS.
+func TestDirFS_OpenFile_permissions(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root bypasses permission bits")
+	}
+	tmpDir := t.TempDir()
+	testFS := NewDirFS(tmpDir)
+
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "none"), nil, 0o000))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "ro"), nil, 0o400))
+
+	_, errno := testFS.OpenFile("none", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.EACCES, errno)
+
+	_, errno = testFS.OpenFile("ro", os.O_RDWR, 0)
+	require.EqualErrno(t, syscall.EACCES, errno)
+
+	f, errno := testFS.OpenFile("ro", os.O_RDONLY, 0)
+	require.Zero(t, errno)
+	require.NoError(t, f.Close())
+}
+
+// TestDirFS_directoryPermissions ensures the host enforces permission bits of
+// directories, as documented on FS.
+func TestDirFS_directoryPermissions(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root bypasses permission bits")
+	}
+	tmpDir := t.TempDir()
+	testFS := NewDirFS(tmpDir)
+
+	for _, dir := range []string{"nosearch", "noread"} {
+		require.Zero(t, testFS.Mkdir(dir, 0o700))
+		require.Zero(t, WriteFile(testFS, path.Join(dir, "file"), nil, 0o600))
+	}
+	require.Zero(t, testFS.Chmod("nosearch", 0o600))
+	require.Zero(t, testFS.Chmod("noread", 0o300))
+	t.Cleanup(func() { // so that the directory can be removed.
+		_ = os.Chmod(path.Join(tmpDir, "nosearch"), 0o700)
+		_ = os.Chmod(path.Join(tmpDir, "noread"), 0o700)
+	})
+
+	t.Run("traversal needs the search bit", func(t *testing.T) {
+		_, errno := testFS.Stat("nosearch/file")
+		require.EqualErrno(t, syscall.EACCES, errno)
+		_, errno = testFS.OpenFile("nosearch/file", os.O_RDONLY, 0)
+		require.EqualErrno(t, syscall.EACCES, errno)
+
+		_, errno = testFS.Stat("noread/file")
+		require.Zero(t, errno)
+	})
+
+	t.Run("listing needs the read bit", func(t *testing.T) {
+		_, errno := ReadDir(testFS, "noread")
+		require.EqualErrno(t, syscall.EACCES, errno)
+
+		_, errno = ReadDir(testFS, "nosearch")
+		require.Zero(t, errno)
+	})
+}
@@ -0,0 +1,461 @@
+package sysfs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+const (
+	// encryptChunkSize is the size of the plaintext of each chunk of a file
+	// of EncryptFS, which is sealed separately for random access.
+	encryptChunkSize = 4096
+
+	// encryptIDSize is the size of the random ID at the start of each file of
+	// EncryptFS, which binds chunks to the file.
+	encryptIDSize = 16
+)
+
+// EncryptFS wraps `base` to encrypt the contents of regular files with
+// AES-GCM, using `key`, which is 16, 24 or 32 bytes. This allows a host to
+// persist data of a module on storage it doesn't trust. An error is returned
+// if the key is invalid.
+//
+// Each file starts with a random ID, followed by chunks of 4KiB of plaintext,
+// sealed separately with a random nonce, so that reads and writes at any
+// offset only decrypt the chunks they touch. The authenticated data of each
+// chunk is the file ID and the chunk index, so chunks can't be moved within or
+// between files. Reading a chunk which was modified fails with syscall.EIO.
+//
+// # Notes
+//
+//   - Only contents are encrypted. Names, directory structure, permissions,
+//     times and approximate sizes of files are visible in `base`.
+//   - Removing whole chunks from the end of a file isn't detected.
+//   - Files open for writing are also read, so `base` must permit reading
+//     them, and its files must implement io.ReaderAt and io.WriterAt.
+//   - Directory listings report the size stored in `base`.
+func EncryptFS(base FS, key []byte) (FS, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptFS{FS: base, aead: aead}, nil
+}
+
+type encryptFS struct {
+	FS
+	aead cipher.AEAD
+}
+
+// Open implements the same method as documented on fs.FS
+func (e *encryptFS) Open(name string) (fs.File, error) {
+	return fsOpen(e, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (e *encryptFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	// Writes read the chunks they change, and the offset of appends is
+	// tracked here, as it is in plaintext.
+	baseFlag := flag &^ os.O_APPEND
+	if flag&os.O_WRONLY != 0 {
+		baseFlag = baseFlag&^os.O_WRONLY | os.O_RDWR
+	}
+	f, errno := e.FS.OpenFile(path, baseFlag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	st, errno := platform.StatFile(f)
+	if errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	} else if !st.Mode.IsRegular() {
+		return f, 0
+	}
+
+	ef := &encryptedFile{wrappedFile: wrappedFile{path: path, f: f}, e: e, append: flag&os.O_APPEND != 0}
+	if errno = ef.init(st.Size, flag&(os.O_WRONLY|os.O_RDWR) != 0); errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	}
+	return ef, 0
+}
+
+// Lstat implements FS.Lstat
+func (e *encryptFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	st, errno := e.FS.Lstat(path)
+	if errno == 0 && st.Mode.IsRegular() {
+		st.Size = e.plainSize(st.Size)
+	}
+	return st, errno
+}
+
+// Stat implements FS.Stat
+func (e *encryptFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	st, errno := e.FS.Stat(path)
+	if errno == 0 && st.Mode.IsRegular() {
+		st.Size = e.plainSize(st.Size)
+	}
+	return st, errno
+}
+
+// Truncate implements FS.Truncate
+func (e *encryptFS) Truncate(path string, size int64) syscall.Errno {
+	f, errno := e.OpenFile(path, os.O_RDWR, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer f.Close()
+
+	ef, ok := f.(*encryptedFile)
+	if !ok {
+		return syscall.EISDIR
+	}
+	return ef.truncate(size)
+}
+
+// sealedChunkSize is the size of each chunk stored in `base`.
+func (e *encryptFS) sealedChunkSize() int64 {
+	return int64(encryptChunkSize + e.aead.NonceSize() + e.aead.Overhead())
+}
+
+// plainSize returns the size of the plaintext of a file, given the size
+// stored in `base`.
+func (e *encryptFS) plainSize(size int64) int64 {
+	if size -= encryptIDSize; size <= 0 {
+		return 0
+	}
+	sealed, overhead := e.sealedChunkSize(), int64(e.aead.NonceSize()+e.aead.Overhead())
+	n := size / sealed * encryptChunkSize
+	if rem := size % sealed; rem > overhead {
+		n += rem - overhead
+	}
+	return n
+}
+
+// encryptedFile encrypts the contents of a regular file of EncryptFS.
+type encryptedFile struct {
+	wrappedFile
+	e  *encryptFS
+	id [encryptIDSize]byte
+	// hasID is false until id is read, which is on the first read when the
+	// file was empty when opened, as another file may write it later.
+	hasID  bool
+	offset int64
+	append bool
+}
+
+// init reads the file ID, or writes a new one if the file is empty.
+func (f *encryptedFile) init(size int64, writable bool) syscall.Errno {
+	switch {
+	case size == 0 && writable:
+		if _, err := io.ReadFull(rand.Reader, f.id[:]); err != nil {
+			return syscall.EIO
+		}
+		if errno := f.writeAt(f.id[:], 0); errno != 0 {
+			return errno
+		}
+		f.hasID = true
+		return 0
+	case size == 0: // read the ID when there is something to decrypt.
+		return 0
+	}
+	return f.readID()
+}
+
+// readID reads the file ID from the start of the underlying file.
+func (f *encryptedFile) readID() syscall.Errno {
+	ra, ok := f.f.(io.ReaderAt)
+	if !ok {
+		return syscall.ENOSYS
+	}
+	if n, err := ra.ReadAt(f.id[:], 0); n < encryptIDSize {
+		if err == nil || err == io.EOF {
+			return syscall.EIO // shorter than an ID.
+		}
+		return platform.UnwrapOSError(err)
+	}
+	f.hasID = true
+	return 0
+}
+
+// GoString implements fmt.GoStringer
+func (f *encryptedFile) GoString() string {
+	return fmt.Sprintf("&sysfs.encryptedFile{path: %q, f: %s}", f.path, goStringFile(f.f))
+}
+
+// Stat implements fs.File
+func (f *encryptedFile) Stat() (fs.FileInfo, error) {
+	st, errno := platform.StatFile(f.f)
+	if errno != 0 {
+		return nil, errno
+	}
+	st.Size = f.e.plainSize(st.Size)
+	return &dirInfo{name: path.Base(f.path), stat: st}, nil
+}
+
+// size returns the size of the plaintext.
+func (f *encryptedFile) size() (int64, syscall.Errno) {
+	st, errno := platform.StatFile(f.f)
+	if errno != 0 {
+		return 0, errno
+	}
+	return f.e.plainSize(st.Size), 0
+}
+
+// Read implements io.Reader
+func (f *encryptedFile) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil // return io.EOF on the next read, like os.File.
+	}
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt
+func (f *encryptedFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, syscall.EINVAL
+	}
+	size, errno := f.size()
+	if errno != 0 {
+		return 0, errno
+	}
+
+	var n int
+	for n < len(p) && off < size {
+		chunk, errno := f.readChunk(off / encryptChunkSize)
+		if errno != 0 {
+			return n, errno
+		}
+		start := off % encryptChunkSize
+		if start >= int64(len(chunk)) {
+			return n, syscall.EIO // shorter than the file size implies.
+		}
+		m := copy(p[n:], chunk[start:])
+		n += m
+		off += int64(m)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Write implements io.Writer
+func (f *encryptedFile) Write(p []byte) (int, error) {
+	if f.append {
+		size, errno := f.size()
+		if errno != 0 {
+			return 0, errno
+		}
+		f.offset = size
+	}
+	n, err := f.WriteAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+// WriteAt implements io.WriterAt
+func (f *encryptedFile) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, syscall.EINVAL
+	} else if len(p) == 0 {
+		return 0, nil // like pwrite, don't fill any gap.
+	}
+	size, errno := f.size()
+	if errno != 0 {
+		return 0, errno
+	}
+	if errno = f.fillGap(size, off); errno != 0 {
+		return 0, errno
+	}
+
+	n, errno := f.writeChunks(p, off)
+	if errno != 0 {
+		return n, errno
+	}
+	return n, nil
+}
+
+// fillGap fills the plaintext between the end of the file, at `size`, and
+// `off` with zeros.
+func (f *encryptedFile) fillGap(size, off int64) syscall.Errno {
+	zeros := make([]byte, encryptChunkSize)
+	for size < off {
+		n := encryptChunkSize - size%encryptChunkSize
+		if n > off-size {
+			n = off - size
+		}
+		if _, errno := f.writeChunks(zeros[:n], size); errno != 0 {
+			return errno
+		}
+		size += n
+	}
+	return 0
+}
+
+// writeChunks writes `p` at `off`, which isn't after the end of the file,
+// sealing each chunk it changes again.
+func (f *encryptedFile) writeChunks(p []byte, off int64) (int, syscall.Errno) {
+	var n int
+	for n < len(p) {
+		i, start := off/encryptChunkSize, int(off%encryptChunkSize)
+		chunk, errno := f.readChunk(i)
+		if errno != 0 {
+			return n, errno
+		}
+		end := start + len(p) - n
+		if end > encryptChunkSize {
+			end = encryptChunkSize
+		}
+		if len(chunk) < end {
+			chunk = append(chunk, make([]byte, end-len(chunk))...)
+		}
+		m := copy(chunk[start:end], p[n:])
+		if errno = f.writeChunk(i, chunk); errno != 0 {
+			return n, errno
+		}
+		n += m
+		off += int64(m)
+	}
+	return n, 0
+}
+
+// readChunk returns the plaintext of the chunk `i`, or nil if the file
+// doesn't have it.
+func (f *encryptedFile) readChunk(i int64) ([]byte, syscall.Errno) {
+	ra, ok := f.f.(io.ReaderAt)
+	if !ok {
+		return nil, syscall.ENOSYS
+	}
+	if !f.hasID {
+		if errno := f.readID(); errno != 0 {
+			return nil, errno
+		}
+	}
+	buf := make([]byte, f.e.sealedChunkSize())
+	n, err := ra.ReadAt(buf, encryptIDSize+i*int64(len(buf)))
+	if err != nil && err != io.EOF {
+		return nil, platform.UnwrapOSError(err)
+	} else if n == 0 {
+		return nil, 0
+	}
+
+	ns := f.e.aead.NonceSize()
+	if n < ns+f.e.aead.Overhead() {
+		return nil, syscall.EIO
+	}
+	plain, err := f.e.aead.Open(buf[ns:ns], buf[:ns], buf[ns:n], f.additionalData(i))
+	if err != nil {
+		return nil, syscall.EIO
+	}
+	return plain, 0
+}
+
+// writeChunk seals `plain` with a new nonce, as the chunk `i`.
+func (f *encryptedFile) writeChunk(i int64, plain []byte) syscall.Errno {
+	ns := f.e.aead.NonceSize()
+	sealed := make([]byte, ns, f.e.sealedChunkSize())
+	if _, err := io.ReadFull(rand.Reader, sealed); err != nil {
+		return syscall.EIO
+	}
+	sealed = f.e.aead.Seal(sealed, sealed[:ns], plain, f.additionalData(i))
+	return f.writeAt(sealed, encryptIDSize+i*f.e.sealedChunkSize())
+}
+
+// additionalData authenticates the chunk `i` of this file.
+func (f *encryptedFile) additionalData(i int64) []byte {
+	ad := make([]byte, encryptIDSize+8)
+	copy(ad, f.id[:])
+	binary.BigEndian.PutUint64(ad[encryptIDSize:], uint64(i))
+	return ad
+}
+
+// writeAt writes `p` at `off` of the underlying file.
+func (f *encryptedFile) writeAt(p []byte, off int64) syscall.Errno {
+	wa, ok := f.f.(io.WriterAt)
+	if !ok {
+		return syscall.ENOSYS
+	}
+	_, err := wa.WriteAt(p, off)
+	return platform.UnwrapOSError(err)
+}
+
+// Seek implements io.Seeker
+func (f *encryptedFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		size, errno := f.size()
+		if errno != 0 {
+			return 0, errno
+		}
+		offset += size
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Truncate implements the same method as documented on os.File
+func (f *encryptedFile) Truncate(size int64) error {
+	if errno := f.truncate(size); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func (f *encryptedFile) truncate(size int64) syscall.Errno {
+	if size < 0 {
+		return syscall.EINVAL
+	}
+	t, ok := f.f.(interface{ Truncate(int64) error })
+	if !ok {
+		return syscall.ENOSYS
+	}
+	cur, errno := f.size()
+	if errno != 0 {
+		return errno
+	} else if size >= cur {
+		return f.fillGap(cur, size)
+	}
+
+	// Seal the last chunk again with only the remaining data.
+	i, rem := size/encryptChunkSize, size%encryptChunkSize
+	sealedSize := encryptIDSize + i*f.e.sealedChunkSize()
+	if rem > 0 {
+		chunk, errno := f.readChunk(i)
+		if errno != 0 {
+			return errno
+		} else if int64(len(chunk)) < rem {
+			return syscall.EIO // shorter than the file size implies.
+		}
+		if errno = f.writeChunk(i, chunk[:rem]); errno != 0 {
+			return errno
+		}
+		sealedSize += int64(f.e.aead.NonceSize()) + rem + int64(f.e.aead.Overhead())
+	}
+	return platform.UnwrapOSError(t.Truncate(sealedSize))
+}
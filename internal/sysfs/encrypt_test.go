@@ -0,0 +1,142 @@
+package sysfs
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+var encryptTestKey = bytes.Repeat([]byte{1}, 32)
+
+func TestEncryptFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := NewDirFS(tmpDir)
+	testFS, err := EncryptFS(base, encryptTestKey)
+	require.NoError(t, err)
+
+	data := bytes.Repeat([]byte("wazero"), 3*encryptChunkSize) // several chunks
+	require.Zero(t, WriteFile(testFS, "file", data, 0o600))
+
+	t.Run("round trip", func(t *testing.T) {
+		b, errno := ReadFile(testFS, "file")
+		require.Zero(t, errno)
+		require.Equal(t, data, b)
+
+		st, errno := testFS.Stat("file")
+		require.Zero(t, errno)
+		require.Equal(t, int64(len(data)), st.Size)
+	})
+
+	t.Run("base stores ciphertext", func(t *testing.T) {
+		b, err := os.ReadFile(path.Join(tmpDir, "file"))
+		require.NoError(t, err)
+		require.True(t, len(b) > len(data))
+		require.False(t, bytes.Contains(b, []byte("wazerowazero")))
+
+		// The same plaintext is sealed differently.
+		require.Zero(t, WriteFile(testFS, "copy", data, 0o600))
+		c, err := os.ReadFile(path.Join(tmpDir, "copy"))
+		require.NoError(t, err)
+		require.False(t, bytes.Equal(b, c))
+	})
+
+	t.Run("random access", func(t *testing.T) {
+		f, errno := testFS.OpenFile("file", os.O_RDWR, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		// Across a chunk boundary.
+		off := int64(encryptChunkSize - 3)
+		_, err := f.(io.WriterAt).WriteAt([]byte("abcdef"), off)
+		require.NoError(t, err)
+		copy(data[off:], "abcdef")
+
+		buf := make([]byte, 10)
+		_, err = f.(io.ReaderAt).ReadAt(buf, off-2)
+		require.NoError(t, err)
+		require.Equal(t, data[off-2:off+8], buf)
+
+		// After the end, which fills the gap with zeros.
+		end := int64(len(data)) + 10
+		_, err = f.(io.WriterAt).WriteAt([]byte("end"), end)
+		require.NoError(t, err)
+		data = append(append(data, make([]byte, 10)...), "end"...)
+
+		b, errno := ReadFile(testFS, "file")
+		require.Zero(t, errno)
+		require.Equal(t, data, b)
+
+		// A zero-length write doesn't fill the gap.
+		_, err = f.(io.WriterAt).WriteAt(nil, int64(len(data))+100)
+		require.NoError(t, err)
+		st, errno := testFS.Stat("file")
+		require.Zero(t, errno)
+		require.Equal(t, int64(len(data)), st.Size)
+	})
+
+	t.Run("append", func(t *testing.T) {
+		require.Zero(t, WriteFile(testFS, "log", []byte("hello"), 0o600))
+		f, errno := testFS.OpenFile("log", os.O_WRONLY|os.O_APPEND, 0)
+		require.Zero(t, errno)
+		_, err := f.(io.Writer).Write([]byte(" world"))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		b, errno := ReadFile(testFS, "log")
+		require.Zero(t, errno)
+		require.Equal(t, "hello world", string(b))
+	})
+
+	t.Run("read-only on an empty file", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "empty"), nil, 0o600))
+		r, errno := testFS.OpenFile("empty", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer r.Close()
+
+		// The ID is read once another file writes it.
+		require.Zero(t, WriteFile(testFS, "empty", []byte("wazero"), 0o600))
+		b, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+	})
+
+	t.Run("Truncate", func(t *testing.T) {
+		require.Zero(t, testFS.Truncate("file", encryptChunkSize+5))
+		b, errno := ReadFile(testFS, "file")
+		require.Zero(t, errno)
+		require.Equal(t, data[:encryptChunkSize+5], b)
+
+		require.Zero(t, testFS.Truncate("file", encryptChunkSize+10))
+		b, errno = ReadFile(testFS, "file")
+		require.Zero(t, errno)
+		require.Equal(t, append(data[:encryptChunkSize+5:encryptChunkSize+5], make([]byte, 5)...), b)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		require.Zero(t, WriteFile(testFS, "tampered", []byte("wazero"), 0o600))
+		b, err := os.ReadFile(path.Join(tmpDir, "tampered"))
+		require.NoError(t, err)
+		b[len(b)-1] ^= 1
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "tampered"), b, 0o600))
+
+		_, errno := ReadFile(testFS, "tampered")
+		require.EqualErrno(t, syscall.EIO, errno)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		otherFS, err := EncryptFS(base, bytes.Repeat([]byte{2}, 32))
+		require.NoError(t, err)
+		_, errno := ReadFile(otherFS, "log")
+		require.EqualErrno(t, syscall.EIO, errno)
+	})
+}
+
+func TestEncryptFS_invalidKey(t *testing.T) {
+	_, err := EncryptFS(NewDirFS(t.TempDir()), []byte("short"))
+	require.Error(t, err)
+}
@@ -0,0 +1,322 @@
+package sysfs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// FaultFS wraps `base` to call `inject` before each operation, on the
+// filesystem or on files it opens. When `inject` returns an error, the
+// operation fails with it instead of reaching `base`. This allows tests to
+// simulate failures, such as syscall.ENOSPC, at precise points.
+//
+// `op` is the name of the method, such as "OpenFile" or "Write", and `path` is
+// the path it acts on, or the path a file was opened with. Methods on files
+// that share their name with one on FS are prefixed, such as "File.Stat".
+//
+// # Notes
+//
+//   - Methods on FS return the error coerced by platform.UnwrapOSError, so it
+//     should be a syscall.Errno. Methods on files return it as-is.
+//   - An injected error on "Close" is returned after closing the file anyway,
+//     so that it isn't leaked.
+func FaultFS(base FS, inject func(op, path string) error) FS {
+	return &faultFS{FS: base, inject: inject}
+}
+
+// FaultFSWithDelay is like FaultFS, except each operation first sleeps for the
+// duration `delay` returns for it, to simulate slow storage. `inject` is
+// optional.
+//
+// When `ctx` is done during a delay, the operation fails without reaching
+// `base`: with syscall.ETIMEDOUT if its deadline passed, or otherwise with
+// syscall.ECANCELED. Cancel it to release operations abandoned by a caller,
+// such as DeadlineFS. `ctx` defaults to context.Background when nil.
+func FaultFSWithDelay(ctx context.Context, base FS, inject func(op, path string) error, delay func(op, path string) time.Duration) FS {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &faultFS{FS: base, inject: inject, delay: delay, ctx: ctx}
+}
+
+type faultFS struct {
+	FS
+	inject func(op, path string) error
+	delay  func(op, path string) time.Duration
+	ctx    context.Context
+}
+
+// fault returns the error to inject for the operation, if any.
+func (f *faultFS) fault(op, path string) syscall.Errno {
+	return platform.UnwrapOSError(f.faultErr(op, path))
+}
+
+// faultErr delays the operation, then returns the error to inject, if any.
+func (f *faultFS) faultErr(op, path string) error {
+	if f.delay != nil {
+		if d := f.delay(op, path); d > 0 {
+			timer := time.NewTimer(d)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+			case <-f.ctx.Done():
+				if f.ctx.Err() == context.DeadlineExceeded {
+					return syscall.ETIMEDOUT
+				}
+				return syscall.ECANCELED
+			}
+		}
+	}
+	if f.inject != nil {
+		return f.inject(op, path)
+	}
+	return nil
+}
+
+// String implements fmt.Stringer
+func (f *faultFS) String() string {
+	return f.FS.String()
+}
+
+// Open implements the same method as documented on fs.FS
+func (f *faultFS) Open(name string) (fs.File, error) {
+	return fsOpen(f, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (f *faultFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	if errno := f.fault("OpenFile", path); errno != 0 {
+		return nil, errno
+	}
+	file, errno := f.FS.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &faultFile{wrappedFile: wrappedFile{path: path, f: file}, fsys: f}, 0
+}
+
+// Lstat implements FS.Lstat
+func (f *faultFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	if errno := f.fault("Lstat", path); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return f.FS.Lstat(path)
+}
+
+// Stat implements FS.Stat
+func (f *faultFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	if errno := f.fault("Stat", path); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return f.FS.Stat(path)
+}
+
+// Readlink implements FS.Readlink
+func (f *faultFS) Readlink(path string) (string, syscall.Errno) {
+	if errno := f.fault("Readlink", path); errno != 0 {
+		return "", errno
+	}
+	return f.FS.Readlink(path)
+}
+
+// Mkdir implements FS.Mkdir
+func (f *faultFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	if errno := f.fault("Mkdir", path); errno != 0 {
+		return errno
+	}
+	return f.FS.Mkdir(path, perm)
+}
+
+// Chmod implements FS.Chmod
+func (f *faultFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	if errno := f.fault("Chmod", path); errno != 0 {
+		return errno
+	}
+	return f.FS.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (f *faultFS) Chown(path string, uid, gid int) syscall.Errno {
+	if errno := f.fault("Chown", path); errno != 0 {
+		return errno
+	}
+	return f.FS.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (f *faultFS) Lchown(path string, uid, gid int) syscall.Errno {
+	if errno := f.fault("Lchown", path); errno != 0 {
+		return errno
+	}
+	return f.FS.Lchown(path, uid, gid)
+}
+
+// Rename implements FS.Rename
+func (f *faultFS) Rename(from, to string) syscall.Errno {
+	if errno := f.fault("Rename", from); errno != 0 {
+		return errno
+	}
+	return f.FS.Rename(from, to)
+}
+
+// Rmdir implements FS.Rmdir
+func (f *faultFS) Rmdir(path string) syscall.Errno {
+	if errno := f.fault("Rmdir", path); errno != 0 {
+		return errno
+	}
+	return f.FS.Rmdir(path)
+}
+
+// Unlink implements FS.Unlink
+func (f *faultFS) Unlink(path string) syscall.Errno {
+	if errno := f.fault("Unlink", path); errno != 0 {
+		return errno
+	}
+	return f.FS.Unlink(path)
+}
+
+// Link implements FS.Link
+func (f *faultFS) Link(oldPath, newPath string) syscall.Errno {
+	if errno := f.fault("Link", newPath); errno != 0 {
+		return errno
+	}
+	return f.FS.Link(oldPath, newPath)
+}
+
+// Symlink implements FS.Symlink
+func (f *faultFS) Symlink(oldPath, linkName string) syscall.Errno {
+	if errno := f.fault("Symlink", linkName); errno != 0 {
+		return errno
+	}
+	return f.FS.Symlink(oldPath, linkName)
+}
+
+// Mknod implements FS.Mknod
+func (f *faultFS) Mknod(path string, mode fs.FileMode, dev uint64) syscall.Errno {
+	if errno := f.fault("Mknod", path); errno != 0 {
+		return errno
+	}
+	return f.FS.Mknod(path, mode, dev)
+}
+
+// Truncate implements FS.Truncate
+func (f *faultFS) Truncate(path string, size int64) syscall.Errno {
+	if errno := f.fault("Truncate", path); errno != 0 {
+		return errno
+	}
+	return f.FS.Truncate(path, size)
+}
+
+// Utimens implements FS.Utimens
+func (f *faultFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	if errno := f.fault("Utimens", path); errno != 0 {
+		return errno
+	}
+	return f.FS.Utimens(path, times, symlinkFollow)
+}
+
+// faultFile injects faults into operations on a file opened by FaultFS.
+type faultFile struct {
+	wrappedFile
+	fsys *faultFS
+}
+
+// GoString implements fmt.GoStringer
+func (f *faultFile) GoString() string {
+	return fmt.Sprintf("&sysfs.faultFile{path: %q, f: %s}", f.path, goStringFile(f.f))
+}
+
+// Stat implements fs.File
+func (f *faultFile) Stat() (fs.FileInfo, error) {
+	if err := f.fsys.faultErr("File.Stat", f.path); err != nil {
+		return nil, err
+	}
+	return f.f.Stat()
+}
+
+// Read implements io.Reader
+func (f *faultFile) Read(p []byte) (int, error) {
+	if err := f.fsys.faultErr("Read", f.path); err != nil {
+		return 0, err
+	}
+	return f.f.Read(p)
+}
+
+// ReadAt implements io.ReaderAt
+func (f *faultFile) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.fsys.faultErr("ReadAt", f.path); err != nil {
+		return 0, err
+	}
+	return f.wrappedFile.ReadAt(p, off)
+}
+
+// Write implements io.Writer
+func (f *faultFile) Write(p []byte) (int, error) {
+	if err := f.fsys.faultErr("Write", f.path); err != nil {
+		return 0, err
+	}
+	return f.wrappedFile.Write(p)
+}
+
+// WriteAt implements io.WriterAt
+func (f *faultFile) WriteAt(p []byte, off int64) (int, error) {
+	if err := f.fsys.faultErr("WriteAt", f.path); err != nil {
+		return 0, err
+	}
+	return f.wrappedFile.WriteAt(p, off)
+}
+
+// Seek implements io.Seeker
+func (f *faultFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.fsys.faultErr("Seek", f.path); err != nil {
+		return 0, err
+	}
+	return f.wrappedFile.Seek(offset, whence)
+}
+
+// ReadDir implements fs.ReadDirFile
+func (f *faultFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if err := f.fsys.faultErr("ReadDir", f.path); err != nil {
+		return nil, err
+	}
+	return f.wrappedFile.ReadDir(n)
+}
+
+// Sync implements the same method as documented on os.File
+func (f *faultFile) Sync() error {
+	if err := f.fsys.faultErr("Sync", f.path); err != nil {
+		return err
+	}
+	return f.wrappedFile.Sync()
+}
+
+// Chmod implements the same method as documented on os.File
+func (f *faultFile) Chmod(mode fs.FileMode) error {
+	if err := f.fsys.faultErr("File.Chmod", f.path); err != nil {
+		return err
+	}
+	return f.wrappedFile.Chmod(mode)
+}
+
+// Truncate implements the same method as documented on os.File
+func (f *faultFile) Truncate(size int64) error {
+	if err := f.fsys.faultErr("File.Truncate", f.path); err != nil {
+		return err
+	}
+	return f.wrappedFile.Truncate(size)
+}
+
+// Close implements fs.File
+func (f *faultFile) Close() error {
+	injected := f.fsys.faultErr("Close", f.path)
+	if err := f.f.Close(); injected == nil {
+		return err
+	}
+	return injected
+}
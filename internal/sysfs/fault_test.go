@@ -0,0 +1,156 @@
+package sysfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// failNth returns an injector that fails the nth call of `op` with `errno`.
+func failNth(op string, n int, errno syscall.Errno) func(string, string) error {
+	var calls int
+	return func(o, _ string) error {
+		if o != op {
+			return nil
+		}
+		if calls++; calls == n {
+			return errno
+		}
+		return nil
+	}
+}
+
+func TestFaultFS_nthWrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := FaultFS(NewDirFS(tmpDir), failNth("Write", 3, syscall.ENOSPC))
+
+	f, errno := testFS.OpenFile("file", os.O_CREATE|os.O_WRONLY, 0o600)
+	require.Zero(t, errno)
+	defer f.Close()
+
+	w := f.(io.Writer)
+	for i := 0; i < 2; i++ {
+		_, err := w.Write([]byte("wa"))
+		require.NoError(t, err)
+	}
+	_, err := w.Write([]byte("ze"))
+	require.EqualErrno(t, syscall.ENOSPC, platform.UnwrapOSError(err))
+	_, err = w.Write([]byte("ro"))
+	require.NoError(t, err)
+
+	// The failed write didn't reach the file.
+	b, err := os.ReadFile(path.Join(tmpDir, "file"))
+	require.NoError(t, err)
+	require.Equal(t, "wawaro", string(b))
+}
+
+func TestFaultFS_propagates(t *testing.T) {
+	srcDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(srcDir))
+
+	// The error propagates through helpers that use the FS, such as CopyFS.
+	dst := FaultFS(NewDirFS(t.TempDir()), failNth("Write", 1, syscall.EIO))
+	require.EqualErrno(t, syscall.EIO, CopyFS(dst, NewDirFS(srcDir)))
+}
+
+func TestFaultFS_ops(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+
+	var faults []string
+	testFS := FaultFS(NewDirFS(tmpDir), func(op, path string) error {
+		if path == "denied" || op == "Sync" || op == "Close" {
+			faults = append(faults, op)
+			return syscall.EACCES
+		}
+		return nil
+	})
+
+	_, errno := testFS.Stat("denied")
+	require.EqualErrno(t, syscall.EACCES, errno)
+	require.EqualErrno(t, syscall.EACCES, testFS.Mkdir("denied", 0o700))
+	_, errno = testFS.OpenFile("denied", os.O_CREATE|os.O_RDWR, 0o600)
+	require.EqualErrno(t, syscall.EACCES, errno)
+
+	// Injection is by operation, so other paths are unaffected.
+	require.Zero(t, testFS.Mkdir("dir", 0o700))
+	_, err := os.Stat(path.Join(tmpDir, "denied"))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	f, errno := testFS.OpenFile("file", os.O_RDWR, 0)
+	require.Zero(t, errno)
+	require.EqualErrno(t, syscall.EACCES, FileSync(f))
+	require.EqualErrno(t, syscall.EACCES, platform.UnwrapOSError(f.Close()))
+
+	// The file was closed, despite the injected error.
+	_, err = f.(*faultFile).f.(io.Reader).Read(make([]byte, 1))
+	require.ErrorIs(t, err, fs.ErrClosed)
+
+	require.Equal(t, []string{"Stat", "Mkdir", "OpenFile", "Sync", "Close"}, faults)
+}
+
+func TestFaultFSWithDelay(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "slow"), []byte("wazero"), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel() // releases any read abandoned by DeadlineFS.
+
+	delayed := FaultFSWithDelay(ctx, NewDirFS(tmpDir), nil, func(op, path string) time.Duration {
+		if op == "Read" && path == "slow" {
+			return time.Hour
+		}
+		return 0
+	})
+
+	t.Run("exceeds DeadlineFS timeout", func(t *testing.T) {
+		testFS := DeadlineFS(delayed, 10*time.Millisecond, nil)
+
+		f, errno := testFS.OpenFile("slow", os.O_RDONLY, 0)
+		require.Zero(t, errno) // only reads are slow.
+		defer f.Close()
+
+		_, err := f.(io.Reader).Read(make([]byte, 6))
+		require.EqualErrno(t, syscall.ETIMEDOUT, platform.UnwrapOSError(err))
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		f, errno := delayed.OpenFile("slow", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		cancel()
+		_, err := f.(io.Reader).Read(make([]byte, 6))
+		require.EqualErrno(t, syscall.ECANCELED, platform.UnwrapOSError(err))
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+
+		testFS := FaultFSWithDelay(ctx, NewDirFS(tmpDir), nil, func(string, string) time.Duration {
+			return time.Hour
+		})
+		_, errno := testFS.Stat("slow")
+		require.EqualErrno(t, syscall.ETIMEDOUT, errno)
+	})
+
+	t.Run("injects after delay", func(t *testing.T) {
+		testFS := FaultFSWithDelay(nil, NewDirFS(tmpDir), failNth("Stat", 1, syscall.EIO), func(string, string) time.Duration {
+			return time.Millisecond
+		})
+		_, errno := testFS.Stat("slow")
+		require.EqualErrno(t, syscall.EIO, errno)
+		_, errno = testFS.Stat("slow")
+		require.Zero(t, errno)
+	})
+}
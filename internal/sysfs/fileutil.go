@@ -0,0 +1,282 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// openFlagsWriteFile are the flags WriteFile opens with. os.O_TRUNC ensures
+// no stale bytes remain when overwriting a longer file.
+const openFlagsWriteFile = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+
+// WriteFile is like os.WriteFile, except the path is relative to the file
+// system, and syscall.Errno are returned instead of an os.PathError. A zero
+// syscall.Errno is success.
+//
+// If the file doesn't exist, it is created with `perm`. Otherwise, it is
+// truncated before writing, without changing its permissions.
+func WriteFile(fsys FS, path string, data []byte, perm fs.FileMode) (errno syscall.Errno) {
+	f, errno := fsys.OpenFile(path, openFlagsWriteFile, perm)
+	if errno != 0 {
+		return
+	}
+	defer func() {
+		if e := platform.UnwrapOSError(f.Close()); errno == 0 {
+			errno = e
+		}
+	}()
+
+	w, ok := f.(io.Writer)
+	if !ok {
+		return syscall.EBADF
+	}
+	_, err := w.Write(data)
+	return platform.UnwrapOSError(err)
+}
+
+// MkdirAll is like os.MkdirAll, except the path is relative to the file
+// system, and syscall.Errno are returned instead of an os.PathError. A zero
+// syscall.Errno is success, including when `path` is already a directory.
+//
+// Each missing directory is created with `perm`. If a component exists, but
+// isn't a directory, this fails with syscall.ENOTDIR. Components are resolved
+// with Stat, so a symbolic link loop fails with syscall.ELOOP.
+func MkdirAll(fsys FS, path string, perm fs.FileMode) syscall.Errno {
+	path = strings.TrimSuffix(path, "/")
+	for i := 0; i <= len(path); i++ {
+		if i < len(path) && path[i] != '/' {
+			continue
+		}
+		dir := path[:i]
+		if dir == "" || dir == "." || strings.HasSuffix(dir, "/") {
+			continue // root, current or repeated separator
+		}
+
+		st, errno := fsys.Stat(dir)
+		switch errno {
+		case 0:
+			if !st.Mode.IsDir() {
+				return syscall.ENOTDIR
+			}
+			continue
+		case syscall.ENOENT:
+		default:
+			return errno
+		}
+
+		if errno = fsys.Mkdir(dir, perm); errno == syscall.EEXIST {
+			// Lost a race with another writer, which is fine if it made a
+			// directory.
+			if st, errno = fsys.Stat(dir); errno == 0 && !st.Mode.IsDir() {
+				errno = syscall.ENOTDIR
+			}
+		}
+		if errno != 0 {
+			return errno
+		}
+	}
+	return 0
+}
+
+// RemoveAll is like os.RemoveAll, except the path is relative to the file
+// system, and syscall.Errno are returned instead of an os.PathError. A zero
+// syscall.Errno is success, including when `path` doesn't exist.
+//
+// Symbolic links are removed, not the files or directories they point to.
+func RemoveAll(fsys FS, path string) syscall.Errno {
+	if errno := removeAll(fsys, path); errno != syscall.ENOENT {
+		return errno
+	}
+	return 0
+}
+
+// SymlinkRel is like FS.Symlink, except the link stores the path of `target`
+// relative to the directory of `linkPath`, as computed by RelPath. Unlike an
+// absolute target, this remains valid if the tree is moved. A zero
+// syscall.Errno is success.
+//
+// Both paths are relative to the root of the file system, and a leading "/"
+// is ignored.
+func SymlinkRel(fsys FS, target, linkPath string) syscall.Errno {
+	target, linkPath = strings.TrimPrefix(target, "/"), strings.TrimPrefix(linkPath, "/")
+	rel, errno := RelPath(path.Dir(linkPath), target)
+	if errno != 0 {
+		return errno
+	}
+	return fsys.Symlink(rel, linkPath)
+}
+
+// ReadFile is like os.ReadFile, except the path is relative to the file
+// system, and syscall.Errno are returned instead of an os.PathError. A zero
+// syscall.Errno is success.
+func ReadFile(fsys FS, path string) ([]byte, syscall.Errno) {
+	f, errno := fsys.OpenFile(path, os.O_RDONLY, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	defer f.Close()
+
+	return readAll(f, -1)
+}
+
+// ReadFileLimit is like ReadFile, except it fails with syscall.EFBIG instead
+// of reading more than `max` bytes. This bounds memory used to read files
+// supplied by untrusted callers. A zero syscall.Errno is success.
+//
+// The size of the file is checked before reading, and again while reading,
+// as it may have grown or its size may not be reported accurately.
+func ReadFileLimit(fsys FS, path string, max int64) ([]byte, syscall.Errno) {
+	f, errno := fsys.OpenFile(path, os.O_RDONLY, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	defer f.Close()
+
+	return readAll(f, max)
+}
+
+// readAll reads `f` until io.EOF, using its size as a hint. If `max` isn't
+// negative, this fails with syscall.EFBIG when `f` is larger.
+func readAll(f fs.File, max int64) ([]byte, syscall.Errno) {
+	// Pre-size the buffer, though the file may grow or shrink meanwhile.
+	var size int64
+	if st, errno := platform.StatFile(f); errno == 0 {
+		size = st.Size
+	}
+	if max >= 0 && size > max {
+		return nil, syscall.EFBIG
+	}
+	buf := make([]byte, 0, size+1) // +1 to read io.EOF without growing.
+	for {
+		if len(buf) == cap(buf) {
+			buf = append(buf, 0)[:len(buf)]
+		}
+		b := buf[len(buf):cap(buf)]
+		if limit := max + 1 - int64(len(buf)); max >= 0 && int64(len(b)) > limit {
+			b = b[:limit] // enough to tell if the file is larger than max.
+		}
+		n, err := f.Read(b)
+		buf = buf[:len(buf)+n]
+		if max >= 0 && int64(len(buf)) > max {
+			return nil, syscall.EFBIG
+		} else if err == io.EOF {
+			return buf, 0
+		} else if err != nil {
+			return nil, platform.UnwrapOSError(err)
+		}
+	}
+}
+
+// CreateExclusive is like WriteFile, except it fails with syscall.EEXIST if
+// the file already exists. This supports lock files, as only one caller can
+// create the file. A zero syscall.Errno is success.
+//
+// If writing `data` fails, the file is removed, so that a later call can
+// create it again.
+func CreateExclusive(fsys FS, path string, data []byte, perm fs.FileMode) syscall.Errno {
+	f, errno := fsys.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if errno != 0 {
+		return errno
+	}
+
+	if w, ok := f.(io.Writer); !ok {
+		errno = syscall.EBADF
+	} else if _, err := w.Write(data); err != nil {
+		errno = platform.UnwrapOSError(err)
+	}
+	if e := platform.UnwrapOSError(f.Close()); errno == 0 {
+		errno = e
+	}
+
+	if errno != 0 {
+		_ = fsys.Unlink(path) // we created it, so clean it up.
+	}
+	return errno
+}
+
+// Offset returns the current offset of the file, without changing it. A zero
+// syscall.Errno is success.
+//
+// This is the same as Seek(0, io.SeekCurrent), except a file which doesn't
+// implement io.Seeker, such as a pipe, fails with syscall.ESPIPE.
+func Offset(f fs.File) (int64, syscall.Errno) {
+	s, ok := f.(io.Seeker)
+	if !ok {
+		return 0, syscall.ESPIPE
+	}
+	offset, err := s.Seek(0, io.SeekCurrent)
+	return offset, platform.UnwrapOSError(err)
+}
+
+// Rewind seeks the file to offset zero, so that it can be read again without
+// reopening it. For a directory, this restarts reading its entries. A zero
+// syscall.Errno is success.
+//
+// This is only valid for seekable files: others, such as pipes, fail with
+// syscall.ESPIPE.
+func Rewind(f fs.File) syscall.Errno {
+	s, ok := f.(io.Seeker)
+	if !ok {
+		return syscall.ESPIPE
+	}
+	_, err := s.Seek(0, io.SeekStart)
+	return platform.UnwrapOSError(err)
+}
+
+// ChmodByHandle is like FS.Chmod, except it opens the file once, then changes
+// the mode of the opened file. This prevents the change applying to a
+// different file, if the path is renamed or replaced by a link concurrently.
+//
+// This falls back to FS.Chmod when the file can't be opened, isn't a regular
+// file or directory, or doesn't support CapabilityChmod. A zero
+// syscall.Errno is success.
+func ChmodByHandle(fsys FS, path string, perm fs.FileMode) syscall.Errno {
+	f := openForHandle(fsys, path, CapabilityChmod)
+	if f == nil {
+		return fsys.Chmod(path, perm)
+	}
+	defer f.Close()
+	return platform.UnwrapOSError(f.(interface{ Chmod(fs.FileMode) error }).Chmod(perm))
+}
+
+// UtimensByHandle is like ChmodByHandle, except it changes the times of the
+// file, like FS.Utimens following symbolic links.
+//
+// Note: This also falls back to FS.Utimens on syscall.EPERM, as Windows can't
+// change the times of a file opened read-only.
+func UtimensByHandle(fsys FS, path string, times *[2]syscall.Timespec) syscall.Errno {
+	f := openForHandle(fsys, path, CapabilityChtimes)
+	if f == nil {
+		return fsys.Utimens(path, times, true)
+	}
+	errno := platform.UtimensFile(f, times)
+	_ = f.Close()
+	if errno == syscall.EPERM {
+		return fsys.Utimens(path, times, true)
+	}
+	return errno
+}
+
+// openForHandle opens the regular file or directory at `path` to change its
+// attributes, or returns nil if it can't, or it doesn't support `c`.
+func openForHandle(fsys FS, path string, c Capability) fs.File {
+	// Only open regular files and directories, as opening others, such as a
+	// named pipe, can block or have side effects.
+	if st, errno := fsys.Stat(path); errno != 0 || (!st.Mode.IsRegular() && !st.Mode.IsDir()) {
+		return nil
+	}
+	f, errno := fsys.OpenFile(path, os.O_RDONLY, 0)
+	if errno != 0 {
+		return nil
+	} else if !Supports(f, c) {
+		_ = f.Close()
+		return nil
+	}
+	return f
+}
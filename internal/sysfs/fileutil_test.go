@@ -0,0 +1,451 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := NewDirFS(tmpDir)
+
+	// Creates a file that doesn't exist.
+	require.Zero(t, WriteFile(testFS, "file", []byte("wazero"), 0o600))
+	b, err := os.ReadFile(path.Join(tmpDir, "file"))
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(b))
+	requireMode(t, testFS, "file", 0o600)
+
+	// Writing shorter data over a longer file leaves no stale bytes.
+	require.Zero(t, WriteFile(testFS, "file", []byte("wa"), 0o644))
+	b, err = os.ReadFile(path.Join(tmpDir, "file"))
+	require.NoError(t, err)
+	require.Equal(t, "wa", string(b))
+	requireMode(t, testFS, "file", 0o600) // unchanged
+}
+
+func TestWriteFile_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	tests := []struct {
+		name          string
+		fs            FS
+		path          string
+		expectedErrno syscall.Errno
+	}{
+		{name: "read-only", fs: NewReadFS(NewDirFS(tmpDir)), path: "animals.txt", expectedErrno: syscall.ENOSYS},
+		{name: "directory", fs: NewDirFS(tmpDir), path: "sub", expectedErrno: syscall.EISDIR},
+		{name: "missing parent", fs: NewDirFS(tmpDir), path: "missing/file", expectedErrno: syscall.ENOENT},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.EqualErrno(t, tc.expectedErrno, WriteFile(tc.fs, tc.path, []byte("wazero"), 0o600))
+		})
+	}
+}
+
+func TestMkdirAll(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := NewDirFS(tmpDir)
+
+	t.Run("deep", func(t *testing.T) {
+		require.Zero(t, MkdirAll(testFS, "a/b/c", 0o700))
+		st, err := os.Stat(path.Join(tmpDir, "a", "b", "c"))
+		require.NoError(t, err)
+		require.True(t, st.IsDir())
+
+		// Existing directories are success.
+		require.Zero(t, MkdirAll(testFS, "a/b/c/", 0o700))
+	})
+
+	t.Run("partially exists", func(t *testing.T) {
+		require.NoError(t, os.Mkdir(path.Join(tmpDir, "partial"), 0o700))
+		require.Zero(t, MkdirAll(testFS, "partial//d/e", 0o700))
+		_, err := os.Stat(path.Join(tmpDir, "partial", "d", "e"))
+		require.NoError(t, err)
+	})
+
+	t.Run("file blocks a component", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), nil, 0o600))
+		require.EqualErrno(t, syscall.ENOTDIR, MkdirAll(testFS, "file/d", 0o700))
+		require.EqualErrno(t, syscall.ENOTDIR, MkdirAll(testFS, "file", 0o700))
+	})
+
+	if runtime.GOOS != "windows" {
+		t.Run("symlink loop", func(t *testing.T) {
+			require.NoError(t, os.Symlink("loop", path.Join(tmpDir, "loop")))
+			require.EqualErrno(t, syscall.ELOOP, MkdirAll(testFS, "loop/d", 0o700))
+		})
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(tmpDir, "tree", "sub"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "tree", "sub", "file"), nil, 0o600))
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "sibling"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "sibling", "keep"), []byte("wazero"), 0o600))
+
+	// Symbolic links to the sibling, which must not be followed.
+	require.NoError(t, os.Symlink("../sibling", path.Join(tmpDir, "tree", "link")))
+	require.NoError(t, os.Symlink("../../sibling/keep", path.Join(tmpDir, "tree", "sub", "link")))
+	require.NoError(t, os.Symlink("sibling", path.Join(tmpDir, "dirlink")))
+
+	testFS := NewDirFS(tmpDir)
+
+	require.Zero(t, RemoveAll(testFS, "tree"))
+	_, err := os.Lstat(path.Join(tmpDir, "tree"))
+	require.True(t, os.IsNotExist(err))
+
+	// A link to a directory is removed, not the directory.
+	require.Zero(t, RemoveAll(testFS, "dirlink"))
+	_, err = os.Lstat(path.Join(tmpDir, "dirlink"))
+	require.True(t, os.IsNotExist(err))
+
+	b, err := os.ReadFile(path.Join(tmpDir, "sibling", "keep"))
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(b))
+
+	// Like os.RemoveAll, a missing path is success.
+	require.Zero(t, RemoveAll(testFS, "tree"))
+}
+
+func TestSymlinkRel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+
+	tmpDir := t.TempDir()
+	treeDir := path.Join(tmpDir, "tree")
+	require.NoError(t, os.MkdirAll(path.Join(treeDir, "a", "b"), 0o700))
+	require.NoError(t, os.MkdirAll(path.Join(treeDir, "c"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(treeDir, "c", "file"), []byte("wazero"), 0o600))
+	testFS := NewDirFS(treeDir)
+
+	require.Zero(t, SymlinkRel(testFS, "c/file", "a/b/link"))
+	require.Zero(t, SymlinkRel(testFS, "/c", "/dirlink"))
+
+	target, errno := testFS.Readlink("a/b/link")
+	require.Zero(t, errno)
+	require.Equal(t, "../../c/file", target)
+	target, errno = testFS.Readlink("dirlink")
+	require.Zero(t, errno)
+	require.Equal(t, "c", target)
+
+	realPath, errno := RealPath(testFS, "a/b/link")
+	require.Zero(t, errno)
+	require.Equal(t, "/c/file", realPath)
+
+	// The links still resolve after the tree moves.
+	movedDir := path.Join(tmpDir, "moved")
+	require.NoError(t, os.Rename(treeDir, movedDir))
+	b, err := os.ReadFile(path.Join(movedDir, "a", "b", "link"))
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(b))
+	_, err = os.Stat(path.Join(movedDir, "dirlink", "file"))
+	require.NoError(t, err)
+}
+
+func TestReadFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "empty"), nil, 0o600))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+	testFS := NewDirFS(tmpDir)
+
+	t.Run("empty", func(t *testing.T) {
+		b, errno := ReadFile(testFS, "empty")
+		require.Zero(t, errno)
+		require.Equal(t, 0, len(b))
+	})
+
+	t.Run("file", func(t *testing.T) {
+		b, errno := ReadFile(testFS, "file")
+		require.Zero(t, errno)
+		require.Equal(t, "wazero", string(b))
+	})
+
+	t.Run("larger than stat size", func(t *testing.T) {
+		b, errno := ReadFile(&staleSizeFS{testFS}, "file")
+		require.Zero(t, errno)
+		require.Equal(t, "wazero", string(b))
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		_, errno := ReadFile(testFS, "missing")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		_, errno := ReadFile(testFS, ".")
+		require.EqualErrno(t, syscall.EISDIR, errno)
+	})
+}
+
+func TestReadFileLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+	testFS := NewDirFS(tmpDir)
+
+	tests := []struct {
+		name          string
+		fs            FS
+		max           int64
+		expectedErrno syscall.Errno
+	}{
+		{name: "under limit", fs: testFS, max: 7},
+		{name: "at limit", fs: testFS, max: 6},
+		{name: "over limit", fs: testFS, max: 5, expectedErrno: syscall.EFBIG},
+		{name: "zero limit", fs: testFS, max: 0, expectedErrno: syscall.EFBIG},
+		{name: "stale size at limit", fs: &staleSizeFS{testFS}, max: 6},
+		{name: "stale size over limit", fs: &staleSizeFS{testFS}, max: 5, expectedErrno: syscall.EFBIG},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			b, errno := ReadFileLimit(tc.fs, "file", tc.max)
+			require.EqualErrno(t, tc.expectedErrno, errno)
+			if errno == 0 {
+				require.Equal(t, "wazero", string(b))
+			}
+		})
+	}
+}
+
+// staleSizeFS opens files whose Stat reports a size of one, as if the file
+// grew after it was stat.
+type staleSizeFS struct{ FS }
+
+func (s *staleSizeFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	f, errno := s.FS.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &staleSizeFile{f}, 0
+}
+
+type staleSizeFile struct{ fs.File }
+
+func (f *staleSizeFile) Stat() (fs.FileInfo, error) {
+	return staleSizeFileInfo{}, nil
+}
+
+type staleSizeFileInfo struct{}
+
+func (staleSizeFileInfo) Name() string       { return "file" }
+func (staleSizeFileInfo) Size() int64        { return 1 }
+func (staleSizeFileInfo) Mode() fs.FileMode  { return 0o600 }
+func (staleSizeFileInfo) ModTime() time.Time { return time.Time{} }
+func (staleSizeFileInfo) IsDir() bool        { return false }
+func (staleSizeFileInfo) Sys() interface{}   { return nil }
+
+func TestCreateExclusive(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := NewDirFS(tmpDir)
+
+	t.Run("creates", func(t *testing.T) {
+		require.Zero(t, CreateExclusive(testFS, "lock", []byte("wazero"), 0o600))
+		b, err := os.ReadFile(path.Join(tmpDir, "lock"))
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+		requireMode(t, testFS, "lock", 0o600)
+	})
+
+	t.Run("already exists", func(t *testing.T) {
+		require.EqualErrno(t, syscall.EEXIST, CreateExclusive(testFS, "lock", []byte("wa"), 0o600))
+
+		// The existing file is unchanged.
+		b, err := os.ReadFile(path.Join(tmpDir, "lock"))
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+	})
+
+	t.Run("removed when write fails", func(t *testing.T) {
+		faultFS := FaultFS(testFS, failNth("Write", 1, syscall.ENOSPC))
+		require.EqualErrno(t, syscall.ENOSPC, CreateExclusive(faultFS, "failed", []byte("wazero"), 0o600))
+
+		_, err := os.Stat(path.Join(tmpDir, "failed"))
+		require.ErrorIs(t, err, fs.ErrNotExist)
+
+		// The file can be created again.
+		require.Zero(t, CreateExclusive(testFS, "failed", []byte("wazero"), 0o600))
+	})
+}
+
+func TestOffset(t *testing.T) {
+	testFS := NewDirFS(t.TempDir())
+
+	f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.Zero(t, errno)
+	defer f.Close()
+
+	requireOffset := func(expected int64) {
+		offset, errno := Offset(f)
+		require.Zero(t, errno)
+		require.Equal(t, expected, offset)
+	}
+
+	requireOffset(0)
+
+	_, err := f.(io.Writer).Write([]byte("wazero"))
+	require.NoError(t, err)
+	requireOffset(6)
+
+	_, err = f.(io.Seeker).Seek(1, io.SeekStart)
+	require.NoError(t, err)
+	requireOffset(1)
+
+	_, err = f.Read(make([]byte, 3))
+	require.NoError(t, err)
+	requireOffset(4)
+
+	// Positional I/O doesn't change the offset.
+	_, err = f.(io.ReaderAt).ReadAt(make([]byte, 2), 0)
+	require.NoError(t, err)
+	_, err = f.(io.WriterAt).WriteAt([]byte("!"), 6)
+	require.NoError(t, err)
+	requireOffset(4)
+
+	// Reading to the end
+	_, err = io.ReadAll(f)
+	require.NoError(t, err)
+	requireOffset(7)
+}
+
+func TestOffset_notSeekable(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	// A file that doesn't implement io.Seeker
+	_, errno := Offset(struct{ fs.File }{r})
+	require.EqualErrno(t, syscall.ESPIPE, errno)
+
+	if runtime.GOOS != "windows" { // windows returns a different error.
+		// A pipe implements io.Seeker, but fails to seek.
+		_, errno = Offset(r)
+		require.EqualErrno(t, syscall.ESPIPE, errno)
+	}
+}
+
+func TestRewind(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+	testFS := NewDirFS(tmpDir)
+
+	t.Run("file", func(t *testing.T) {
+		f, errno := testFS.OpenFile("animals.txt", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		first, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.NotEqual(t, 0, len(first))
+
+		require.Zero(t, Rewind(f))
+		second, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.Equal(t, first, second)
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		f, errno := testFS.OpenFile("sub", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		first, errno := platform.Readdir(f, -1)
+		require.Zero(t, errno)
+		require.Equal(t, 1, len(first))
+
+		// Without rewinding, there are no more entries.
+		next, errno := platform.Readdir(f, -1)
+		require.Zero(t, errno)
+		require.Equal(t, 0, len(next))
+
+		require.Zero(t, Rewind(f))
+		second, errno := platform.Readdir(f, -1)
+		require.Zero(t, errno)
+		require.Equal(t, first, second)
+	})
+
+	t.Run("not seekable", func(t *testing.T) {
+		f, errno := testFS.OpenFile("animals.txt", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		require.EqualErrno(t, syscall.ESPIPE, Rewind(struct{ fs.File }{f}))
+	})
+}
+
+func TestChmodByHandle(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows only supports the write bit")
+	}
+
+	tmpDir := t.TempDir()
+	file, moved := path.Join(tmpDir, "file"), path.Join(tmpDir, "moved")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+
+	requireMode := func(name string, expected fs.FileMode) {
+		st, err := os.Stat(name)
+		require.NoError(t, err)
+		require.Equal(t, expected, st.Mode().Perm())
+	}
+
+	t.Run("file", func(t *testing.T) {
+		require.Zero(t, ChmodByHandle(NewDirFS(tmpDir), "file", 0o640))
+		requireMode(file, 0o640)
+	})
+
+	t.Run("concurrent rename", func(t *testing.T) {
+		// Replace the file after it was opened, but before its mode changes.
+		testFS := FaultFS(NewDirFS(tmpDir), func(op, _ string) error {
+			if op == "File.Chmod" {
+				require.NoError(t, os.Rename(file, moved))
+				require.NoError(t, os.WriteFile(file, nil, 0o600))
+			}
+			return nil
+		})
+		require.Zero(t, ChmodByHandle(testFS, "file", 0o400))
+
+		// The file opened changed, not the one which replaced it.
+		requireMode(moved, 0o400)
+		requireMode(file, 0o600)
+	})
+
+	t.Run("falls back to path", func(t *testing.T) {
+		testFS := FixtureFS(map[string][]byte{"file": nil})
+		require.EqualErrno(t, syscall.ENOSYS, ChmodByHandle(testFS, "file", 0o400))
+		require.EqualErrno(t, syscall.ENOENT, ChmodByHandle(NewDirFS(tmpDir), "missing", 0o400))
+	})
+}
+
+func TestUtimensByHandle(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), nil, 0o600))
+
+	times := [2]syscall.Timespec{
+		syscall.NsecToTimespec(1234567890 * 1e9),
+		syscall.NsecToTimespec(1234567890 * 1e9),
+	}
+	require.Zero(t, UtimensByHandle(NewDirFS(tmpDir), "file", &times))
+
+	st, err := os.Stat(path.Join(tmpDir, "file"))
+	require.NoError(t, err)
+	require.Equal(t, int64(1234567890), st.ModTime().Unix())
+}
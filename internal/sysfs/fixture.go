@@ -0,0 +1,371 @@
+package sysfs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// FixtureFS returns a minimal writable filesystem for test fixtures, whose
+// initial files are `files`, keyed by slash-separated path. Unlike
+// fstest.MapFS, files can be created, written, truncated and unlinked.
+//
+// Directories are implied by the names of the files in them, so an empty
+// directory can't exist. Other operations, such as Mkdir, Symlink or Mknod,
+// return syscall.ENOSYS.
+func FixtureFS(files map[string][]byte) FS {
+	ret := &fixtureFS{files: make(map[string]*fixtureData, len(files))}
+	for name, data := range files {
+		ret.files[procPath(name)] = &fixtureData{data: append([]byte{}, data...)}
+	}
+	return ret
+}
+
+type fixtureFS struct {
+	// UnimplementedFS is embedded for the operations that aren't supported.
+	UnimplementedFS
+
+	// mux guards files and their data, as open files share them.
+	mux sync.Mutex
+	// files are the data of each file, keyed by the cleaned path. The data is
+	// a pointer, so that files stay usable after they are unlinked.
+	files map[string]*fixtureData
+}
+
+type fixtureData struct {
+	data []byte
+}
+
+// String implements fmt.Stringer
+func (f *fixtureFS) String() string {
+	return "fixture"
+}
+
+// Open implements the same method as documented on fs.FS
+func (f *fixtureFS) Open(name string) (fs.File, error) {
+	return fsOpen(f, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (f *fixtureFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	path = procPath(path)
+	if names := f.dirNames(path); names != nil {
+		if flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+			return nil, syscall.EISDIR
+		} else if flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+			return nil, syscall.EEXIST
+		}
+		return &fixtureDir{fsys: f, path: path, names: names}, 0
+	} else if flag&platform.O_DIRECTORY != 0 {
+		if _, ok := f.files[path]; ok {
+			return nil, syscall.ENOTDIR
+		}
+		return nil, syscall.ENOENT
+	}
+
+	d, ok := f.files[path]
+	switch {
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, syscall.ENOENT
+	case !ok:
+		if dir := pathDir(path); f.dirNames(dir) == nil {
+			return nil, syscall.ENOENT // the parent must already exist.
+		}
+		d = &fixtureData{}
+		f.files[path] = d
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, syscall.EEXIST
+	}
+
+	ret := &fixtureFile{fsys: f, path: path, d: d, flag: flag}
+	if flag&os.O_TRUNC != 0 && ret.writable() {
+		d.data = d.data[:0]
+	}
+	return ret, 0
+}
+
+// dirNames returns the sorted names in the directory `dir`, or nil if it
+// isn't a directory. The caller must hold the lock.
+func (f *fixtureFS) dirNames(dir string) []string {
+	prefix := dir + "/"
+	names := []string{} // non-nil, as the root always exists.
+	for name := range f.files {
+		if dir != "." {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			name = name[len(prefix):]
+		}
+		child, _, _ := strings.Cut(name, "/")
+		names = appendUnique(names, child)
+	}
+	if len(names) == 0 && dir != "." {
+		return nil
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pathDir is path.Dir, for use where the parameter `path` shadows it.
+func pathDir(name string) string {
+	return path.Dir(name)
+}
+
+// Lstat implements FS.Lstat
+func (f *fixtureFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	return f.Stat(path) // there are no symbolic links.
+}
+
+// Stat implements FS.Stat
+func (f *fixtureFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return f.stat(procPath(path))
+}
+
+// stat returns the status of the cleaned path. The caller must hold the lock.
+func (f *fixtureFS) stat(path string) (platform.Stat_t, syscall.Errno) {
+	if d, ok := f.files[path]; ok {
+		return platform.Stat_t{Mode: 0o666, Nlink: 1, Size: int64(len(d.data))}, 0
+	} else if f.dirNames(path) != nil {
+		return platform.Stat_t{Mode: fs.ModeDir | 0o777, Nlink: 1}, 0
+	}
+	return platform.Stat_t{}, syscall.ENOENT
+}
+
+// Truncate implements FS.Truncate
+func (f *fixtureFS) Truncate(path string, size int64) syscall.Errno {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	path = procPath(path)
+	if d, ok := f.files[path]; ok {
+		return d.truncate(size)
+	} else if f.dirNames(path) != nil {
+		return syscall.EISDIR
+	}
+	return syscall.ENOENT
+}
+
+// Unlink implements FS.Unlink
+func (f *fixtureFS) Unlink(path string) syscall.Errno {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	path = procPath(path)
+	if _, ok := f.files[path]; ok {
+		delete(f.files, path)
+		return 0
+	} else if f.dirNames(path) != nil {
+		return syscall.EISDIR
+	}
+	return syscall.ENOENT
+}
+
+// truncate resizes the data, zero-filling any growth. The caller must hold
+// the lock.
+func (d *fixtureData) truncate(size int64) syscall.Errno {
+	if size < 0 {
+		return syscall.EINVAL
+	} else if size <= int64(len(d.data)) {
+		d.data = d.data[:size]
+	} else {
+		d.data = append(d.data, make([]byte, size-int64(len(d.data)))...)
+	}
+	return 0
+}
+
+// fixtureFile is a file opened by fixtureFS. Writes are visible to other
+// files open on the same path.
+type fixtureFile struct {
+	fsys   *fixtureFS
+	path   string
+	d      *fixtureData
+	flag   int
+	offset int64
+}
+
+// GoString implements fmt.GoStringer
+func (f *fixtureFile) GoString() string {
+	return fmt.Sprintf("&sysfs.fixtureFile{path: %q}", f.path)
+}
+
+func (f *fixtureFile) readable() bool {
+	return f.flag&os.O_WRONLY == 0
+}
+
+func (f *fixtureFile) writable() bool {
+	return f.flag&(os.O_WRONLY|os.O_RDWR) != 0
+}
+
+// Stat implements fs.File
+func (f *fixtureFile) Stat() (fs.FileInfo, error) {
+	f.fsys.mux.Lock()
+	defer f.fsys.mux.Unlock()
+
+	return &dirInfo{name: path.Base(f.path), stat: platform.Stat_t{Mode: 0o666, Nlink: 1, Size: int64(len(f.d.data))}}, nil
+}
+
+// Read implements io.Reader
+func (f *fixtureFile) Read(p []byte) (n int, err error) {
+	n, err = f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil // unlike io.ReaderAt, EOF is only returned when nothing was read.
+	}
+	return
+}
+
+// ReadAt implements io.ReaderAt
+func (f *fixtureFile) ReadAt(p []byte, off int64) (int, error) {
+	if !f.readable() {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	}
+
+	f.fsys.mux.Lock()
+	defer f.fsys.mux.Unlock()
+
+	if off >= int64(len(f.d.data)) {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	if n := copy(p, f.d.data[off:]); n < len(p) {
+		return n, io.EOF
+	} else {
+		return n, nil
+	}
+}
+
+// Write implements io.Writer
+func (f *fixtureFile) Write(p []byte) (n int, err error) {
+	if f.flag&os.O_APPEND != 0 {
+		f.fsys.mux.Lock()
+		f.offset = int64(len(f.d.data))
+		f.fsys.mux.Unlock()
+	}
+	n, err = f.WriteAt(p, f.offset)
+	f.offset += int64(n)
+	return
+}
+
+// WriteAt implements io.WriterAt
+func (f *fixtureFile) WriteAt(p []byte, off int64) (int, error) {
+	if !f.writable() {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	}
+
+	f.fsys.mux.Lock()
+	defer f.fsys.mux.Unlock()
+
+	if end := off + int64(len(p)); end > int64(len(f.d.data)) {
+		_ = f.d.truncate(end) // zero-fills any gap before the offset.
+	}
+	return copy(f.d.data[off:], p), nil
+}
+
+// Seek implements io.Seeker
+func (f *fixtureFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		f.fsys.mux.Lock()
+		offset += int64(len(f.d.data))
+		f.fsys.mux.Unlock()
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Truncate implements the same method as documented on os.File
+func (f *fixtureFile) Truncate(size int64) error {
+	if !f.writable() {
+		return syscall.EBADF
+	}
+
+	f.fsys.mux.Lock()
+	defer f.fsys.mux.Unlock()
+
+	if errno := f.d.truncate(size); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Close implements fs.File
+func (f *fixtureFile) Close() error { return nil }
+
+// fixtureDir lists the names in a directory of fixtureFS, as of when it was
+// opened.
+type fixtureDir struct {
+	fsys  *fixtureFS
+	path  string
+	names []string
+}
+
+// GoString implements fmt.GoStringer
+func (d *fixtureDir) GoString() string {
+	return fmt.Sprintf("&sysfs.fixtureDir{path: %q}", d.path)
+}
+
+// Stat implements fs.File
+func (d *fixtureDir) Stat() (fs.FileInfo, error) {
+	return &dirInfo{name: path.Base(d.path), stat: platform.Stat_t{Mode: fs.ModeDir | 0o777, Nlink: 1}}, nil
+}
+
+// Read implements fs.File
+func (d *fixtureDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: syscall.EISDIR}
+}
+
+// Close implements fs.File
+func (d *fixtureDir) Close() error { return nil }
+
+// ReadDir implements fs.ReadDirFile
+func (d *fixtureDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	d.fsys.mux.Lock()
+	defer d.fsys.mux.Unlock()
+
+	var list []fs.DirEntry
+	for len(d.names) > 0 && (count <= 0 || len(list) < count) {
+		name := d.names[0]
+		d.names = d.names[1:]
+
+		st, errno := d.fsys.stat(path.Join(d.path, name))
+		if errno == syscall.ENOENT {
+			continue // unlinked since the directory was opened.
+		} else if errno != 0 {
+			return list, errno
+		}
+		list = append(list, &dirInfo{name: name, stat: st})
+	}
+
+	if len(list) == 0 && count > 0 {
+		return nil, io.EOF
+	}
+	return list, nil
+}
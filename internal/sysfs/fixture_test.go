@@ -0,0 +1,130 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	gofstest "testing/fstest"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestFixtureFS(t *testing.T) {
+	fixture := map[string][]byte{"a.txt": []byte("wazero"), "sub/b.txt": nil}
+	testFS := FixtureFS(fixture)
+	stdFS := StdFS(testFS)
+
+	require.NoError(t, gofstest.TestFS(stdFS, "a.txt", "sub/b.txt"))
+
+	t.Run("copies the fixture", func(t *testing.T) {
+		require.Zero(t, testFS.Truncate("a.txt", 2))
+		require.Equal(t, "wazero", string(fixture["a.txt"]))
+		require.Zero(t, testFS.Truncate("a.txt", 6))
+	})
+
+	t.Run("read", func(t *testing.T) {
+		b, err := fs.ReadFile(stdFS, "a.txt")
+		require.NoError(t, err)
+		require.Equal(t, "wa\x00\x00\x00\x00", string(b)) // truncated above
+	})
+
+	t.Run("create and write", func(t *testing.T) {
+		f, errno := testFS.OpenFile("sub/c.txt", os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o600)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		_, err := f.(io.Writer).Write([]byte("wazero"))
+		require.NoError(t, err)
+		_, err = f.(io.WriterAt).WriteAt([]byte("!"), 8) // past EOF
+		require.NoError(t, err)
+
+		st, errno := testFS.Stat("sub/c.txt")
+		require.Zero(t, errno)
+		require.Equal(t, int64(9), st.Size)
+
+		b, err := fs.ReadFile(stdFS, "sub/c.txt")
+		require.NoError(t, err)
+		require.Equal(t, "wazero\x00\x00!", string(b))
+
+		_, errno = testFS.OpenFile("sub/c.txt", os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o600)
+		require.EqualErrno(t, syscall.EEXIST, errno)
+		_, errno = testFS.OpenFile("missing/c.txt", os.O_CREATE|os.O_RDWR, 0o600)
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("append and truncate", func(t *testing.T) {
+		f, errno := testFS.OpenFile("sub/b.txt", os.O_APPEND|os.O_WRONLY, 0)
+		require.Zero(t, errno)
+		_, err := f.(io.Writer).Write([]byte("wa"))
+		require.NoError(t, err)
+		_, err = f.(io.Writer).Write([]byte("zero"))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		b, err := fs.ReadFile(stdFS, "sub/b.txt")
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+
+		f, errno = testFS.OpenFile("sub/b.txt", os.O_TRUNC|os.O_WRONLY, 0)
+		require.Zero(t, errno)
+		require.NoError(t, f.Close())
+
+		st, errno := testFS.Stat("sub/b.txt")
+		require.Zero(t, errno)
+		require.Zero(t, st.Size)
+	})
+
+	t.Run("access mode", func(t *testing.T) {
+		f, errno := testFS.OpenFile("a.txt", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		_, err := f.(io.Writer).Write([]byte("wazero"))
+		require.EqualErrno(t, syscall.EBADF, platform.UnwrapOSError(err))
+
+		_, errno = testFS.OpenFile("sub", os.O_RDWR, 0)
+		require.EqualErrno(t, syscall.EISDIR, errno)
+		_, errno = testFS.OpenFile("a.txt", os.O_RDONLY|platform.O_DIRECTORY, 0)
+		require.EqualErrno(t, syscall.ENOTDIR, errno)
+	})
+
+	t.Run("unlink", func(t *testing.T) {
+		f, errno := testFS.OpenFile("sub/c.txt", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		require.Zero(t, testFS.Unlink("sub/c.txt"))
+		_, errno = testFS.Stat("sub/c.txt")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+		require.EqualErrno(t, syscall.ENOENT, testFS.Unlink("sub/c.txt"))
+		require.EqualErrno(t, syscall.EISDIR, testFS.Unlink("sub"))
+
+		// The open file is still readable.
+		b, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.Equal(t, "wazero\x00\x00!", string(b))
+	})
+
+	t.Run("directory implied by files", func(t *testing.T) {
+		require.Zero(t, testFS.Unlink("sub/b.txt"))
+		_, errno := testFS.Stat("sub")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+
+		dirents, err := fs.ReadDir(stdFS, ".")
+		require.NoError(t, err)
+		require.Equal(t, 1, len(dirents))
+		require.Equal(t, "a.txt", dirents[0].Name())
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		require.EqualErrno(t, syscall.ENOSYS, testFS.Symlink("a.txt", "link"))
+		require.EqualErrno(t, syscall.ENOSYS, testFS.Link("a.txt", "link"))
+		require.EqualErrno(t, syscall.ENOSYS, testFS.Mknod("dev", fs.ModeDevice|0o600, 0))
+		require.EqualErrno(t, syscall.ENOSYS, testFS.Mkdir("dir", 0o700))
+		_, errno := testFS.Readlink("a.txt")
+		require.EqualErrno(t, syscall.ENOSYS, errno)
+	})
+}
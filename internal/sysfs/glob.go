@@ -0,0 +1,99 @@
+package sysfs
+
+import (
+	"path"
+	"strings"
+)
+
+// globDepthLimit bounds recursion on patterns with many path separators,
+// the same as fs.Glob.
+const globDepthLimit = 10000
+
+// Glob is like fs.Glob, except it reads `fsys` directly. It returns the names
+// of all files matching `pattern`, in lexical order, or nil if none match.
+// The syntax of patterns is the same as path.Match, and the only possible
+// error is path.ErrBadPattern. Like fs.Glob, I/O errors are ignored.
+//
+// Symbolic links are matched, but not followed: a pattern such as "a/*/c"
+// doesn't match "a/link/c" when "a/link" is a symbolic link.
+func Glob(fsys FS, pattern string) ([]string, error) {
+	return globWithLimit(fsys, pattern, 0)
+}
+
+func globWithLimit(fsys FS, pattern string, depth int) (matches []string, err error) {
+	if depth > globDepthLimit {
+		return nil, path.ErrBadPattern
+	}
+
+	// Check the pattern is well-formed.
+	if _, err = path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if !hasMeta(pattern) {
+		if _, errno := fsys.Lstat(pattern); errno != 0 {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	dir, file := path.Split(pattern)
+	dir = cleanGlobPath(dir)
+	if !hasMeta(dir) {
+		return glob(fsys, dir, file, nil)
+	}
+
+	// Prevent infinite recursion.
+	if dir == pattern {
+		return nil, path.ErrBadPattern
+	}
+
+	var dirs []string
+	if dirs, err = globWithLimit(fsys, dir, depth+1); err != nil {
+		return nil, err
+	}
+	for _, d := range dirs {
+		if matches, err = glob(fsys, d, file, matches); err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// cleanGlobPath prepares `dir` for glob matching.
+func cleanGlobPath(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return dir[:len(dir)-1] // chop off trailing separator
+}
+
+// glob appends to `matches` the entries of `dir` matching `pattern`. `dir`
+// is skipped unless it is a directory, so that symbolic links aren't
+// followed.
+func glob(fsys FS, dir, pattern string, matches []string) ([]string, error) {
+	if st, errno := fsys.Lstat(dir); errno != 0 || !st.Mode.IsDir() {
+		return matches, nil
+	}
+
+	entries, errno := ReadDir(fsys, dir)
+	if errno != 0 {
+		return matches, nil
+	}
+	for _, e := range entries {
+		n := e.Name()
+		matched, err := path.Match(pattern, n)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			matches = append(matches, path.Join(dir, n))
+		}
+	}
+	return matches, nil
+}
+
+// hasMeta reports whether `p` contains any of the magic characters recognized
+// by path.Match.
+func hasMeta(p string) bool {
+	return strings.ContainsAny(p, `*?[\`)
+}
@@ -0,0 +1,55 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestGlob(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"a/b", "a/bb", "a/x", "outside/b"} {
+		require.NoError(t, os.MkdirAll(path.Join(tmpDir, dir), 0o700))
+	}
+	for _, file := range []string{"a/b/c.txt", "a/bb/c.txt", "a/x/d.txt", "a/e.txt", "outside/b/c.txt"} {
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, file), nil, 0o600))
+	}
+	// A link out of the "a" tree, which must not be followed.
+	require.NoError(t, os.Symlink("../outside/b", path.Join(tmpDir, "a", "link")))
+	testFS := NewDirFS(tmpDir)
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{pattern: "a/*/c.txt", expected: []string{"a/b/c.txt", "a/bb/c.txt"}},
+		{pattern: "a/?/*.txt", expected: []string{"a/b/c.txt", "a/x/d.txt"}},
+		{pattern: "a/[a-c]*/c.txt", expected: []string{"a/b/c.txt", "a/bb/c.txt"}},
+		{pattern: "a/*", expected: []string{"a/b", "a/bb", "a/e.txt", "a/link", "a/x"}},
+		{pattern: "*/*/c.txt", expected: []string{"a/b/c.txt", "a/bb/c.txt", "outside/b/c.txt"}},
+		{pattern: "a/e.txt", expected: []string{"a/e.txt"}},
+		{pattern: "a/missing", expected: nil},
+		{pattern: "a/*/missing", expected: nil},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.pattern, func(t *testing.T) {
+			matches, err := Glob(testFS, tc.pattern)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, matches)
+		})
+	}
+}
+
+func TestGlob_Errors(t *testing.T) {
+	testFS := NewDirFS(t.TempDir())
+
+	for _, pattern := range []string{"[", "a/[", "[/a", `a\`} {
+		_, err := Glob(testFS, pattern)
+		require.Equal(t, path.ErrBadPattern, err)
+	}
+}
@@ -0,0 +1,223 @@
+package sysfs
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// gzipSuffix is the suffix of the files GzipFS decompresses.
+const gzipSuffix = ".gz"
+
+// GzipFS returns a read-only view of `base`, where each regular file ending
+// in ".gz" is also visible decompressed, at the same path without the suffix.
+// For example, "lib.wasm.gz" can be read as "lib.wasm". Files that exist in
+// `base` take precedence.
+//
+// Decompressed files are only read sequentially: ReadAt and Seek return
+// syscall.ENOSYS. Their size is recorded in the gzip trailer, which is modulo
+// 4GiB, and inaccurate for files that concatenate multiple gzip streams.
+func GzipFS(base FS) FS {
+	return &gzipFS{readFS: readFS{fs: base}}
+}
+
+type gzipFS struct {
+	// readFS is embedded for its write methods, which return syscall.EROFS.
+	readFS
+}
+
+// String implements fmt.Stringer
+func (g *gzipFS) String() string {
+	return "gzip[" + g.fs.String() + "]"
+}
+
+// Open implements the same method as documented on fs.FS
+func (g *gzipFS) Open(name string) (fs.File, error) {
+	return fsOpen(g, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (g *gzipFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	f, errno := g.readFS.OpenFile(path, flag, perm)
+	switch errno {
+	case 0:
+		if st, errno := platform.StatFile(f); errno == 0 && st.Mode.IsDir() {
+			return g.openDir(f, path)
+		}
+		return f, 0
+	case syscall.ENOENT:
+		if flag&platform.O_DIRECTORY != 0 {
+			return nil, errno
+		}
+		return g.openDecompressed(path)
+	default:
+		return nil, errno
+	}
+}
+
+// openDecompressed opens the compressed file `name` + ".gz" for reading,
+// decompressed.
+func (g *gzipFS) openDecompressed(name string) (fs.File, syscall.Errno) {
+	st, errno := g.statDecompressed(name)
+	if errno != 0 {
+		return nil, errno
+	}
+	f, errno := g.fs.OpenFile(name+gzipSuffix, os.O_RDONLY, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, gzipErrno(err)
+	}
+	return &gzipFile{f: f, zr: zr, info: dirInfo{name: path.Base(name), stat: st}}, 0
+}
+
+// openDir lists the directory `dir`, opened as `f`, adding the names of
+// decompressed files.
+func (g *gzipFS) openDir(f fs.File, dir string) (fs.File, syscall.Errno) {
+	rd, ok := f.(fs.ReadDirFile)
+	if !ok {
+		return f, 0
+	}
+	entries, err := rd.ReadDir(-1)
+	if errno := platform.UnwrapOSError(err); errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	}
+
+	names := make(map[string]struct{}, len(entries))
+	for _, e := range entries {
+		names[e.Name()] = struct{}{}
+	}
+	dirents := append([]fs.DirEntry{}, entries...)
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), gzipSuffix)
+		if _, ok := names[name]; ok || name == e.Name() || name == "" {
+			continue
+		}
+		if st, errno := g.statDecompressed(path.Join(dir, name)); errno == 0 {
+			dirents = append(dirents, &dirInfo{name: name, stat: st})
+		}
+	}
+	sort.Slice(dirents, func(i, j int) bool { return dirents[i].Name() < dirents[j].Name() })
+	return &mergedDir{dirs: []fs.File{f}, dirents: dirents}, 0
+}
+
+// Lstat implements FS.Lstat
+func (g *gzipFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	st, errno := g.fs.Lstat(path)
+	if errno == syscall.ENOENT {
+		return g.statDecompressed(path)
+	}
+	return st, errno
+}
+
+// Stat implements FS.Stat
+func (g *gzipFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	st, errno := g.fs.Stat(path)
+	if errno == syscall.ENOENT {
+		return g.statDecompressed(path)
+	}
+	return st, errno
+}
+
+// statDecompressed returns the stat of the compressed file `name` + ".gz",
+// with the decompressed size.
+func (g *gzipFS) statDecompressed(name string) (platform.Stat_t, syscall.Errno) {
+	gzName := name + gzipSuffix
+	st, errno := g.fs.Stat(gzName)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	} else if !st.Mode.IsRegular() {
+		return platform.Stat_t{}, syscall.ENOENT
+	}
+
+	f, errno := g.fs.OpenFile(gzName, os.O_RDONLY, 0)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	defer f.Close()
+
+	// The last 4 bytes of a gzip stream are the decompressed size.
+	size := st.Size
+	st.Size = 0
+	var trailer [4]byte
+	if ra, ok := f.(io.ReaderAt); ok && size >= int64(len(trailer)) {
+		if _, err := ra.ReadAt(trailer[:], size-int64(len(trailer))); err == nil {
+			st.Size = int64(binary.LittleEndian.Uint32(trailer[:]))
+		}
+	}
+	return st, 0
+}
+
+// gzipErrno converts an error reading a gzip stream to a syscall.Errno.
+// Corrupt data is syscall.EIO.
+func gzipErrno(err error) syscall.Errno {
+	if err == gzip.ErrHeader || err == gzip.ErrChecksum || err == io.ErrUnexpectedEOF {
+		return syscall.EIO
+	}
+	return platform.UnwrapOSError(err)
+}
+
+// gzipFile reads a compressed file of GzipFS, decompressed.
+type gzipFile struct {
+	f    fs.File
+	zr   *gzip.Reader
+	info dirInfo
+}
+
+// GoString implements fmt.GoStringer
+func (f *gzipFile) GoString() string {
+	return fmt.Sprintf("&sysfs.gzipFile{f: %s}", goStringFile(f.f))
+}
+
+// Supports implements CapabilityFile
+func (f *gzipFile) Supports(c Capability) bool {
+	switch c {
+	case CapabilityReadAt, CapabilitySeek:
+		return false // only read sequentially.
+	}
+	return hasMethod(f, c)
+}
+
+// Stat implements fs.File
+func (f *gzipFile) Stat() (fs.FileInfo, error) { return &f.info, nil }
+
+// Read implements io.Reader
+func (f *gzipFile) Read(p []byte) (int, error) {
+	n, err := f.zr.Read(p)
+	if err != nil && err != io.EOF {
+		return n, gzipErrno(err)
+	}
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt
+func (f *gzipFile) ReadAt([]byte, int64) (int, error) {
+	return 0, syscall.ENOSYS
+}
+
+// Seek implements io.Seeker
+func (f *gzipFile) Seek(int64, int) (int64, error) {
+	return 0, syscall.ENOSYS
+}
+
+// Close implements fs.File
+func (f *gzipFile) Close() error {
+	err := f.zr.Close()
+	if e := f.f.Close(); e != nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package sysfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// writeGzip writes `data` compressed to the file `name`.
+func writeGzip(t *testing.T, name string, data []byte) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(name, buf.Bytes(), 0o600))
+}
+
+func TestGzipFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	data := bytes.Repeat([]byte("wazero"), 32*1024) // larger than read buffers
+	writeGzip(t, path.Join(tmpDir, "data.gz"), data)
+	writeGzip(t, path.Join(tmpDir, "both.gz"), []byte("compressed"))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "both"), []byte("plain"), 0o600))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "corrupt.gz"), []byte("not gzip"), 0o600))
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "dir.gz"), 0o700))
+	testFS := GzipFS(NewDirFS(tmpDir))
+
+	t.Run("decompressed", func(t *testing.T) {
+		b, errno := ReadFile(testFS, "data")
+		require.Zero(t, errno)
+		require.Equal(t, data, b)
+
+		st, errno := testFS.Stat("data")
+		require.Zero(t, errno)
+		require.Equal(t, int64(len(data)), st.Size)
+	})
+
+	t.Run("compressed", func(t *testing.T) {
+		b, errno := ReadFile(testFS, "data.gz")
+		require.Zero(t, errno)
+		expected, err := os.ReadFile(path.Join(tmpDir, "data.gz"))
+		require.NoError(t, err)
+		require.Equal(t, expected, b)
+	})
+
+	t.Run("existing file takes precedence", func(t *testing.T) {
+		b, errno := ReadFile(testFS, "both")
+		require.Zero(t, errno)
+		require.Equal(t, "plain", string(b))
+	})
+
+	t.Run("ReadDir", func(t *testing.T) {
+		requireDirNames(t, testFS, ".", "both", "both.gz", "corrupt", "corrupt.gz", "data", "data.gz", "dir.gz")
+	})
+
+	t.Run("not a regular file", func(t *testing.T) {
+		_, errno := testFS.Stat("dir")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		_, errno := testFS.OpenFile("corrupt", os.O_RDONLY, 0)
+		require.EqualErrno(t, syscall.EIO, errno)
+	})
+
+	t.Run("sequential only", func(t *testing.T) {
+		f, errno := testFS.OpenFile("data", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		require.False(t, Supports(f, CapabilityReadAt))
+		_, err := f.(io.ReaderAt).ReadAt(make([]byte, 1), 0)
+		require.EqualErrno(t, syscall.ENOSYS, err.(syscall.Errno))
+		require.False(t, Supports(f, CapabilitySeek))
+		_, err = f.(io.Seeker).Seek(0, io.SeekStart)
+		require.EqualErrno(t, syscall.ENOSYS, err.(syscall.Errno))
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		_, errno := testFS.OpenFile("data", os.O_RDWR, 0)
+		require.EqualErrno(t, syscall.ENOSYS, errno)
+		require.EqualErrno(t, syscall.EROFS, testFS.Unlink("data.gz"))
+	})
+}
@@ -0,0 +1,242 @@
+package sysfs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// LimitFS wraps `base` to limit how many bytes files can grow in total to
+// `maxBytes`, failing writes which exceed it with syscall.ENOSPC, which WASI
+// returns as ErrnoNospc. This bounds the storage an untrusted guest can use.
+//
+// The quota applies to the growth of regular files since LimitFS was called.
+// Shrinking files, with Truncate or O_TRUNC, and removing them with Unlink or
+// Rename, frees quota, including for files which existed before.
+//
+// # Notes
+//
+//   - A write which exceeds the quota writes as many bytes as fit, then
+//     returns syscall.ENOSPC, like a full disk.
+//   - Metadata, such as directories and symbolic links, isn't counted.
+//   - A file with multiple hard links is freed when the last is removed.
+func LimitFS(base FS, maxBytes int64) FS {
+	return &limitFS{FS: base, max: maxBytes}
+}
+
+type limitFS struct {
+	FS
+	max int64
+
+	// mux guards used, which is the growth of files so far.
+	mux  sync.Mutex
+	used int64
+}
+
+// reserve adds up to `n` bytes to the quota used, and returns how many were.
+func (l *limitFS) reserve(n int64) int64 {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if avail := l.max - l.used; n > avail {
+		n = avail
+	}
+	if n < 0 {
+		n = 0
+	}
+	l.used += n
+	return n
+}
+
+// release frees `n` bytes of the quota used.
+func (l *limitFS) release(n int64) {
+	if n <= 0 {
+		return
+	}
+	l.mux.Lock()
+	l.used -= n
+	l.mux.Unlock()
+}
+
+// removedSize returns the size freed by removing `path`: the size of a
+// regular file, unless it has other links.
+func (l *limitFS) removedSize(path string) int64 {
+	if st, errno := l.FS.Lstat(path); errno == 0 && st.Mode.IsRegular() && st.Nlink <= 1 {
+		return st.Size
+	}
+	return 0
+}
+
+// Open implements the same method as documented on fs.FS
+func (l *limitFS) Open(name string) (fs.File, error) {
+	return fsOpen(l, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (l *limitFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	var truncated int64
+	if flag&os.O_TRUNC != 0 {
+		if st, errno := l.FS.Stat(path); errno == 0 && st.Mode.IsRegular() {
+			truncated = st.Size
+		}
+	}
+
+	f, errno := l.FS.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	l.release(truncated)
+
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return f, 0
+	}
+	return &limitFile{wrappedFile: wrappedFile{path: path, f: f}, l: l, append: flag&os.O_APPEND != 0}, 0
+}
+
+// Rename implements FS.Rename
+func (l *limitFS) Rename(from, to string) syscall.Errno {
+	replaced := l.removedSize(to)
+	if errno := l.FS.Rename(from, to); errno != 0 {
+		return errno
+	}
+	l.release(replaced)
+	return 0
+}
+
+// Unlink implements FS.Unlink
+func (l *limitFS) Unlink(path string) syscall.Errno {
+	removed := l.removedSize(path)
+	if errno := l.FS.Unlink(path); errno != 0 {
+		return errno
+	}
+	l.release(removed)
+	return 0
+}
+
+// Truncate implements FS.Truncate
+func (l *limitFS) Truncate(path string, size int64) syscall.Errno {
+	st, errno := l.FS.Stat(path)
+	if errno != 0 {
+		return errno
+	}
+	return l.truncate(st.Size, size, func() syscall.Errno {
+		return l.FS.Truncate(path, size)
+	})
+}
+
+// truncate changes the size of a file from `cur` to `size` with `fn`,
+// updating the quota used.
+func (l *limitFS) truncate(cur, size int64, fn func() syscall.Errno) syscall.Errno {
+	if size <= cur {
+		if errno := fn(); errno != 0 {
+			return errno
+		}
+		l.release(cur - size)
+		return 0
+	}
+	growth := size - cur
+	if granted := l.reserve(growth); granted < growth {
+		l.release(granted) // the size can't change partially.
+		return syscall.ENOSPC
+	}
+	if errno := fn(); errno != 0 {
+		l.release(growth)
+		return errno
+	}
+	return 0
+}
+
+// limitFile counts the growth of a file of LimitFS.
+type limitFile struct {
+	wrappedFile
+	l      *limitFS
+	append bool
+}
+
+// GoString implements fmt.GoStringer
+func (f *limitFile) GoString() string {
+	return fmt.Sprintf("&sysfs.limitFile{path: %q, f: %s}", f.path, goStringFile(f.f))
+}
+
+// Write implements io.Writer
+func (f *limitFile) Write(p []byte) (int, error) {
+	w, ok := f.f.(io.Writer)
+	if !ok {
+		return 0, syscall.ENOSYS
+	}
+	st, errno := platform.StatFile(f.f)
+	if errno != 0 {
+		return 0, errno
+	}
+	off := st.Size // the offset of appends, or if it is unknown.
+	if s, ok := f.f.(io.Seeker); ok && !f.append {
+		var err error
+		if off, err = s.Seek(0, io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	}
+	return f.write(p, off, st.Size, w.Write)
+}
+
+// WriteAt implements io.WriterAt
+func (f *limitFile) WriteAt(p []byte, off int64) (int, error) {
+	wa, ok := f.f.(io.WriterAt)
+	if !ok {
+		return 0, syscall.ENOSYS
+	}
+	st, errno := platform.StatFile(f.f)
+	if errno != 0 {
+		return 0, errno
+	}
+	return f.write(p, off, st.Size, func(p []byte) (int, error) { return wa.WriteAt(p, off) })
+}
+
+// write writes as much of `p` at `off` with `fn` as the quota allows, given
+// the file is `size` bytes.
+func (f *limitFile) write(p []byte, off, size int64, fn func([]byte) (int, error)) (int, error) {
+	growth := off + int64(len(p)) - size
+	if growth <= 0 {
+		return fn(p)
+	}
+
+	granted := f.l.reserve(growth)
+	short := growth - granted
+	if short >= int64(len(p)) {
+		f.l.release(granted)
+		return 0, syscall.ENOSPC
+	}
+
+	n, err := fn(p[:int64(len(p))-short])
+	grown := off + int64(n) - size
+	if grown < 0 {
+		grown = 0
+	}
+	f.l.release(granted - grown) // in case of a short write.
+	if err == nil && short > 0 {
+		err = syscall.ENOSPC
+	}
+	return n, err
+}
+
+// Truncate implements the same method as documented on os.File
+func (f *limitFile) Truncate(size int64) error {
+	t, ok := f.f.(interface{ Truncate(int64) error })
+	if !ok {
+		return syscall.ENOSYS
+	}
+	st, errno := platform.StatFile(f.f)
+	if errno != 0 {
+		return errno
+	}
+	if errno = f.l.truncate(st.Size, size, func() syscall.Errno {
+		return platform.UnwrapOSError(t.Truncate(size))
+	}); errno != 0 {
+		return errno
+	}
+	return nil
+}
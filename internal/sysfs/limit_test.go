@@ -0,0 +1,59 @@
+package sysfs
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestLimitFS(t *testing.T) {
+	testFS := LimitFS(NewDirFS(t.TempDir()), 10)
+
+	// Write up to the limit.
+	require.Zero(t, WriteFile(testFS, "a", []byte("123456"), 0o600))
+	require.Zero(t, WriteFile(testFS, "b", []byte("1234"), 0o600))
+
+	// Exceeding it fails, once the bytes which fit are written.
+	f, errno := testFS.OpenFile("c", os.O_WRONLY|os.O_CREATE, 0o600)
+	require.Zero(t, errno)
+	n, err := f.(io.Writer).Write([]byte("1"))
+	require.EqualErrno(t, syscall.ENOSPC, err.(syscall.Errno))
+	require.Equal(t, 0, n)
+	require.NoError(t, f.Close())
+
+	// Overwriting doesn't grow the file, so it is allowed.
+	f, errno = testFS.OpenFile("a", os.O_WRONLY, 0)
+	require.Zero(t, errno)
+	_, err = f.(io.WriterAt).WriteAt([]byte("abc"), 3)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// Removing a file frees its size.
+	require.Zero(t, testFS.Unlink("b"))
+	f, errno = testFS.OpenFile("c", os.O_WRONLY|os.O_APPEND, 0)
+	require.Zero(t, errno)
+	n, err = f.(io.Writer).Write([]byte("123456"))
+	require.EqualErrno(t, syscall.ENOSPC, err.(syscall.Errno))
+	require.Equal(t, 4, n)
+	require.NoError(t, f.Close())
+
+	// Truncating frees the difference, and growing needs quota.
+	require.EqualErrno(t, syscall.ENOSPC, testFS.Truncate("c", 5))
+	require.Zero(t, testFS.Truncate("a", 2))
+	require.Zero(t, testFS.Truncate("c", 8))
+
+	// As does truncating on open.
+	require.EqualErrno(t, syscall.ENOSPC, WriteFile(testFS, "d", []byte("1"), 0o600))
+	require.Zero(t, WriteFile(testFS, "c", []byte("12345678"), 0o600))
+
+	// Replacing a file with rename frees it.
+	require.Zero(t, testFS.Rename("a", "c"))
+	require.Zero(t, WriteFile(testFS, "d", []byte("12345678"), 0o600))
+
+	b, errno := ReadFile(testFS, "c")
+	require.Zero(t, errno)
+	require.Equal(t, "12", string(b))
+}
@@ -0,0 +1,176 @@
+package sysfs
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// openFlagsMutate are flags which allow OpenFile to change the file system.
+const openFlagsMutate = os.O_WRONLY | os.O_RDWR | os.O_CREATE | os.O_TRUNC | os.O_APPEND
+
+// NewLockFS wraps `base` so that host code can hold maintenance windows,
+// such as for taking a snapshot, with LockFS.Lock.
+func NewLockFS(base FS) *LockFS {
+	return &LockFS{FS: base}
+}
+
+// LockFS is an FS whose mutating operations hold a shared advisory lock,
+// returned by NewLockFS. During a maintenance window, reads proceed
+// concurrently, but mutations block until the window ends.
+//
+// Mutating operations include OpenFile with flags that allow writing or
+// creating a file, and writes through files opened with them.
+//
+// Note: The lock is advisory: it doesn't block mutations which don't go
+// through LockFS.
+type LockFS struct {
+	FS
+	// mux is held for reading by each mutation, and for writing by each
+	// maintenance window.
+	mux sync.RWMutex
+}
+
+// Lock starts a maintenance window, once mutations in progress complete, and
+// returns a function which ends it. Calling the returned function again has
+// no effect.
+func (l *LockFS) Lock() (release func()) {
+	l.mux.Lock()
+	var once sync.Once
+	return func() { once.Do(l.mux.Unlock) }
+}
+
+// mutate runs `fn`, which mutates the tree, outside maintenance windows.
+func (l *LockFS) mutate(fn func() syscall.Errno) syscall.Errno {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+	return fn()
+}
+
+// String implements fmt.Stringer
+func (l *LockFS) String() string {
+	return l.FS.String()
+}
+
+// Open implements the same method as documented on fs.FS
+func (l *LockFS) Open(name string) (fs.File, error) {
+	return fsOpen(l, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (l *LockFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	if flag&openFlagsMutate == 0 {
+		return l.FS.OpenFile(path, flag, perm)
+	}
+	var f fs.File
+	errno := l.mutate(func() (errno syscall.Errno) {
+		f, errno = l.FS.OpenFile(path, flag, perm)
+		return
+	})
+	if errno != 0 {
+		return nil, errno
+	}
+	return &lockFile{wrappedFile: wrappedFile{path: path, f: f}, l: l}, 0
+}
+
+// Mkdir implements FS.Mkdir
+func (l *LockFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	return l.mutate(func() syscall.Errno { return l.FS.Mkdir(path, perm) })
+}
+
+// Chmod implements FS.Chmod
+func (l *LockFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	return l.mutate(func() syscall.Errno { return l.FS.Chmod(path, perm) })
+}
+
+// Chown implements FS.Chown
+func (l *LockFS) Chown(path string, uid, gid int) syscall.Errno {
+	return l.mutate(func() syscall.Errno { return l.FS.Chown(path, uid, gid) })
+}
+
+// Lchown implements FS.Lchown
+func (l *LockFS) Lchown(path string, uid, gid int) syscall.Errno {
+	return l.mutate(func() syscall.Errno { return l.FS.Lchown(path, uid, gid) })
+}
+
+// Rename implements FS.Rename
+func (l *LockFS) Rename(from, to string) syscall.Errno {
+	return l.mutate(func() syscall.Errno { return l.FS.Rename(from, to) })
+}
+
+// Rmdir implements FS.Rmdir
+func (l *LockFS) Rmdir(path string) syscall.Errno {
+	return l.mutate(func() syscall.Errno { return l.FS.Rmdir(path) })
+}
+
+// Unlink implements FS.Unlink
+func (l *LockFS) Unlink(path string) syscall.Errno {
+	return l.mutate(func() syscall.Errno { return l.FS.Unlink(path) })
+}
+
+// Link implements FS.Link
+func (l *LockFS) Link(oldPath, newPath string) syscall.Errno {
+	return l.mutate(func() syscall.Errno { return l.FS.Link(oldPath, newPath) })
+}
+
+// Symlink implements FS.Symlink
+func (l *LockFS) Symlink(oldPath, linkName string) syscall.Errno {
+	return l.mutate(func() syscall.Errno { return l.FS.Symlink(oldPath, linkName) })
+}
+
+// Mknod implements FS.Mknod
+func (l *LockFS) Mknod(path string, mode fs.FileMode, dev uint64) syscall.Errno {
+	return l.mutate(func() syscall.Errno { return l.FS.Mknod(path, mode, dev) })
+}
+
+// Truncate implements FS.Truncate
+func (l *LockFS) Truncate(path string, size int64) syscall.Errno {
+	return l.mutate(func() syscall.Errno { return l.FS.Truncate(path, size) })
+}
+
+// Utimens implements FS.Utimens
+func (l *LockFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	return l.mutate(func() syscall.Errno { return l.FS.Utimens(path, times, symlinkFollow) })
+}
+
+// lockFile is a file of LockFS opened for writing, whose writes hold the
+// shared lock.
+type lockFile struct {
+	wrappedFile
+	l *LockFS
+}
+
+// GoString implements fmt.GoStringer
+func (f *lockFile) GoString() string {
+	return fmt.Sprintf("&sysfs.lockFile{path: %q, f: %s}", f.path, goStringFile(f.f))
+}
+
+// Write implements io.Writer
+func (f *lockFile) Write(p []byte) (int, error) {
+	f.l.mux.RLock()
+	defer f.l.mux.RUnlock()
+	return f.wrappedFile.Write(p)
+}
+
+// WriteAt implements io.WriterAt
+func (f *lockFile) WriteAt(p []byte, off int64) (int, error) {
+	f.l.mux.RLock()
+	defer f.l.mux.RUnlock()
+	return f.wrappedFile.WriteAt(p, off)
+}
+
+// Chmod implements the same method as documented on os.File
+func (f *lockFile) Chmod(mode fs.FileMode) error {
+	f.l.mux.RLock()
+	defer f.l.mux.RUnlock()
+	return f.wrappedFile.Chmod(mode)
+}
+
+// Truncate implements the same method as documented on os.File
+func (f *lockFile) Truncate(size int64) error {
+	f.l.mux.RLock()
+	defer f.l.mux.RUnlock()
+	return f.wrappedFile.Truncate(size)
+}
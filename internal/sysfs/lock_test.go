@@ -0,0 +1,81 @@
+package sysfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestLockFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+
+	testFS := NewLockFS(NewDirFS(tmpDir))
+
+	// A file opened for writing before the window.
+	openFile, errno := testFS.OpenFile("file", os.O_RDWR, 0)
+	require.Zero(t, errno)
+	defer openFile.Close()
+
+	release := testFS.Lock()
+	defer release()
+
+	// Start writes, which block during the maintenance window.
+	mkdir := make(chan syscall.Errno, 1)
+	go func() { mkdir <- testFS.Mkdir("dir", 0o700) }()
+	write := make(chan syscall.Errno, 1)
+	go func() { write <- WriteFile(testFS, "file", []byte("wa"), 0o600) }()
+	writeAt := make(chan error, 1)
+	go func() {
+		_, err := openFile.(io.WriterAt).WriteAt([]byte("ze"), 2)
+		writeAt <- err
+	}()
+
+	// Reads proceed during the window.
+	_, errno = testFS.Stat("file")
+	require.Zero(t, errno)
+	f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.Zero(t, errno)
+	b, err := io.ReadAll(f.(io.Reader))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.Equal(t, "wazero", string(b))
+
+	select {
+	case <-mkdir:
+		t.Fatal("Mkdir didn't block")
+	case <-write:
+		t.Fatal("WriteFile didn't block")
+	case <-writeAt:
+		t.Fatal("WriteAt didn't block")
+	case <-time.After(10 * time.Millisecond):
+	}
+	_, err = os.Stat(path.Join(tmpDir, "dir"))
+	require.True(t, os.IsNotExist(err))
+
+	// Ending the window unblocks the writes.
+	release()
+	require.Zero(t, <-mkdir)
+	require.Zero(t, <-write)
+	require.NoError(t, <-writeAt)
+
+	release() // no-op
+	require.Zero(t, testFS.Rmdir("dir"))
+
+	// Another window can start after.
+	release = testFS.Lock()
+	unlink := make(chan syscall.Errno, 1)
+	go func() { unlink <- testFS.Unlink("file") }()
+	select {
+	case <-unlink:
+		t.Fatal("Unlink didn't block")
+	case <-time.After(10 * time.Millisecond):
+	}
+	release()
+	require.Zero(t, <-unlink)
+}
@@ -0,0 +1,222 @@
+package sysfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// ManifestEntry describes a file in the tree returned by Manifest.
+type ManifestEntry struct {
+	// Path is relative to the root of the manifest, such as "dir/file".
+	Path string
+
+	// Mode includes the type and permission bits of the file.
+	Mode fs.FileMode
+
+	// Size is the length in bytes of a regular file or the target of a
+	// symbolic link, or zero otherwise.
+	Size int64
+
+	// Mtim is the last data modification time in nanoseconds since epoch,
+	// the same as platform.Stat_t.
+	Mtim int64
+
+	// Hash is the SHA-256 of the contents of a regular file, or nil for other
+	// types of files.
+	Hash []byte
+
+	// Link is the target of a symbolic link, or empty for other types of
+	// files.
+	Link string
+}
+
+// Manifest returns an entry for each file and directory in the tree rooted at
+// `root`, excluding `root` itself, sorted by path. As it includes the hash of
+// each regular file, comparing manifests detects changes in content, for
+// example to check a build is reproducible. A zero syscall.Errno is success.
+//
+// Symbolic links are listed with their target, instead of being followed.
+func Manifest(fsys FS, root string) ([]ManifestEntry, syscall.Errno) {
+	root = path.Clean(root) // as WalkDir joins paths with path.Join
+	var entries []ManifestEntry
+	err := WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if p == root {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		e := ManifestEntry{Path: p, Mode: info.Mode(), Mtim: info.ModTime().UnixNano()}
+		if root != "." {
+			e.Path = strings.TrimPrefix(p, root+"/")
+		}
+
+		var errno syscall.Errno
+		switch info.Mode().Type() {
+		case 0: // regular file
+			e.Size = info.Size()
+			e.Hash, errno = hashFile(fsys, p)
+		case fs.ModeSymlink:
+			e.Link, errno = fsys.Readlink(p)
+			e.Size = int64(len(e.Link))
+		}
+		if errno != 0 {
+			return errno
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err.(syscall.Errno)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, 0
+}
+
+// hashFile returns the SHA-256 of the contents of the file at `p`.
+func hashFile(fsys FS, p string) ([]byte, syscall.Errno) {
+	f, errno := fsys.OpenFile(p, os.O_RDONLY, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, platform.UnwrapOSError(err)
+	}
+	return h.Sum(nil), 0
+}
+
+// ApplyManifest creates the files and directories described by `entries`,
+// which are sorted by path as returned by Manifest, in the directory `root`
+// of `fsys`. The contents of regular files are read from `content`, given
+// their hash, such as from a content-addressed store. A zero syscall.Errno
+// is success.
+//
+// Existing directories are reused. Symbolic links are created after all other
+// files, and regular files are opened with platform.O_NOFOLLOW, so that no
+// file is written through a link to outside `root`. After files are created,
+// their permissions and modification times are set to those in the manifest,
+// starting with the deepest, so that creating files doesn't change the times
+// of their directory.
+//
+// # Errors
+//
+//   - syscall.EINVAL: an entry path is absolute, empty or contains "..".
+//   - syscall.EIO: `content` returned data that doesn't match the hash. The
+//     file with the wrong content is removed.
+//   - syscall.ENOTSUP: an entry isn't a directory, regular file or symbolic
+//     link.
+//
+// An error from `content` is converted with platform.UnwrapOSError.
+func ApplyManifest(fsys FS, root string, entries []ManifestEntry, content func(hash []byte) (io.Reader, error)) syscall.Errno {
+	for i := range entries {
+		if p := entries[i].Path; p == "." || !fs.ValidPath(p) {
+			return syscall.EINVAL
+		}
+	}
+
+	// Create symbolic links last, as a later entry could otherwise be written
+	// through one.
+	for _, symlinks := range []bool{false, true} {
+		for i := range entries {
+			e := &entries[i]
+			if (e.Mode.Type() == fs.ModeSymlink) != symlinks {
+				continue
+			}
+			if errno := applyEntry(fsys, path.Join(root, e.Path), e, content); errno != 0 {
+				return errno
+			}
+		}
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := &entries[i]
+		p := path.Join(root, e.Path)
+		if e.Mode.Type() != fs.ModeSymlink {
+			if errno := fsys.Chmod(p, e.Mode.Perm()); errno != 0 {
+				return errno
+			}
+		}
+		times := [2]syscall.Timespec{syscall.NsecToTimespec(e.Mtim), syscall.NsecToTimespec(e.Mtim)}
+		if errno := fsys.Utimens(p, &times, false); errno != 0 && errno != syscall.ENOSYS {
+			return errno
+		}
+	}
+	return 0
+}
+
+// applyEntry creates the file `p` described by `e`. Permissions are set later
+// by ApplyManifest, so that read-only directories can be populated first.
+func applyEntry(fsys FS, p string, e *ManifestEntry, content func(hash []byte) (io.Reader, error)) syscall.Errno {
+	switch e.Mode.Type() {
+	case fs.ModeDir:
+		errno := fsys.Mkdir(p, 0o700)
+		if errno == syscall.EEXIST {
+			if st, err := fsys.Lstat(p); err == 0 && st.Mode.IsDir() {
+				errno = 0
+			}
+		}
+		return errno
+	case fs.ModeSymlink:
+		return fsys.Symlink(e.Link, p)
+	case 0: // regular file
+		r, err := content(e.Hash)
+		if err != nil {
+			return platform.UnwrapOSError(err)
+		}
+		if c, ok := r.(io.Closer); ok {
+			defer c.Close()
+		}
+		return writeHashed(fsys, p, r, e.Hash)
+	default:
+		return syscall.ENOTSUP
+	}
+}
+
+// writeHashed writes `r` to the file `p`, failing with syscall.EIO if the
+// SHA-256 of what was written isn't `hash`, in which case `p` is removed.
+func writeHashed(fsys FS, p string, r io.Reader, hash []byte) (errno syscall.Errno) {
+	f, errno := fsys.OpenFile(p, openFlagsWriteFile|platform.O_NOFOLLOW, 0o600)
+	if errno != 0 {
+		return
+	}
+	mismatch := false
+	defer func() {
+		if e := platform.UnwrapOSError(f.Close()); errno == 0 {
+			errno = e
+		}
+		if mismatch {
+			_ = fsys.Unlink(p)
+		}
+	}()
+
+	w, ok := f.(io.Writer)
+	if !ok {
+		return syscall.EBADF
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), r); err != nil {
+		return platform.UnwrapOSError(err)
+	}
+	if !bytes.Equal(h.Sum(nil), hash) {
+		mismatch = true
+		return syscall.EIO
+	}
+	return 0
+}
@@ -0,0 +1,171 @@
+package sysfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestManifest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+
+	tmpDir := t.TempDir()
+	treeDir := path.Join(tmpDir, "tree")
+	require.NoError(t, os.MkdirAll(path.Join(treeDir, "a", "b"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(treeDir, "a", "b", "file"), []byte("wazero"), 0o600))
+	require.NoError(t, os.WriteFile(path.Join(treeDir, "a-"), nil, 0o400))
+	require.NoError(t, os.Symlink("a/b/file", path.Join(treeDir, "link")))
+
+	// Fix times, so that the manifest is stable.
+	mtime := time.Unix(1234567890, 0)
+	for _, p := range []string{"a/b/file", "a-", "a/b", "a"} {
+		require.NoError(t, os.Chtimes(path.Join(treeDir, p), mtime, mtime))
+	}
+	linkSt, err := os.Lstat(path.Join(treeDir, "link"))
+	require.NoError(t, err)
+
+	wazeroHash := sha256.Sum256([]byte("wazero"))
+	emptyHash := sha256.Sum256(nil)
+	expected := []ManifestEntry{
+		{Path: "a", Mode: fs.ModeDir | 0o700, Mtim: mtime.UnixNano()},
+		{Path: "a-", Mode: 0o400, Mtim: mtime.UnixNano(), Hash: emptyHash[:]},
+		{Path: "a/b", Mode: fs.ModeDir | 0o700, Mtim: mtime.UnixNano()},
+		{Path: "a/b/file", Mode: 0o600, Size: 6, Mtim: mtime.UnixNano(), Hash: wazeroHash[:]},
+		{Path: "link", Mode: linkSt.Mode(), Size: 8, Mtim: linkSt.ModTime().UnixNano(), Link: "a/b/file"},
+	}
+
+	t.Run("root", func(t *testing.T) {
+		entries, errno := Manifest(NewDirFS(treeDir), ".")
+		require.Zero(t, errno)
+		require.Equal(t, expected, entries)
+	})
+
+	t.Run("subdirectory", func(t *testing.T) {
+		entries, errno := Manifest(NewDirFS(tmpDir), "tree/")
+		require.Zero(t, errno)
+		require.Equal(t, expected, entries)
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		_, errno := Manifest(NewDirFS(tmpDir), "missing")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+}
+
+func TestApplyManifest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+
+	srcDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(srcDir, "a", "b"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(srcDir, "a", "b", "file"), []byte("wazero"), 0o600))
+	require.NoError(t, os.WriteFile(path.Join(srcDir, "a", "copy"), []byte("wazero"), 0o400))
+	require.NoError(t, os.WriteFile(path.Join(srcDir, "empty"), nil, 0o600))
+	require.NoError(t, os.Symlink("a/b/file", path.Join(srcDir, "link")))
+	require.NoError(t, os.Chmod(path.Join(srcDir, "a", "b"), 0o500)) // read-only
+	defer os.Chmod(path.Join(srcDir, "a", "b"), 0o700)               //nolint
+
+	srcFS := NewDirFS(srcDir)
+	entries, errno := Manifest(srcFS, ".")
+	require.Zero(t, errno)
+
+	// A content-addressed store, keyed by hash.
+	store := map[string][]byte{}
+	for _, e := range entries {
+		if e.Hash != nil {
+			b, errno := ReadFile(srcFS, e.Path)
+			require.Zero(t, errno)
+			store[string(e.Hash)] = b
+		}
+	}
+	content := func(hash []byte) (io.Reader, error) {
+		if b, ok := store[string(hash)]; ok {
+			return bytes.NewReader(b), nil
+		}
+		return nil, syscall.ENOENT
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		dstDir := t.TempDir()
+		require.NoError(t, os.Mkdir(path.Join(dstDir, "tree"), 0o700))
+		dstFS := NewDirFS(dstDir)
+		defer os.Chmod(path.Join(dstDir, "tree", "a", "b"), 0o700) //nolint
+
+		require.Zero(t, ApplyManifest(dstFS, "tree", entries, content))
+
+		actual, errno := Manifest(dstFS, "tree")
+		require.Zero(t, errno)
+		require.Equal(t, entries, actual)
+	})
+
+	t.Run("hash mismatch", func(t *testing.T) {
+		dstFS := NewDirFS(t.TempDir())
+		errno := ApplyManifest(dstFS, ".", entries, func([]byte) (io.Reader, error) {
+			return strings.NewReader("corrupt"), nil
+		})
+		require.EqualErrno(t, syscall.EIO, errno)
+
+		// The file with the wrong content isn't left behind.
+		_, errno = dstFS.Lstat("a/b/file")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		for _, p := range []string{"", ".", "/file", "../file", "a/../../file", "a//file"} {
+			errno := ApplyManifest(NewDirFS(t.TempDir()), ".", []ManifestEntry{{Path: p}}, content)
+			require.EqualErrno(t, syscall.EINVAL, errno, p)
+		}
+	})
+
+	t.Run("write through symlink", func(t *testing.T) {
+		outsideDir := t.TempDir()
+		wazeroHash := sha256.Sum256([]byte("wazero"))
+		escape := []ManifestEntry{
+			{Path: "link", Mode: fs.ModeSymlink, Link: outsideDir},
+			{Path: "link/file", Mode: 0o600, Hash: wazeroHash[:]},
+		}
+		errno := ApplyManifest(NewDirFS(t.TempDir()), ".", escape, content)
+		require.EqualErrno(t, syscall.ENOENT, errno)
+
+		_, err := os.Lstat(path.Join(outsideDir, "file"))
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("replace symlink", func(t *testing.T) {
+		outsideDir := t.TempDir()
+		dstDir := t.TempDir()
+		require.NoError(t, os.Symlink(path.Join(outsideDir, "file"), path.Join(dstDir, "file")))
+
+		wazeroHash := sha256.Sum256([]byte("wazero"))
+		errno := ApplyManifest(NewDirFS(dstDir), ".", []ManifestEntry{{Path: "file", Mode: 0o600, Hash: wazeroHash[:]}}, content)
+		require.EqualErrno(t, syscall.ELOOP, errno)
+
+		_, err := os.Lstat(path.Join(outsideDir, "file"))
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("missing content", func(t *testing.T) {
+		errno := ApplyManifest(NewDirFS(t.TempDir()), ".", entries, func([]byte) (io.Reader, error) {
+			return nil, syscall.ENOENT
+		})
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		errno := ApplyManifest(NewDirFS(t.TempDir()), ".", []ManifestEntry{{Path: "fifo", Mode: fs.ModeNamedPipe}}, content)
+		require.EqualErrno(t, syscall.ENOTSUP, errno)
+	})
+}
@@ -0,0 +1,255 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// MergeFS presents the read-only union of the input filesystems.
+//
+// Directories that exist in more than one filesystem are merged, so listing
+// them includes entries from each. Any other path resolves to the first
+// filesystem which contains it, in the order given. Use StrictMergeFS to fail
+// instead.
+//
+// Like NewReadFS, any attempt to write returns syscall.EROFS, or
+// syscall.ENOSYS when opening a file for writes.
+func MergeFS(fsys ...FS) FS {
+	return newMergeFS(fsys, false)
+}
+
+// StrictMergeFS is like MergeFS, except any path which isn't a directory must
+// exist in at most one filesystem: when it doesn't, operations on it fail
+// with syscall.EEXIST instead of choosing the first.
+func StrictMergeFS(fsys ...FS) FS {
+	return newMergeFS(fsys, true)
+}
+
+func newMergeFS(fsys []FS, strict bool) FS {
+	switch len(fsys) {
+	case 0:
+		return UnimplementedFS{}
+	case 1:
+		return NewReadFS(fsys[0])
+	}
+
+	ret := &mergeFS{fs: make([]FS, len(fsys)), strict: strict}
+	for i, f := range fsys {
+		ret.fs[i] = NewReadFS(f)
+	}
+	return ret
+}
+
+type mergeFS struct {
+	// readFS is embedded for its write methods, which return syscall.EROFS.
+	readFS
+	fs []FS
+	// strict is true when conflicts fail with syscall.EEXIST.
+	strict bool
+}
+
+// String implements fmt.Stringer
+func (m *mergeFS) String() string {
+	var ret strings.Builder
+	ret.WriteString("merge[")
+	for i, f := range m.fs {
+		if i > 0 {
+			ret.WriteString(" ")
+		}
+		ret.WriteString(f.String())
+	}
+	ret.WriteString("]")
+	return ret.String()
+}
+
+// lookup returns the indexes of filesystems which contain `path` according to
+// `stat`. More than one index is only returned when they are all directories:
+// otherwise, the first wins, unless strict.
+func (m *mergeFS) lookup(path string, stat func(FS, string) (platform.Stat_t, syscall.Errno)) (matches []int, st platform.Stat_t, errno syscall.Errno) {
+	notDir := false
+	for i, f := range m.fs {
+		s, errno := stat(f, path)
+		if errno == syscall.ENOENT {
+			continue
+		} else if errno == syscall.ENOTDIR {
+			notDir = true
+			continue
+		} else if errno != 0 {
+			return nil, platform.Stat_t{}, errno
+		}
+
+		if len(matches) == 0 {
+			st = s
+		} else if !st.Mode.IsDir() || !s.Mode.IsDir() {
+			if m.strict {
+				return nil, platform.Stat_t{}, syscall.EEXIST
+			}
+			continue // the first wins
+		}
+		matches = append(matches, i)
+	}
+
+	if len(matches) == 0 {
+		errno = syscall.ENOENT
+	} else if notDir && !m.strict {
+		// A parent is a file in a filesystem. When that file wins, `path`
+		// doesn't exist in the others either.
+		if _, parent, errno := m.lookup(parentDir(path), statFS); errno != 0 {
+			return nil, platform.Stat_t{}, errno
+		} else if !parent.Mode.IsDir() {
+			return nil, platform.Stat_t{}, syscall.ENOTDIR
+		}
+	}
+	return
+}
+
+func statFS(f FS, path string) (platform.Stat_t, syscall.Errno)  { return f.Stat(path) }
+func lstatFS(f FS, path string) (platform.Stat_t, syscall.Errno) { return f.Lstat(path) }
+
+// Open implements the same method as documented on fs.FS
+func (m *mergeFS) Open(name string) (fs.File, error) {
+	return fsOpen(m, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (m *mergeFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	switch flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR) {
+	case os.O_WRONLY, os.O_RDWR:
+		return nil, syscall.ENOSYS
+	}
+
+	matches, _, errno := m.lookup(path, statFS)
+	if errno != 0 {
+		return nil, errno
+	} else if len(matches) == 1 {
+		return m.fs[matches[0]].OpenFile(path, flag, perm)
+	}
+
+	dir := &mergedDir{dirs: make([]fs.File, 0, len(matches))}
+	for _, i := range matches {
+		f, errno := m.fs[i].OpenFile(path, flag, perm)
+		if errno != 0 {
+			_ = dir.Close()
+			return nil, errno
+		}
+		dir.dirs = append(dir.dirs, f)
+	}
+	return dir, 0
+}
+
+// Lstat implements FS.Lstat
+func (m *mergeFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	_, st, errno := m.lookup(path, lstatFS)
+	return st, errno
+}
+
+// Stat implements FS.Stat
+func (m *mergeFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	_, st, errno := m.lookup(path, statFS)
+	return st, errno
+}
+
+// Readlink implements FS.Readlink
+func (m *mergeFS) Readlink(path string) (string, syscall.Errno) {
+	matches, st, errno := m.lookup(path, lstatFS)
+	if errno != 0 {
+		return "", errno
+	} else if st.Mode.IsDir() {
+		return "", syscall.EINVAL
+	}
+	return m.fs[matches[0]].Readlink(path)
+}
+
+// mergedDir is a directory open in more than one filesystem. Its listing is
+// the union of each, where the first filesystem wins on duplicate names.
+type mergedDir struct {
+	dirs     []fs.File
+	dirents  []fs.DirEntry
+	direntsI int
+}
+
+// GoString implements fmt.GoStringer
+func (d *mergedDir) GoString() string {
+	var ret strings.Builder
+	ret.WriteString("&sysfs.mergedDir{dirs: [")
+	for i, f := range d.dirs {
+		if i > 0 {
+			ret.WriteString(", ")
+		}
+		ret.WriteString(goStringFile(f))
+	}
+	ret.WriteString("]}")
+	return ret.String()
+}
+
+// Close implements fs.File
+func (d *mergedDir) Close() (err error) {
+	for _, f := range d.dirs {
+		if e := f.Close(); e != nil {
+			err = e
+		}
+	}
+	return
+}
+
+// Stat implements fs.File
+func (d *mergedDir) Stat() (fs.FileInfo, error) { return d.dirs[0].Stat() }
+
+// Read implements fs.File
+func (d *mergedDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: syscall.EISDIR}
+}
+
+// ReadDir implements fs.ReadDirFile
+func (d *mergedDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	if d.dirents == nil {
+		if err := d.readDir(); err != nil {
+			return nil, err
+		}
+	}
+
+	// logic similar to go:embed
+	n := len(d.dirents) - d.direntsI
+	if n == 0 {
+		if count <= 0 {
+			return nil, nil
+		}
+		return nil, io.EOF
+	}
+	if count > 0 && n > count {
+		n = count
+	}
+	list := make([]fs.DirEntry, n)
+	copy(list, d.dirents[d.direntsI:])
+	d.direntsI += n
+	return list, nil
+}
+
+func (d *mergedDir) readDir() error {
+	seen := map[string]struct{}{}
+	d.dirents = []fs.DirEntry{}
+	for _, f := range d.dirs {
+		rd, ok := f.(fs.ReadDirFile)
+		if !ok {
+			return syscall.ENOTDIR
+		}
+		entries, err := rd.ReadDir(-1)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if _, ok := seen[e.Name()]; !ok {
+				seen[e.Name()] = struct{}{}
+				d.dirents = append(d.dirents, e)
+			}
+		}
+	}
+	sort.Slice(d.dirents, func(i, j int) bool { return d.dirents[i].Name() < d.dirents[j].Name() })
+	return nil
+}
@@ -0,0 +1,130 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	gofstest "testing/fstest"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMergeFS_new(t *testing.T) {
+	require.Equal(t, UnimplementedFS{}, MergeFS())
+
+	dirFS := NewDirFS(t.TempDir())
+	require.Equal(t, NewReadFS(dirFS), MergeFS(dirFS))
+
+	merged := MergeFS(dirFS, Adapt(fstest.FS))
+	require.Equal(t, "merge["+dirFS.String()+" "+Adapt(fstest.FS).String()+"]", merged.String())
+}
+
+func TestMergeFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	extra := gofstest.MapFS{
+		"sub/extra.txt": {Data: []byte("extra")},
+		"more/more.txt": {Data: []byte("more")},
+	}
+	testFS := MergeFS(NewDirFS(tmpDir), Adapt(extra))
+
+	// Files from either filesystem are visible.
+	st, errno := testFS.Stat("animals.txt")
+	require.Zero(t, errno)
+	require.Equal(t, int64(30), st.Size)
+	st, errno = testFS.Stat("more/more.txt")
+	require.Zero(t, errno)
+	require.Equal(t, int64(4), st.Size)
+
+	_, errno = testFS.Stat("missing")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+
+	// Directories in both filesystems are merged.
+	f, errno := testFS.OpenFile("sub", os.O_RDONLY, 0)
+	require.Zero(t, errno)
+	names := requireReaddirnames(t, f, -1)
+	require.NoError(t, f.Close())
+	require.Equal(t, []string{"extra.txt", "test.txt"}, names)
+
+	// The root directory is merged, too.
+	f, errno = testFS.OpenFile(".", os.O_RDONLY, 0)
+	require.Zero(t, errno)
+	names = requireReaddirnames(t, f, -1)
+	require.NoError(t, f.Close())
+	require.Equal(t, []string{"animals.txt", "dir", "empty.txt", "emptydir", "more", "sub"}, names)
+
+	// Writes are rejected.
+	_, errno = testFS.OpenFile("animals.txt", os.O_RDWR, 0)
+	require.EqualErrno(t, syscall.ENOSYS, errno)
+	require.EqualErrno(t, syscall.EROFS, testFS.Mkdir("new", 0o700))
+	require.EqualErrno(t, syscall.EROFS, testFS.Unlink("animals.txt"))
+}
+
+func TestMergeFS_Conflict(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	conflicts := gofstest.MapFS{
+		"animals.txt":      {Data: []byte("conflict")},
+		"sub/test.txt/foo": {Data: []byte("dir in one, file in another")},
+		"emptydir":         {Data: []byte("file in one, dir in another")},
+	}
+
+	t.Run("first wins", func(t *testing.T) {
+		testFS := MergeFS(NewDirFS(tmpDir), Adapt(conflicts))
+
+		b, errno := ReadFile(testFS, "animals.txt")
+		require.Zero(t, errno)
+		require.Equal(t, int64(30), int64(len(b)))
+
+		st, errno := testFS.Stat("sub/test.txt")
+		require.Zero(t, errno)
+		require.True(t, st.Mode.IsRegular())
+		_, errno = testFS.Stat("sub/test.txt/foo")
+		require.EqualErrno(t, syscall.ENOTDIR, errno)
+
+		requireDirNames(t, testFS, "sub", "test.txt")
+
+		// A file in a later filesystem is hidden by a directory too.
+		st, errno = testFS.Stat("emptydir")
+		require.Zero(t, errno)
+		require.True(t, st.Mode.IsDir())
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		testFS := StrictMergeFS(NewDirFS(tmpDir), Adapt(conflicts))
+
+		for _, p := range []string{"animals.txt", "sub/test.txt", "emptydir"} {
+			_, errno := testFS.Stat(p)
+			require.EqualErrno(t, syscall.EEXIST, errno)
+
+			_, errno = testFS.OpenFile(p, os.O_RDONLY, 0)
+			require.EqualErrno(t, syscall.EEXIST, errno)
+		}
+
+		// Non-conflicting paths still work.
+		_, errno := testFS.Stat(path.Join("sub", "test.txt", "foo"))
+		require.Zero(t, errno)
+	})
+}
+
+func TestMergeFS_TestFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	// Split the test files across two filesystems.
+	second := gofstest.MapFS{}
+	for name, f := range fstest.FS {
+		if path.Dir(name) == "dir" || name == "dir" {
+			second[name] = f
+			require.NoError(t, os.RemoveAll(path.Join(tmpDir, name)))
+		}
+	}
+
+	testFS := MergeFS(NewDirFS(tmpDir), Adapt(second))
+	require.NoError(t, fstest.TestFS(testFS.(fs.FS)))
+}
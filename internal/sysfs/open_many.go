@@ -0,0 +1,70 @@
+package sysfs
+
+import (
+	"context"
+	"io/fs"
+	"sync"
+	"syscall"
+)
+
+// openManyParallelism bounds the count of concurrent OpenFile calls made by
+// OpenMany.
+const openManyParallelism = 16
+
+// OpenMany opens each of the `paths` in `fsys` with the same `flag`,
+// concurrently. This reduces latency when opening many files on backends with
+// a high per-call latency, such as network filesystems.
+//
+// Results are index-correlated with `paths`: for each path, either the file
+// is non-nil or the syscall.Errno is non-zero.
+//
+// Files are opened with zero permissions, so `flag` shouldn't include
+// os.O_CREATE.
+//
+// When `ctx` is done before all files are opened, every file opened so far is
+// closed and the result for each path is syscall.ECANCELED, unless opening it
+// failed for another reason.
+func OpenMany(ctx context.Context, fsys FS, paths []string, flag int) ([]fs.File, []syscall.Errno) {
+	files := make([]fs.File, len(paths))
+	errnos := make([]syscall.Errno, len(paths))
+
+	sem := make(chan struct{}, openManyParallelism)
+	var wg sync.WaitGroup
+	for i := range paths {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			// Don't start any more opens, but let those in flight finish so
+			// their files can be closed.
+			for ; i < len(paths); i++ {
+				errnos[i] = syscall.ECANCELED
+			}
+			wg.Wait()
+			return closeAll(files, errnos)
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			files[i], errnos[i] = fsys.OpenFile(paths[i], flag, 0)
+		}(i)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return closeAll(files, errnos)
+	}
+	return files, errnos
+}
+
+// closeAll closes any files opened by OpenMany, replacing their results with
+// syscall.ECANCELED.
+func closeAll(files []fs.File, errnos []syscall.Errno) ([]fs.File, []syscall.Errno) {
+	for i, f := range files {
+		if f != nil {
+			_ = f.Close()
+			files[i], errnos[i] = nil, syscall.ECANCELED
+		}
+	}
+	return files, errnos
+}
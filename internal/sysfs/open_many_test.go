@@ -0,0 +1,103 @@
+package sysfs
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// testCtx is an arbitrary, non-default context.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+func TestOpenMany(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+	testFS := NewDirFS(tmpDir)
+
+	paths := []string{"animals.txt", "missing", "sub/test.txt", "sub/missing", "dir"}
+	files, errnos := OpenMany(testCtx, testFS, paths, os.O_RDONLY)
+	require.Equal(t, len(paths), len(files))
+	require.Equal(t, len(paths), len(errnos))
+
+	for i, p := range paths {
+		switch p {
+		case "missing", "sub/missing":
+			require.Nil(t, files[i])
+			require.EqualErrno(t, syscall.ENOENT, errnos[i])
+		default:
+			require.Zero(t, errnos[i])
+			st, err := files[i].Stat()
+			require.NoError(t, err)
+			require.Equal(t, p[len(p)-len(st.Name()):], st.Name())
+			require.NoError(t, files[i].Close())
+		}
+	}
+}
+
+func TestOpenMany_Cancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	// Make more paths than can be opened at once.
+	paths := make([]string, openManyParallelism*4)
+	for i := range paths {
+		paths[i] = "animals.txt"
+	}
+	paths[0] = "missing"
+
+	ctx, cancel := context.WithCancel(testCtx)
+	testFS := &closeTrackingFS{FS: NewDirFS(tmpDir), onOpen: cancel}
+
+	files, errnos := OpenMany(ctx, testFS, paths, os.O_RDONLY)
+	for i := range paths {
+		require.Nil(t, files[i])
+		if i == 0 {
+			require.EqualErrno(t, syscall.ENOENT, errnos[i])
+		} else {
+			require.EqualErrno(t, syscall.ECANCELED, errnos[i])
+		}
+	}
+	require.Equal(t, testFS.opened, testFS.closed)
+}
+
+// closeTrackingFS counts files opened and closed, calling onOpen after each
+// open.
+type closeTrackingFS struct {
+	FS
+	onOpen func()
+
+	mux            sync.Mutex
+	opened, closed int
+}
+
+// OpenFile implements FS.OpenFile
+func (c *closeTrackingFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	defer c.onOpen()
+	f, errno := c.FS.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	c.mux.Lock()
+	c.opened++
+	c.mux.Unlock()
+	return &closeTrackingFile{File: f, c: c}, 0
+}
+
+type closeTrackingFile struct {
+	fs.File
+	c *closeTrackingFS
+}
+
+// Close implements fs.File
+func (f *closeTrackingFile) Close() error {
+	f.c.mux.Lock()
+	f.c.closed++
+	f.c.mux.Unlock()
+	return f.File.Close()
+}
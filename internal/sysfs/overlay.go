@@ -0,0 +1,616 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+const (
+	// whiteoutPrefix prefixes the name of a file in the upper layer of an
+	// overlay, which hides the file of the rest of its name in the lower
+	// layer. This is the same convention as aufs, as creating the character
+	// devices overlayfs uses requires privileges.
+	whiteoutPrefix = ".wh."
+
+	// opaqueMarker is a file in a directory of the upper layer of an overlay,
+	// which hides the contents of the same directory in the lower layer.
+	opaqueMarker = whiteoutPrefix + whiteoutPrefix + ".opq"
+)
+
+// NewOverlayFS presents a copy-on-write view of `lower`, where changes are
+// written to `upper`, similar to overlayfs on Linux. `lower` is only read,
+// so it can be read-only, or shared with other overlays.
+//
+// Paths in `upper` hide the same path in `lower`, except directories, which
+// are merged. Opening a file of `lower` for writing, or otherwise changing
+// it, first copies it to `upper`, along with its parent directories.
+// Removing a file of `lower` records a whiteout in `upper`: a file with the
+// same name, prefixed with ".wh.", which hides it. Names with this prefix are
+// reserved: they are never listed, and can't be created.
+//
+// # Notes
+//
+//   - Symbolic links are resolved through the overlay, so a link in one
+//     layer can refer to a file in the other. Absolute targets are relative
+//     to the root of the overlay.
+//   - Like overlayfs without the "redirect_dir" feature, renaming a directory
+//     of `lower` fails with syscall.EXDEV. Callers can copy it instead.
+func NewOverlayFS(upper, lower FS) FS {
+	return &overlayFS{upper: upper, lower: lower}
+}
+
+type overlayFS struct {
+	UnimplementedFS
+	upper, lower FS
+}
+
+// String implements fmt.Stringer
+func (o *overlayFS) String() string {
+	return "overlay[" + o.upper.String() + " " + o.lower.String() + "]"
+}
+
+// whiteout returns the path of the whiteout which hides `p` in the lower
+// layer.
+func whiteout(p string) string {
+	return path.Join(path.Dir(p), whiteoutPrefix+path.Base(p))
+}
+
+// opaque returns the path of the marker which makes the directory `dir`
+// opaque.
+func opaque(dir string) string {
+	return path.Join(dir, opaqueMarker)
+}
+
+// parentDir returns the directory containing `p`.
+func parentDir(p string) string {
+	return path.Dir(path.Clean(p))
+}
+
+// isReserved returns true if the name of `p` is reserved for whiteouts.
+func isReserved(p string) bool {
+	return strings.HasPrefix(path.Base(p), whiteoutPrefix)
+}
+
+// exists returns true if `p` exists in `fsys`, without following links.
+func exists(fsys FS, p string) bool {
+	_, errno := fsys.Lstat(p)
+	return errno == 0
+}
+
+// hiddenInLower returns true if the cleaned path `p` in the lower layer is hidden by a whiteout
+// of it or a parent, or by an opaque parent directory.
+func (o *overlayFS) hiddenInLower(p string) bool {
+	for ; p != "."; p = path.Dir(p) {
+		if exists(o.upper, whiteout(p)) || exists(o.upper, opaque(path.Dir(p))) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the layer which contains `p`, with its Lstat.
+func (o *overlayFS) resolve(p string) (FS, platform.Stat_t, syscall.Errno) {
+	p = path.Clean(p)
+	if isReserved(p) {
+		return nil, platform.Stat_t{}, syscall.ENOENT
+	}
+	if st, errno := o.upper.Lstat(p); errno == 0 {
+		return o.upper, st, 0
+	} else if errno != syscall.ENOENT {
+		return nil, st, errno
+	}
+	if p != "." && o.hiddenInLower(p) {
+		return nil, platform.Stat_t{}, syscall.ENOENT
+	}
+	st, errno := o.lower.Lstat(p)
+	if errno != 0 {
+		return nil, st, errno
+	}
+	return o.lower, st, 0
+}
+
+// lookup resolves the symbolic links in `p` through the overlay, returning a
+// path without any. The last element is only resolved if `follow` is true,
+// and needn't exist, so that it can be created.
+func (o *overlayFS) lookup(p string, follow bool) (string, syscall.Errno) {
+	resolved, links := ".", 0
+	rest := strings.Split(strings.Trim(p, "/"), "/")
+	for len(rest) > 0 {
+		elem := rest[0]
+		rest = rest[1:]
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		next := path.Join(resolved, elem)
+		layer, st, errno := o.resolve(next)
+		if errno == syscall.ENOENT && len(rest) == 0 {
+			return next, 0
+		} else if errno != 0 {
+			return "", errno
+		}
+		switch {
+		case st.Mode.Type() == fs.ModeSymlink && (follow || len(rest) > 0):
+			if links++; links > maxSymlinks {
+				return "", syscall.ELOOP
+			}
+			target, errno := layer.Readlink(next)
+			if errno != 0 {
+				return "", errno
+			}
+			if strings.HasPrefix(target, "/") {
+				resolved = "."
+			}
+			rest = append(strings.Split(strings.Trim(target, "/"), "/"), rest...)
+		case len(rest) > 0 && !st.Mode.IsDir():
+			return "", syscall.ENOTDIR
+		default:
+			resolved = next
+		}
+	}
+	return resolved, 0
+}
+
+// lookupParent resolves the symbolic links in the parent directories of `p`
+// through the overlay, leaving its last element, which is the one operated on.
+func (o *overlayFS) lookupParent(p string) (string, syscall.Errno) {
+	p = path.Clean(p)
+	dir, errno := o.lookup(path.Dir(p), true)
+	if errno != 0 {
+		return "", errno
+	}
+	return path.Join(dir, path.Base(p)), 0
+}
+
+// inLower returns true if `p` is visible in the lower layer.
+func (o *overlayFS) inLower(p string) bool {
+	p = path.Clean(p)
+	return exists(o.lower, p) && (p == "." || !o.hiddenInLower(p))
+}
+
+// copyUp copies `p` from the lower layer to the upper one, including its
+// parents, unless it is already there.
+func (o *overlayFS) copyUp(p string) syscall.Errno {
+	p = path.Clean(p)
+	if p == "." || exists(o.upper, p) {
+		return 0
+	}
+	layer, st, errno := o.resolve(p)
+	if errno != 0 {
+		return errno
+	} else if layer == o.upper {
+		return 0
+	}
+	if errno = o.copyUp(parentDir(p)); errno != 0 {
+		return errno
+	}
+
+	switch st.Mode.Type() {
+	case fs.ModeDir:
+		return o.upper.Mkdir(p, st.Mode.Perm())
+	case fs.ModeSymlink:
+		target, errno := o.lower.Readlink(p)
+		if errno != 0 {
+			return errno
+		}
+		return o.upper.Symlink(target, p)
+	case 0: // regular file
+		if errno = copyFile(o.upper, p, o.lower, p, os.O_EXCL, st.Mode.Perm(), st.Size); errno != 0 {
+			return errno
+		}
+		times := [2]syscall.Timespec{syscall.NsecToTimespec(st.Atim), syscall.NsecToTimespec(st.Mtim)}
+		return o.upper.Utimens(p, &times, true)
+	default:
+		return copyNode(o.upper, p, st)
+	}
+}
+
+// prepareCreate prepares to create `p` in the upper layer, which must not
+// exist. Its parent is copied up and any whiteout of `p` removed.
+// removedWhiteout is true if `p` existed in the lower layer, but was removed.
+func (o *overlayFS) prepareCreate(p string) (removedWhiteout bool, errno syscall.Errno) {
+	if isReserved(p) {
+		return false, syscall.EINVAL
+	}
+	if _, _, errno = o.resolve(p); errno == 0 {
+		return false, syscall.EEXIST
+	} else if errno != syscall.ENOENT {
+		return false, errno
+	}
+
+	dir := parentDir(p)
+	if _, st, errno := o.resolve(dir); errno != 0 {
+		return false, errno
+	} else if !st.Mode.IsDir() {
+		return false, syscall.ENOTDIR
+	}
+	if errno = o.copyUp(dir); errno != 0 {
+		return false, errno
+	}
+
+	switch errno = o.upper.Unlink(whiteout(p)); errno {
+	case 0:
+		return true, 0
+	case syscall.ENOENT:
+		return false, 0
+	default:
+		return false, errno
+	}
+}
+
+// remove records that `p` was removed from the upper layer, with a whiteout
+// if it is still visible in the lower layer.
+func (o *overlayFS) remove(p string) syscall.Errno {
+	if !o.inLower(p) {
+		return 0
+	}
+	if errno := o.copyUp(parentDir(p)); errno != 0 {
+		return errno
+	}
+	return WriteFile(o.upper, whiteout(p), nil, 0o600)
+}
+
+// Open implements the same method as documented on fs.FS
+func (o *overlayFS) Open(name string) (fs.File, error) {
+	return fsOpen(o, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (o *overlayFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	path, errno := o.lookup(path, flag&platform.O_NOFOLLOW == 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	layer, st, errno := o.resolve(path)
+	switch {
+	case errno == syscall.ENOENT && flag&os.O_CREATE != 0:
+		if _, errno = o.prepareCreate(path); errno != 0 {
+			return nil, errno
+		}
+		return o.upper.OpenFile(path, flag, perm)
+	case errno != 0:
+		return nil, errno
+	case flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, syscall.EEXIST
+	case flag&openFlagsMutate != 0:
+		if st.Mode.IsDir() {
+			return nil, syscall.EISDIR
+		} else if errno = o.copyUp(path); errno != 0 {
+			return nil, errno
+		}
+		return o.upper.OpenFile(path, flag, perm)
+	case !st.Mode.IsDir():
+		return layer.OpenFile(path, flag, perm)
+	}
+	return o.openDir(path, flag, perm)
+}
+
+// openDir opens the directory `p`, merging the listings of both layers.
+func (o *overlayFS) openDir(p string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	dir := &mergedDir{}
+	var upperDirents, lowerDirents []fs.DirEntry
+	var errno syscall.Errno
+	if st, e := o.upper.Lstat(p); e == 0 && st.Mode.IsDir() {
+		if upperDirents, errno = o.openLayerDir(dir, o.upper, p, flag, perm); errno != 0 {
+			_ = dir.Close()
+			return nil, errno
+		}
+	}
+	if o.inLower(p) && !exists(o.upper, opaque(p)) {
+		if st, e := o.lower.Lstat(p); e == 0 && st.Mode.IsDir() {
+			if lowerDirents, errno = o.openLayerDir(dir, o.lower, p, flag, perm); errno != 0 {
+				_ = dir.Close()
+				return nil, errno
+			}
+		}
+	}
+
+	seen := map[string]struct{}{}
+	dir.dirents = []fs.DirEntry{}
+	for _, e := range upperDirents {
+		if name := e.Name(); strings.HasPrefix(name, whiteoutPrefix) {
+			seen[strings.TrimPrefix(name, whiteoutPrefix)] = struct{}{} // hides the lower
+		} else {
+			seen[name] = struct{}{}
+			dir.dirents = append(dir.dirents, e)
+		}
+	}
+	for _, e := range lowerDirents {
+		if _, ok := seen[e.Name()]; !ok {
+			dir.dirents = append(dir.dirents, e)
+		}
+	}
+	sort.Slice(dir.dirents, func(i, j int) bool { return dir.dirents[i].Name() < dir.dirents[j].Name() })
+	return dir, 0
+}
+
+// openLayerDir opens the directory `p` in `layer`, adding it to `dir`, and
+// returns its entries.
+func (o *overlayFS) openLayerDir(dir *mergedDir, layer FS, p string, flag int, perm fs.FileMode) ([]fs.DirEntry, syscall.Errno) {
+	f, errno := layer.OpenFile(p, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	dir.dirs = append(dir.dirs, f)
+
+	rd, ok := f.(fs.ReadDirFile)
+	if !ok {
+		return nil, syscall.ENOTDIR
+	}
+	entries, err := rd.ReadDir(-1)
+	return entries, platform.UnwrapOSError(err)
+}
+
+// Lstat implements FS.Lstat
+func (o *overlayFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	path, errno := o.lookup(path, false)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	_, st, errno := o.resolve(path)
+	return st, errno
+}
+
+// Stat implements FS.Stat
+func (o *overlayFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	path, errno := o.lookup(path, true)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	_, st, errno := o.resolve(path)
+	return st, errno
+}
+
+// Readlink implements FS.Readlink
+func (o *overlayFS) Readlink(path string) (string, syscall.Errno) {
+	path, errno := o.lookup(path, false)
+	if errno != 0 {
+		return "", errno
+	}
+	layer, _, errno := o.resolve(path)
+	if errno != 0 {
+		return "", errno
+	}
+	return layer.Readlink(path)
+}
+
+// Mkdir implements FS.Mkdir
+func (o *overlayFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	path, errno := o.lookupParent(path)
+	if errno != 0 {
+		return errno
+	}
+	removedWhiteout, errno := o.prepareCreate(path)
+	if errno != 0 {
+		return errno
+	}
+	if errno = o.upper.Mkdir(path, perm); errno != 0 || !removedWhiteout {
+		return errno
+	}
+	// Don't merge with the directory previously removed from the lower layer.
+	return WriteFile(o.upper, opaque(path), nil, 0o600)
+}
+
+// Chmod implements FS.Chmod
+func (o *overlayFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	path, errno := o.lookup(path, true)
+	if errno != 0 {
+		return errno
+	}
+	if errno = o.copyUp(path); errno != 0 {
+		return errno
+	}
+	return o.upper.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (o *overlayFS) Chown(path string, uid, gid int) syscall.Errno {
+	path, errno := o.lookup(path, true)
+	if errno != 0 {
+		return errno
+	}
+	if errno = o.copyUp(path); errno != 0 {
+		return errno
+	}
+	return o.upper.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (o *overlayFS) Lchown(path string, uid, gid int) syscall.Errno {
+	path, errno := o.lookupParent(path)
+	if errno != 0 {
+		return errno
+	}
+	if errno = o.copyUp(path); errno != 0 {
+		return errno
+	}
+	return o.upper.Lchown(path, uid, gid)
+}
+
+// Rename implements FS.Rename
+func (o *overlayFS) Rename(from, to string) syscall.Errno {
+	from, errno := o.lookupParent(from)
+	if errno != 0 {
+		return errno
+	}
+	if to, errno = o.lookupParent(to); errno != 0 {
+		return errno
+	}
+	if isReserved(to) {
+		return syscall.EINVAL
+	}
+	_, fromSt, errno := o.resolve(from)
+	if errno != 0 {
+		return errno
+	}
+	if fromSt.Mode.IsDir() && o.inLower(from) {
+		return syscall.EXDEV // Lower directories would need to be copied.
+	}
+
+	toExists := false
+	if _, toSt, errno := o.resolve(to); errno == 0 {
+		switch {
+		case fromSt.Mode.IsDir() && !toSt.Mode.IsDir():
+			return syscall.ENOTDIR
+		case !fromSt.Mode.IsDir() && toSt.Mode.IsDir():
+			return syscall.EISDIR
+		case toSt.Mode.IsDir(): // Only empty directories can be replaced.
+			if errno = o.Rmdir(to); errno != 0 {
+				return errno
+			}
+		default: // The rename replaces the file.
+			toExists = true
+		}
+	} else if errno != syscall.ENOENT {
+		return errno
+	}
+
+	if errno = o.copyUp(from); errno != 0 {
+		return errno
+	}
+	var removedWhiteout bool
+	if toExists {
+		errno = o.copyUp(parentDir(to))
+	} else {
+		removedWhiteout, errno = o.prepareCreate(to)
+	}
+	if errno != 0 {
+		return errno
+	}
+	if errno = o.upper.Rename(from, to); errno != 0 {
+		return errno
+	}
+	if removedWhiteout && fromSt.Mode.IsDir() {
+		// Don't merge with the directory previously removed from the lower
+		// layer.
+		if errno = WriteFile(o.upper, opaque(to), nil, 0o600); errno != 0 {
+			return errno
+		}
+	}
+	return o.remove(from)
+}
+
+// Rmdir implements FS.Rmdir
+func (o *overlayFS) Rmdir(path string) syscall.Errno {
+	path, errno := o.lookupParent(path)
+	if errno != 0 {
+		return errno
+	}
+	_, st, errno := o.resolve(path)
+	if errno != 0 {
+		return errno
+	} else if !st.Mode.IsDir() {
+		return syscall.ENOTDIR
+	}
+
+	dirents, errno := ReadDir(o, path)
+	if errno != 0 {
+		return errno
+	} else if len(dirents) > 0 {
+		return syscall.ENOTEMPTY
+	}
+
+	// The upper directory may contain whiteouts, which can be removed now.
+	if exists(o.upper, path) {
+		if errno = removeAll(o.upper, path); errno != 0 {
+			return errno
+		}
+	}
+	return o.remove(path)
+}
+
+// Unlink implements FS.Unlink
+func (o *overlayFS) Unlink(path string) syscall.Errno {
+	path, errno := o.lookupParent(path)
+	if errno != 0 {
+		return errno
+	}
+	layer, st, errno := o.resolve(path)
+	if errno != 0 {
+		return errno
+	} else if st.Mode.IsDir() {
+		return syscall.EISDIR
+	}
+	if layer == o.upper {
+		if errno = o.upper.Unlink(path); errno != 0 {
+			return errno
+		}
+	}
+	return o.remove(path)
+}
+
+// Link implements FS.Link
+func (o *overlayFS) Link(oldPath, newPath string) syscall.Errno {
+	oldPath, errno := o.lookupParent(oldPath)
+	if errno != 0 {
+		return errno
+	}
+	if newPath, errno = o.lookupParent(newPath); errno != 0 {
+		return errno
+	}
+	if errno = o.copyUp(oldPath); errno != 0 {
+		return errno
+	}
+	if _, errno = o.prepareCreate(newPath); errno != 0 {
+		return errno
+	}
+	return o.upper.Link(oldPath, newPath)
+}
+
+// Symlink implements FS.Symlink
+func (o *overlayFS) Symlink(oldPath, linkName string) syscall.Errno {
+	linkName, errno := o.lookupParent(linkName)
+	if errno != 0 {
+		return errno
+	}
+	if _, errno = o.prepareCreate(linkName); errno != 0 {
+		return errno
+	}
+	return o.upper.Symlink(oldPath, linkName)
+}
+
+// Mknod implements FS.Mknod
+func (o *overlayFS) Mknod(path string, mode fs.FileMode, dev uint64) syscall.Errno {
+	path, errno := o.lookupParent(path)
+	if errno != 0 {
+		return errno
+	}
+	if _, errno = o.prepareCreate(path); errno != 0 {
+		return errno
+	}
+	return o.upper.Mknod(path, mode, dev)
+}
+
+// Truncate implements FS.Truncate
+func (o *overlayFS) Truncate(path string, size int64) syscall.Errno {
+	path, errno := o.lookup(path, true)
+	if errno != 0 {
+		return errno
+	}
+	if errno = o.copyUp(path); errno != 0 {
+		return errno
+	}
+	return o.upper.Truncate(path, size)
+}
+
+// Utimens implements FS.Utimens
+func (o *overlayFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	path, errno := o.lookup(path, symlinkFollow)
+	if errno != 0 {
+		return errno
+	}
+	if errno = o.copyUp(path); errno != 0 {
+		return errno
+	}
+	return o.upper.Utimens(path, times, symlinkFollow)
+}
@@ -0,0 +1,222 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// newOverlayTestFS returns an overlay with a lower layer containing "file"
+// and "dir/file", and an empty upper layer.
+func newOverlayTestFS(t *testing.T) (overlay, upper, lower FS) {
+	lower = NewDirFS(t.TempDir())
+	require.Zero(t, WriteFile(lower, "file", []byte("lower"), 0o600))
+	require.Zero(t, lower.Mkdir("dir", 0o700))
+	require.Zero(t, WriteFile(lower, "dir/file", []byte("lower"), 0o600))
+
+	upper = NewDirFS(t.TempDir())
+	return NewOverlayFS(upper, lower), upper, lower
+}
+
+// requireDirNames requires the entries of `dir` in `fsys` are `expected`.
+func requireDirNames(t *testing.T, fsys FS, dir string, expected ...string) {
+	dirents, errno := ReadDir(fsys, dir)
+	require.Zero(t, errno)
+	var names []string
+	for _, e := range dirents {
+		names = append(names, e.Name())
+	}
+	require.Equal(t, expected, names)
+}
+
+func TestOverlayFS_copyUp(t *testing.T) {
+	testFS, upper, lower := newOverlayTestFS(t)
+
+	// Reads fall through to the lower layer.
+	b, errno := ReadFile(testFS, "dir/file")
+	require.Zero(t, errno)
+	require.Equal(t, "lower", string(b))
+	require.False(t, exists(upper, "dir"))
+
+	// Writing copies the file and its parent to the upper layer.
+	f, errno := testFS.OpenFile("dir/file", os.O_WRONLY|os.O_APPEND, 0)
+	require.Zero(t, errno)
+	_, err := f.(io.Writer).Write([]byte("+upper"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b, errno = ReadFile(testFS, "dir/file")
+	require.Zero(t, errno)
+	require.Equal(t, "lower+upper", string(b))
+	b, errno = ReadFile(upper, "dir/file")
+	require.Zero(t, errno)
+	require.Equal(t, "lower+upper", string(b))
+
+	// The lower layer is unchanged.
+	b, errno = ReadFile(lower, "dir/file")
+	require.Zero(t, errno)
+	require.Equal(t, "lower", string(b))
+
+	// Metadata changes also copy up.
+	require.Zero(t, testFS.Truncate("file", 2))
+	b, errno = ReadFile(testFS, "file")
+	require.Zero(t, errno)
+	require.Equal(t, "lo", string(b))
+	b, errno = ReadFile(lower, "file")
+	require.Zero(t, errno)
+	require.Equal(t, "lower", string(b))
+}
+
+func TestOverlayFS_whiteout(t *testing.T) {
+	testFS, upper, lower := newOverlayTestFS(t)
+
+	require.Zero(t, testFS.Unlink("dir/file"))
+	_, errno := testFS.Lstat("dir/file")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+	requireDirNames(t, testFS, "dir")
+
+	// The lower layer is unchanged, and the whiteout is only in the upper.
+	require.True(t, exists(lower, "dir/file"))
+	require.True(t, exists(upper, "dir/.wh.file"))
+
+	// Whiteout names are reserved.
+	_, errno = testFS.Lstat("dir/.wh.file")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+	_, errno = testFS.OpenFile("dir/.wh.other", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, syscall.EINVAL, errno)
+
+	// Creating the file again removes the whiteout.
+	require.Zero(t, WriteFile(testFS, "dir/file", []byte("upper"), 0o600))
+	b, errno := ReadFile(testFS, "dir/file")
+	require.Zero(t, errno)
+	require.Equal(t, "upper", string(b))
+	require.False(t, exists(upper, "dir/.wh.file"))
+
+	// Removing a directory hides its contents in the lower layer.
+	require.Zero(t, testFS.Unlink("dir/file"))
+	require.Zero(t, testFS.Rmdir("dir"))
+	_, errno = testFS.Lstat("dir/file")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+
+	// A new directory with the same name doesn't merge with the lower one.
+	require.Zero(t, testFS.Mkdir("dir", 0o700))
+	requireDirNames(t, testFS, "dir")
+	require.True(t, exists(lower, "dir/file"))
+}
+
+func TestOverlayFS_ReadDir(t *testing.T) {
+	testFS, upper, _ := newOverlayTestFS(t)
+	require.Zero(t, upper.Mkdir("dir", 0o700))
+	require.Zero(t, WriteFile(upper, "dir/upper", nil, 0o600))
+	require.Zero(t, WriteFile(upper, "dir/file", []byte("upper"), 0o600))
+	require.Zero(t, WriteFile(upper, "only-upper", nil, 0o600))
+
+	// Listings are merged, with names in both layers listed once.
+	requireDirNames(t, testFS, ".", "dir", "file", "only-upper")
+	requireDirNames(t, testFS, "dir", "file", "upper")
+
+	// The upper file hides the lower one.
+	b, errno := ReadFile(testFS, "dir/file")
+	require.Zero(t, errno)
+	require.Equal(t, "upper", string(b))
+
+	// Whiteouts aren't listed, and hide lower entries.
+	require.Zero(t, testFS.Unlink("file"))
+	requireDirNames(t, testFS, ".", "dir", "only-upper")
+}
+
+func TestOverlayFS_symlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+
+	testFS, upper, lower := newOverlayTestFS(t)
+	require.Zero(t, lower.Symlink("file", "link"))
+	require.Zero(t, lower.Symlink("/dir/file", "abs"))
+	require.Zero(t, lower.Symlink("dir", "dirlink"))
+	require.Zero(t, lower.Symlink("../missing", "dir/dangling"))
+	require.Zero(t, upper.Symlink("dir/file", "upper-link"))
+
+	// Links resolve through the overlay, so they see files copied up after.
+	require.Zero(t, WriteFile(testFS, "file", []byte("upper"), 0o600))
+	require.Zero(t, WriteFile(testFS, "dir/file", []byte("upper"), 0o600))
+	for _, name := range []string{"link", "abs", "dirlink/file", "upper-link"} {
+		b, errno := ReadFile(testFS, name)
+		require.Zero(t, errno, name)
+		require.Equal(t, "upper", string(b), name)
+
+		st, errno := testFS.Stat(name)
+		require.Zero(t, errno, name)
+		require.Equal(t, int64(5), st.Size, name)
+	}
+
+	// Writing through a lower link copies up its target, not the link.
+	require.Zero(t, WriteFile(testFS, "dirlink/file", []byte("again"), 0o600))
+	require.False(t, exists(upper, "dirlink"))
+	b, errno := ReadFile(testFS, "dir/file")
+	require.Zero(t, errno)
+	require.Equal(t, "again", string(b))
+
+	// Creating through a dangling link creates its target.
+	require.Zero(t, WriteFile(testFS, "dir/dangling", []byte("created"), 0o600))
+	b, errno = ReadFile(upper, "missing")
+	require.Zero(t, errno)
+	require.Equal(t, "created", string(b))
+
+	// The links themselves aren't followed by Lstat and Readlink.
+	st, errno := testFS.Lstat("dirlink")
+	require.Zero(t, errno)
+	require.Equal(t, fs.ModeSymlink, st.Mode.Type())
+	target, errno := testFS.Readlink("dirlink")
+	require.Zero(t, errno)
+	require.Equal(t, "dir", target)
+
+	// The lower layer is unchanged.
+	b, errno = ReadFile(lower, "dir/file")
+	require.Zero(t, errno)
+	require.Equal(t, "lower", string(b))
+
+	// Removing through a lower link removes the file in its target.
+	require.Zero(t, testFS.Unlink("dirlink/file"))
+	_, errno = testFS.Lstat("dir/file")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+	require.False(t, exists(upper, "dirlink"))
+	st, errno = testFS.Lstat("dirlink")
+	require.Zero(t, errno)
+	require.Equal(t, fs.ModeSymlink, st.Mode.Type())
+	require.True(t, exists(lower, "dir/file"))
+
+	require.Zero(t, lower.Symlink("loop", "loop"))
+	_, errno = testFS.Stat("loop")
+	require.EqualErrno(t, syscall.ELOOP, errno)
+}
+
+func TestOverlayFS_Rename(t *testing.T) {
+	testFS, _, lower := newOverlayTestFS(t)
+
+	// A lower file is copied up, then hidden at its old name.
+	require.Zero(t, testFS.Rename("file", "dir/renamed"))
+	requireDirNames(t, testFS, ".", "dir")
+	requireDirNames(t, testFS, "dir", "file", "renamed")
+	require.True(t, exists(lower, "file"))
+
+	// Replacing a lower file.
+	require.Zero(t, testFS.Rename("dir/renamed", "dir/file"))
+	requireDirNames(t, testFS, "dir", "file")
+	b, errno := ReadFile(testFS, "dir/file")
+	require.Zero(t, errno)
+	require.Equal(t, "lower", string(b))
+
+	// Directories of the lower layer aren't renamed.
+	require.EqualErrno(t, syscall.EXDEV, testFS.Rename("dir", "other"))
+
+	// But directories only in the upper layer are.
+	require.Zero(t, testFS.Mkdir("new", 0o700))
+	require.Zero(t, testFS.Rename("new", "other"))
+	requireDirNames(t, testFS, ".", "dir", "other")
+}
@@ -0,0 +1,207 @@
+package sysfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// NewProcFS returns a read-only synthetic filesystem, similar to /proc, whose
+// file contents are served by `provider` each time a file is opened. This
+// allows a host to expose dynamic data to a guest, such as "self/status".
+//
+// `names` are the slash-separated paths of the files the filesystem declares,
+// relative to its root. Parent directories are implied by these names. A
+// declared file only exists while `provider` returns true for it.
+func NewProcFS(names []string, provider func(path string) ([]byte, bool)) FS {
+	ret := &procFS{provider: provider, dirs: map[string][]string{".": nil}, files: map[string]struct{}{}}
+	for _, name := range names {
+		name = procPath(name)
+		ret.files[name] = struct{}{}
+
+		// Add the name to each parent directory, creating them as needed.
+		for child, dir := name, path.Dir(name); ; child, dir = dir, path.Dir(dir) {
+			_, exists := ret.dirs[dir]
+			ret.dirs[dir] = appendUnique(ret.dirs[dir], path.Base(child))
+			if exists || dir == "." {
+				break
+			}
+		}
+	}
+	for _, children := range ret.dirs {
+		sort.Strings(children)
+	}
+	return ret
+}
+
+// procPath cleans the path, coercing the root to ".".
+func procPath(name string) string {
+	if name = cleanPath(name); name == "" {
+		return "."
+	}
+	return name
+}
+
+func appendUnique(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+type procFS struct {
+	// readFS is embedded for its write methods, which return syscall.EROFS.
+	readFS
+	provider func(path string) ([]byte, bool)
+	// dirs are the names in each directory, keyed by the cleaned path.
+	dirs map[string][]string
+	// files are the cleaned paths of declared files.
+	files map[string]struct{}
+}
+
+// String implements fmt.Stringer
+func (p *procFS) String() string {
+	return "proc"
+}
+
+// Open implements the same method as documented on fs.FS
+func (p *procFS) Open(name string) (fs.File, error) {
+	return fsOpen(p, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (p *procFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	switch flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR) {
+	case os.O_WRONLY, os.O_RDWR:
+		return nil, syscall.ENOSYS
+	}
+
+	path = procPath(path)
+	if names, ok := p.dirs[path]; ok {
+		return &procDir{p: p, path: path, names: names}, 0
+	} else if data, errno := p.read(path); errno != 0 {
+		return nil, errno
+	} else {
+		return &procFile{Reader: bytes.NewReader(data), info: procInfo{name: path, size: int64(len(data)), mode: 0o444}}, 0
+	}
+}
+
+func (p *procFS) read(path string) ([]byte, syscall.Errno) {
+	if _, ok := p.files[path]; !ok {
+		return nil, syscall.ENOENT
+	} else if data, ok := p.provider(path); !ok {
+		return nil, syscall.ENOENT
+	} else {
+		return data, 0
+	}
+}
+
+// Lstat implements FS.Lstat
+func (p *procFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	return p.Stat(path) // there are no symbolic links.
+}
+
+// Stat implements FS.Stat
+func (p *procFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	path = procPath(path)
+	if _, ok := p.dirs[path]; ok {
+		return platform.Stat_t{Mode: fs.ModeDir | 0o555, Nlink: 1}, 0
+	} else if data, errno := p.read(path); errno != 0 {
+		return platform.Stat_t{}, errno
+	} else {
+		return platform.Stat_t{Mode: 0o444, Nlink: 1, Size: int64(len(data))}, 0
+	}
+}
+
+// Readlink implements FS.Readlink
+func (p *procFS) Readlink(string) (string, syscall.Errno) {
+	return "", syscall.EINVAL // there are no symbolic links.
+}
+
+// procFile is a snapshot of the data returned by the provider on open.
+type procFile struct {
+	*bytes.Reader
+	info procInfo
+}
+
+// GoString implements fmt.GoStringer
+func (f *procFile) GoString() string {
+	return fmt.Sprintf("&sysfs.procFile{path: %q}", f.info.name)
+}
+
+// Stat implements fs.File
+func (f *procFile) Stat() (fs.FileInfo, error) { return &f.info, nil }
+
+// Close implements fs.File
+func (f *procFile) Close() error { return nil }
+
+// procDir lists the names declared in a directory of procFS.
+type procDir struct {
+	p     *procFS
+	path  string
+	names []string
+}
+
+// GoString implements fmt.GoStringer
+func (d *procDir) GoString() string {
+	return fmt.Sprintf("&sysfs.procDir{path: %q}", d.path)
+}
+
+// Stat implements fs.File
+func (d *procDir) Stat() (fs.FileInfo, error) {
+	return &procInfo{name: d.path, mode: fs.ModeDir | 0o555}, nil
+}
+
+// Read implements fs.File
+func (d *procDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: syscall.EISDIR}
+}
+
+// Close implements fs.File
+func (d *procDir) Close() error { return nil }
+
+// ReadDir implements fs.ReadDirFile
+func (d *procDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	var list []fs.DirEntry
+	for len(d.names) > 0 && (count <= 0 || len(list) < count) {
+		name := d.names[0]
+		d.names = d.names[1:]
+
+		st, errno := d.p.Stat(path.Join(d.path, name))
+		if errno == syscall.ENOENT {
+			continue // the provider no longer has the file.
+		} else if errno != 0 {
+			return list, errno
+		}
+		list = append(list, &dirInfo{name: name, stat: st})
+	}
+
+	if len(list) == 0 && count > 0 {
+		return nil, io.EOF
+	}
+	return list, nil
+}
+
+// procInfo implements fs.FileInfo for procFS.
+type procInfo struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+func (i *procInfo) Name() string       { return path.Base(i.name) }
+func (i *procInfo) Size() int64        { return i.size }
+func (i *procInfo) Mode() fs.FileMode  { return i.mode }
+func (i *procInfo) ModTime() time.Time { return time.Unix(0, 0) }
+func (i *procInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *procInfo) Sys() interface{}   { return nil }
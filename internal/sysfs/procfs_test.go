@@ -0,0 +1,76 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func newTestProcFS() FS {
+	status := 0
+	return NewProcFS([]string{"self/status", "uptime", "missing"}, func(path string) ([]byte, bool) {
+		switch path {
+		case "self/status":
+			status++ // dynamic
+			return []byte{'0' + byte(status)}, true
+		case "uptime":
+			return []byte("1234"), true
+		}
+		return nil, false
+	})
+}
+
+func TestProcFS_Read(t *testing.T) {
+	testFS := newTestProcFS()
+
+	for _, expected := range []string{"1", "2"} {
+		f, errno := testFS.OpenFile("/self/status", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		b, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(b))
+		require.NoError(t, f.Close())
+	}
+
+	st, errno := testFS.Stat("uptime")
+	require.Zero(t, errno)
+	require.Equal(t, fs.FileMode(0o444), st.Mode)
+	require.Equal(t, int64(4), st.Size)
+
+	// Names not declared, or declared but not provided, don't exist.
+	for _, p := range []string{"missing", "other"} {
+		_, errno = testFS.OpenFile(p, os.O_RDONLY, 0)
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	}
+
+	// Writes are rejected.
+	_, errno = testFS.OpenFile("uptime", os.O_WRONLY, 0)
+	require.EqualErrno(t, syscall.ENOSYS, errno)
+	require.EqualErrno(t, syscall.EROFS, testFS.Unlink("uptime"))
+}
+
+func TestProcFS_ReadDir(t *testing.T) {
+	testFS := newTestProcFS()
+
+	for _, root := range []string{"", ".", "/"} {
+		f, errno := testFS.OpenFile(root, os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		dirents := requireReaddir(t, f, -1, false)
+		require.NoError(t, f.Close())
+
+		require.Equal(t, 2, len(dirents))
+		require.Equal(t, "self", dirents[0].Name)
+		require.True(t, dirents[0].IsDir())
+		require.Equal(t, "uptime", dirents[1].Name)
+		require.False(t, dirents[1].IsDir())
+	}
+
+	f, errno := testFS.OpenFile("self", os.O_RDONLY, 0)
+	require.Zero(t, errno)
+	defer f.Close()
+	require.Equal(t, []string{"status"}, requireReaddirnames(t, f, -1))
+}
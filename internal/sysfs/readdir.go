@@ -0,0 +1,295 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// ReadDir is like os.ReadDir, except the path is relative to the file system,
+// and syscall.Errno are returned instead of an os.PathError. A zero
+// syscall.Errno is success.
+//
+// Entries are sorted by name. The name and type of each entry are read with
+// the directory listing, so inspecting them doesn't stat the file. Calling
+// Info doesn't stat either: only the fields of the fs.FileInfo the listing
+// didn't include, such as Size and ModTime, stat the file on first use. This
+// avoids a stat per entry for callers that only need the type, for example
+// fs.FileInfo.IsDir.
+func ReadDir(fsys FS, dir string) ([]fs.DirEntry, syscall.Errno) {
+	f, errno := fsys.OpenFile(dir, os.O_RDONLY, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	defer f.Close()
+
+	var entries []fs.DirEntry
+	if rd, ok := f.(fs.ReadDirFile); ok {
+		es, err := rd.ReadDir(-1)
+		if errno = platform.UnwrapOSError(err); errno != 0 {
+			return nil, errno
+		}
+		entries = make([]fs.DirEntry, 0, len(es))
+		for _, e := range es {
+			entries = append(entries, &dirEntry{fs: fsys, path: path.Join(dir, e.Name()), name: e.Name(), typ: e.Type()})
+		}
+	} else {
+		dirents, errno := platform.Readdir(f, -1)
+		if errno != 0 {
+			return nil, errno
+		}
+		entries = make([]fs.DirEntry, 0, len(dirents))
+		for _, d := range dirents {
+			entries = append(entries, &dirEntry{fs: fsys, path: path.Join(dir, d.Name), name: d.Name, typ: d.Type})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, 0
+}
+
+// DirsFirst orders directories before other entries, then by name. This is
+// the default order of ReadDirSorted.
+func DirsFirst(a, b fs.DirEntry) bool {
+	if aDir, bDir := a.IsDir(), b.IsDir(); aDir != bDir {
+		return aDir
+	}
+	return a.Name() < b.Name()
+}
+
+// ReadDirSorted is like ReadDir, except entries are sorted by `less`, or
+// DirsFirst if nil. The sort is stable, so entries `less` considers equal
+// remain sorted by name.
+func ReadDirSorted(fsys FS, dir string, less func(a, b fs.DirEntry) bool) ([]fs.DirEntry, syscall.Errno) {
+	entries, errno := ReadDir(fsys, dir)
+	if errno != 0 {
+		return nil, errno
+	}
+	if less == nil {
+		less = DirsFirst
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return less(entries[i], entries[j]) })
+	return entries, 0
+}
+
+// direntBatch is how many entries DirIterator reads at a time.
+const direntBatch = 64
+
+// DirIterator iterates over the entries of a directory, like ReadDir, except
+// it reads them in batches instead of all at once. This allows callers to
+// stop early without reading the rest of a large directory. For example:
+//
+//	it, errno := sysfs.NewDirIterator(fsys, dir)
+//	if errno != 0 {
+//		return errno
+//	}
+//	defer it.Close()
+//
+//	for it.Next() {
+//		entry := it.Entry()
+//		// ...
+//	}
+//	return it.Err()
+//
+// Unlike ReadDir, entries are in directory order, not sorted by name.
+type DirIterator struct {
+	fsys  FS
+	dir   string
+	f     fs.File
+	n     int // the batch size, which tests override.
+	batch []*platform.Dirent
+	entry fs.DirEntry
+	errno syscall.Errno
+}
+
+// NewDirIterator opens the directory `dir` to iterate over its entries. A zero
+// syscall.Errno is success. The caller must Close the iterator when done.
+func NewDirIterator(fsys FS, dir string) (*DirIterator, syscall.Errno) {
+	f, errno := fsys.OpenFile(dir, os.O_RDONLY, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &DirIterator{fsys: fsys, dir: dir, f: f, n: direntBatch}, 0
+}
+
+// Next advances to the next entry, returning false when there are no more
+// entries, or an error occurred. See Err.
+func (it *DirIterator) Next() bool {
+	if it.f == nil || it.errno != 0 {
+		return false
+	}
+	if len(it.batch) == 0 {
+		if it.batch, it.errno = platform.Readdir(it.f, it.n); it.errno != 0 || len(it.batch) == 0 {
+			it.entry = nil
+			return false
+		}
+	}
+	d := it.batch[0]
+	it.batch = it.batch[1:]
+	it.entry = &dirEntry{fs: it.fsys, path: path.Join(it.dir, d.Name), name: d.Name, typ: d.Type}
+	return true
+}
+
+// Entry returns the entry Next advanced to.
+func (it *DirIterator) Entry() fs.DirEntry {
+	return it.entry
+}
+
+// Err returns the error which stopped Next, or zero if it reached the end of
+// the directory.
+func (it *DirIterator) Err() syscall.Errno {
+	return it.errno
+}
+
+// Close closes the directory, after which Next returns false. A zero
+// syscall.Errno is success.
+func (it *DirIterator) Close() syscall.Errno {
+	if it.f == nil {
+		return 0
+	}
+	f := it.f
+	it.f, it.batch, it.entry = nil, nil, nil
+	return platform.UnwrapOSError(f.Close())
+}
+
+// TreeIterator iterates over the entries of a directory tree, depth-first,
+// like fs.WalkDir. Unlike fs.WalkDir, the caller drives iteration, so it can
+// stop at any point without a callback. Usage is the same as DirIterator,
+// with Path returning the path of each entry.
+//
+// Directories are opened when the iterator descends into them, and closed as
+// soon as their entries are exhausted, so at most one directory per level of
+// depth is open at a time. Symbolic links are not followed.
+type TreeIterator struct {
+	fsys FS
+	// stack are the open directories, from the root to the current one.
+	stack   []*DirIterator
+	path    string
+	entry   fs.DirEntry
+	descend bool
+	errno   syscall.Errno
+}
+
+// NewTreeIterator opens the directory `dir` to iterate over the tree under
+// it. A zero syscall.Errno is success. The caller must Close the iterator
+// when done.
+func NewTreeIterator(fsys FS, dir string) (*TreeIterator, syscall.Errno) {
+	it, errno := NewDirIterator(fsys, dir)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &TreeIterator{fsys: fsys, stack: []*DirIterator{it}}, 0
+}
+
+// Next advances to the next entry, returning false when there are no more
+// entries, or an error occurred. See Err.
+func (t *TreeIterator) Next() bool {
+	if t.errno != 0 {
+		return false
+	}
+	if t.descend {
+		t.descend = false
+		it, errno := NewDirIterator(t.fsys, t.path)
+		if errno != 0 {
+			return t.fail(errno)
+		}
+		t.stack = append(t.stack, it)
+	}
+	for len(t.stack) > 0 {
+		top := t.stack[len(t.stack)-1]
+		if top.Next() {
+			t.entry = top.Entry()
+			t.path = top.Entry().(*dirEntry).path
+			t.descend = t.entry.IsDir()
+			return true
+		}
+		errno := top.Err()
+		if e := top.Close(); errno == 0 {
+			errno = e
+		}
+		t.stack = t.stack[:len(t.stack)-1]
+		if errno != 0 {
+			return t.fail(errno)
+		}
+	}
+	t.path, t.entry = "", nil
+	return false
+}
+
+func (t *TreeIterator) fail(errno syscall.Errno) bool {
+	t.errno = errno
+	t.path, t.entry = "", nil
+	return false
+}
+
+// SkipDir skips the entries of the directory Next advanced to, if any.
+func (t *TreeIterator) SkipDir() {
+	t.descend = false
+}
+
+// Path returns the path of the entry Next advanced to, relative to the file
+// system.
+func (t *TreeIterator) Path() string {
+	return t.path
+}
+
+// Entry returns the entry Next advanced to.
+func (t *TreeIterator) Entry() fs.DirEntry {
+	return t.entry
+}
+
+// Err returns the error which stopped Next, or zero if it reached the end of
+// the tree.
+func (t *TreeIterator) Err() syscall.Errno {
+	return t.errno
+}
+
+// Close closes any open directories, after which Next returns false. A zero
+// syscall.Errno is success.
+func (t *TreeIterator) Close() (errno syscall.Errno) {
+	for _, it := range t.stack {
+		if e := it.Close(); errno == 0 {
+			errno = e
+		}
+	}
+	t.stack, t.path, t.entry, t.descend = nil, "", nil, false
+	return
+}
+
+// dirEntry is a fs.DirEntry populated from a directory listing. Like
+// os.DirEntry, Info stats the file, unless the stat is already known.
+type dirEntry struct {
+	fs   FS
+	path string
+	name string
+	typ  fs.FileMode
+
+	// st is the result of FS.Lstat, populated on first use.
+	st *platform.Stat_t
+}
+
+// Name implements fs.DirEntry
+func (e *dirEntry) Name() string { return e.name }
+
+// IsDir implements fs.DirEntry
+func (e *dirEntry) IsDir() bool { return e.typ.IsDir() }
+
+// Type implements fs.DirEntry
+func (e *dirEntry) Type() fs.FileMode { return e.typ }
+
+// Info implements fs.DirEntry, returning an error if the file can't be
+// stat, such as when it was removed after the listing.
+func (e *dirEntry) Info() (fs.FileInfo, error) {
+	if e.st == nil {
+		st, errno := e.fs.Lstat(e.path)
+		if errno != 0 {
+			return nil, errno
+		}
+		e.st = &st
+	}
+	return &dirInfo{name: e.name, stat: *e.st}, nil
+}
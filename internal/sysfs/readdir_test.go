@@ -0,0 +1,305 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestReadDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	tests := []struct {
+		name string
+		fs   FS
+	}{
+		{name: "dirFS", fs: NewDirFS(tmpDir)},
+		{name: "fs.FS", fs: Adapt(fstest.FS)},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			counting := &statCountingFS{FS: tc.fs}
+
+			entries, errno := ReadDir(counting, "dir")
+			require.Zero(t, errno)
+			require.Equal(t, 3, len(entries))
+
+			// Names and types don't require a stat.
+			for i, expected := range []struct {
+				name  string
+				isDir bool
+			}{{"-", false}, {"a-", true}, {"ab-", false}} {
+				require.Equal(t, expected.name, entries[i].Name())
+				require.Equal(t, expected.isDir, entries[i].IsDir())
+				require.Equal(t, expected.isDir, entries[i].Type().IsDir())
+			}
+			require.Zero(t, counting.stats)
+
+			// Info stats once.
+			info, err := entries[0].Info()
+			require.NoError(t, err)
+			require.Equal(t, fs.FileMode(0o400), info.Mode())
+			require.Zero(t, info.Size())
+			_, err = entries[0].Info()
+			require.NoError(t, err)
+			require.Equal(t, 1, counting.stats)
+		})
+	}
+}
+
+// TestReadDir_Info ensures Info returns an error when the file can't be stat,
+// instead of a FileInfo without its permissions, size or times.
+func TestReadDir_Info(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), nil, 0o600))
+	testFS := NewDirFS(tmpDir)
+
+	entries, errno := ReadDir(testFS, ".")
+	require.Zero(t, errno)
+	require.NoError(t, os.Remove(path.Join(tmpDir, "file")))
+
+	_, err := entries[0].Info()
+	require.EqualErrno(t, syscall.ENOENT, err.(syscall.Errno))
+}
+
+func TestReadDir_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+	testFS := NewDirFS(tmpDir)
+
+	_, errno := ReadDir(testFS, "missing")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+
+	_, errno = ReadDir(testFS, "animals.txt")
+	require.EqualErrno(t, syscall.ENOTDIR, errno)
+}
+
+func TestReadDirSorted(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "a"), nil, 0o600))
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "b"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "cc"), nil, 0o600))
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "d"), 0o700))
+	testFS := NewDirFS(tmpDir)
+
+	names := func(entries []fs.DirEntry) (names []string) {
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		return
+	}
+
+	t.Run("default", func(t *testing.T) {
+		entries, errno := ReadDirSorted(testFS, ".", nil)
+		require.Zero(t, errno)
+		require.Equal(t, []string{"b", "d", "a", "cc"}, names(entries))
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		// Sort by name length, which is stable for names of the same length.
+		entries, errno := ReadDirSorted(testFS, ".", func(a, b fs.DirEntry) bool {
+			return len(a.Name()) > len(b.Name())
+		})
+		require.Zero(t, errno)
+		require.Equal(t, []string{"cc", "a", "b", "d"}, names(entries))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, errno := ReadDirSorted(testFS, "missing", nil)
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+}
+
+// statCountingFS counts calls to Stat or Lstat.
+type statCountingFS struct {
+	FS
+	stats int
+}
+
+// Lstat implements FS.Lstat
+func (c *statCountingFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	c.stats++
+	return c.FS.Lstat(path)
+}
+
+// Stat implements FS.Stat
+func (c *statCountingFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	c.stats++
+	return c.FS.Stat(path)
+}
+
+func TestDirIterator(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	// readAll iterates over all entries in "dir", reading one at a time to
+	// exercise batching.
+	readAll := func(fsys FS) ([]string, syscall.Errno) {
+		it, errno := NewDirIterator(fsys, "dir")
+		require.Zero(t, errno)
+		defer it.Close()
+		it.n = 1
+
+		var names []string
+		for it.Next() {
+			names = append(names, it.Entry().Name())
+		}
+		sort.Strings(names)
+		return names, it.Err()
+	}
+
+	t.Run("all", func(t *testing.T) {
+		for _, fsys := range []FS{NewDirFS(tmpDir), Adapt(fstest.FS)} {
+			names, errno := readAll(fsys)
+			require.Zero(t, errno)
+			require.Equal(t, []string{"-", "a-", "ab-"}, names)
+		}
+	})
+
+	t.Run("entries are typed", func(t *testing.T) {
+		it, errno := NewDirIterator(NewDirFS(tmpDir), "dir")
+		require.Zero(t, errno)
+		defer it.Close()
+
+		for it.Next() {
+			require.Equal(t, it.Entry().Name() == "a-", it.Entry().IsDir())
+		}
+		require.Zero(t, it.Err())
+	})
+
+	t.Run("stop early", func(t *testing.T) {
+		it, errno := NewDirIterator(NewDirFS(tmpDir), "dir")
+		require.Zero(t, errno)
+
+		require.True(t, it.Next())
+		require.Zero(t, it.Close())
+
+		// Closing ends the iteration.
+		require.False(t, it.Next())
+		require.Nil(t, it.Entry())
+		require.Zero(t, it.Close())
+	})
+
+	t.Run("error mid-iteration", func(t *testing.T) {
+		testFS := FaultFS(NewDirFS(tmpDir), failNth("ReadDir", 2, syscall.EACCES))
+		names, errno := readAll(testFS)
+		require.EqualErrno(t, syscall.EACCES, errno)
+		require.Equal(t, 1, len(names))
+	})
+
+	t.Run("not a directory", func(t *testing.T) {
+		_, errno := NewDirIterator(NewDirFS(tmpDir), "missing")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+
+		it, errno := NewDirIterator(NewDirFS(tmpDir), "animals.txt")
+		require.Zero(t, errno)
+		defer it.Close()
+
+		require.False(t, it.Next())
+		require.EqualErrno(t, syscall.ENOTDIR, it.Err())
+	})
+}
+
+func TestTreeIterator(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	var expected []string
+	require.NoError(t, fs.WalkDir(os.DirFS(tmpDir), ".", func(path string, d fs.DirEntry, err error) error {
+		if path != "." {
+			expected = append(expected, path)
+		}
+		return err
+	}))
+
+	// requireNoLeaks ensures each directory opened was closed.
+	requireNoLeaks := func(t *testing.T, counting *CountingFS, failedOpens uint64) {
+		ops := counting.Snapshot().Ops
+		require.NotEqual(t, uint64(0), ops["OpenFile"])
+		require.Equal(t, ops["OpenFile"]-failedOpens, ops["Close"])
+	}
+
+	t.Run("all", func(t *testing.T) {
+		counting := NewCountingFS(NewDirFS(tmpDir), false)
+		it, errno := NewTreeIterator(counting, ".")
+		require.Zero(t, errno)
+
+		var paths []string
+		for it.Next() {
+			paths = append(paths, it.Path())
+			require.Equal(t, path.Base(it.Path()), it.Entry().Name())
+		}
+		require.Zero(t, it.Err())
+
+		sort.Strings(paths)
+		require.Equal(t, expected, paths)
+
+		// Directories were closed as soon as they were exhausted.
+		requireNoLeaks(t, counting, 0)
+		require.Zero(t, it.Close())
+	})
+
+	t.Run("stop early", func(t *testing.T) {
+		counting := NewCountingFS(NewDirFS(tmpDir), false)
+		it, errno := NewTreeIterator(counting, ".")
+		require.Zero(t, errno)
+
+		// Stop once inside a subdirectory.
+		for it.Next() && !strings.Contains(it.Path(), "/") {
+		}
+		require.Zero(t, it.Close())
+		require.False(t, it.Next())
+
+		requireNoLeaks(t, counting, 0)
+	})
+
+	t.Run("skip dir", func(t *testing.T) {
+		it, errno := NewTreeIterator(NewDirFS(tmpDir), ".")
+		require.Zero(t, errno)
+		defer it.Close()
+
+		var paths []string
+		for it.Next() {
+			if it.Entry().IsDir() {
+				it.SkipDir()
+			}
+			paths = append(paths, it.Path())
+		}
+		require.Zero(t, it.Err())
+
+		for _, p := range paths {
+			require.False(t, strings.Contains(p, "/"), p)
+		}
+	})
+
+	t.Run("error opening a subdirectory", func(t *testing.T) {
+		counting := NewCountingFS(FaultFS(NewDirFS(tmpDir), func(op, path string) error {
+			if op == "OpenFile" && path == "dir" {
+				return syscall.EACCES
+			}
+			return nil
+		}), false)
+		it, errno := NewTreeIterator(counting, ".")
+		require.Zero(t, errno)
+
+		for it.Next() {
+		}
+		require.EqualErrno(t, syscall.EACCES, it.Err())
+		require.Equal(t, "", it.Path())
+
+		require.Zero(t, it.Close())
+		requireNoLeaks(t, counting, 1)
+	})
+}
@@ -23,6 +23,7 @@ func NewReadFS(fs FS) FS {
 }
 
 type readFS struct {
+	ReadOnly
 	fs FS
 }
 
@@ -70,6 +71,11 @@ func (r *readFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, sys
 	return maskForReads(f), 0
 }
 
+// directoryFlagEnforced implements directoryFlagFS
+func (r *readFS) directoryFlagEnforced() bool {
+	return DirectoryFlagEnforced(r.fs)
+}
+
 // maskForReads masks the file with read-only interfaces used by wazero.
 //
 // This technique was adapted from similar code in zipkin-go.
@@ -190,6 +196,11 @@ func (r *readFS) Link(_, _ string) syscall.Errno {
 	return syscall.EROFS
 }
 
+// Mknod implements FS.Mknod
+func (r *readFS) Mknod(string, fs.FileMode, uint64) syscall.Errno {
+	return syscall.EROFS
+}
+
 // Symlink implements FS.Symlink
 func (r *readFS) Symlink(_, _ string) syscall.Errno {
 	return syscall.EROFS
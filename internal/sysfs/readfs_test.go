@@ -57,6 +57,14 @@ func TestReadFS_MkDir(t *testing.T) {
 	require.EqualErrno(t, syscall.EROFS, err)
 }
 
+func TestReadFS_Mknod(t *testing.T) {
+	writeable := NewDirFS(t.TempDir())
+	testFS := NewReadFS(writeable)
+
+	err := testFS.Mknod("fifo", fs.ModeNamedPipe|0o600, 0)
+	require.EqualErrno(t, syscall.EROFS, err)
+}
+
 func TestReadFS_Chmod(t *testing.T) {
 	writeable := NewDirFS(t.TempDir())
 	testFS := NewReadFS(writeable)
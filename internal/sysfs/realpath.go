@@ -0,0 +1,181 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// maxSymlinks is the maximum count of symbolic links followed when resolving
+// a path, the same as MAXSYMLINKS on Linux.
+const maxSymlinks = 40
+
+// RealPath returns the canonical absolute path of `name` in `fsys`, after
+// resolving any symbolic links, "." and "..", like realpath(3). The result is
+// from the perspective of the guest, so it starts with "/", which is the root
+// of `fsys`.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. The below are expected otherwise:
+//   - syscall.ENOENT: `name` or a link it traverses doesn't exist.
+//   - syscall.ENOTDIR: a component of `name`, other than the last, isn't a
+//     directory.
+//   - syscall.ELOOP: more than 40 symbolic links were followed.
+//
+// # Notes
+//
+//   - An absolute link target is resolved from the root of `fsys`, so links
+//     can't escape it. Targets that are absolute host paths resolve
+//     differently than on the host.
+func RealPath(fsys FS, name string) (string, syscall.Errno) {
+	resolved := "" // the root
+	rest := name
+	for links := 0; rest != ""; {
+		var elem string
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			elem, rest = rest[:i], rest[i+1:]
+		} else {
+			elem, rest = rest, ""
+		}
+
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if i := strings.LastIndexByte(resolved, '/'); i >= 0 {
+				resolved = resolved[:i]
+			} else {
+				resolved = "" // can't go above the root
+			}
+			continue
+		}
+
+		next := elem
+		if resolved != "" {
+			next = resolved + "/" + elem
+		}
+		st, errno := fsys.Lstat(next)
+		if errno != 0 {
+			return "", errno
+		}
+
+		switch {
+		case st.Mode&fs.ModeSymlink != 0:
+			if links++; links > maxSymlinks {
+				return "", syscall.ELOOP
+			}
+			target, errno := fsys.Readlink(next)
+			if errno != 0 {
+				return "", errno
+			}
+			if strings.HasPrefix(target, "/") {
+				resolved = ""
+			}
+			if rest != "" {
+				target += "/" + rest
+			}
+			rest = target
+		case rest != "" && !st.Mode.IsDir():
+			return "", syscall.ENOTDIR
+		default:
+			resolved = next
+		}
+	}
+	return "/" + resolved, 0
+}
+
+// RelPath is like filepath.Rel, except for slash-separated paths in an FS. It
+// returns the path of `target` relative to the directory `base`, such that
+// path.Join(base, result) is equivalent to `target`. For example, this can be
+// the target of a relative symbolic link in `base`.
+//
+// Paths are lexically cleaned, so symbolic links aren't considered. This fails
+// with syscall.EINVAL when only one of the paths is absolute, or `base` has
+// more leading ".." than `target`, as the result would need to know the name
+// of the parent directory.
+func RelPath(base, target string) (string, syscall.Errno) {
+	base, target = path.Clean(base), path.Clean(target)
+	if base == target {
+		return ".", 0
+	} else if strings.HasPrefix(base, "/") != strings.HasPrefix(target, "/") {
+		return "", syscall.EINVAL
+	}
+
+	baseElems, targetElems := splitCleanPath(base), splitCleanPath(target)
+	i := 0
+	for i < len(baseElems) && i < len(targetElems) && baseElems[i] == targetElems[i] {
+		i++
+	}
+
+	rel := make([]string, 0, len(baseElems)-i+len(targetElems)-i)
+	for _, elem := range baseElems[i:] {
+		if elem == ".." {
+			return "", syscall.EINVAL
+		}
+		rel = append(rel, "..")
+	}
+	rel = append(rel, targetElems[i:]...)
+	return strings.Join(rel, "/"), 0
+}
+
+// splitCleanPath returns the components of a path returned by path.Clean,
+// ignoring any leading "/".
+func splitCleanPath(p string) []string {
+	p = strings.TrimPrefix(p, "/")
+	if p == "" || p == "." {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// OpenWithPath is like FS.OpenFile, except it also returns the canonical
+// absolute path of the file, as returned by RealPath. This is useful to key
+// files by path, regardless of the links used to open them.
+//
+// The host follows links when opening, so a component of the path replaced by
+// a link after it was resolved could open a file outside `fsys`. To detect
+// this, the path is resolved again after opening, and the open fails with
+// syscall.EAGAIN unless it still refers to the file opened. As this is only
+// known after opening, flags which change the file when opening it, such as
+// os.O_TRUNC, fail with syscall.EINVAL.
+func OpenWithPath(fsys FS, name string, flag int, perm fs.FileMode) (fs.File, string, syscall.Errno) {
+	if flag&(os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, "", syscall.EINVAL
+	}
+	realPath, errno := RealPath(fsys, name)
+	if errno != 0 {
+		return nil, "", errno
+	}
+	// Open the resolved path, so that it corresponds to the file opened.
+	f, errno := fsys.OpenFile(strings.TrimPrefix(realPath, "/"), flag, perm)
+	if errno != 0 {
+		return nil, "", errno
+	}
+	if errno = verifyOpenPath(fsys, f, realPath); errno != 0 {
+		_ = f.Close()
+		return nil, "", errno
+	}
+	return f, realPath, 0
+}
+
+// verifyOpenPath returns syscall.EAGAIN unless `realPath` still resolves to
+// the file `f`, which was opened from it.
+func verifyOpenPath(fsys FS, f fs.File, realPath string) syscall.Errno {
+	opened, errno := platform.StatFile(f)
+	if errno != 0 {
+		return errno
+	}
+	if again, errno := RealPath(fsys, realPath); errno != 0 || again != realPath {
+		return syscall.EAGAIN
+	}
+	st, errno := fsys.Lstat(strings.TrimPrefix(realPath, "/"))
+	if errno != 0 || st.Dev != opened.Dev || st.Ino != opened.Ino {
+		return syscall.EAGAIN
+	}
+	return 0
+}
@@ -0,0 +1,127 @@
+package sysfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestRealPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(tmpDir, "a", "b"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "a", "b", "file"), []byte("wazero"), 0o600))
+	require.NoError(t, os.Symlink("a/b", path.Join(tmpDir, "dirlink")))
+	require.NoError(t, os.Symlink("b/file", path.Join(tmpDir, "a", "filelink")))
+	require.NoError(t, os.Symlink("../filelink", path.Join(tmpDir, "a", "b", "uplink")))
+	require.NoError(t, os.Symlink("/a/b", path.Join(tmpDir, "abslink")))
+	require.NoError(t, os.Symlink("loop", path.Join(tmpDir, "loop")))
+	require.NoError(t, os.Symlink("missing", path.Join(tmpDir, "dangling")))
+
+	testFS := NewDirFS(tmpDir)
+
+	tests := []struct {
+		name, path, expected string
+		expectedErrno        syscall.Errno
+	}{
+		{name: "root", path: ".", expected: "/"},
+		{name: "file", path: "a/b/file", expected: "/a/b/file"},
+		{name: "dot dot", path: "a/./b/../b/file", expected: "/a/b/file"},
+		{name: "above root", path: "../../a", expected: "/a"},
+		{name: "dir link", path: "dirlink/file", expected: "/a/b/file"},
+		{name: "file link", path: "a/filelink", expected: "/a/b/file"},
+		{name: "relative link", path: "dirlink/uplink", expected: "/a/b/file"},
+		{name: "absolute link", path: "abslink/file", expected: "/a/b/file"},
+		{name: "link itself", path: "dirlink", expected: "/a/b"},
+		{name: "missing", path: "a/missing", expectedErrno: syscall.ENOENT},
+		{name: "dangling", path: "dangling", expectedErrno: syscall.ENOENT},
+		{name: "not dir", path: "a/b/file/x", expectedErrno: syscall.ENOTDIR},
+		{name: "loop", path: "loop", expectedErrno: syscall.ELOOP},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			actual, errno := RealPath(testFS, tc.path)
+			require.EqualErrno(t, tc.expectedErrno, errno)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestRelPath(t *testing.T) {
+	tests := []struct {
+		base, target, expected string
+	}{
+		{base: "a", target: "a", expected: "."},
+		{base: "a/", target: "a/./b/..", expected: "."},
+		{base: "a", target: "a/file", expected: "file"},
+		{base: "a/b/c", target: "a", expected: "../.."},
+		{base: "a/b", target: "a/c/file", expected: "../c/file"},
+		{base: ".", target: "a/file", expected: "a/file"},
+		{base: "a", target: ".", expected: ".."},
+		{base: "/a/b", target: "/c", expected: "../../c"},
+		{base: "/", target: "/a", expected: "a"},
+		{base: "../a", target: "../b", expected: "../b"},
+		{base: "a", target: "../b", expected: "../../b"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.base+" "+tc.target, func(t *testing.T) {
+			rel, errno := RelPath(tc.base, tc.target)
+			require.Zero(t, errno)
+			require.Equal(t, tc.expected, rel)
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		// One path is absolute.
+		_, errno := RelPath("/a", "b")
+		require.EqualErrno(t, syscall.EINVAL, errno)
+		_, errno = RelPath("a", "/b")
+		require.EqualErrno(t, syscall.EINVAL, errno)
+
+		// The name of the parent of "." isn't known.
+		_, errno = RelPath("../a", "b")
+		require.EqualErrno(t, syscall.EINVAL, errno)
+	})
+}
+
+func TestOpenWithPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "dir"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "dir", "file"), []byte("wazero"), 0o600))
+	require.NoError(t, os.Symlink("dir/file", path.Join(tmpDir, "link")))
+
+	testFS := NewDirFS(tmpDir)
+
+	f, realPath, errno := OpenWithPath(testFS, "link", os.O_RDONLY, 0)
+	require.Zero(t, errno)
+	defer f.Close()
+	require.Equal(t, "/dir/file", realPath)
+
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(b))
+
+	_, _, errno = OpenWithPath(testFS, "missing", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.ENOENT, errno)
+
+	// Flags which change the file before the path can be verified.
+	_, _, errno = OpenWithPath(testFS, "link", os.O_WRONLY|os.O_TRUNC, 0)
+	require.EqualErrno(t, syscall.EINVAL, errno)
+}
@@ -0,0 +1,227 @@
+package sysfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// RetryPolicy configures RetryFS.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times an operation is tried,
+	// including the first. Values less than one are treated as one.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry, which doubles before each
+	// subsequent retry, up to MaxBackoff. Zero retries immediately.
+	Backoff time.Duration
+
+	// MaxBackoff limits the delay between retries, unless zero.
+	MaxBackoff time.Duration
+
+	// Retryable returns true when an error is transient. When nil, this
+	// defaults to IsTransient.
+	Retryable func(error) bool
+
+	// Context, when non-nil, stops retrying once it is done, returning the
+	// last error.
+	Context context.Context
+}
+
+// IsTransient returns true if the error is syscall.EAGAIN or syscall.EINTR, or
+// implements a `Temporary() bool` method returning true, such as net.Error.
+func IsTransient(err error) bool {
+	if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+		return true
+	}
+	var t interface{ Temporary() bool }
+	return errors.As(err, &t) && t.Temporary()
+}
+
+// RetryFS wraps `base` such that operations which read, including opening
+// files without flags that allow writing or creating them, are retried
+// according to `policy` when they fail with a transient error. Other errors
+// are returned immediately.
+//
+// This is intended for backends which can fail spuriously, such as network
+// filesystems. Operations that change the filesystem aren't retried, as they
+// may not be idempotent. Reads are only retried when they returned no data.
+func RetryFS(base FS, policy RetryPolicy) FS {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsTransient
+	}
+	return &retryFS{FS: base, policy: policy}
+}
+
+type retryFS struct {
+	FS
+	policy RetryPolicy
+}
+
+// retry calls `op` until it succeeds, fails with a non-transient error, or
+// the policy stops it.
+func (r *retryFS) retry(op func() error) error {
+	backoff := r.policy.Backoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= r.policy.MaxAttempts || !r.policy.Retryable(err) {
+			return err
+		}
+		if !r.sleep(backoff) {
+			return err
+		}
+		if backoff *= 2; r.policy.MaxBackoff > 0 && backoff > r.policy.MaxBackoff {
+			backoff = r.policy.MaxBackoff
+		}
+	}
+}
+
+// sleep waits for `d`, returning false if the context was done first.
+func (r *retryFS) sleep(d time.Duration) bool {
+	ctx := r.policy.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// retryErrno is like retry, for operations that return syscall.Errno.
+func (r *retryFS) retryErrno(op func() syscall.Errno) syscall.Errno {
+	if err := r.retry(func() error {
+		if errno := op(); errno != 0 {
+			return errno
+		}
+		return nil
+	}); err != nil {
+		return err.(syscall.Errno)
+	}
+	return 0
+}
+
+// Open implements the same method as documented on fs.FS
+func (r *retryFS) Open(name string) (fs.File, error) {
+	return fsOpen(r, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (r *retryFS) OpenFile(path string, flag int, perm fs.FileMode) (f fs.File, errno syscall.Errno) {
+	if flag&openFlagsMutate != 0 { // e.g. O_CREAT or O_TRUNC may have applied.
+		f, errno = r.FS.OpenFile(path, flag, perm)
+	} else {
+		errno = r.retryErrno(func() (errno syscall.Errno) {
+			f, errno = r.FS.OpenFile(path, flag, perm)
+			return
+		})
+	}
+	if errno != 0 {
+		return nil, errno
+	}
+	return &retryFile{wrappedFile: wrappedFile{path: path, f: f}, r: r}, 0
+}
+
+// Lstat implements FS.Lstat
+func (r *retryFS) Lstat(path string) (st platform.Stat_t, errno syscall.Errno) {
+	errno = r.retryErrno(func() (errno syscall.Errno) {
+		st, errno = r.FS.Lstat(path)
+		return
+	})
+	return
+}
+
+// Stat implements FS.Stat
+func (r *retryFS) Stat(path string) (st platform.Stat_t, errno syscall.Errno) {
+	errno = r.retryErrno(func() (errno syscall.Errno) {
+		st, errno = r.FS.Stat(path)
+		return
+	})
+	return
+}
+
+// Readlink implements FS.Readlink
+func (r *retryFS) Readlink(path string) (dst string, errno syscall.Errno) {
+	errno = r.retryErrno(func() (errno syscall.Errno) {
+		dst, errno = r.FS.Readlink(path)
+		return
+	})
+	return
+}
+
+// retryFile retries reads on a file opened by retryFS.
+type retryFile struct {
+	wrappedFile
+	r *retryFS
+}
+
+// GoString implements fmt.GoStringer
+func (f *retryFile) GoString() string {
+	return fmt.Sprintf("&sysfs.retryFile{path: %q, f: %s}", f.path, goStringFile(f.f))
+}
+
+// Stat implements fs.File
+func (f *retryFile) Stat() (info fs.FileInfo, err error) {
+	err = f.r.retry(func() (err error) {
+		info, err = f.f.Stat()
+		return
+	})
+	return
+}
+
+// Read implements io.Reader
+func (f *retryFile) Read(p []byte) (int, error) {
+	return f.read(p, f.f.Read)
+}
+
+// ReadAt implements io.ReaderAt
+func (f *retryFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.read(p, func(buf []byte) (int, error) { return f.wrappedFile.ReadAt(buf, off) })
+}
+
+// read retries `read` while it fails without returning data, as otherwise the
+// data would be lost.
+func (f *retryFile) read(p []byte, read func([]byte) (int, error)) (n int, err error) {
+	if len(p) == 0 {
+		return // nothing to retry
+	}
+	_ = f.r.retry(func() error {
+		if n, err = read(p); n > 0 || err == io.EOF {
+			return nil
+		}
+		return err
+	})
+	return
+}
+
+// Write implements io.Writer
+func (f *retryFile) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return f.wrappedFile.Write(p)
+}
+
+// WriteAt implements io.WriterAt
+func (f *retryFile) WriteAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return f.wrappedFile.WriteAt(p, off)
+}
@@ -0,0 +1,174 @@
+package sysfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// flakyFS fails the first `failures` operations attempted with `errno`.
+type flakyFS struct {
+	UnimplementedFS
+	errno    syscall.Errno
+	failures int
+	attempts int
+}
+
+func (f *flakyFS) fail() bool {
+	f.attempts++
+	return f.attempts <= f.failures
+}
+
+// OpenFile implements FS.OpenFile
+func (f *flakyFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	if f.fail() {
+		return nil, f.errno
+	}
+	return &flakyFile{fs: f}, 0
+}
+
+// Stat implements FS.Stat
+func (f *flakyFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	if f.fail() {
+		return platform.Stat_t{}, f.errno
+	}
+	return platform.Stat_t{Size: 6}, 0
+}
+
+// Mkdir implements FS.Mkdir
+func (f *flakyFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	if f.fail() {
+		return f.errno
+	}
+	return 0
+}
+
+type flakyFile struct {
+	fs.File
+	fs *flakyFS
+}
+
+func (f *flakyFile) Read(p []byte) (int, error) {
+	if f.fs.fail() {
+		return 0, f.fs.errno
+	}
+	return copy(p, "wazero"), nil
+}
+
+func (f *flakyFile) Close() error {
+	return nil
+}
+
+func TestRetryFS(t *testing.T) {
+	t.Run("succeeds after failures", func(t *testing.T) {
+		base := &flakyFS{errno: syscall.EAGAIN, failures: 2}
+		testFS := RetryFS(base, RetryPolicy{MaxAttempts: 3})
+
+		st, errno := testFS.Stat("file")
+		require.Zero(t, errno)
+		require.Equal(t, int64(6), st.Size)
+		require.Equal(t, 3, base.attempts)
+	})
+
+	t.Run("exceeds max attempts", func(t *testing.T) {
+		base := &flakyFS{errno: syscall.EAGAIN, failures: 3}
+		testFS := RetryFS(base, RetryPolicy{MaxAttempts: 3})
+
+		_, errno := testFS.OpenFile("file", 0, 0)
+		require.EqualErrno(t, syscall.EAGAIN, errno)
+		require.Equal(t, 3, base.attempts)
+	})
+
+	t.Run("not transient", func(t *testing.T) {
+		base := &flakyFS{errno: syscall.ENOENT, failures: 1}
+		testFS := RetryFS(base, RetryPolicy{MaxAttempts: 3})
+
+		_, errno := testFS.Stat("file")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+		require.Equal(t, 1, base.attempts)
+	})
+
+	t.Run("custom predicate", func(t *testing.T) {
+		base := &flakyFS{errno: syscall.EIO, failures: 1}
+		testFS := RetryFS(base, RetryPolicy{
+			MaxAttempts: 3,
+			Retryable:   func(err error) bool { return err == syscall.EIO },
+		})
+
+		_, errno := testFS.Stat("file")
+		require.Zero(t, errno)
+		require.Equal(t, 2, base.attempts)
+	})
+
+	t.Run("writes aren't retried", func(t *testing.T) {
+		base := &flakyFS{errno: syscall.EAGAIN, failures: 1}
+		testFS := RetryFS(base, RetryPolicy{MaxAttempts: 3})
+
+		require.EqualErrno(t, syscall.EAGAIN, testFS.Mkdir("dir", 0o700))
+		require.Equal(t, 1, base.attempts)
+
+		base.attempts = 0
+		_, errno := testFS.OpenFile("file", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		require.EqualErrno(t, syscall.EAGAIN, errno)
+		require.Equal(t, 1, base.attempts)
+	})
+
+	t.Run("read", func(t *testing.T) {
+		base := &flakyFS{errno: syscall.EAGAIN}
+		testFS := RetryFS(base, RetryPolicy{MaxAttempts: 3})
+
+		f, errno := testFS.OpenFile("file", 0, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		base.attempts, base.failures = 0, 2
+		buf := make([]byte, 6)
+		n, err := f.(io.Reader).Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(buf[:n]))
+		require.Equal(t, 3, base.attempts)
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		base := &flakyFS{errno: syscall.EAGAIN, failures: 2}
+		testFS := RetryFS(base, RetryPolicy{MaxAttempts: 3, Backoff: 10 * time.Millisecond})
+
+		start := time.Now()
+		_, errno := testFS.Stat("file")
+		require.Zero(t, errno)
+		require.True(t, time.Since(start) >= 30*time.Millisecond) // 10ms + 20ms
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		base := &flakyFS{errno: syscall.EAGAIN, failures: 2}
+		testFS := RetryFS(base, RetryPolicy{MaxAttempts: 3, Backoff: time.Hour, Context: ctx})
+
+		_, errno := testFS.Stat("file")
+		require.EqualErrno(t, syscall.EAGAIN, errno)
+		require.Equal(t, 1, base.attempts)
+	})
+}
+
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "temporary" }
+func (temporaryError) Temporary() bool { return true }
+
+func TestIsTransient(t *testing.T) {
+	require.True(t, IsTransient(syscall.EAGAIN))
+	require.True(t, IsTransient(syscall.EINTR))
+	require.True(t, IsTransient(&fs.PathError{Err: syscall.EAGAIN}))
+	require.True(t, IsTransient(temporaryError{}))
+	require.False(t, IsTransient(syscall.ENOENT))
+	require.False(t, IsTransient(io.EOF))
+}
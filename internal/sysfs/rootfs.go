@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"os"
+	"path"
 	"strings"
 	"syscall"
 	"time"
@@ -11,12 +13,82 @@ import (
 	"github.com/tetratelabs/wazero/internal/platform"
 )
 
-func NewRootFS(fs []FS, guestPaths []string) (FS, error) {
+// ValidateMounts returns an error if NewRootFS can't compose the file systems
+// `fs` at the index-correlated `guestPaths`. This allows configuration to be
+// checked before use, such as by the CLI. The following are invalid:
+//   - `fs` and `guestPaths` have different lengths, or an FS is nil.
+//   - The same guest path is mounted more than once, after cleaning.
+//   - A guest path is nested, such as "/usr/bin", as it would overlap "/usr".
+func ValidateMounts(fs []FS, guestPaths []string) error {
+	if len(fs) != len(guestPaths) {
+		return fmt.Errorf("%d file systems for %d guest paths", len(fs), len(guestPaths))
+	}
+
+	seen := make(map[string]struct{}, len(guestPaths))
+	for i, guestPath := range guestPaths {
+		if fs[i] == nil {
+			return fmt.Errorf("nil file system for guest path %q", guestPath)
+		}
+
+		cleaned := StripPrefixesAndTrailingSlash(guestPath)
+		if _, ok := seen[cleaned]; ok {
+			if cleaned == "" {
+				return fmt.Errorf("multiple root filesystems are invalid: %s", stringFS(fs, guestPaths))
+			}
+			return fmt.Errorf("duplicate guest path %q: %s", guestPath, stringFS(fs, guestPaths))
+		}
+		seen[cleaned] = struct{}{}
+
+		// ../ mounts are special cased and aren't returned in a directory
+		// listing, so we can ignore them for now.
+		if !strings.HasPrefix(cleaned, "..") && strings.Contains(cleaned, "/") {
+			return fmt.Errorf("only single-level guest paths allowed: %s", stringFS(fs, guestPaths))
+		}
+	}
+	return nil
+}
+
+// RootFSOption configures a CompositeFS returned by NewRootFS.
+type RootFSOption func(*CompositeFS)
+
+// MoveAcrossMounts makes Rename of a regular file between mounts fall back to
+// copying it to the destination mount, then unlinking the source. Without
+// this, such a rename fails with syscall.EXDEV, as it would on real mounts.
+//
+// Note: Unlike a rename, this isn't atomic. Other renames across mounts, such
+// as of directories, still fail with syscall.EXDEV.
+func MoveAcrossMounts() RootFSOption {
+	return func(c *CompositeFS) {
+		c.moveAcrossMounts = true
+	}
+}
+
+// MountUmask clears the permission bits in `umask` from files, directories and
+// nodes created in the mount at `guestPath`, like the umask of a process. This
+// allows mounts to create files with different permissions. The umask of the
+// guest, and of the host process, still apply. NewRootFS fails if nothing is
+// mounted at `guestPath`.
+//
+// Note: Like POSIX, Chmod isn't affected.
+func MountUmask(guestPath string, umask fs.FileMode) RootFSOption {
+	return func(c *CompositeFS) {
+		if c.mountUmasks == nil {
+			c.mountUmasks = map[string]fs.FileMode{}
+		}
+		c.mountUmasks[StripPrefixesAndTrailingSlash(guestPath)] = umask.Perm()
+	}
+}
+
+func NewRootFS(fs []FS, guestPaths []string, opts ...RootFSOption) (FS, error) {
+	if err := ValidateMounts(fs, guestPaths); err != nil {
+		return nil, err
+	}
+
 	switch len(fs) {
 	case 0:
 		return UnimplementedFS{}, nil
 	case 1:
-		if StripPrefixesAndTrailingSlash(guestPaths[0]) == "" {
+		if StripPrefixesAndTrailingSlash(guestPaths[0]) == "" && len(opts) == 0 {
 			return fs[0], nil
 		}
 	}
@@ -37,16 +109,8 @@ func NewRootFS(fs []FS, guestPaths []string) (FS, error) {
 		// Clean the prefix in the same way path matches will.
 		cleaned := StripPrefixesAndTrailingSlash(guestPath)
 		if cleaned == "" {
-			if ret.rootIndex != -1 {
-				return nil, fmt.Errorf("multiple root filesystems are invalid: %s", ret.string)
-			}
 			ret.rootIndex = i
-		} else if strings.HasPrefix(cleaned, "..") {
-			// ../ mounts are special cased and aren't returned in a directory
-			// listing, so we can ignore them for now.
-		} else if strings.Contains(cleaned, "/") {
-			return nil, fmt.Errorf("only single-level guest paths allowed: %s", ret.string)
-		} else {
+		} else if !strings.HasPrefix(cleaned, "..") { // ../ mounts aren't listed.
 			ret.rootGuestPaths[cleaned] = i
 		}
 		ret.cleanedGuestPaths[i] = cleaned
@@ -58,9 +122,46 @@ func NewRootFS(fs []FS, guestPaths []string) (FS, error) {
 		ret.cleanedGuestPaths = append(ret.cleanedGuestPaths, "")
 		ret.fs = append(ret.fs, &fakeRootFS{})
 	}
+
+	for _, opt := range opts {
+		opt(ret)
+	}
+	if err := ret.resolveUmasks(); err != nil {
+		return nil, err
+	}
 	return ret, nil
 }
 
+// resolveUmasks sets umasks from the guest paths given to MountUmask.
+func (c *CompositeFS) resolveUmasks() error {
+	if len(c.mountUmasks) == 0 {
+		return nil
+	}
+	c.umasks = make([]fs.FileMode, len(c.fs))
+	for cleaned, umask := range c.mountUmasks {
+		i := -1
+		for j := range c.guestPaths { // excludes any fakeRootFS.
+			if c.cleanedGuestPaths[j] == cleaned {
+				i = j
+			}
+		}
+		if i == -1 {
+			return fmt.Errorf("umask for guest path %q, which isn't mounted: %s", cleaned, c.string)
+		}
+		c.umasks[i] = umask
+	}
+	return nil
+}
+
+// umask returns the permission bits to clear from modes of new files in the
+// mount `matchIndex`.
+func (c *CompositeFS) umask(matchIndex int) fs.FileMode {
+	if c.umasks == nil {
+		return 0
+	}
+	return c.umasks[matchIndex]
+}
+
 type CompositeFS struct {
 	UnimplementedFS
 	// string is cached for convenience.
@@ -77,6 +178,12 @@ type CompositeFS struct {
 	rootGuestPaths map[string]int
 	// rootIndex is the index in fs that is the root filesystem
 	rootIndex int
+	// moveAcrossMounts is set by MoveAcrossMounts.
+	moveAcrossMounts bool
+	// mountUmasks are set by MountUmask, keyed by cleaned guest path.
+	mountUmasks map[string]fs.FileMode
+	// umasks is index-correlated with fs, or nil if no mount has a umask.
+	umasks []fs.FileMode
 }
 
 // String implements fmt.Stringer
@@ -117,6 +224,16 @@ func (c *CompositeFS) FS() (fs []FS) {
 	return
 }
 
+// directoryFlagEnforced implements directoryFlagFS
+func (c *CompositeFS) directoryFlagEnforced() bool {
+	for _, f := range c.fs {
+		if !DirectoryFlagEnforced(f) {
+			return false
+		}
+	}
+	return len(c.fs) > 0
+}
+
 // Open implements the same method as documented on fs.FS
 func (c *CompositeFS) Open(name string) (fs.File, error) {
 	return fsOpen(c, name)
@@ -125,6 +242,9 @@ func (c *CompositeFS) Open(name string) (fs.File, error) {
 // OpenFile implements FS.OpenFile
 func (c *CompositeFS) OpenFile(path string, flag int, perm fs.FileMode) (f fs.File, err syscall.Errno) {
 	matchIndex, relativePath := c.chooseFS(path)
+	if flag&os.O_CREATE != 0 {
+		perm &^= c.umask(matchIndex)
+	}
 
 	f, err = c.fs[matchIndex].OpenFile(relativePath, flag, perm)
 	if err != 0 {
@@ -152,6 +272,11 @@ type openRootDir struct {
 	direntsI int            // the read offset, an index into the files slice
 }
 
+// GoString implements fmt.GoStringer
+func (d *openRootDir) GoString() string {
+	return fmt.Sprintf("&sysfs.openRootDir{c: %q, f: %s}", d.c.string, goStringFile(d.f))
+}
+
 func (d *openRootDir) Close() error { return d.f.Close() }
 
 func (d *openRootDir) Stat() (fs.FileInfo, error) { return d.f.Stat() }
@@ -260,7 +385,7 @@ func (c *CompositeFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
 // Mkdir implements FS.Mkdir
 func (c *CompositeFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
 	matchIndex, relativePath := c.chooseFS(path)
-	return c.fs[matchIndex].Mkdir(relativePath, perm)
+	return c.fs[matchIndex].Mkdir(relativePath, perm&^c.umask(matchIndex))
 }
 
 // Chmod implements FS.Chmod
@@ -285,10 +410,59 @@ func (c *CompositeFS) Lchown(path string, uid, gid int) syscall.Errno {
 func (c *CompositeFS) Rename(from, to string) syscall.Errno {
 	fromFS, fromPath := c.chooseFS(from)
 	toFS, toPath := c.chooseFS(to)
-	if fromFS != toFS {
-		return syscall.ENOSYS // not yet anyway
+	if fromFS == toFS {
+		return c.fs[fromFS].Rename(fromPath, toPath)
+	} else if !c.moveAcrossMounts {
+		return syscall.EXDEV // renames can't cross mounts
 	}
-	return c.fs[fromFS].Rename(fromPath, toPath)
+	return moveFile(c.fs[fromFS], fromPath, c.fs[toFS], toPath)
+}
+
+// moveFile moves the regular file `fromPath` in `fromFS` to `toPath` in
+// `toFS` by copying it to a new file in the same directory as `toPath`, then
+// renaming it over `toPath`, and unlinking the source. If the copy fails, the
+// new file is removed, and the source and any destination are left as-is.
+func moveFile(fromFS FS, fromPath string, toFS FS, toPath string) syscall.Errno {
+	st, errno := fromFS.Lstat(fromPath)
+	if errno != 0 {
+		return errno
+	} else if !st.Mode.IsRegular() {
+		return syscall.EXDEV
+	}
+
+	// Like rename, a destination directory can't be replaced by a file.
+	if toSt, errno := toFS.Lstat(toPath); errno == 0 && toSt.Mode.IsDir() {
+		return syscall.EISDIR
+	}
+
+	src, errno := fromFS.OpenFile(fromPath, syscall.O_RDONLY, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer src.Close()
+
+	dst, tmpPath, errno := createUnique(toFS, path.Dir(toPath), os.O_WRONLY, st.Mode.Perm())
+	if errno != 0 {
+		return errno
+	}
+
+	if w, ok := dst.(io.Writer); !ok {
+		errno = syscall.EBADF
+	} else if _, err := io.Copy(w, src); err != nil {
+		errno = platform.UnwrapOSError(err)
+	}
+	if e := platform.UnwrapOSError(dst.Close()); errno == 0 {
+		errno = e
+	}
+	if errno == 0 {
+		errno = toFS.Rename(tmpPath, toPath)
+	}
+
+	if errno != 0 {
+		_ = toFS.Unlink(tmpPath) // don't leave a partial copy.
+		return errno
+	}
+	return fromFS.Unlink(fromPath)
 }
 
 // Readlink implements FS.Readlink
@@ -302,7 +476,7 @@ func (c *CompositeFS) Link(oldName, newName string) syscall.Errno {
 	fromFS, oldNamePath := c.chooseFS(oldName)
 	toFS, newNamePath := c.chooseFS(newName)
 	if fromFS != toFS {
-		return syscall.ENOSYS // not yet anyway
+		return syscall.EXDEV // hard links can't cross mounts
 	}
 	return c.fs[fromFS].Link(oldNamePath, newNamePath)
 }
@@ -313,6 +487,12 @@ func (c *CompositeFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFo
 	return c.fs[matchIndex].Utimens(relativePath, times, symlinkFollow)
 }
 
+// Mknod implements FS.Mknod
+func (c *CompositeFS) Mknod(path string, mode fs.FileMode, dev uint64) syscall.Errno {
+	matchIndex, relativePath := c.chooseFS(path)
+	return c.fs[matchIndex].Mknod(relativePath, mode&^c.umask(matchIndex), dev)
+}
+
 // Symlink implements FS.Symlink
 func (c *CompositeFS) Symlink(oldName, link string) (err syscall.Errno) {
 	fromFS, oldNamePath := c.chooseFS(oldName)
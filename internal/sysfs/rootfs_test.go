@@ -116,6 +116,66 @@ func TestNewRootFS(t *testing.T) {
 	})
 }
 
+func TestValidateMounts(t *testing.T) {
+	testFS := NewDirFS(".")
+
+	tests := []struct {
+		name        string
+		fs          []FS
+		guestPaths  []string
+		expectedErr string
+	}{
+		{name: "empty"},
+		{name: "root and single-level", fs: []FS{testFS, testFS}, guestPaths: []string{"/", "/tmp"}},
+		{name: "parent", fs: []FS{testFS, testFS}, guestPaths: []string{"/", "../tmp"}},
+		{
+			name:        "length mismatch",
+			fs:          []FS{testFS},
+			expectedErr: "1 file systems for 0 guest paths",
+		},
+		{
+			name:        "nil file system",
+			fs:          []FS{testFS, nil},
+			guestPaths:  []string{"/", "/tmp"},
+			expectedErr: `nil file system for guest path "/tmp"`,
+		},
+		{
+			name:        "duplicate",
+			fs:          []FS{testFS, testFS},
+			guestPaths:  []string{"/tmp", "tmp/"},
+			expectedErr: `duplicate guest path "tmp/": [.:/tmp .:tmp/]`,
+		},
+		{
+			name:        "multiple roots",
+			fs:          []FS{testFS, testFS},
+			guestPaths:  []string{"/", "."},
+			expectedErr: "multiple root filesystems are invalid: [.:/ .:.]",
+		},
+		{
+			name:        "overlapping",
+			fs:          []FS{testFS, testFS},
+			guestPaths:  []string{"/usr", "/usr/bin"},
+			expectedErr: "only single-level guest paths allowed: [.:/usr .:/usr/bin]",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateMounts(tc.fs, tc.guestPaths)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+
+				// NewRootFS returns the same error, instead of panicking.
+				_, err = NewRootFS(tc.fs, tc.guestPaths)
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
 func readDirNames(t *testing.T, f fs.File) []string {
 	names, errno := platform.Readdirnames(f, -1)
 	require.Zero(t, errno)
@@ -133,6 +193,19 @@ func TestRootFS_String(t *testing.T) {
 	require.Equal(t, "[.:/ .:/tmp]", testFS.String())
 }
 
+func TestRootFS_DirectoryFlagEnforced(t *testing.T) {
+	dirFS := NewDirFS(t.TempDir())
+
+	testFS, err := NewRootFS([]FS{dirFS, NewReadFS(dirFS)}, []string{"/", "/ro"})
+	require.NoError(t, err)
+	require.Equal(t, DirectoryFlagEnforced(dirFS), DirectoryFlagEnforced(testFS))
+
+	// Only true when all mounts enforce it.
+	testFS, err = NewRootFS([]FS{dirFS, Adapt(gofstest.MapFS{})}, []string{"/", "/map"})
+	require.NoError(t, err)
+	require.False(t, DirectoryFlagEnforced(testFS))
+}
+
 func TestRootFS_Open(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -168,6 +241,108 @@ func TestRootFS_Stat(t *testing.T) {
 	testStat(t, testFS)
 }
 
+func TestRootFS_Link(t *testing.T) {
+	rootDir, mntDir := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(rootDir, "file"), nil, 0o600))
+	require.NoError(t, os.WriteFile(path.Join(mntDir, "file"), nil, 0o600))
+
+	testFS, err := NewRootFS([]FS{NewDirFS(rootDir), NewDirFS(mntDir)}, []string{"/", "/mnt"})
+	require.NoError(t, err)
+
+	// Links within a mount succeed.
+	require.Zero(t, testFS.Link("file", "link"))
+	require.Zero(t, testFS.Link("mnt/file", "mnt/link"))
+
+	// Links across mounts fail, as they would on real mounts.
+	require.EqualErrno(t, syscall.EXDEV, testFS.Link("file", "mnt/other"))
+	require.EqualErrno(t, syscall.EXDEV, testFS.Link("mnt/file", "other"))
+
+	_, err = os.Stat(path.Join(mntDir, "other"))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path.Join(rootDir, "other"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestRootFS_Rename(t *testing.T) {
+	newRootFS := func(t *testing.T, opts ...RootFSOption) (rootDir, mntDir string, testFS FS) {
+		rootDir, mntDir = t.TempDir(), t.TempDir()
+		require.NoError(t, os.WriteFile(path.Join(rootDir, "file"), []byte("wazero"), 0o640))
+		require.NoError(t, os.Mkdir(path.Join(rootDir, "dir"), 0o700))
+		require.NoError(t, os.Mkdir(path.Join(mntDir, "dir"), 0o700))
+
+		testFS, err := NewRootFS([]FS{NewDirFS(rootDir), NewDirFS(mntDir)}, []string{"/", "/mnt"}, opts...)
+		require.NoError(t, err)
+		return
+	}
+
+	t.Run("across mounts", func(t *testing.T) {
+		rootDir, mntDir, testFS := newRootFS(t)
+
+		require.EqualErrno(t, syscall.EXDEV, testFS.Rename("file", "mnt/file"))
+
+		// Nothing moved.
+		_, err := os.Stat(path.Join(rootDir, "file"))
+		require.NoError(t, err)
+		_, err = os.Stat(path.Join(mntDir, "file"))
+		require.True(t, os.IsNotExist(err))
+
+		// Renames within a mount still work.
+		require.Zero(t, testFS.Rename("file", "renamed"))
+	})
+
+	t.Run("MoveAcrossMounts", func(t *testing.T) {
+		rootDir, mntDir, testFS := newRootFS(t, MoveAcrossMounts())
+
+		require.Zero(t, testFS.Rename("file", "mnt/file"))
+
+		_, err := os.Stat(path.Join(rootDir, "file"))
+		require.True(t, os.IsNotExist(err))
+		b, err := os.ReadFile(path.Join(mntDir, "file"))
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+
+		// Directories can't be moved across mounts.
+		require.EqualErrno(t, syscall.EXDEV, testFS.Rename("dir", "mnt/other"))
+
+		// Nor can a file replace a directory.
+		require.EqualErrno(t, syscall.EISDIR, testFS.Rename("mnt/file", "dir"))
+		_, err = os.Stat(path.Join(mntDir, "file"))
+		require.NoError(t, err)
+
+		// Missing sources fail as usual.
+		require.EqualErrno(t, syscall.ENOENT, testFS.Rename("missing", "mnt/missing"))
+	})
+
+	t.Run("MoveAcrossMounts copy fails", func(t *testing.T) {
+		rootDir, mntDir := t.TempDir(), t.TempDir()
+		require.NoError(t, os.WriteFile(path.Join(rootDir, "file"), []byte("wazero"), 0o640))
+		require.NoError(t, os.WriteFile(path.Join(mntDir, "target"), []byte("existing"), 0o640))
+		require.NoError(t, os.Symlink("target", path.Join(mntDir, "file")))
+
+		failingFS := FaultFS(NewDirFS(rootDir), func(op, _ string) error {
+			if op == "Read" {
+				return syscall.EIO
+			}
+			return nil
+		})
+		testFS, err := NewRootFS([]FS{failingFS, NewDirFS(mntDir)}, []string{"/", "/mnt"}, MoveAcrossMounts())
+		require.NoError(t, err)
+
+		require.EqualErrno(t, syscall.EIO, testFS.Rename("file", "mnt/file"))
+
+		// The source, the destination and its target are unchanged.
+		_, err = os.Stat(path.Join(rootDir, "file"))
+		require.NoError(t, err)
+		dest, err := os.Readlink(path.Join(mntDir, "file"))
+		require.NoError(t, err)
+		require.Equal(t, "target", dest)
+		b, err := os.ReadFile(path.Join(mntDir, "target"))
+		require.NoError(t, err)
+		require.Equal(t, "existing", string(b))
+		requireDirEntries(t, NewDirFS(mntDir), ".", 2)
+	})
+}
+
 func TestRootFS_TestFS(t *testing.T) {
 	t.Parallel()
 
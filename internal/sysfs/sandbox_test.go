@@ -0,0 +1,181 @@
+package sysfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// secret is the content of a file outside the root, which no path resolved
+// by RealPath or opened by OpenWithPath may reach.
+const secret = "secret"
+
+// requireSandboxDirs returns a root directory, and a sibling outside of it
+// which contains the file "secret".
+func requireSandboxDirs(t *testing.T) (root, outside string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+
+	tmpDir := t.TempDir()
+	root, outside = path.Join(tmpDir, "root"), path.Join(tmpDir, "outside")
+	require.NoError(t, os.MkdirAll(path.Join(root, "a", "b"), 0o700))
+	require.NoError(t, os.Mkdir(outside, 0o700))
+	require.NoError(t, os.WriteFile(path.Join(outside, "secret"), []byte(secret), 0o600))
+	return
+}
+
+// requireInRoot fails unless opening `name` either fails, or opens a file
+// inside the root.
+func requireInRoot(t *testing.T, fsys FS, name string) {
+	realPath, errno := RealPath(fsys, name)
+	if errno == 0 {
+		require.True(t, strings.HasPrefix(realPath, "/"), realPath)
+		require.False(t, strings.Contains(realPath, ".."), realPath)
+	}
+
+	f, realPath, errno := OpenWithPath(fsys, name, os.O_RDONLY, 0)
+	if errno != 0 {
+		return
+	}
+	defer f.Close()
+
+	require.True(t, strings.HasPrefix(realPath, "/"), realPath)
+	if r, ok := f.(io.Reader); ok {
+		b, _ := io.ReadAll(r)
+		require.NotEqual(t, secret, string(b))
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	root, outside := requireSandboxDirs(t)
+
+	links := map[string]string{
+		"abs":         outside,
+		"abs_secret":  path.Join(outside, "secret"),
+		"up":          "../outside",
+		"up_chain":    "a/b/../../../../../outside/secret",
+		"a/b/up":      "../../../outside",
+		"slash":       "/",
+		"slash_up":    "/../outside/secret",
+		"link_link":   "up",
+		"a/link_link": "../a/b/up",
+	}
+	for link, target := range links {
+		require.NoError(t, os.Symlink(target, path.Join(root, link)))
+	}
+
+	testFS := NewDirFS(root)
+
+	for _, name := range []string{
+		"../outside/secret",
+		"../../../../outside/secret",
+		"a/b/../../../outside/secret",
+		"/../outside/secret",
+		"abs/secret",
+		"abs_secret",
+		"up/secret",
+		"up_chain",
+		"a/b/up/secret",
+		"slash/../outside/secret",
+		"slash_up",
+		"link_link/secret",
+		"a/link_link/secret",
+	} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			requireInRoot(t, testFS, name)
+		})
+	}
+
+	t.Run("slash is the root", func(t *testing.T) {
+		realPath, errno := RealPath(testFS, "slash/a")
+		require.Zero(t, errno)
+		require.Equal(t, "/a", realPath)
+	})
+}
+
+func TestSandbox_acrossMounts(t *testing.T) {
+	root, _ := requireSandboxDirs(t)
+	mnt := path.Join(path.Dir(root), "mnt")
+	require.NoError(t, os.Mkdir(mnt, 0o700))
+
+	// The links are in the mount, but resolve from the root of the composite.
+	require.NoError(t, os.Symlink("../../outside", path.Join(mnt, "up")))
+	require.NoError(t, os.Symlink("/", path.Join(mnt, "slash")))
+	require.NoError(t, os.Symlink("up", path.Join(mnt, "link_link")))
+	require.NoError(t, os.Symlink("/mnt/up", path.Join(root, "to_mnt")))
+
+	testFS, err := NewRootFS([]FS{NewDirFS(root), NewDirFS(mnt)}, []string{"/", "/mnt"})
+	require.NoError(t, err)
+
+	for _, name := range []string{
+		"mnt/up/secret",
+		"mnt/../../outside/secret",
+		"mnt/slash/../outside/secret",
+		"mnt/link_link/secret",
+		"to_mnt/secret",
+	} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			requireInRoot(t, testFS, name)
+		})
+	}
+
+	t.Run("slash is the root of the composite", func(t *testing.T) {
+		realPath, errno := RealPath(testFS, "mnt/slash/mnt")
+		require.Zero(t, errno)
+		require.Equal(t, "/mnt", realPath)
+	})
+}
+
+// TestSandbox_swap swaps a path between a directory and a link while it is
+// resolved, using FaultFS to do so at precise points.
+func TestSandbox_swap(t *testing.T) {
+	root, outside := requireSandboxDirs(t)
+	swap := path.Join(root, "swap")
+
+	toDir := func() {
+		require.NoError(t, os.Remove(swap))
+		require.NoError(t, os.Mkdir(swap, 0o700))
+		require.NoError(t, os.WriteFile(path.Join(swap, "secret"), nil, 0o600))
+	}
+	toLink := func() {
+		require.NoError(t, os.RemoveAll(swap))
+		require.NoError(t, os.Symlink(outside, swap))
+	}
+
+	t.Run("link to directory before readlink", func(t *testing.T) {
+		require.NoError(t, os.Symlink(outside, swap))
+		defer os.RemoveAll(swap)
+
+		testFS := FaultFS(NewDirFS(root), func(op, p string) error {
+			if op == "Readlink" && p == "swap" {
+				toDir()
+			}
+			return nil
+		})
+		requireInRoot(t, testFS, "swap/secret")
+	})
+
+	t.Run("directory to link before open", func(t *testing.T) {
+		require.NoError(t, os.Mkdir(swap, 0o700))
+		require.NoError(t, os.WriteFile(path.Join(swap, "secret"), nil, 0o600))
+		defer os.RemoveAll(swap)
+
+		testFS := FaultFS(NewDirFS(root), func(op, p string) error {
+			if op == "OpenFile" && p == "swap/secret" {
+				toLink()
+			}
+			return nil
+		})
+		_, _, errno := OpenWithPath(testFS, "swap/secret", os.O_RDONLY, 0)
+		require.EqualErrno(t, syscall.EAGAIN, errno)
+	})
+}
@@ -0,0 +1,127 @@
+package sysfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// StdFS returns a view of `fsys` for use with the standard library, such as
+// fs.WalkDir. Besides fs.FS, it implements fs.SubFS, fs.GlobFS,
+// fs.ReadFileFS and fs.StatFS, so that the functions of the same name in
+// package fs use `fsys` directly, instead of wrapping it or opening files.
+func StdFS(fsys FS) fs.FS {
+	return &stdFS{fs: fsys, dir: "."}
+}
+
+// stdFS implements fs.FS for FS, rooted at dir, which is a valid fs.FS path.
+type stdFS struct {
+	fs  FS
+	dir string
+}
+
+// join returns the path of `name` in the underlying FS, after verifying it is
+// valid for fs.FS.
+func (s *stdFS) join(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Join(s.dir, name), nil
+}
+
+// fixErr reports errors relative to this view, instead of the underlying FS.
+func fixErr(err error, name string) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		pe.Path = name
+	}
+	return err
+}
+
+// Open implements fs.FS
+func (s *stdFS) Open(name string) (fs.File, error) {
+	fullName, err := s.join("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := fsOpen(s.fs, fullName)
+	return f, fixErr(err, name)
+}
+
+// Sub implements fs.SubFS
+func (s *stdFS) Sub(dir string) (fs.FS, error) {
+	fullDir, err := s.join("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+	return &stdFS{fs: s.fs, dir: fullDir}, nil
+}
+
+// Glob implements fs.GlobFS
+//
+// Unlike fs.Glob on other file systems, this doesn't follow symbolic links to
+// directories, as documented on Glob.
+func (s *stdFS) Glob(pattern string) ([]string, error) {
+	if s.dir == "." {
+		return Glob(s.fs, pattern)
+	}
+	// Validate the pattern before joining it, which could hide an error.
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	matches, err := Glob(s.fs, escapeMeta(s.dir)+"/"+pattern)
+	for i, m := range matches {
+		matches[i] = m[len(s.dir)+1:]
+	}
+	return matches, err
+}
+
+// escapeMeta escapes the characters of `p` recognized by path.Match, so that
+// it only matches itself.
+func escapeMeta(p string) string {
+	if !hasMeta(p) {
+		return p
+	}
+	var b strings.Builder
+	for _, c := range p {
+		if strings.ContainsRune(`*?[\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// ReadFile implements fs.ReadFileFS
+func (s *stdFS) ReadFile(name string) ([]byte, error) {
+	fullName, err := s.join("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, errno := s.fs.OpenFile(fullName, os.O_RDONLY, 0)
+	if errno != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errno}
+	}
+	defer f.Close()
+
+	buf, errno := readAll(f, -1)
+	if errno != 0 {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errno}
+	}
+	return buf, nil
+}
+
+// Stat implements fs.StatFS
+func (s *stdFS) Stat(name string) (fs.FileInfo, error) {
+	fullName, err := s.join("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	st, errno := s.fs.Stat(fullName)
+	if errno != 0 {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: errno}
+	}
+	return &dirInfo{name: path.Base(name), stat: st}, nil
+}
@@ -0,0 +1,166 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	gofstest "testing/fstest"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestStdFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	testFS := StdFS(NewDirFS(tmpDir))
+	require.NoError(t, gofstest.TestFS(testFS, "animals.txt", "sub/test.txt"))
+}
+
+func TestStdFS_Sub(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	testFS := StdFS(NewDirFS(tmpDir))
+	_, ok := testFS.(fs.SubFS)
+	require.True(t, ok)
+
+	// fs.Sub returns the result of Sub, instead of wrapping testFS.
+	sub, err := fs.Sub(testFS, "sub")
+	require.NoError(t, err)
+	_, ok = sub.(*stdFS)
+	require.True(t, ok)
+	require.NoError(t, gofstest.TestFS(sub, "test.txt"))
+
+	b, err := fs.ReadFile(sub, "test.txt")
+	require.NoError(t, err)
+	expected, err := os.ReadFile(path.Join(tmpDir, "sub", "test.txt"))
+	require.NoError(t, err)
+	require.Equal(t, expected, b)
+
+	// Errors are relative to the sub-directory.
+	_, err = sub.Open("missing")
+	require.EqualError(t, err, "open missing: no such file or directory")
+
+	_, err = fs.Sub(testFS, "../sub")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+func TestStdFS_Glob(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	testFS := StdFS(NewDirFS(tmpDir))
+	_, ok := testFS.(fs.GlobFS)
+	require.True(t, ok)
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{pattern: "*.txt", expected: []string{"animals.txt", "empty.txt"}},
+		{pattern: "sub/*", expected: []string{"sub/test.txt"}},
+		{pattern: "*/test.txt", expected: []string{"sub/test.txt"}},
+		{pattern: "missing*", expected: nil},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.pattern, func(t *testing.T) {
+			matches, err := fs.Glob(testFS, tc.pattern)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, matches)
+
+			// The result is the same as the generic implementation.
+			generic, err := fs.Glob(os.DirFS(tmpDir), tc.pattern)
+			require.NoError(t, err)
+			require.Equal(t, generic, matches)
+		})
+	}
+
+	_, err := fs.Glob(testFS, "[")
+	require.ErrorIs(t, err, path.ErrBadPattern)
+
+	t.Run("Sub", func(t *testing.T) {
+		require.NoError(t, os.Mkdir(path.Join(tmpDir, "a[1]"), 0o700))
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "a[1]", "b.txt"), nil, 0o600))
+
+		sub, err := fs.Sub(testFS, "sub")
+		require.NoError(t, err)
+		matches, err := fs.Glob(sub, "*.txt")
+		require.NoError(t, err)
+		require.Equal(t, []string{"test.txt"}, matches)
+
+		// Characters of the directory recognized by path.Match are escaped.
+		sub, err = fs.Sub(testFS, "a[1]")
+		require.NoError(t, err)
+		matches, err = fs.Glob(sub, "*")
+		require.NoError(t, err)
+		require.Equal(t, []string{"b.txt"}, matches)
+
+		_, err = fs.Glob(sub, "[")
+		require.ErrorIs(t, err, path.ErrBadPattern)
+	})
+}
+
+func TestStdFS_ReadFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	testFS := StdFS(NewDirFS(tmpDir))
+	_, ok := testFS.(fs.ReadFileFS)
+	require.True(t, ok)
+
+	// Hide ReadFile to compare with the generic implementation.
+	genericFS := struct{ fs.FS }{testFS}
+
+	for _, name := range []string{"animals.txt", "empty.txt", "sub/test.txt"} {
+		actual, err := fs.ReadFile(testFS, name)
+		require.NoError(t, err)
+		expected, err := fs.ReadFile(genericFS, name)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	}
+
+	_, err := fs.ReadFile(testFS, "missing")
+	require.EqualError(t, err, "open missing: no such file or directory")
+
+	_, err = fs.ReadFile(testFS, "sub")
+	require.ErrorIs(t, err, syscall.EISDIR)
+}
+
+func TestStdFS_Stat(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	testFS := StdFS(NewDirFS(tmpDir))
+	_, ok := testFS.(fs.StatFS)
+	require.True(t, ok)
+
+	// Hide Stat to compare with the generic implementation.
+	genericFS := struct{ fs.FS }{testFS}
+
+	for _, name := range []string{".", "animals.txt", "sub", "sub/test.txt"} {
+		actual, err := fs.Stat(testFS, name)
+		require.NoError(t, err)
+		expected, err := fs.Stat(genericFS, name)
+		require.NoError(t, err)
+
+		if name == "." {
+			// The generic name is that of the host directory.
+			require.Equal(t, ".", actual.Name())
+		} else {
+			require.Equal(t, expected.Name(), actual.Name())
+		}
+		require.Equal(t, expected.Size(), actual.Size())
+		require.Equal(t, expected.Mode(), actual.Mode())
+		require.Equal(t, expected.ModTime().UnixNano(), actual.ModTime().UnixNano())
+		require.Equal(t, expected.IsDir(), actual.IsDir())
+	}
+
+	_, err := fs.Stat(testFS, "missing")
+	require.EqualError(t, err, "stat missing: no such file or directory")
+}
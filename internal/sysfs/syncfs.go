@@ -0,0 +1,190 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// SyncFS is like CopyFS, except it only copies files which differ between
+// `src` and `dst`, making it cheap to run repeatedly, like rsync.
+//
+// A regular file is considered unchanged when its size and modification time
+// are the same in both filesystems. To allow this, the modification time of
+// each file copied is set to that of its source.
+//
+// Unless `keepExtra` is true, files in `dst` which don't exist in `src` are
+// removed, so that `dst` mirrors `src`.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. The first error encountered stops the
+// sync, leaving any changes already made in place.
+func SyncFS(dst, src FS, keepExtra bool) syscall.Errno {
+	return syncDir(dst, src, ".", keepExtra)
+}
+
+// syncDir syncs the contents of the directory `dir` from `src` to `dst`. The
+// directory itself must already exist in `dst`.
+func syncDir(dst, src FS, dir string, keepExtra bool) syscall.Errno {
+	srcNames, errno := readDirNameSet(src, dir)
+	if errno != 0 {
+		return errno
+	}
+
+	for name := range srcNames {
+		if errno = syncEntry(dst, src, path.Join(dir, name), keepExtra); errno != 0 {
+			return errno
+		}
+	}
+
+	if keepExtra {
+		return 0
+	}
+
+	dstNames, errno := readDirNameSet(dst, dir)
+	if errno != 0 {
+		return errno
+	}
+	for name := range dstNames {
+		if _, ok := srcNames[name]; !ok {
+			if errno = removeAll(dst, path.Join(dir, name)); errno != 0 {
+				return errno
+			}
+		}
+	}
+	return 0
+}
+
+// syncEntry syncs the file at path `p` from `src` to `dst`, recursing into
+// directories.
+func syncEntry(dst, src FS, p string, keepExtra bool) syscall.Errno {
+	srcSt, errno := src.Lstat(p)
+	if errno != 0 {
+		return errno
+	}
+
+	dstSt, errno := dst.Lstat(p)
+	switch {
+	case errno == syscall.ENOENT: // copy below
+	case errno != 0:
+		return errno
+	case srcSt.Mode.Type() != dstSt.Mode.Type(): // replace below
+		if errno = removeAll(dst, p); errno != 0 {
+			return errno
+		}
+	case srcSt.Mode.IsDir():
+		return syncDir(dst, src, p, keepExtra)
+	default:
+		if same, errno := sameFile(dst, src, p, &dstSt, &srcSt); errno != 0 {
+			return errno
+		} else if same {
+			return 0
+		}
+		// Remove the file, instead of overwriting it, in case it is read-only.
+		if errno = dst.Unlink(p); errno != 0 {
+			return errno
+		}
+	}
+
+	switch srcSt.Mode.Type() {
+	case fs.ModeDir: // recurse instead of copying, so that times are synced.
+		if errno = dst.Mkdir(p, srcSt.Mode.Perm()); errno != 0 {
+			return errno
+		}
+		return syncDir(dst, src, p, keepExtra)
+	case 0: // regular file
+		if errno = copyEntry(dst, src, p); errno != 0 {
+			return errno
+		}
+		times := [2]syscall.Timespec{
+			syscall.NsecToTimespec(srcSt.Atim),
+			syscall.NsecToTimespec(srcSt.Mtim),
+		}
+		return dst.Utimens(p, &times, true)
+	default:
+		return copyEntry(dst, src, p)
+	}
+}
+
+// sameFile returns true when the non-directory at path `p` is the same in
+// `src` and `dst`, given they are of the same type.
+func sameFile(dst, src FS, p string, dstSt, srcSt *platform.Stat_t) (bool, syscall.Errno) {
+	if dstSt.Mode.Perm() != srcSt.Mode.Perm() {
+		return false, 0
+	}
+
+	switch srcSt.Mode.Type() {
+	case 0: // regular file
+		return dstSt.Size == srcSt.Size && dstSt.Mtim == srcSt.Mtim, 0
+	case fs.ModeSymlink:
+		dstTarget, errno := dst.Readlink(p)
+		if errno != 0 {
+			return false, errno
+		}
+		srcTarget, errno := src.Readlink(p)
+		if errno != 0 {
+			return false, errno
+		}
+		return dstTarget == srcTarget, 0
+	default:
+		return dstSt.Rdev == srcSt.Rdev, 0
+	}
+}
+
+// readDirNameSet returns the names of the entries in the directory `dir`.
+func readDirNameSet(fsys FS, dir string) (map[string]struct{}, syscall.Errno) {
+	f, errno := fsys.OpenFile(dir, os.O_RDONLY, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	dirents, errno := platform.Readdir(f, -1)
+	_ = f.Close()
+	if errno != 0 {
+		return nil, errno
+	}
+
+	names := make(map[string]struct{}, len(dirents))
+	for _, d := range dirents {
+		names[d.Name] = struct{}{}
+	}
+	return names, 0
+}
+
+// removeAll removes the file or directory tree at path `p`. Symbolic links
+// are unlinked, rather than followed, so that nothing outside the tree is
+// removed.
+func removeAll(fsys FS, p string) syscall.Errno {
+	st, errno := fsys.Lstat(p)
+	if errno != 0 {
+		return errno
+	} else if !st.Mode.IsDir() {
+		return fsys.Unlink(p)
+	}
+
+	// Don't follow a symbolic link, in case one replaced the directory since
+	// Lstat.
+	f, errno := fsys.OpenFile(p, os.O_RDONLY|platform.O_DIRECTORY|platform.O_NOFOLLOW, 0)
+	switch errno {
+	case 0:
+	case syscall.ELOOP, syscall.ENOTDIR:
+		return fsys.Unlink(p)
+	default:
+		return errno
+	}
+	dirents, errno := platform.Readdir(f, -1)
+	_ = f.Close()
+	if errno != 0 {
+		return errno
+	}
+
+	for _, d := range dirents {
+		if errno = removeAll(fsys, path.Join(p, d.Name)); errno != 0 {
+			return errno
+		}
+	}
+	return fsys.Rmdir(p)
+}
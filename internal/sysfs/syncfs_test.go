@@ -0,0 +1,114 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// writeCountingFS counts the operations that change the filesystem.
+type writeCountingFS struct {
+	FS
+	writes int
+}
+
+// OpenFile implements FS.OpenFile
+func (c *writeCountingFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	if flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		c.writes++
+	}
+	return c.FS.OpenFile(path, flag, perm)
+}
+
+// Mkdir implements FS.Mkdir
+func (c *writeCountingFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	c.writes++
+	return c.FS.Mkdir(path, perm)
+}
+
+// Unlink implements FS.Unlink
+func (c *writeCountingFS) Unlink(path string) syscall.Errno {
+	c.writes++
+	return c.FS.Unlink(path)
+}
+
+// Rmdir implements FS.Rmdir
+func (c *writeCountingFS) Rmdir(path string) syscall.Errno {
+	c.writes++
+	return c.FS.Rmdir(path)
+}
+
+func TestSyncFS(t *testing.T) {
+	srcDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(srcDir))
+	srcFS := NewDirFS(srcDir)
+
+	dstDir := t.TempDir()
+	dstFS := &writeCountingFS{FS: NewDirFS(dstDir)}
+
+	require.Zero(t, SyncFS(dstFS, srcFS, false))
+	require.NotEqual(t, 0, dstFS.writes)
+	require.NoError(t, fstest.TestFS(os.DirFS(dstDir)))
+	for _, name := range []string{"animals.txt", "sub/test.txt", "empty.txt"} {
+		requireSameContent(t, path.Join(srcDir, name), path.Join(dstDir, name))
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		dstFS.writes = 0
+		require.Zero(t, SyncFS(dstFS, srcFS, false))
+		require.Equal(t, 0, dstFS.writes)
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		// Change the size of one file, and only the mtime of another.
+		require.NoError(t, os.WriteFile(path.Join(srcDir, "empty.txt"), []byte("wazero"), 0o600))
+		mtime := time.Unix(1234567890, 0)
+		require.NoError(t, os.Chtimes(path.Join(srcDir, "animals.txt"), mtime, mtime))
+
+		dstFS.writes = 0
+		require.Zero(t, SyncFS(dstFS, srcFS, false))
+		require.Equal(t, 4, dstFS.writes) // unlink and open each file
+
+		requireSameContent(t, path.Join(srcDir, "empty.txt"), path.Join(dstDir, "empty.txt"))
+		st, err := os.Stat(path.Join(dstDir, "animals.txt"))
+		require.NoError(t, err)
+		require.Equal(t, mtime.UnixNano(), st.ModTime().UnixNano())
+
+		dstFS.writes = 0
+		require.Zero(t, SyncFS(dstFS, srcFS, false))
+		require.Equal(t, 0, dstFS.writes)
+	})
+
+	t.Run("keepExtra", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path.Join(dstDir, "extra.txt"), nil, 0o600))
+
+		require.Zero(t, SyncFS(dstFS, srcFS, true))
+		_, err := os.Stat(path.Join(dstDir, "extra.txt"))
+		require.NoError(t, err)
+	})
+
+	t.Run("removes extra", func(t *testing.T) {
+		require.NoError(t, os.MkdirAll(path.Join(dstDir, "extra", "dir"), 0o700))
+
+		require.Zero(t, SyncFS(dstFS, srcFS, false))
+		for _, name := range []string{"extra.txt", "extra"} {
+			_, err := os.Stat(path.Join(dstDir, name))
+			require.ErrorIs(t, err, fs.ErrNotExist)
+		}
+	})
+
+	t.Run("replaces type", func(t *testing.T) {
+		// Replace a directory in the destination with a file.
+		require.NoError(t, os.Remove(path.Join(dstDir, "empty.txt")))
+		require.NoError(t, os.Mkdir(path.Join(dstDir, "empty.txt"), 0o700))
+
+		require.Zero(t, SyncFS(dstFS, srcFS, false))
+		requireSameContent(t, path.Join(srcDir, "empty.txt"), path.Join(dstDir, "empty.txt"))
+	})
+}
@@ -0,0 +1,71 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// SyncOnCloseFS wraps `base` such that files opened for writes are synced
+// before they are closed. This ensures durability of data written by modules
+// that exit without calling fd_sync.
+//
+// Note: Files that don't implement platform.File, such as those from fs.FS,
+// are returned as-is, as they can't be synced.
+func SyncOnCloseFS(base FS) FS {
+	return &syncOnCloseFS{FS: base}
+}
+
+type syncOnCloseFS struct {
+	FS
+}
+
+// Open implements the same method as documented on fs.FS
+func (s *syncOnCloseFS) Open(name string) (fs.File, error) {
+	return fsOpen(s, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (s *syncOnCloseFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	f, errno := s.FS.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	switch flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR) {
+	case os.O_WRONLY, os.O_RDWR:
+	default: // os.O_RDONLY has nothing to sync.
+		return f, 0
+	}
+
+	pf, ok := f.(platform.File)
+	if !ok {
+		return f, 0
+	}
+	if p, ok := f.(platform.PathFile); ok {
+		return &syncOnClosePathFile{syncOnCloseFile{pf}, p}, 0
+	}
+	return &syncOnCloseFile{pf}, 0
+}
+
+// syncOnCloseFile calls Sync before Close.
+type syncOnCloseFile struct {
+	platform.File
+}
+
+// Close implements fs.File
+func (f *syncOnCloseFile) Close() error {
+	syncErr := f.File.Sync()
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+// syncOnClosePathFile retains platform.PathFile of the underlying file.
+type syncOnClosePathFile struct {
+	syncOnCloseFile
+	platform.PathFile
+}
@@ -0,0 +1,66 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestSyncOnCloseFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	counting := &syncCountingFS{FS: NewDirFS(tmpDir)}
+	testFS := SyncOnCloseFS(counting)
+
+	// Write without an explicit Sync.
+	f, errno := testFS.OpenFile("file", os.O_WRONLY|os.O_CREATE, 0o600)
+	require.Zero(t, errno)
+	_, err := f.(io.Writer).Write([]byte("wazero"))
+	require.NoError(t, err)
+	require.Zero(t, counting.syncs)
+
+	// Close syncs the data.
+	require.NoError(t, f.Close())
+	require.Equal(t, 1, counting.syncs)
+
+	b, err := os.ReadFile(path.Join(tmpDir, "file"))
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(b))
+
+	// Files opened for reads aren't synced.
+	f, errno = testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.Zero(t, errno)
+	require.NoError(t, f.Close())
+	require.Equal(t, 1, counting.syncs)
+}
+
+// syncCountingFS counts calls to Sync on the files it opens.
+type syncCountingFS struct {
+	FS
+	syncs int
+}
+
+// OpenFile implements FS.OpenFile
+func (s *syncCountingFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	f, errno := s.FS.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &syncCountingFile{File: f.(platform.File), s: s}, 0
+}
+
+type syncCountingFile struct {
+	platform.File
+	s *syncCountingFS
+}
+
+// Sync implements the same method as documented on os.File
+func (f *syncCountingFile) Sync() error {
+	f.s.syncs++
+	return f.File.Sync()
+}
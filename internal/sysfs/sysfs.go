@@ -6,6 +6,7 @@
 package sysfs
 
 import (
+	"fmt"
 	"io"
 	"io/fs"
 	"syscall"
@@ -29,6 +30,22 @@ import (
 // hard coded values and panics otherwise. More commonly, WASI maps syscall
 // errors to u32 numeric values.
 //
+// # Permissions
+//
+// Implementations enforce permission bits where they can, like the host does
+// for NewDirFS. For example, OpenFile fails with syscall.EACCES when the mode
+// of a file doesn't permit the access requested by its flag, such as reading
+// a file with mode 0o000. Likewise, paths through a directory without the
+// search (execute) bit, and listing a directory without the read bit, fail
+// with syscall.EACCES. Privileged users, such as root, may bypass this.
+//
+// # Concurrency
+//
+// Methods may be called concurrently, for example by modules sharing a mount,
+// so implementations must be safe for concurrent use. NewDirFS relies on the
+// host for this. Files returned by OpenFile needn't be: callers synchronize
+// their use of each file.
+//
 // # Notes
 //
 // A writable filesystem abstraction is not yet implemented as of Go 1.20. See
@@ -261,6 +278,25 @@ type FS interface {
 	//     See https://learn.microsoft.com/en-us/windows/security/threat-protection/security-policy-settings/create-symbolic-links
 	Symlink(oldPath, linkName string) syscall.Errno
 
+	// Mknod is similar to platform.Mknod, except the path is relative to
+	// this file system. This creates a regular file, named pipe (FIFO) or
+	// device, depending on the type of `mode`.
+	//
+	// # Errors
+	//
+	// The following errors are expected:
+	//   - syscall.EINVAL: `path` is invalid or `mode` has an unsupported type.
+	//   - syscall.EEXIST: `path` exists.
+	//   - syscall.ENOENT: the parent of `path` doesn't exist.
+	//   - syscall.EPERM: `mode` is a device, but the caller isn't privileged.
+	//
+	// # Notes
+	//
+	//   - `dev` is only used for devices, and is made with platform.Mkdev.
+	//   - Windows will always return syscall.ENOSYS
+	//   - This is similar to https://linux.die.net/man/2/mknod
+	Mknod(path string, mode fs.FileMode, dev uint64) syscall.Errno
+
 	// Readlink is similar to syscall.Readlink, except the path is relative to
 	// this file system.
 	//
@@ -316,6 +352,50 @@ type FS interface {
 	Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno
 }
 
+// directoryFlagFS is implemented by FS implementations that know whether
+// their OpenFile enforces platform.O_DIRECTORY.
+type directoryFlagFS interface {
+	directoryFlagEnforced() bool
+}
+
+// DirectoryFlagEnforced returns true when FS.OpenFile fails with
+// syscall.ENOTDIR if platform.O_DIRECTORY is set and the path isn't a
+// directory. When true, callers can skip a stat of the opened file to verify
+// it is a directory.
+func DirectoryFlagEnforced(fs FS) bool {
+	if f, ok := fs.(directoryFlagFS); ok {
+		return f.directoryFlagEnforced()
+	}
+	return false
+}
+
+// readOnlyFS is a marker implemented by FS implementations that reject all
+// writes. Implementations embed ReadOnly to implement it.
+type readOnlyFS interface {
+	readOnly()
+}
+
+// ReadOnly is embedded by FS implementations that reject all writes, to mark
+// them as such. For example, IsWritable returns false for them.
+type ReadOnly struct{}
+
+// readOnly implements readOnlyFS
+func (ReadOnly) readOnly() {}
+
+// IsWritable returns false when `fsys` is known to reject all writes, for
+// example the result of NewReadFS or Adapt. This doesn't attempt a write, so
+// it has no side effects.
+//
+// Note: A true result doesn't guarantee a write will succeed, for example if
+// the host denies permission to the path.
+func IsWritable(fsys FS) bool {
+	switch fsys.(type) {
+	case UnimplementedFS, readOnlyFS:
+		return false
+	}
+	return true
+}
+
 // ReaderAtOffset gets an io.Reader from a fs.File that reads from an offset,
 // yet doesn't affect the underlying position. This is used to implement
 // syscall.Pread.
@@ -333,11 +413,36 @@ func ReaderAtOffset(f fs.File, offset int64) io.Reader {
 	}
 }
 
+// FileSync is like syscall.Fsync, except it accepts any fs.File.
+func FileSync(f fs.File) (err syscall.Errno) {
+	return platform.Fsync(f)
+}
+
 // FileDatasync is like syscall.Fdatasync except that's only defined in linux.
 func FileDatasync(f fs.File) (err syscall.Errno) {
 	return platform.Fdatasync(f)
 }
 
+// Readahead hints that `length` bytes of the file at `offset` will be read
+// soon, such as before a sequential scan. A `length` of zero means until the
+// end of the file. See platform.Readahead for details.
+func Readahead(f fs.File, offset, length int64) syscall.Errno {
+	return platform.Readahead(f, offset, length)
+}
+
+// goStringFile returns a legible fmt.GoStringer format of the file, used by
+// wrappers to include the file they wrap.
+func goStringFile(f fs.File) string {
+	switch f := f.(type) {
+	case fmt.GoStringer:
+		return f.GoString()
+	case interface{ Name() string }: // e.g. *os.File
+		return fmt.Sprintf("%T(%q)", f, f.Name())
+	default:
+		return fmt.Sprintf("%T", f)
+	}
+}
+
 type enosysReader struct{}
 
 // enosysReader implements io.Reader
@@ -4,6 +4,8 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"path"
+	"strconv"
 	"testing"
 )
 
@@ -61,3 +63,44 @@ func BenchmarkReaderAtOffset(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkReadDir_Info(b *testing.B) {
+	tmpDir := b.TempDir()
+	for i := 0; i < 100; i++ {
+		if err := os.WriteFile(path.Join(tmpDir, strconv.Itoa(i)), nil, 0o600); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	benches := []struct {
+		name string
+		info func(fs.FileInfo)
+	}{
+		{name: "IsDir", info: func(info fs.FileInfo) { _ = info.IsDir() }},
+		{name: "Size", info: func(info fs.FileInfo) { _ = info.Size() }},
+	}
+
+	for _, bc := range benches {
+		bc := bc
+
+		b.Run(bc.name, func(b *testing.B) {
+			counting := &statCountingFS{FS: NewDirFS(tmpDir)}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				entries, errno := ReadDir(counting, ".")
+				if errno != 0 {
+					b.Fatal(errno)
+				}
+				for _, e := range entries {
+					info, err := e.Info()
+					if err != nil {
+						b.Fatal(err)
+					}
+					bc.info(info)
+				}
+			}
+			b.ReportMetric(float64(counting.stats)/float64(b.N), "stats/op")
+		})
+	}
+}
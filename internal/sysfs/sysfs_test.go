@@ -4,16 +4,20 @@ import (
 	"bytes"
 	"embed"
 	_ "embed"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path"
 	"runtime"
 	"sort"
+	"strings"
 	"syscall"
 	"testing"
 	gofstest "testing/fstest"
+	"time"
 
+	"github.com/tetratelabs/wazero/internal/fstest"
 	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
@@ -670,6 +674,45 @@ func TestWriterAtOffset_empty(t *testing.T) {
 	}
 }
 
+// TestWriterAtOffset_pastEOF ensures writing past the end of a file fills
+// the gap with zeros, as described on platform.File.
+func TestWriterAtOffset_pastEOF(t *testing.T) {
+	tmpDir := t.TempDir()
+	dirFS := NewDirFS(tmpDir)
+
+	tests := []struct {
+		name string
+		fs   FS
+	}{
+		{name: "sysfs.dirFS", fs: dirFS},
+		{name: "sysfs.CountingFS", fs: NewCountingFS(dirFS, false)},
+		{name: "sysfs.DeadlineFS", fs: DeadlineFS(dirFS, time.Minute, nil)},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			name := path.Base(t.Name())
+			f, errno := tc.fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+			require.Zero(t, errno)
+			defer f.Close()
+
+			n, err := WriterAtOffset(f, 100).Write([]byte("x"))
+			require.NoError(t, err)
+			require.Equal(t, 1, n)
+
+			st, errno := platform.StatFile(f)
+			require.Zero(t, errno)
+			require.Equal(t, int64(101), st.Size)
+
+			b, err := io.ReadAll(ReaderAtOffset(f, 0))
+			require.NoError(t, err)
+			require.Equal(t, append(make([]byte, 100), 'x'), b)
+		})
+	}
+}
+
 func TestWriterAtOffset_Unsupported(t *testing.T) {
 	tmpDir := t.TempDir()
 	dirFS := NewDirFS(tmpDir)
@@ -743,3 +786,111 @@ func requireIno(t *testing.T, dirents []*platform.Dirent, expectIno bool) {
 func joinPath(dirName, baseName string) string {
 	return path.Join(dirName, baseName)
 }
+
+func TestDirectoryFlagEnforced(t *testing.T) {
+	dirFS := NewDirFS(t.TempDir())
+
+	require.Equal(t, platform.DirectoryFlagEnforced, DirectoryFlagEnforced(dirFS))
+	require.Equal(t, platform.DirectoryFlagEnforced, DirectoryFlagEnforced(NewReadFS(dirFS)))
+	require.False(t, DirectoryFlagEnforced(Adapt(gofstest.MapFS{})))
+	require.False(t, DirectoryFlagEnforced(NewReadFS(Adapt(gofstest.MapFS{}))))
+	require.False(t, DirectoryFlagEnforced(UnimplementedFS{}))
+}
+
+func TestGoStringFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	procFS := NewProcFS([]string{"uptime"}, func(string) ([]byte, bool) { return nil, true })
+	rootFS, err := NewRootFS([]FS{procFS, NewDirFS(tmpDir)}, []string{"/", "/tmp"})
+	require.NoError(t, err)
+	testFS := DeadlineFS(rootFS, time.Minute, nil)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{
+			path:     "/",
+			expected: `&sysfs.deadlineFile{path: "/", timeout: 1m0s, f: &sysfs.openRootDir{c: "[proc:/ ` + tmpDir + `:/tmp]", f: &sysfs.procDir{path: "."}}}`,
+		},
+		{
+			path:     "/uptime",
+			expected: `&sysfs.deadlineFile{path: "/uptime", timeout: 1m0s, f: &sysfs.procFile{path: "uptime"}}`,
+		},
+		{
+			path:     "/tmp/animals.txt",
+			expected: `&sysfs.deadlineFile{path: "/tmp/animals.txt", timeout: 1m0s, f: *os.File("` + joinPath(tmpDir, "animals.txt") + `")}`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.path, func(t *testing.T) {
+			if runtime.GOOS == "windows" && strings.HasPrefix(tc.path, "/tmp") {
+				t.Skip("windows wraps *os.File")
+			}
+
+			f, errno := testFS.OpenFile(tc.path, os.O_RDONLY, 0)
+			require.Zero(t, errno)
+			defer f.Close()
+
+			require.Equal(t, tc.expected, fmt.Sprintf("%#v", f))
+		})
+	}
+}
+
+func TestIsWritable(t *testing.T) {
+	dirFS := NewDirFS(t.TempDir())
+	procFS := NewProcFS(nil, func(string) ([]byte, bool) { return nil, false })
+
+	tests := []struct {
+		name     string
+		fs       FS
+		expected bool
+	}{
+		{name: "UnimplementedFS", fs: UnimplementedFS{}, expected: false},
+		{name: "NewDirFS", fs: dirFS, expected: true},
+		{name: "NewReadFS", fs: NewReadFS(dirFS), expected: false},
+		{name: "Adapt", fs: Adapt(gofstest.MapFS{}), expected: false},
+		{name: "MergeFS", fs: MergeFS(dirFS, procFS), expected: false},
+		{name: "NewProcFS", fs: procFS, expected: false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, IsWritable(tc.fs))
+		})
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	dirFS := NewDirFS(t.TempDir())
+	rootFS, err := NewRootFS([]FS{dirFS, NewReadFS(dirFS)}, []string{"/", "/ro"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		fs       FS
+		expected bool
+	}{
+		{name: "NewDirFS", fs: dirFS, expected: false},
+		{name: "NewRootFS", fs: rootFS, expected: false},
+		{name: "NewReadFS", fs: NewReadFS(dirFS), expected: true},
+		{name: "Adapt", fs: Adapt(gofstest.MapFS{}), expected: true},
+		{name: "MergeFS", fs: MergeFS(dirFS, dirFS), expected: true},
+		{name: "NewProcFS", fs: NewProcFS(nil, nil), expected: true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, ok := tc.fs.(readOnlyFS)
+			require.Equal(t, tc.expected, ok)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package sysfs
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// TarFS returns a read-only FS of the files in the tar archive `r`, which is
+// `size` bytes. This allows mounting an archive, such as the standard library
+// of a language runtime, without extracting it.
+//
+// The archive is indexed when TarFS is called, so opening a file doesn't scan
+// it again. Files are read directly from `r`, and support ReadAt and Seek.
+// Symbolic and hard links in the archive are resolved within it.
+//
+// An error is returned if the archive is malformed, contains a path outside
+// of it, such as "../file", or contains a sparse file.
+func TarFS(r io.ReaderAt, size int64) (FS, error) {
+	sr := io.NewSectionReader(r, 0, size)
+	tr := tar.NewReader(sr)
+	a := newArchiveFS("tar")
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		} else if isSparse(hdr) {
+			return nil, fmt.Errorf("sparse file in archive: %q", hdr.Name)
+		}
+
+		name, err := archivePath(hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if hdr.Typeflag == tar.TypeLink {
+			target, err := archivePath(hdr.Linkname)
+			if err != nil {
+				return nil, err
+			}
+			e, ok := a.entries[target]
+			if !ok || e.st.Mode.IsDir() {
+				return nil, fmt.Errorf("invalid hard link in archive: %q -> %q", hdr.Name, hdr.Linkname)
+			}
+			e.st.Nlink++
+			a.add(name, e)
+			continue
+		}
+
+		// The reader is positioned at the start of the file's contents.
+		offset, err := sr.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		e := &archiveEntry{st: tarStat(hdr)}
+		switch e.st.Mode.Type() {
+		case 0: // regular file
+			fileSize := hdr.Size
+			e.open = func() (io.Reader, syscall.Errno) {
+				return io.NewSectionReader(r, offset, fileSize), 0
+			}
+		case fs.ModeSymlink:
+			e.link = hdr.Linkname
+			e.st.Size = int64(len(hdr.Linkname))
+		case fs.ModeDir:
+			e.st.Size = 0
+		}
+		a.add(name, e)
+	}
+	a.sortNames()
+	return a, nil
+}
+
+// isSparse returns true if `hdr` is a sparse file, whose contents aren't
+// stored contiguously.
+func isSparse(hdr *tar.Header) bool {
+	if hdr.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for k := range hdr.PAXRecords {
+		if strings.HasPrefix(k, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// tarStat converts the tar header to a platform.Stat_t.
+func tarStat(hdr *tar.Header) platform.Stat_t {
+	st := platform.Stat_t{
+		Uid:   uint32(hdr.Uid),
+		Gid:   uint32(hdr.Gid),
+		Mode:  hdr.FileInfo().Mode(),
+		Nlink: 1,
+		Size:  hdr.Size,
+		Mtim:  hdr.ModTime.UnixNano(),
+	}
+	st.Atim, st.Ctim = st.Mtim, st.Mtim
+	if !hdr.AccessTime.IsZero() {
+		st.Atim = hdr.AccessTime.UnixNano()
+	}
+	if !hdr.ChangeTime.IsZero() {
+		st.Ctim = hdr.ChangeTime.UnixNano()
+	}
+	return st
+}
@@ -0,0 +1,163 @@
+package sysfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// tarBytes returns a tar archive of `headers`, with the contents of regular
+// files in `contents`, keyed by name.
+func tarBytes(t *testing.T, headers []*tar.Header, contents map[string][]byte) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range headers {
+		data := contents[hdr.Name]
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(data))
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func TestTarFS(t *testing.T) {
+	big := bytes.Repeat([]byte("wazero"), 32*1024) // larger than read buffers
+	mtime := time.Unix(1672531200, 0)
+	archive := tarBytes(t, []*tar.Header{
+		{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0o755, ModTime: mtime},
+		{Name: "dir/file", Typeflag: tar.TypeReg, Mode: 0o644, ModTime: mtime},
+		{Name: "big", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "implied/file", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "dir/file"},
+		{Name: "abs", Typeflag: tar.TypeSymlink, Linkname: "/dir/file"},
+		{Name: "dirlink", Typeflag: tar.TypeSymlink, Linkname: "dir"},
+		{Name: "hard", Typeflag: tar.TypeLink, Linkname: "dir/file"},
+		{Name: "loop", Typeflag: tar.TypeSymlink, Linkname: "loop"},
+	}, map[string][]byte{
+		"dir/file":     []byte("wazero"),
+		"big":          big,
+		"implied/file": []byte("implied"),
+	})
+	testFS, err := TarFS(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	t.Run("ReadDir", func(t *testing.T) {
+		requireDirNames(t, testFS, ".", "abs", "big", "dir", "dirlink", "hard", "implied", "link", "loop")
+		requireDirNames(t, testFS, "dir", "file")
+		requireDirNames(t, testFS, "implied", "file")
+		requireDirNames(t, testFS, "dirlink", "file")
+	})
+
+	t.Run("Stat", func(t *testing.T) {
+		st, errno := testFS.Stat("dir/file")
+		require.Zero(t, errno)
+		require.Equal(t, fs.FileMode(0o644), st.Mode)
+		require.Equal(t, int64(6), st.Size)
+		require.Equal(t, mtime.UnixNano(), st.Mtim)
+
+		st, errno = testFS.Stat("implied")
+		require.Zero(t, errno)
+		require.True(t, st.Mode.IsDir())
+
+		_, errno = testFS.Stat("missing")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+		_, errno = testFS.Stat("dir/file/child")
+		require.EqualErrno(t, syscall.ENOTDIR, errno)
+		_, errno = testFS.Stat("loop")
+		require.EqualErrno(t, syscall.ELOOP, errno)
+	})
+
+	t.Run("symlinks", func(t *testing.T) {
+		st, errno := testFS.Lstat("link")
+		require.Zero(t, errno)
+		require.Equal(t, fs.ModeSymlink, st.Mode.Type())
+
+		target, errno := testFS.Readlink("link")
+		require.Zero(t, errno)
+		require.Equal(t, "dir/file", target)
+		_, errno = testFS.Readlink("dir/file")
+		require.EqualErrno(t, syscall.EINVAL, errno)
+
+		for _, name := range []string{"link", "abs", "dirlink/file", "dirlink/../dir/file"} {
+			b, errno := ReadFile(testFS, name)
+			require.Zero(t, errno, name)
+			require.Equal(t, "wazero", string(b), name)
+		}
+
+		_, errno = testFS.OpenFile("link", os.O_RDONLY|platform.O_NOFOLLOW, 0)
+		require.EqualErrno(t, syscall.ELOOP, errno)
+	})
+
+	t.Run("hard link", func(t *testing.T) {
+		b, errno := ReadFile(testFS, "hard")
+		require.Zero(t, errno)
+		require.Equal(t, "wazero", string(b))
+
+		st, errno := testFS.Stat("hard")
+		require.Zero(t, errno)
+		require.Equal(t, uint64(2), st.Nlink)
+		fileSt, errno := testFS.Stat("dir/file")
+		require.Zero(t, errno)
+		require.Equal(t, fileSt.Ino, st.Ino)
+	})
+
+	t.Run("large file", func(t *testing.T) {
+		f, errno := testFS.OpenFile("big", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		b, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.Equal(t, big, b)
+
+		// Reads are independent of the offset of the file.
+		buf := make([]byte, 6)
+		n, err := f.(io.ReaderAt).ReadAt(buf, 6*1000)
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(buf[:n]))
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		_, errno := testFS.OpenFile("dir/file", os.O_RDWR, 0)
+		require.EqualErrno(t, syscall.ENOSYS, errno)
+		require.EqualErrno(t, syscall.EROFS, testFS.Mkdir("new", 0o755))
+		require.EqualErrno(t, syscall.EROFS, testFS.Unlink("dir/file"))
+	})
+}
+
+func TestTarFS_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []*tar.Header
+	}{
+		{
+			name:    "outside archive",
+			headers: []*tar.Header{{Name: "../file", Typeflag: tar.TypeReg}},
+		},
+		{
+			name:    "hard link to missing",
+			headers: []*tar.Header{{Name: "hard", Typeflag: tar.TypeLink, Linkname: "missing"}},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			archive := tarBytes(t, tc.headers, nil)
+			_, err := TarFS(bytes.NewReader(archive), int64(len(archive)))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMountUmask(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows only supports the read-only bit")
+	}
+
+	privateDir, sharedDir := t.TempDir(), t.TempDir()
+	rootFS, err := NewRootFS([]FS{NewDirFS(privateDir), NewDirFS(sharedDir)},
+		[]string{"/private", "/shared"}, MountUmask("/private", 0o077), MountUmask("/shared", 0o022))
+	require.NoError(t, err)
+
+	for _, dir := range []string{"private", "shared"} {
+		f, errno := rootFS.OpenFile(path.Join(dir, "file"), os.O_RDWR|os.O_CREATE, 0o666)
+		require.Zero(t, errno)
+		require.NoError(t, f.Close())
+
+		require.Zero(t, rootFS.Mkdir(path.Join(dir, "dir"), 0o777))
+	}
+
+	requirePerm := func(dir, name string, expected fs.FileMode) {
+		st, err := os.Stat(path.Join(dir, name))
+		require.NoError(t, err)
+		require.Equal(t, expected, st.Mode().Perm())
+	}
+
+	requirePerm(privateDir, "file", 0o600)
+	requirePerm(privateDir, "dir", 0o700)
+	requirePerm(sharedDir, "file", 0o644)
+	requirePerm(sharedDir, "dir", 0o755)
+
+	t.Run("chmod isn't masked", func(t *testing.T) {
+		require.Zero(t, rootFS.Chmod("private/file", 0o644))
+		requirePerm(privateDir, "file", 0o644)
+	})
+
+	t.Run("existing files aren't masked", func(t *testing.T) {
+		f, errno := rootFS.OpenFile("private/file", os.O_RDWR, 0)
+		require.Zero(t, errno)
+		require.NoError(t, f.Close())
+		requirePerm(privateDir, "file", 0o644)
+	})
+
+	t.Run("single root mount", func(t *testing.T) {
+		rootDir := t.TempDir()
+		rootFS, err := NewRootFS([]FS{NewDirFS(rootDir)}, []string{"/"}, MountUmask("/", 0o077))
+		require.NoError(t, err)
+
+		require.Zero(t, WriteFile(rootFS, "file", nil, 0o666))
+		requirePerm(rootDir, "file", 0o600)
+	})
+}
+
+func TestMountUmask_notMounted(t *testing.T) {
+	_, err := NewRootFS([]FS{NewDirFS(".")}, []string{"/tmp"}, MountUmask("/usr", 0o077))
+	require.EqualError(t, err, `umask for guest path "usr", which isn't mounted: [.:/tmp]`)
+}
@@ -76,6 +76,11 @@ func (UnimplementedFS) Link(_, _ string) syscall.Errno {
 	return syscall.ENOSYS
 }
 
+// Mknod implements FS.Mknod
+func (UnimplementedFS) Mknod(string, fs.FileMode, uint64) syscall.Errno {
+	return syscall.ENOSYS
+}
+
 // Symlink implements FS.Symlink
 func (UnimplementedFS) Symlink(_, _ string) syscall.Errno {
 	return syscall.ENOSYS
@@ -0,0 +1,176 @@
+package sysfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"sync"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// VerifyFS wraps `base` to check the contents of files against their SHA-256,
+// in `digests`, keyed by path, such as "lib/python3.11/os.py". This protects
+// read-only mounts from tampering. Reads of a file whose contents don't match
+// fail with syscall.EIO. Files not in `digests` aren't checked.
+//
+// Sequential reads are hashed as they go, and checked when they reach the end
+// of the file, so a mismatch is only reported then. ReadAt and Seek check the
+// whole file first instead, which requires the file to implement io.ReaderAt.
+//
+// Once a file matches, it isn't checked again until its size, modification
+// time or inode change. This only guards against accidental changes: anything
+// which can write to `base` can also restore the modification time.
+func VerifyFS(base FS, digests map[string][]byte) FS {
+	cleaned := make(map[string][]byte, len(digests))
+	for name, digest := range digests {
+		cleaned[procPath(name)] = digest
+	}
+	return &verifyFS{FS: base, digests: cleaned, verified: map[string]platform.Stat_t{}}
+}
+
+type verifyFS struct {
+	FS
+	digests map[string][]byte
+
+	// mux guards verified, which is the stat of each file when it matched.
+	mux      sync.Mutex
+	verified map[string]platform.Stat_t
+}
+
+// isVerified returns true if the file `name`, with the stat `st`, matched
+// since it last changed.
+func (v *verifyFS) isVerified(name string, st *platform.Stat_t) bool {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+
+	prev, ok := v.verified[name]
+	return ok && prev.Ino == st.Ino && prev.Size == st.Size && prev.Mtim == st.Mtim
+}
+
+// setVerified records the file `name` matched, when it had the stat `st`.
+func (v *verifyFS) setVerified(name string, st *platform.Stat_t) {
+	v.mux.Lock()
+	v.verified[name] = *st
+	v.mux.Unlock()
+}
+
+// Open implements the same method as documented on fs.FS
+func (v *verifyFS) Open(name string) (fs.File, error) {
+	return fsOpen(v, name)
+}
+
+// OpenFile implements FS.OpenFile
+func (v *verifyFS) OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	f, errno := v.FS.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	name := procPath(path)
+	digest, ok := v.digests[name]
+	if !ok {
+		return f, 0
+	}
+
+	st, errno := platform.StatFile(f)
+	if errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	} else if !st.Mode.IsRegular() || v.isVerified(name, &st) {
+		return f, 0
+	}
+	return &verifyFile{wrappedFile: wrappedFile{path: name, f: f}, v: v, digest: digest, st: st, h: sha256.New()}, 0
+}
+
+// verifyFile checks the contents of a file of VerifyFS match its digest.
+type verifyFile struct {
+	wrappedFile
+	v      *verifyFS
+	digest []byte
+	// st is the stat of the file when it was opened.
+	st platform.Stat_t
+	// h hashes sequential reads, or is nil once the file matched.
+	h hash.Hash
+}
+
+// GoString implements fmt.GoStringer
+func (f *verifyFile) GoString() string {
+	return fmt.Sprintf("&sysfs.verifyFile{path: %q, f: %s}", f.path, goStringFile(f.f))
+}
+
+// Read implements io.Reader
+func (f *verifyFile) Read(p []byte) (int, error) {
+	n, err := f.f.Read(p)
+	if f.h == nil {
+		return n, err
+	}
+	f.h.Write(p[:n])
+	if err == io.EOF {
+		if errno := f.check(f.h.Sum(nil)); errno != 0 {
+			return 0, errno
+		}
+	}
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt
+func (f *verifyFile) ReadAt(p []byte, off int64) (int, error) {
+	ra, ok := f.f.(io.ReaderAt)
+	if !ok {
+		return 0, syscall.ENOSYS
+	}
+	if errno := f.verifyAll(); errno != 0 {
+		return 0, errno
+	}
+	return ra.ReadAt(p, off)
+}
+
+// Seek implements io.Seeker
+func (f *verifyFile) Seek(offset int64, whence int) (int64, error) {
+	s, ok := f.f.(io.Seeker)
+	if !ok {
+		return 0, syscall.ENOSYS
+	}
+	if errno := f.verifyAll(); errno != 0 {
+		return 0, errno
+	}
+	return s.Seek(offset, whence)
+}
+
+// verifyAll checks the whole file, unless it already matched, as reads are
+// no longer sequential.
+func (f *verifyFile) verifyAll() syscall.Errno {
+	if f.h == nil {
+		return 0
+	}
+	ra, ok := f.f.(io.ReaderAt)
+	if !ok {
+		return syscall.ENOSYS
+	}
+	st, errno := platform.StatFile(f.f)
+	if errno != 0 {
+		return errno
+	}
+	// Hash through the open file, as the path may now be a different one.
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(ra, 0, st.Size)); err != nil {
+		return platform.UnwrapOSError(err)
+	}
+	f.st = st
+	return f.check(h.Sum(nil))
+}
+
+// check returns syscall.EIO unless `sum` is the expected digest. Otherwise,
+// the file isn't checked again.
+func (f *verifyFile) check(sum []byte) syscall.Errno {
+	if !bytes.Equal(sum, f.digest) {
+		return syscall.EIO
+	}
+	f.h = nil
+	f.v.setVerified(f.path, &f.st)
+	return 0
+}
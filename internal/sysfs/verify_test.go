@@ -0,0 +1,90 @@
+package sysfs
+
+import (
+	"crypto/sha256"
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestVerifyFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "match"), []byte("wazero"), 0o600))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "tampered"), []byte("wazer0"), 0o600))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "unlisted"), []byte("anything"), 0o600))
+	digest := sha256.Sum256([]byte("wazero"))
+	testFS := VerifyFS(NewDirFS(tmpDir), map[string][]byte{
+		"match":     digest[:],
+		"/tampered": digest[:], // paths are cleaned
+	})
+
+	t.Run("match", func(t *testing.T) {
+		b, errno := ReadFile(testFS, "match")
+		require.Zero(t, errno)
+		require.Equal(t, "wazero", string(b))
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		_, errno := ReadFile(testFS, "tampered")
+		require.EqualErrno(t, syscall.EIO, errno)
+	})
+
+	t.Run("partial reads", func(t *testing.T) {
+		f, errno := testFS.OpenFile("tampered", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		// Reads succeed until the end of the file is reached.
+		buf := make([]byte, 3)
+		n, err := f.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, "waz", string(buf[:n]))
+		_, err = io.ReadFull(f, buf)
+		require.NoError(t, err)
+		_, err = f.Read(buf)
+		require.EqualErrno(t, syscall.EIO, err.(syscall.Errno))
+	})
+
+	t.Run("ReadAt checks the whole file", func(t *testing.T) {
+		f, errno := testFS.OpenFile("tampered", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		_, err := f.(io.ReaderAt).ReadAt(make([]byte, 1), 0)
+		require.EqualErrno(t, syscall.EIO, err.(syscall.Errno))
+	})
+
+	t.Run("ReadAt checks the open file", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("open files can't be replaced on windows")
+		}
+		f, errno := testFS.OpenFile("tampered", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		// Replacing the path doesn't change the file which was opened.
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "replacement"), []byte("wazero"), 0o600))
+		require.NoError(t, os.Rename(path.Join(tmpDir, "replacement"), path.Join(tmpDir, "tampered")))
+		defer os.WriteFile(path.Join(tmpDir, "tampered"), []byte("wazer0"), 0o600) //nolint
+
+		_, err := f.(io.ReaderAt).ReadAt(make([]byte, 1), 0)
+		require.EqualErrno(t, syscall.EIO, err.(syscall.Errno))
+	})
+
+	t.Run("changed after it matched", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "match"), []byte("wazero!"), 0o600))
+		_, errno := ReadFile(testFS, "match")
+		require.EqualErrno(t, syscall.EIO, errno)
+	})
+
+	t.Run("unlisted", func(t *testing.T) {
+		b, errno := ReadFile(testFS, "unlisted")
+		require.Zero(t, errno)
+		require.Equal(t, "anything", string(b))
+	})
+}
@@ -0,0 +1,68 @@
+package sysfs
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+)
+
+// SkipAll is used as a return value from an fs.WalkDirFunc passed to WalkDir
+// to skip all remaining files and directories. It is the same as fs.SkipAll,
+// which isn't available until Go 1.20.
+var SkipAll = errors.New("skip everything and stop the walk")
+
+// WalkDir is like fs.WalkDir, except it reads `fsys` directly. It calls `fn`
+// for each file or directory in the tree rooted at `root`, including `root`,
+// in lexical order.
+//
+// Like fs.WalkDir, `fn` can return fs.SkipDir to skip a directory, or the
+// remaining entries of the directory containing a file. Returning SkipAll
+// stops the walk. An error reading a directory is passed to `fn` a second time
+// for that directory, which decides whether to continue. Errors are
+// syscall.Errno.
+//
+// Symbolic links are reported, but not followed, except for `root`.
+func WalkDir(fsys FS, root string, fn fs.WalkDirFunc) error {
+	var err error
+	if st, errno := fsys.Stat(root); errno != 0 {
+		err = fn(root, nil, errno)
+	} else {
+		d := &dirEntry{fs: fsys, path: root, name: path.Base(root), typ: st.Mode.Type(), st: &st}
+		err = walkDir(fsys, root, d, fn)
+	}
+	if err == fs.SkipDir || err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkDir recursively descends `name`, calling `fn`.
+func walkDir(fsys FS, name string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(name, d, nil); err != nil || !d.IsDir() {
+		if err == fs.SkipDir && d.IsDir() {
+			err = nil // successfully skipped the directory
+		}
+		return err
+	}
+
+	entries, errno := ReadDir(fsys, name)
+	if errno != 0 {
+		// Let the function decide whether to continue.
+		if err := fn(name, d, errno); err != nil {
+			if err == fs.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, e := range entries {
+		if err := walkDir(fsys, path.Join(name, e.Name()), e, fn); err != nil {
+			if err == fs.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
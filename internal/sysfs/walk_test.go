@@ -0,0 +1,122 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestWalkDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"a/b", "a/c", "d"} {
+		require.NoError(t, os.MkdirAll(path.Join(tmpDir, dir), 0o700))
+	}
+	for _, file := range []string{"a/b/x", "a/b/y", "a/c/z", "a/w", "d/v", "e"} {
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, file), nil, 0o600))
+	}
+	testFS := NewDirFS(tmpDir)
+
+	// walk returns the paths visited, stopping with the result of `stop`.
+	walk := func(t *testing.T, fsys FS, root string, stop func(string, fs.DirEntry) error) []string {
+		var visited []string
+		err := WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+			require.NoError(t, err)
+			visited = append(visited, p)
+			if stop != nil {
+				return stop(p, d)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		return visited
+	}
+
+	t.Run("all", func(t *testing.T) {
+		require.Equal(t, []string{
+			".", "a", "a/b", "a/b/x", "a/b/y", "a/c", "a/c/z", "a/w", "d", "d/v", "e",
+		}, walk(t, testFS, ".", nil))
+	})
+
+	t.Run("subtree", func(t *testing.T) {
+		require.Equal(t, []string{"a/b", "a/b/x", "a/b/y"}, walk(t, testFS, "a/b", nil))
+	})
+
+	t.Run("SkipDir on directory", func(t *testing.T) {
+		require.Equal(t, []string{
+			".", "a", "a/b", "a/c", "a/c/z", "a/w", "d", "d/v", "e",
+		}, walk(t, testFS, ".", func(p string, _ fs.DirEntry) error {
+			if p == "a/b" {
+				return fs.SkipDir
+			}
+			return nil
+		}))
+	})
+
+	t.Run("SkipDir on file", func(t *testing.T) {
+		// Skips the remaining entries of the parent.
+		require.Equal(t, []string{
+			".", "a", "a/b", "a/b/x", "a/c", "a/c/z", "a/w", "d", "d/v", "e",
+		}, walk(t, testFS, ".", func(p string, _ fs.DirEntry) error {
+			if p == "a/b/x" {
+				return fs.SkipDir
+			}
+			return nil
+		}))
+	})
+
+	t.Run("SkipAll", func(t *testing.T) {
+		require.Equal(t, []string{".", "a", "a/b", "a/b/x"}, walk(t, testFS, ".", func(p string, _ fs.DirEntry) error {
+			if p == "a/b/x" {
+				return SkipAll
+			}
+			return nil
+		}))
+	})
+
+	t.Run("error reading directory", func(t *testing.T) {
+		faultFS := FaultFS(testFS, func(op, p string) error {
+			if op == "OpenFile" && p == "a/b" {
+				return syscall.EACCES
+			}
+			return nil
+		})
+
+		var visited []string
+		var errs []error
+		err := WalkDir(faultFS, "a", func(p string, d fs.DirEntry, err error) error {
+			visited = append(visited, p)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		// The directory is visited twice: the second time with the error.
+		require.Equal(t, []string{"a", "a/b", "a/b", "a/c", "a/c/z", "a/w"}, visited)
+		require.Equal(t, []error{syscall.EACCES}, errs)
+	})
+
+	t.Run("error returned", func(t *testing.T) {
+		err := WalkDir(testFS, ".", func(p string, _ fs.DirEntry, _ error) error {
+			if p == "a/c" {
+				return syscall.EIO
+			}
+			return nil
+		})
+		require.EqualErrno(t, syscall.EIO, err.(syscall.Errno))
+	})
+
+	t.Run("missing root", func(t *testing.T) {
+		err := WalkDir(testFS, "missing", func(p string, d fs.DirEntry, err error) error {
+			require.Equal(t, "missing", p)
+			require.Nil(t, d)
+			return err
+		})
+		require.EqualErrno(t, syscall.ENOENT, err.(syscall.Errno))
+	})
+}
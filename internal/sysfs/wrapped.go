@@ -0,0 +1,100 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"syscall"
+)
+
+// wrappedFile forwards each operation to the file it wraps, returning
+// syscall.ENOSYS for methods that file doesn't implement. File wrappers embed
+// it, and only override the methods they change.
+type wrappedFile struct {
+	// path is the path the file was opened with.
+	path string
+	f    fs.File
+}
+
+// Supports implements CapabilityFile
+func (f *wrappedFile) Supports(c Capability) bool {
+	return supportsWrapped(f, f.f, c)
+}
+
+// Stat implements fs.File
+func (f *wrappedFile) Stat() (fs.FileInfo, error) {
+	return f.f.Stat()
+}
+
+// Read implements io.Reader
+func (f *wrappedFile) Read(p []byte) (int, error) {
+	return f.f.Read(p)
+}
+
+// ReadAt implements io.ReaderAt
+func (f *wrappedFile) ReadAt(p []byte, off int64) (int, error) {
+	if ra, ok := f.f.(io.ReaderAt); ok {
+		return ra.ReadAt(p, off)
+	}
+	return 0, syscall.ENOSYS
+}
+
+// Write implements io.Writer
+func (f *wrappedFile) Write(p []byte) (int, error) {
+	if w, ok := f.f.(io.Writer); ok {
+		return w.Write(p)
+	}
+	return 0, syscall.ENOSYS
+}
+
+// WriteAt implements io.WriterAt
+func (f *wrappedFile) WriteAt(p []byte, off int64) (int, error) {
+	if wa, ok := f.f.(io.WriterAt); ok {
+		return wa.WriteAt(p, off)
+	}
+	return 0, syscall.ENOSYS
+}
+
+// Seek implements io.Seeker
+func (f *wrappedFile) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := f.f.(io.Seeker); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, syscall.ENOSYS
+}
+
+// ReadDir implements fs.ReadDirFile
+func (f *wrappedFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if rd, ok := f.f.(fs.ReadDirFile); ok {
+		return rd.ReadDir(n)
+	}
+	return nil, syscall.ENOTDIR
+}
+
+// Sync implements the same method as documented on os.File
+func (f *wrappedFile) Sync() error {
+	if s, ok := f.f.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil // same as platform.Fsync on fake files.
+}
+
+// Chmod implements the same method as documented on os.File
+func (f *wrappedFile) Chmod(mode fs.FileMode) error {
+	if c, ok := f.f.(interface{ Chmod(fs.FileMode) error }); ok {
+		return c.Chmod(mode)
+	}
+	return syscall.ENOSYS
+}
+
+// Truncate implements the same method as documented on os.File
+func (f *wrappedFile) Truncate(size int64) error {
+	if t, ok := f.f.(interface{ Truncate(int64) error }); ok {
+		return t.Truncate(size)
+	}
+	return syscall.ENOSYS
+}
+
+// Close implements fs.File
+func (f *wrappedFile) Close() error {
+	return f.f.Close()
+}
@@ -0,0 +1,53 @@
+package sysfs
+
+import (
+	"crypto/sha256"
+	"io/fs"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// TestWrappedFile ensures file wrappers forward the methods to change the
+// attributes of a file, such as used by fd_filestat_set_size.
+func TestWrappedFile(t *testing.T) {
+	digest := sha256.Sum256([]byte("wazero"))
+	tests := []struct {
+		name string
+		wrap func(FS) FS
+	}{
+		{name: "CountingFS", wrap: func(base FS) FS { return NewCountingFS(base, false) }},
+		{name: "DeadlineFS", wrap: func(base FS) FS { return DeadlineFS(base, time.Minute, nil) }},
+		{name: "FaultFS", wrap: func(base FS) FS { return FaultFS(base, func(string, string) error { return nil }) }},
+		{name: "LimitFS", wrap: func(base FS) FS { return LimitFS(base, 1024) }},
+		{name: "RetryFS", wrap: func(base FS) FS { return RetryFS(base, RetryPolicy{}) }},
+		{name: "VerifyFS", wrap: func(base FS) FS { return VerifyFS(base, map[string][]byte{"file": digest[:]}) }},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			base := NewDirFS(t.TempDir())
+			require.Zero(t, WriteFile(base, "file", []byte("wazero"), 0o600))
+
+			f, errno := tc.wrap(base).OpenFile("file", os.O_RDWR, 0)
+			require.Zero(t, errno)
+			defer f.Close()
+
+			require.NoError(t, f.(interface{ Truncate(int64) error }).Truncate(2))
+			st, errno := base.Stat("file")
+			require.Zero(t, errno)
+			require.Equal(t, int64(2), st.Size)
+
+			require.NoError(t, f.(interface{ Chmod(fs.FileMode) error }).Chmod(0o400))
+			if runtime.GOOS != "windows" { // only the write bit is supported
+				st, errno = base.Stat("file")
+				require.Zero(t, errno)
+				require.Equal(t, fs.FileMode(0o400), st.Mode.Perm())
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// backendFS opens files that fail the test when read or written.
+type backendFS struct {
+	UnimplementedFS
+	t *testing.T
+}
+
+// OpenFile implements FS.OpenFile
+func (b *backendFS) OpenFile(string, int, fs.FileMode) (fs.File, syscall.Errno) {
+	return &backendFile{t: b.t}, 0
+}
+
+type backendFile struct {
+	fs.File
+	t *testing.T
+}
+
+func (f *backendFile) Read([]byte) (int, error) {
+	f.t.Fatal("unexpected Read")
+	return 0, nil
+}
+
+func (f *backendFile) ReadAt([]byte, int64) (int, error) {
+	f.t.Fatal("unexpected ReadAt")
+	return 0, nil
+}
+
+func (f *backendFile) Write([]byte) (int, error) {
+	f.t.Fatal("unexpected Write")
+	return 0, nil
+}
+
+func (f *backendFile) WriteAt([]byte, int64) (int, error) {
+	f.t.Fatal("unexpected WriteAt")
+	return 0, nil
+}
+
+func (f *backendFile) Close() error {
+	return nil
+}
+
+// TestZeroLength ensures zero-length reads and writes succeed without reaching
+// the underlying file.
+func TestZeroLength(t *testing.T) {
+	base := &backendFS{t: t}
+
+	tests := []struct {
+		name string
+		fs   FS
+	}{
+		{name: "sysfs.CountingFS", fs: NewCountingFS(base, false)},
+		{name: "sysfs.DeadlineFS", fs: DeadlineFS(base, time.Minute, nil)},
+		{name: "sysfs.RetryFS", fs: RetryFS(base, RetryPolicy{MaxAttempts: 2})},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			f, errno := tc.fs.OpenFile("file", os.O_RDWR, 0)
+			require.Zero(t, errno)
+			defer f.Close()
+
+			requireZeroLength(t, f)
+		})
+	}
+
+	// The host returns (0, nil) as well, so behavior is the same.
+	t.Run("sysfs.dirFS", func(t *testing.T) {
+		f, errno := NewDirFS(t.TempDir()).OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		requireZeroLength(t, f)
+	})
+}
+
+func requireZeroLength(t *testing.T, f fs.File) {
+	n, err := f.Read(nil)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	n, err = f.(io.ReaderAt).ReadAt(nil, 0)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	n, err = f.(io.Writer).Write(nil)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	n, err = f.(io.WriterAt).WriteAt(nil, 0)
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
@@ -0,0 +1,101 @@
+package sysfs
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// ZipFS returns a read-only FS of the files in the zip archive `r`, which is
+// `size` bytes. Directories implied by the names of files in the archive,
+// such as "dir" for "dir/file", are listed even if the archive doesn't
+// include them.
+//
+// Stored files are read directly from `r`, and support ReadAt and Seek.
+// Compressed files are decompressed on the fly, so they are only read
+// sequentially: ReadAt and Seek return syscall.ENOSYS. Symbolic links in the
+// archive are resolved within it.
+//
+// An error is returned if the archive is malformed or contains a path
+// outside of it, such as "../file".
+func ZipFS(r io.ReaderAt, size int64) (FS, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	a := newArchiveFS("zip")
+	for _, f := range zr.File {
+		name, err := archivePath(f.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		e := &archiveEntry{st: platform.Stat_t{Mode: f.Mode(), Nlink: 1}}
+		if !f.Modified.IsZero() {
+			e.st.Mtim = f.Modified.UnixNano()
+			e.st.Atim, e.st.Ctim = e.st.Mtim, e.st.Mtim
+		}
+		switch e.st.Mode.Type() {
+		case 0: // regular file
+			e.st.Size = int64(f.UncompressedSize64)
+			if e.open, err = zipOpener(r, f); err != nil {
+				return nil, err
+			}
+		case fs.ModeSymlink: // the target is the contents
+			b, errno := readZipFile(f)
+			if errno != 0 {
+				return nil, errno
+			}
+			e.link = string(b)
+			e.st.Size = int64(len(b))
+		}
+		a.add(name, e)
+	}
+	a.sortNames()
+	return a, nil
+}
+
+// zipOpener returns a function which opens the file `f` in the zip archive
+// `r`.
+func zipOpener(r io.ReaderAt, f *zip.File) (func() (io.Reader, syscall.Errno), error) {
+	if f.Method == zip.Store {
+		offset, err := f.DataOffset()
+		if err != nil {
+			return nil, err
+		}
+		size := int64(f.UncompressedSize64)
+		return func() (io.Reader, syscall.Errno) {
+			return io.NewSectionReader(r, offset, size), 0
+		}, nil
+	}
+
+	return func() (io.Reader, syscall.Errno) {
+		rc, err := f.Open()
+		if err == zip.ErrAlgorithm {
+			return nil, syscall.ENOTSUP
+		} else if err != nil {
+			return nil, syscall.EIO
+		}
+		// Hide io.ReaderAt or io.Seeker the decompressor may implement.
+		return struct{ io.ReadCloser }{rc}, 0
+	}, nil
+}
+
+// readZipFile reads the contents of the file `f`.
+func readZipFile(f *zip.File) ([]byte, syscall.Errno) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, syscall.EIO
+	}
+	defer rc.Close()
+
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, syscall.EIO
+	}
+	return b, 0
+}
@@ -0,0 +1,134 @@
+package sysfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestZipFS(t *testing.T) {
+	big := bytes.Repeat([]byte("wazero"), 32*1024) // larger than read buffers
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []struct {
+		header *zip.FileHeader
+		data   []byte
+	}{
+		{header: &zip.FileHeader{Name: "a/b/stored", Method: zip.Store}, data: []byte("stored")},
+		{header: &zip.FileHeader{Name: "a/b/deflated", Method: zip.Deflate}, data: big},
+		{header: &zip.FileHeader{Name: "a/file", Method: zip.Deflate}, data: []byte("wazero")},
+		{header: &zip.FileHeader{Name: "empty/"}},
+		{header: &zip.FileHeader{Name: "link"}, data: []byte("a/file")},
+	} {
+		if f.header.Name == "link" {
+			f.header.SetMode(fs.ModeSymlink | 0o777)
+		}
+		w, err := zw.CreateHeader(f.header)
+		require.NoError(t, err)
+		_, err = w.Write(f.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	testFS, err := ZipFS(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	t.Run("ReadDir", func(t *testing.T) {
+		requireDirNames(t, testFS, ".", "a", "empty", "link")
+		requireDirNames(t, testFS, "a", "b", "file")
+		requireDirNames(t, testFS, "a/b", "deflated", "stored")
+		requireDirNames(t, testFS, "empty")
+
+		// The fs.FS view of a directory lists only its children, too.
+		d, err := testFS.(fs.FS).Open("a")
+		require.NoError(t, err)
+		defer d.Close()
+		entries, err := d.(fs.ReadDirFile).ReadDir(-1)
+		require.NoError(t, err)
+		require.Equal(t, 2, len(entries))
+		require.Equal(t, "b", entries[0].Name())
+		require.True(t, entries[0].IsDir())
+		require.Equal(t, "file", entries[1].Name())
+	})
+
+	t.Run("Stat", func(t *testing.T) {
+		st, errno := testFS.Stat("a/b")
+		require.Zero(t, errno)
+		require.True(t, st.Mode.IsDir())
+
+		st, errno = testFS.Stat("a/b/deflated")
+		require.Zero(t, errno)
+		require.Equal(t, int64(len(big)), st.Size)
+
+		st, errno = testFS.Lstat("link")
+		require.Zero(t, errno)
+		require.Equal(t, fs.ModeSymlink, st.Mode.Type())
+		st, errno = testFS.Stat("link")
+		require.Zero(t, errno)
+		require.Equal(t, int64(6), st.Size)
+
+		_, errno = testFS.Stat("a/missing")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("stored", func(t *testing.T) {
+		f, errno := testFS.OpenFile("a/b/stored", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		require.True(t, Supports(f, CapabilityReadAt))
+		buf := make([]byte, 3)
+		n, err := f.(io.ReaderAt).ReadAt(buf, 3)
+		require.NoError(t, err)
+		require.Equal(t, "red", string(buf[:n]))
+	})
+
+	t.Run("deflated", func(t *testing.T) {
+		f, errno := testFS.OpenFile("a/b/deflated", os.O_RDONLY, 0)
+		require.Zero(t, errno)
+		defer f.Close()
+
+		var b strings.Builder
+		_, err := io.Copy(&b, f)
+		require.NoError(t, err)
+		require.Equal(t, string(big), b.String())
+
+		require.False(t, Supports(f, CapabilityReadAt))
+		require.False(t, Supports(f, CapabilitySeek))
+		_, err = f.(io.ReaderAt).ReadAt(make([]byte, 1), 0)
+		require.EqualErrno(t, syscall.ENOSYS, err.(syscall.Errno))
+	})
+
+	t.Run("symlink", func(t *testing.T) {
+		b, errno := ReadFile(testFS, "link")
+		require.Zero(t, errno)
+		require.Equal(t, "wazero", string(b))
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		_, errno := testFS.OpenFile("a/file", os.O_WRONLY, 0)
+		require.EqualErrno(t, syscall.ENOSYS, errno)
+		require.EqualErrno(t, syscall.EROFS, testFS.Rmdir("empty"))
+	})
+}
+
+func TestZipFS_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	_, err := zw.Create("../file")
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	_, err = ZipFS(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.Error(t, err)
+
+	_, err = ZipFS(strings.NewReader("not a zip"), 9)
+	require.Error(t, err)
+}
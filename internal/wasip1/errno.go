@@ -291,6 +291,8 @@ func ToErrno(errno syscall.Errno) Errno {
 		return ErrnoNametoolong
 	case syscall.ENOENT:
 		return ErrnoNoent
+	case syscall.ENOSPC:
+		return ErrnoNospc
 	case syscall.ENOSYS:
 		return ErrnoNosys
 	case syscall.ENOTDIR:
@@ -303,6 +305,8 @@ func ToErrno(errno syscall.Errno) Errno {
 		return ErrnoPerm
 	case syscall.EROFS:
 		return ErrnoRofs
+	case syscall.EXDEV:
+		return ErrnoXdev
 	default:
 		return ErrnoIo
 	}
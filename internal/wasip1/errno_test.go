@@ -75,6 +75,11 @@ func TestToErrno(t *testing.T) {
 			input:    syscall.ENOENT,
 			expected: ErrnoNoent,
 		},
+		{
+			name:     "syscall.ENOSPC",
+			input:    syscall.ENOSPC,
+			expected: ErrnoNospc,
+		},
 		{
 			name:     "syscall.ENOSYS",
 			input:    syscall.ENOSYS,
@@ -105,6 +110,11 @@ func TestToErrno(t *testing.T) {
 			input:    syscall.EROFS,
 			expected: ErrnoRofs,
 		},
+		{
+			name:     "syscall.EXDEV",
+			input:    syscall.EXDEV,
+			expected: ErrnoXdev,
+		},
 		{
 			name:     "syscall.EqualErrno unexpected == ErrnoIo",
 			input:    syscall.Errno(0xfe),
@@ -2,6 +2,7 @@ package wasip1
 
 import (
 	"fmt"
+	"io/fs"
 )
 
 const (
@@ -123,6 +124,31 @@ func FiletypeName(filetype uint8) string {
 	return fmt.Sprintf("filetype(%d)", filetype)
 }
 
+// ToFiletype returns the WASI filetype of a file with the given mode,
+// ignoring permission bits. Modes which have no WASI equivalent, such as named
+// pipes, are FILETYPE_UNKNOWN.
+func ToFiletype(fm fs.FileMode) uint8 {
+	switch {
+	case fm.IsRegular():
+		return FILETYPE_REGULAR_FILE
+	case fm&fs.ModeSymlink != 0:
+		// Check before any other type bit, as the link itself is described,
+		// not its target, the same as lstat.
+		return FILETYPE_SYMBOLIC_LINK
+	case fm.IsDir():
+		return FILETYPE_DIRECTORY
+	case fm&fs.ModeDevice != 0:
+		// Unlike ModeDevice and ModeCharDevice, FILETYPE_CHARACTER_DEVICE and
+		// FILETYPE_BLOCK_DEVICE are set mutually exclusively.
+		if fm&fs.ModeCharDevice != 0 {
+			return FILETYPE_CHARACTER_DEVICE
+		}
+		return FILETYPE_BLOCK_DEVICE
+	default: // unknown
+		return FILETYPE_UNKNOWN
+	}
+}
+
 var filetypeToString = [...]string{
 	"UNKNOWN",
 	"BLOCK_DEVICE",
@@ -0,0 +1,46 @@
+package wasip1
+
+import (
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestToFiletype(t *testing.T) {
+	tests := []struct {
+		mode     fs.FileMode
+		expected uint8
+	}{
+		{mode: 0, expected: FILETYPE_REGULAR_FILE},
+		{mode: fs.ModeDir, expected: FILETYPE_DIRECTORY},
+		{mode: fs.ModeSymlink, expected: FILETYPE_SYMBOLIC_LINK},
+		{mode: fs.ModeSymlink | fs.ModeDir, expected: FILETYPE_SYMBOLIC_LINK},
+		{mode: fs.ModeSymlink | fs.ModeDevice, expected: FILETYPE_SYMBOLIC_LINK},
+		{mode: fs.ModeSymlink | fs.ModeSocket, expected: FILETYPE_SYMBOLIC_LINK},
+		{mode: fs.ModeDevice, expected: FILETYPE_BLOCK_DEVICE},
+		{mode: fs.ModeDevice | fs.ModeCharDevice, expected: FILETYPE_CHARACTER_DEVICE},
+		{mode: fs.ModeCharDevice, expected: FILETYPE_UNKNOWN}, // needs ModeDevice
+		{mode: fs.ModeNamedPipe, expected: FILETYPE_UNKNOWN},
+		{mode: fs.ModeSocket, expected: FILETYPE_UNKNOWN},
+		{mode: fs.ModeIrregular, expected: FILETYPE_UNKNOWN},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.mode.String(), func(t *testing.T) {
+			require.Equal(t, tc.expected, ToFiletype(tc.mode))
+			// Permission bits don't affect the filetype.
+			require.Equal(t, tc.expected, ToFiletype(tc.mode|0o777))
+		})
+	}
+}
+
+func TestToFiletype_DevNull(t *testing.T) {
+	st, err := os.Stat(os.DevNull)
+	require.NoError(t, err)
+
+	// Should be a character device, and not contain permissions
+	require.Equal(t, FILETYPE_CHARACTER_DEVICE, ToFiletype(st.Mode()))
+}
@@ -148,6 +148,11 @@ func Config(fnd api.FunctionDefinition) (pSampler logging.ParamSampler, pLoggers
 			logger = logFsRightsBase(idx).Log
 		case "fs_rights_inheriting":
 			logger = logFsRightsInheriting(idx).Log
+		case "iovs_len":
+			// Follow the iovec count with the sum of their lengths, which is
+			// the size requested by the read or write.
+			pLoggers = append(pLoggers, logging.NewParamLogger(idx, name, types[idx]))
+			logger = logIovsSize(idx - 1).Log
 		case "result.nread", "result.nwritten", "result.opened_fd", "result.nevents", "result.bufused":
 			name = resultParamName(name)
 			logger = logMemI32(idx).Log
@@ -249,6 +254,25 @@ func (i logFdstat) Log(_ context.Context, mod api.Module, w logging.Writer, para
 	}
 }
 
+type logIovsSize uint32
+
+// Log writes the sum of the lengths of the iovec array at params[i], whose
+// count is params[i+1]. This writes no value if the array is out of memory.
+func (i logIovsSize) Log(_ context.Context, mod api.Module, w logging.Writer, params []uint64) {
+	w.WriteString("size=") //nolint
+	iovs, iovsCount := uint32(params[i]), uint32(params[i+1])
+	if iovsCount > 1<<16 { // avoid overflowing the byte count below
+		return
+	}
+	if buf, ok := mod.Memory().Read(iovs, iovsCount<<3); ok {
+		var size uint64
+		for ; len(buf) > 0; buf = buf[8:] {
+			size += uint64(le.Uint32(buf[4:]))
+		}
+		writeI64(w, size)
+	}
+}
+
 type logString uint32
 
 func (i logString) Log(_ context.Context, mod api.Module, w logging.Writer, params []uint64) {
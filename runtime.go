@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"sync/atomic"
 
 	"github.com/tetratelabs/wazero/api"
@@ -276,11 +277,26 @@ func (r *runtime) InstantiateModule(
 
 	code := compiled.(*compiledModule)
 	config := mConfig.(*moduleConfig)
+	if b, ok := ctx.Value(experimentalapi.StdioBufferingKey{}).(experimentalapi.StdioBuffering); ok {
+		config = config.withStdioBuffering(b)
+	}
 
 	var sysCtx *internalsys.Context
 	if sysCtx, err = config.toSysContext(); err != nil {
 		return
 	}
+	if m, ok := ctx.Value(experimentalapi.FileModeKey{}).(fs.FileMode); ok {
+		sysCtx.FS().SetFileMode(m)
+	}
+	if m, ok := ctx.Value(experimentalapi.UmaskKey{}).(fs.FileMode); ok {
+		sysCtx.FS().SetUmask(m)
+	}
+	if m, ok := ctx.Value(experimentalapi.MaxIOSizeKey{}).(uint32); ok {
+		sysCtx.FS().SetMaxIOSize(m)
+	}
+	if s, ok := ctx.Value(experimentalapi.StrictIovecsKey{}).(bool); ok {
+		sysCtx.FS().SetStrictIovecs(s)
+	}
 
 	name := config.name
 	if !config.nameSet && code.module.NameSection != nil && code.module.NameSection.ModuleName != "" {
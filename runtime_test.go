@@ -4,6 +4,7 @@ import (
 	"context"
 	_ "embed"
 	"errors"
+	"io/fs"
 	"sync"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/filecache"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/platform"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -467,6 +469,31 @@ func TestRuntime_InstantiateModule_WithName(t *testing.T) {
 	require.Nil(t, ret)
 }
 
+func TestRuntime_InstantiateModule_FSContext(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, binaryNamedZero)
+	require.NoError(t, err)
+
+	m, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("default"))
+	require.NoError(t, err)
+	require.Equal(t, internalsys.DefaultFileMode, m.(*wasm.CallContext).Sys.FS().FileMode())
+
+	require.Equal(t, internalsys.DefaultUmask, m.(*wasm.CallContext).Sys.FS().Umask())
+
+	ctx := context.WithValue(testCtx, experimental.FileModeKey{}, fs.FileMode(0o644))
+	ctx = context.WithValue(ctx, experimental.UmaskKey{}, fs.FileMode(0o022))
+	ctx = context.WithValue(ctx, experimental.MaxIOSizeKey{}, uint32(4096))
+	ctx = context.WithValue(ctx, experimental.StrictIovecsKey{}, true)
+	m, err = r.InstantiateModule(ctx, compiled, NewModuleConfig().WithName("configured"))
+	require.NoError(t, err)
+	require.Equal(t, fs.FileMode(0o644), m.(*wasm.CallContext).Sys.FS().FileMode())
+	require.Equal(t, fs.FileMode(0o022), m.(*wasm.CallContext).Sys.FS().Umask())
+	require.Equal(t, uint32(4096), m.(*wasm.CallContext).Sys.FS().MaxIOSize())
+	require.True(t, m.(*wasm.CallContext).Sys.FS().StrictIovecs())
+}
+
 func TestRuntime_InstantiateModule_ExitError(t *testing.T) {
 	r := NewRuntime(testCtx)
 	defer r.Close(testCtx)